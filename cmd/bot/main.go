@@ -1,45 +1,77 @@
-package bot
+// bot запускает Telegram-бота в режиме long polling. Клиентам он показывает каталог туров,
+// даты выезда и принимает заявку, которая попадает в CRM как лид с источником telegram.
+// Сотрудники привязывают чат командой /link и получают уведомления и списки задач.
+//
+// В режиме telegram.mode: webhook бот обслуживается основным сервером, этот процесс не нужен.
+//
+//	TELEGRAM_APITOKEN=... go run ./cmd/bot
+package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
 	"os"
-	"turcompany/internal/handlers"
+	"os/signal"
+	"syscall"
+	"time"
+	"turcompany/internal/app"
+	"turcompany/internal/config"
+	"turcompany/internal/repositories"
 	"turcompany/internal/services"
+
+	_ "github.com/lib/pq"
 )
 
-func bot() {
-	// 1. Load Config
-	token := os.Getenv("TELEGRAM_APITOKEN")
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	cfg := config.LoadConfig()
+
+	if cfg.Telegram.Mode == app.TelegramModeWebhook {
+		log.Fatal("telegram.mode: webhook — бот обслуживается основным сервером (cmd/web)")
+	}
+	token := cfg.TelegramToken()
 	if token == "" {
-		log.Panic("TELEGRAM_APITOKEN environment variable not set")
+		log.Fatal("Не задан токен бота: TELEGRAM_APITOKEN или telegram.token")
+	}
+	if cfg.Telegram.LeadOwnerID <= 0 {
+		log.Fatal("Не задан telegram.lead_owner_id — менеджер для лидов из бота")
 	}
 
-	// 2. Initialize Services and Handlers
-	tourSvc := services.NewTourService()                // <-- Create the tour service
-	tgHandlers := handlers.NewTelegramHandlers(tourSvc) // <-- Pass the service instance here
+	db, err := sql.Open("postgres", cfg.Database.DSN)
+	if err != nil {
+		log.Fatal("Ошибка подключения к базе данных: ", err)
+	}
+	defer db.Close()
 
-	// Now, initialize the bot service
-	botService, err := services.NewTelegramBotService(token)
+	botService, err := services.NewTelegramBotService(token, cfg.Telegram.Debug)
 	if err != nil {
-		log.Panic(err)
+		log.Fatal("Ошибка авторизации бота: ", err)
+	}
+	// getUpdates не работает, пока зарегистрирован webhook
+	if err := botService.DeleteWebhook(); err != nil {
+		log.Fatal("Ошибка удаления webhook: ", err)
 	}
 
-	// 3. Get the updates channel from the service
-	updates := botService.GetUpdatesChannel()
+	staffNotifier := services.NewTelegramNotifier(repositories.NewTelegramLinkRepository(db), botService.Bot)
+	tgHandlers := app.NewTelegramHandlers(cfg, db, botService, staffNotifier)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 4. Run the main loop here, in main(), not in the service
 	log.Println("Bot is running...")
-	for update := range updates {
-		if update.Message == nil {
-			continue
+	for update := range botService.GetUpdatesChannel(ctx) {
+		if err := tgHandlers.Dispatch(update); err != nil {
+			log.Printf("Telegram update %d: %v", update.UpdateID, err)
 		}
+	}
 
-		// Route the update to the correct handler
-		if update.Message.IsCommand() {
-			// Pass the bot instance from the service to the handler
-			tgHandlers.HandleCommand(botService.Bot, update.Message)
-		} else {
-			// tgHandlers.HandleMessage(botService.Bot, update.Message)
-		}
+	// Дорабатываем уже полученные обновления
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := tgHandlers.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Не все обновления Telegram обработаны: %v", err)
 	}
+	log.Println("Bot stopped")
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"turcompany/internal/benchdb"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// BenchmarkChatQueries замеряет запросы чата на самой длинной беседе данных msgbench.
+func BenchmarkChatQueries(b *testing.B) {
+	db := benchdb.Open(b)
+	ctx := context.Background()
+
+	var userID, partnerID, lastID int64
+	err := db.QueryRowContext(ctx, `
+		SELECT m.sender_id, m.receiver_id, MAX(m.id)
+		FROM messages m JOIN users u ON u.id = m.sender_id
+		WHERE u.email LIKE $1
+		GROUP BY m.sender_id, m.receiver_id
+		ORDER BY COUNT(*) DESC LIMIT 1`, benchEmailPattern).Scan(&userID, &partnerID, &lastID)
+	if err != nil {
+		b.Fatalf("Нет данных msgbench, запустите go run ./cmd/msgbench: %v", err)
+	}
+
+	repo := repositories.NewMessageRepository(db)
+	middleID := lastID / 2
+
+	queries := []struct {
+		name string
+		fn   func() error
+	}{
+		{"history_latest", func() error {
+			_, err := repo.FindConversationHistory(ctx, userID, partnerID, models.MessageCursor{Limit: 50})
+			return err
+		}},
+		{"history_before", func() error {
+			_, err := repo.FindConversationHistory(ctx, userID, partnerID, models.MessageCursor{Before: middleID, Limit: 50})
+			return err
+		}},
+		{"history_after", func() error {
+			_, err := repo.FindConversationHistory(ctx, userID, partnerID, models.MessageCursor{After: middleID, Limit: 50})
+			return err
+		}},
+		{"conversations", func() error {
+			_, err := repo.FindConversations(ctx, userID)
+			return err
+		}},
+		{"search_all", func() error {
+			_, err := repo.SearchMessages(ctx, userID, 0, "договор оплата", 50, 0)
+			return err
+		}},
+		{"search_partner", func() error {
+			_, err := repo.SearchMessages(ctx, userID, partnerID, "тур", 50, 0)
+			return err
+		}},
+	}
+
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := q.fn(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// msgbench заполняет базу синтетической перепиской для бенчмарков запросов чата:
+// постраничной истории, списка бесед и полнотекстового поиска.
+//
+// Запускать на отдельной базе с применёнными миграциями:
+//
+//	go run ./cmd/msgbench -dsn "postgres://..." -messages 1000000
+//	BENCH_DSN="postgres://..." go test -run '^$' -bench . ./cmd/msgbench
+//
+// Повторное заполнение удаляет прежние данные msgbench; бенчмарки используют уже созданные.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"time"
+	"turcompany/internal/benchdb"
+
+	_ "github.com/lib/pq"
+)
+
+const benchEmailPattern = "msgbench-%@example.test"
+
+func main() {
+	dsn := flag.String("dsn", "", "строка подключения к PostgreSQL")
+	messages := flag.Int("messages", 1000000, "количество сообщений")
+	users := flag.Int("users", 2000, "количество пользователей")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("Не задан -dsn")
+	}
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		log.Fatal("Ошибка подключения к базе данных: ", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	if err := seedData(context.Background(), db, *users, *messages); err != nil {
+		log.Fatal("Ошибка заполнения: ", err)
+	}
+	log.Printf("Заполнено %d сообщений за %s", *messages, time.Since(start).Round(time.Millisecond))
+}
+
+// seedData создаёт пользователей и сообщения одним набором INSERT ... SELECT.
+// Треть сообщений приходится на первых 20 пользователей, чтобы были длинные беседы.
+func seedData(ctx context.Context, db *sql.DB, users, messages int) error {
+	return benchdb.Exec(ctx, db, []benchdb.Step{
+		{Name: "очистка сообщений", Query: `DELETE FROM messages WHERE sender_id IN (SELECT id FROM users WHERE email LIKE $1)
+			OR receiver_id IN (SELECT id FROM users WHERE email LIKE $1)`, Args: []interface{}{benchEmailPattern}},
+		{Name: "очистка пользователей", Query: `DELETE FROM users WHERE email LIKE $1`, Args: []interface{}{benchEmailPattern}},
+		{Name: "пользователи", Query: `INSERT INTO users (company_name, email, password_hash)
+			SELECT 'Bench ' || g, 'msgbench-' || g || '@example.test', '-'
+			FROM generate_series(1, $1) g`, Args: []interface{}{users}},
+		{Name: "сообщения", Query: `
+			WITH bench AS (
+				SELECT array_agg(id ORDER BY id) AS ids FROM users WHERE email LIKE $1
+			), words AS (
+				SELECT ARRAY['тур', 'договор', 'оплата', 'виза', 'отель', 'перелёт', 'счёт', 'скидка',
+				             'трансфер', 'страховка', 'booking', 'invoice', 'hotel', 'flight', 'visa'] AS w
+			), pairs AS (
+				SELECT g,
+					CASE WHEN g % 3 = 0 THEN 1 + (g / 3) % 20 ELSE 1 + floor(random() * $2::int)::int END AS a,
+					1 + floor(random() * $2::int)::int AS b
+				FROM generate_series(1, $3::int) g
+			)
+			INSERT INTO messages (sender_id, receiver_id, content, sent_at, read_at)
+			SELECT
+				bench.ids[p.a], bench.ids[CASE WHEN p.b = p.a THEN 1 + p.b % $2::int ELSE p.b END],
+				words.w[1 + (p.g % 15)] || ' ' || words.w[1 + ((p.g / 15) % 15)] || ' ' || md5(p.g::text),
+				NOW() - make_interval(secs => $3::int - p.g),
+				CASE WHEN random() < 0.9 THEN NOW() END
+			FROM pairs p, bench, words
+			ORDER BY p.g`, Args: []interface{}{benchEmailPattern, users, messages}},
+		{Name: "статистика", Query: `ANALYZE messages`},
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"turcompany/internal/benchdb"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+	"turcompany/internal/services"
+)
+
+// dashboardBudget время ответа дашборда, которое должно выдерживаться на миллионе лидов.
+const dashboardBudget = 100 * time.Millisecond
+
+// BenchmarkReports сравнивает дашборд по сводкам с отчётами по живым таблицам на данных
+// reportbench. Дашборд, который в среднем медленнее dashboardBudget, проваливает бенчмарк.
+func BenchmarkReports(b *testing.B) {
+	db := benchdb.Open(b)
+	ctx := context.Background()
+
+	var ownerID int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MIN(id), 0) FROM users WHERE email LIKE $1`, benchEmailPattern).Scan(&ownerID)
+	if err != nil || ownerID == 0 {
+		b.Fatalf("Нет данных reportbench, запустите go run ./cmd/reportbench: %v", err)
+	}
+
+	reportService := services.NewReportService(
+		repositories.NewLeadRepository(db),
+		repositories.NewDealRepository(db),
+		repositories.NewReportRepository(db),
+		services.RevenuePolicy{BaseCurrency: "KZT", ExchangeRates: map[string]float64{"USD": 480, "EUR": 520}},
+	)
+	now := time.Now().UTC()
+	from := now.AddDate(-1, 0, 0)
+	year := models.ReportFilter{From: &from, To: &now}
+	owner := year
+	owner.OwnerID = &ownerID
+
+	queries := []struct {
+		name string
+		fn   func() error
+	}{
+		{"dashboard", func() error {
+			_, err := reportService.Dashboard(ctx, models.ReportFilter{}, "")
+			return err
+		}},
+		{"dashboard_daily", func() error {
+			_, err := reportService.Dashboard(ctx, models.ReportFilter{Interval: models.ReportIntervalDay, From: &from}, "")
+			return err
+		}},
+		{"dashboard_owner", func() error {
+			_, err := reportService.Dashboard(ctx, models.ReportFilter{OwnerID: &ownerID}, "USD")
+			return err
+		}},
+		{"live_summary", func() error {
+			_, err := reportService.GetSummary()
+			return err
+		}},
+		{"live_funnel", func() error {
+			_, err := reportService.Funnel(ctx, year)
+			return err
+		}},
+		{"live_conversion", func() error {
+			_, err := reportService.Conversion(ctx, year)
+			return err
+		}},
+		{"live_funnel_owner", func() error {
+			_, err := reportService.Funnel(ctx, owner)
+			return err
+		}},
+	}
+
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := q.fn(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if perOp := b.Elapsed() / time.Duration(b.N); strings.HasPrefix(q.name, "dashboard") && perOp > dashboardBudget {
+				b.Errorf("%s: %s на запрос, больше %s", q.name, perOp.Round(time.Microsecond), dashboardBudget)
+			}
+		})
+	}
+}
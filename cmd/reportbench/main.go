@@ -0,0 +1,122 @@
+// reportbench заполняет базу синтетическими лидами и сделками и обновляет дневные сводки
+// для бенчмарков дашборда по сводкам и отчётов, которые считаются по живым таблицам.
+//
+// Запускать на отдельной базе с применёнными миграциями от имени владельца таблиц
+// (на время заполнения отключаются триггеры сводок):
+//
+//	go run ./cmd/reportbench -dsn "postgres://..." -leads 1000000
+//	BENCH_DSN="postgres://..." go test -run '^$' -bench . ./cmd/reportbench
+//
+// Повторное заполнение удаляет прежние данные reportbench; бенчмарки используют уже созданные.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"time"
+	"turcompany/internal/benchdb"
+	"turcompany/internal/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+const benchEmailPattern = "reportbench-%@example.test"
+
+func main() {
+	dsn := flag.String("dsn", "", "строка подключения к PostgreSQL")
+	leads := flag.Int("leads", 1000000, "количество лидов")
+	users := flag.Int("users", 200, "количество менеджеров")
+	days := flag.Int("days", 730, "за сколько дней до сегодня распределить лиды")
+	batch := flag.Int("batch", 50, "дней в одной транзакции обновления сводок")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("Не задан -dsn")
+	}
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		log.Fatal("Ошибка подключения к базе данных: ", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	start := time.Now()
+	if err := seedData(ctx, db, *users, *leads, *days); err != nil {
+		log.Fatal("Ошибка заполнения: ", err)
+	}
+	log.Printf("Заполнено %d лидов за %s", *leads, time.Since(start).Round(time.Millisecond))
+
+	start = time.Now()
+	reportRepo := repositories.NewReportRepository(db)
+	refreshed := 0
+	for {
+		n, err := reportRepo.RefreshRollups(ctx, *batch, time.Now())
+		if err != nil {
+			log.Fatal("Ошибка обновления сводок: ", err)
+		}
+		refreshed += n
+		if n < *batch {
+			break
+		}
+	}
+	log.Printf("Пересчитано %d дней сводок за %s", refreshed, time.Since(start).Round(time.Millisecond))
+}
+
+// seedData создаёт менеджеров, лиды и сделки по трети лидов одним набором INSERT ... SELECT
+// и ставит в очередь все дни сводок. Всё выполняется в одной транзакции: триггеры сводок
+// отключаются только внутри неё, и при ошибке откат включает их обратно.
+func seedData(ctx context.Context, db *sql.DB, users, leads, days int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = benchdb.Exec(ctx, tx, []benchdb.Step{
+		{Name: "отключение триггеров", Query: `ALTER TABLE leads DISABLE TRIGGER report_rollup_leads;
+			ALTER TABLE deals DISABLE TRIGGER report_rollup_deals`},
+		{Name: "очистка сделок", Query: `DELETE FROM deals WHERE lead_id IN (SELECT l.id FROM leads l
+			JOIN users u ON u.id = l.owner_id WHERE u.email LIKE $1)`, Args: []interface{}{benchEmailPattern}},
+		{Name: "очистка лидов", Query: `DELETE FROM leads WHERE owner_id IN (SELECT id FROM users WHERE email LIKE $1)`,
+			Args: []interface{}{benchEmailPattern}},
+		{Name: "очистка пользователей", Query: `DELETE FROM users WHERE email LIKE $1`, Args: []interface{}{benchEmailPattern}},
+		{Name: "пользователи", Query: `INSERT INTO users (company_name, email, password_hash)
+			SELECT 'Bench ' || g, 'reportbench-' || g || '@example.test', '-'
+			FROM generate_series(1, $1) g`, Args: []interface{}{users}},
+		{Name: "лиды", Query: `
+			WITH bench AS (
+				SELECT array_agg(id ORDER BY id) AS ids FROM users WHERE email LIKE $1
+			)
+			INSERT INTO leads (title, owner_id, status, source, created_at)
+			SELECT 'Лид ' || g, bench.ids[1 + g % $2::int],
+				(ARRAY['new', 'in_progress', 'qualified', 'converted', 'lost'])[1 + g % 5],
+				(ARRAY['crm', 'telegram', 'email', 'site'])[1 + (g / 5) % 4],
+				NOW() - make_interval(secs => floor(random() * $4::int * 86400)::int)
+			FROM generate_series(1, $3::int) g, bench`, Args: []interface{}{benchEmailPattern, users, leads, days}},
+		{Name: "сделки", Query: `
+			INSERT INTO deals (lead_id, owner_id, amount, currency, status, created_at)
+			SELECT l.id, l.owner_id, (100 + floor(random() * 5000))::text,
+				(ARRAY['KZT', 'KZT', 'USD', 'EUR'])[1 + l.id % 4],
+				(ARRAY['new', 'negotiation', 'contract', 'paid', 'won', 'lost'])[1 + l.id % 6],
+				l.created_at + make_interval(hours => 1 + (l.id % 72)::int)
+			FROM leads l JOIN users u ON u.id = l.owner_id
+			WHERE u.email LIKE $1 AND l.id % 3 = 0`, Args: []interface{}{benchEmailPattern}},
+		{Name: "включение триггеров", Query: `ALTER TABLE leads ENABLE TRIGGER report_rollup_leads;
+			ALTER TABLE deals ENABLE TRIGGER report_rollup_deals`},
+		{Name: "очередь сводок", Query: `
+			INSERT INTO report_rollup_queue (entity, day)
+			SELECT 'lead', created_at::date FROM leads WHERE created_at IS NOT NULL
+			UNION SELECT 'lead', day FROM report_lead_daily
+			UNION SELECT 'deal', (created_at AT TIME ZONE 'UTC')::date FROM deals
+			UNION SELECT 'deal', day FROM report_deal_daily
+			ON CONFLICT DO NOTHING`},
+		{Name: "статистика", Query: `ANALYZE leads; ANALYZE deals`},
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
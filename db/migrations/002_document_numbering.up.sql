@@ -0,0 +1,11 @@
+-- Счётчики номеров документов (отдельно по типу и году)
+CREATE TABLE IF NOT EXISTS document_number_sequences (
+    doc_type VARCHAR(100) NOT NULL,
+    year INT NOT NULL,
+    last_value BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (doc_type, year)
+);
+
+-- Номер документа, выданный при создании
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS number VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS documents_number_key ON documents (number);
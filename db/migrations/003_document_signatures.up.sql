@@ -0,0 +1,12 @@
+-- Электронные подписи документов (подтверждение по SMS)
+CREATE TABLE IF NOT EXISTS document_signatures (
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL UNIQUE REFERENCES documents(id) ON DELETE CASCADE,
+    sms_confirmation_id INT REFERENCES sms_confirmations(id) ON DELETE SET NULL,
+    phone VARCHAR(20) NOT NULL,
+    ip VARCHAR(64),
+    user_agent TEXT,
+    file_path VARCHAR(255) NOT NULL,
+    file_hash CHAR(64) NOT NULL, -- SHA-256 подписанного файла (hex)
+    signed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- Коды подтверждения хранятся в виде солёного SHA-256, а не открытым текстом
+ALTER TABLE sms_confirmations ADD COLUMN IF NOT EXISTS code_salt VARCHAR(64);
+ALTER TABLE sms_confirmations ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+-- Старые коды в открытом виде больше не проверяются
+UPDATE sms_confirmations SET confirmed = TRUE WHERE code_salt IS NULL AND confirmed = FALSE;
+
+-- Подсчёт отправок на номер за сутки
+CREATE INDEX IF NOT EXISTS sms_confirmations_phone_sent_at_idx ON sms_confirmations (phone, sent_at);
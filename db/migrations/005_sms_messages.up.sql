@@ -0,0 +1,17 @@
+-- Журнал исходящих SMS и статусов их доставки
+CREATE TABLE IF NOT EXISTS sms_messages (
+    id SERIAL PRIMARY KEY,
+    document_id INT REFERENCES documents(id) ON DELETE SET NULL,
+    provider VARCHAR(50),
+    message_id VARCHAR(100),
+    recipient VARCHAR(20) NOT NULL,
+    template TEXT NOT NULL, -- текст без подставленного кода
+    cost NUMERIC(12, 4),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, sent, delivered, failed
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sms_messages_recipient_idx ON sms_messages (recipient, created_at DESC);
+CREATE INDEX IF NOT EXISTS sms_messages_status_idx ON sms_messages (status, created_at);
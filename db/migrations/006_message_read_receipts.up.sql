@@ -0,0 +1,5 @@
+-- Время прочтения сообщения получателем (NULL — не прочитано)
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMPTZ;
+
+-- Быстрый подсчёт непрочитанных сообщений получателя
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON messages (receiver_id, sender_id) WHERE read_at IS NULL;
@@ -0,0 +1,9 @@
+-- Индексы для постраничной истории беседы (keyset по id) и списка бесед
+CREATE INDEX IF NOT EXISTS messages_sender_receiver_id_idx ON messages (sender_id, receiver_id, id);
+CREATE INDEX IF NOT EXISTS messages_receiver_sender_id_idx ON messages (receiver_id, sender_id, id);
+
+-- Полнотекстовый поиск по содержимому сообщений.
+-- Конфигурация 'simple': переписка ведётся и на русском, и на английском.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+CREATE INDEX IF NOT EXISTS messages_content_tsv_idx ON messages USING GIN (content_tsv);
@@ -0,0 +1,43 @@
+-- Групповые беседы; могут быть привязаны к лиду, сделке или документу (entity_type/entity_id, как у задач)
+CREATE TABLE IF NOT EXISTS conversations (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(100), -- 'lead', 'deal' или 'document'
+    entity_id INT,
+    created_by INT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((entity_type IS NULL) = (entity_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS conversations_entity_idx ON conversations (entity_type, entity_id);
+
+-- Участники беседы и их позиция прочтения
+CREATE TABLE IF NOT EXISTS conversation_participants (
+    conversation_id INT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id),
+    role VARCHAR(20) NOT NULL DEFAULT 'member', -- 'owner' или 'member'
+    last_read_message_id INT NOT NULL DEFAULT 0,
+    last_read_at TIMESTAMPTZ,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS conversation_participants_user_idx ON conversation_participants (user_id);
+
+-- Сообщение адресовано либо получателю, либо беседе
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS conversation_id INT REFERENCES conversations(id) ON DELETE CASCADE;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_target_check;
+ALTER TABLE messages ADD CONSTRAINT messages_target_check
+    CHECK ((receiver_id IS NULL) <> (conversation_id IS NULL)) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS messages_conversation_id_idx ON messages (conversation_id, id) WHERE conversation_id IS NOT NULL;
+
+-- Упоминания пользователей в сообщениях бесед
+CREATE TABLE IF NOT EXISTS message_mentions (
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id),
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS message_mentions_user_idx ON message_mentions (user_id, message_id);
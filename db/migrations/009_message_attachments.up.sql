@@ -0,0 +1,16 @@
+-- Вложения сообщений. Файл загружается до отправки (message_id IS NULL)
+-- и привязывается к сообщению при отправке.
+CREATE TABLE IF NOT EXISTS message_attachments (
+    id SERIAL PRIMARY KEY,
+    message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+    uploader_id INT NOT NULL REFERENCES users(id),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    file_path TEXT NOT NULL,
+    thumbnail_path TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS message_attachments_message_idx ON message_attachments (message_id);
+CREATE INDEX IF NOT EXISTS message_attachments_pending_idx ON message_attachments (uploader_id) WHERE message_id IS NULL;
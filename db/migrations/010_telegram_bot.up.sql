@@ -0,0 +1,34 @@
+-- Каталог туров для Telegram-бота
+CREATE TABLE IF NOT EXISTS tours (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Даты выезда тура; price переопределяет цену тура, если задана
+CREATE TABLE IF NOT EXISTS tour_departures (
+    id SERIAL PRIMARY KEY,
+    tour_id INT NOT NULL REFERENCES tours(id) ON DELETE CASCADE,
+    departs_at TIMESTAMPTZ NOT NULL,
+    returns_at TIMESTAMPTZ NOT NULL,
+    seats INT NOT NULL DEFAULT 0,
+    price NUMERIC(12, 2),
+    CHECK (returns_at >= departs_at)
+);
+
+CREATE INDEX IF NOT EXISTS tour_departures_tour_idx ON tour_departures (tour_id, departs_at);
+
+-- Источник лида: crm (создан менеджером), telegram и т.д.
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'crm';
+
+-- Состояние диалога с ботом для каждого чата
+CREATE TABLE IF NOT EXISTS telegram_sessions (
+    chat_id BIGINT PRIMARY KEY,
+    state VARCHAR(50) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
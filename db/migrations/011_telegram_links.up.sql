@@ -0,0 +1,23 @@
+-- Привязка Telegram-чата к пользователю CRM для уведомлений сотрудникам
+CREATE TABLE IF NOT EXISTS telegram_links (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    chat_id BIGINT NOT NULL UNIQUE,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    linked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Одноразовые коды привязки; хранится только SHA-256 кода
+CREATE TABLE IF NOT EXISTS telegram_link_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS telegram_link_codes_user_idx ON telegram_link_codes (user_id);
+
+-- Отметка об отправленном напоминании о сроке задачи
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_notified_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS tasks_due_notify_idx ON tasks (due_date)
+    WHERE due_notified_at IS NULL AND status IN ('new', 'in_progress');
@@ -0,0 +1,42 @@
+-- Контакты лида для сопоставления входящих сообщений
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS phone VARCHAR(32);
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS telegram_chat_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS leads_phone_idx ON leads (phone) WHERE phone IS NOT NULL;
+CREATE INDEX IF NOT EXISTS leads_email_idx ON leads (LOWER(email)) WHERE email IS NOT NULL;
+CREATE INDEX IF NOT EXISTS leads_telegram_chat_idx ON leads (telegram_chat_id) WHERE telegram_chat_id IS NOT NULL;
+
+-- Единая лента переписки с клиентом: одна на лид, сообщения из всех каналов
+CREATE TABLE IF NOT EXISTS inbox_threads (
+    id SERIAL PRIMARY KEY,
+    lead_id INT NOT NULL UNIQUE REFERENCES leads(id) ON DELETE CASCADE,
+    last_channel VARCHAR(20) NOT NULL,
+    last_message_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    unread_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS inbox_threads_last_message_idx ON inbox_threads (last_message_at DESC, id DESC);
+
+-- channel: telegram, email или internal (заметка сотрудника, клиенту не отправляется);
+-- direction: in — от клиента, out — от сотрудника; address — адрес клиента в канале
+CREATE TABLE IF NOT EXISTS inbox_messages (
+    id SERIAL PRIMARY KEY,
+    thread_id INT NOT NULL REFERENCES inbox_threads(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    direction VARCHAR(3) NOT NULL CHECK (direction IN ('in', 'out')),
+    author_id INT REFERENCES users(id) ON DELETE SET NULL,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    subject VARCHAR(500) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    external_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS inbox_messages_thread_idx ON inbox_messages (thread_id, id);
+-- Повторно доставленное входящее (webhook, письмо) не дублируется
+CREATE UNIQUE INDEX IF NOT EXISTS inbox_messages_inbound_uniq ON inbox_messages (channel, external_id)
+    WHERE direction = 'in' AND external_id IS NOT NULL;
@@ -0,0 +1,41 @@
+-- Руководитель сотрудника, которому эскалируются просроченные задачи
+ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_id INT REFERENCES users(id) ON DELETE SET NULL;
+
+-- Отметки о просрочке и эскалации; сбрасываются при переносе срока
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS overdue_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS tasks_open_due_idx ON tasks (due_date)
+    WHERE due_date IS NOT NULL AND status IN ('new', 'in_progress');
+
+-- Отправленные напоминания: одно на этап и срок задачи, новый срок — новые напоминания
+CREATE TABLE IF NOT EXISTS task_reminders (
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    stage VARCHAR(20) NOT NULL,
+    due_date TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (task_id, stage, due_date)
+);
+
+-- Напоминания теперь ведёт task_reminders
+DROP INDEX IF EXISTS tasks_due_notify_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_notified_at;
+
+-- Уведомления в интерфейсе CRM
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Аренда фоновых заданий: на нескольких экземплярах задание выполняет только держатель
+CREATE TABLE IF NOT EXISTS scheduler_leases (
+    name VARCHAR(100) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,47 @@
+-- Время создания и изменения задач, которые приложение пишет с самого начала
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Повторяющиеся задачи: правило RRULE и серия, к которой относится повторение
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS series_id INT REFERENCES tasks(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS series_start TIMESTAMPTZ;
+
+-- Одно повторение серии на дату, даже при одновременном закрытии задачи
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_series_due_idx ON tasks (series_id, due_date) WHERE series_id IS NOT NULL;
+
+-- Дата вылета тура по сделке, от неё считаются сроки задач из шаблонов
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS departure_date TIMESTAMPTZ;
+
+-- Шаблоны задач, создаваемых при переходе сделки в этап
+CREATE TABLE IF NOT EXISTS task_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    deal_status VARCHAR(50) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS task_templates_status_idx ON task_templates (deal_status) WHERE active;
+
+-- Задачи шаблона; срок — offset_days от даты вылета (отрицательный — до вылета)
+CREATE TABLE IF NOT EXISTS task_template_items (
+    id SERIAL PRIMARY KEY,
+    template_id INT NOT NULL REFERENCES task_templates(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    assignee_id INT REFERENCES users(id) ON DELETE SET NULL,
+    offset_days INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS task_template_items_template_idx ON task_template_items (template_id, position);
+
+-- Применённые к сделкам шаблоны: задачи по шаблону создаются один раз
+CREATE TABLE IF NOT EXISTS task_template_runs (
+    template_id INT NOT NULL REFERENCES task_templates(id) ON DELETE CASCADE,
+    deal_id INT NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_id, deal_id)
+);
@@ -0,0 +1,16 @@
+-- Лента событий по лидам и сделкам: кто и что сделал с клиентом
+CREATE TABLE IF NOT EXISTS activities (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INT NOT NULL,
+    lead_id INT REFERENCES leads(id) ON DELETE CASCADE,
+    deal_id INT REFERENCES deals(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS activities_lead_idx ON activities (lead_id, created_at DESC) WHERE lead_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS activities_deal_idx ON activities (deal_id, created_at DESC) WHERE deal_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Причина проигрыша сделки (статус lost) для отчёта по выигранным и проигранным сделкам
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS loss_reason TEXT NOT NULL DEFAULT '';
+
+-- Отчёты группируют лиды и сделки по владельцу, источнику и дате создания
+CREATE INDEX IF NOT EXISTS leads_created_at_idx ON leads (created_at);
+CREATE INDEX IF NOT EXISTS deals_lead_id_idx ON deals (lead_id);
+
+-- История этапов строится по событиям смены статуса
+CREATE INDEX IF NOT EXISTS activities_status_changes_idx ON activities (entity_type, entity_id, created_at)
+    WHERE type IN ('lead_status_changed', 'deal_status_changed');
@@ -0,0 +1,5 @@
+-- Направление тура для отчёта по выручке
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS destination VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Выручка и прогноз выбирают сделки по статусу
+CREATE INDEX IF NOT EXISTS deals_status_idx ON deals (status);
@@ -0,0 +1,36 @@
+-- Сохранённые отчёты, которые рассылаются по расписанию cron
+CREATE TABLE IF NOT EXISTS scheduled_reports (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    report_type VARCHAR(50) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    format VARCHAR(10) NOT NULL,
+    lang VARCHAR(5) NOT NULL DEFAULT 'ru',
+    recipients TEXT[] NOT NULL,
+    schedule VARCHAR(100) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_reports_due_idx ON scheduled_reports (next_run_at) WHERE active;
+
+-- История отправок: по расписанию и вручную
+CREATE TABLE IF NOT EXISTS scheduled_report_deliveries (
+    id SERIAL PRIMARY KEY,
+    report_id INT NOT NULL REFERENCES scheduled_reports(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    recipients TEXT[] NOT NULL,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    file_size INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    triggered_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_report_deliveries_report_idx ON scheduled_report_deliveries (report_id, id DESC);
@@ -0,0 +1,96 @@
+-- Дневные сводки для дашборда: лиды и сделки, созданные за день, по владельцу лида,
+-- источнику и текущему статусу. Сделки без лида попадают в сводку с owner_id = 0.
+CREATE TABLE IF NOT EXISTS report_lead_daily (
+    day DATE NOT NULL,
+    owner_id INT NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    status VARCHAR(100) NOT NULL,
+    leads INT NOT NULL,
+    converted INT NOT NULL, -- лиды, по которым есть сделка
+    PRIMARY KEY (day, owner_id, source, status)
+);
+
+CREATE TABLE IF NOT EXISTS report_deal_daily (
+    day DATE NOT NULL,
+    owner_id INT NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    status VARCHAR(100) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    deals INT NOT NULL,
+    amount NUMERIC NOT NULL,
+    PRIMARY KEY (day, owner_id, source, status, currency)
+);
+
+-- Дни, сводки за которые нужно пересчитать. Заполняется триггерами, разбирается фоновым
+-- обновлением; строку обновляют, а не пропускают, чтобы обновление не забрало день,
+-- изменение которого ещё не закоммичено.
+CREATE TABLE IF NOT EXISTS report_rollup_queue (
+    entity VARCHAR(10) NOT NULL, -- lead или deal
+    day DATE NOT NULL,
+    marked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity, day)
+);
+
+CREATE TABLE IF NOT EXISTS report_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    refreshed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Пересчёт дня выбирает сделки по дате создания
+CREATE INDEX IF NOT EXISTS deals_created_at_idx ON deals (created_at);
+
+-- report_rollup_mark ставит день в очередь; $1 — lead или deal, $2 — день (NULL пропускается)
+CREATE OR REPLACE FUNCTION report_rollup_mark(TEXT, DATE) RETURNS VOID AS $$
+    INSERT INTO report_rollup_queue (entity, day) SELECT $1, $2 WHERE $2 IS NOT NULL
+    ON CONFLICT (entity, day) DO UPDATE SET marked_at = NOW();
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION report_rollup_leads_changed() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM report_rollup_mark('lead', OLD.created_at::date);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM report_rollup_mark('lead', NEW.created_at::date);
+    END IF;
+    -- Сделки берут владельца и источник у лида
+    IF TG_OP = 'UPDATE' AND (NEW.owner_id IS DISTINCT FROM OLD.owner_id OR NEW.source IS DISTINCT FROM OLD.source) THEN
+        PERFORM report_rollup_mark('deal', (d.created_at AT TIME ZONE 'UTC')::date)
+        FROM deals d WHERE d.lead_id = NEW.id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION report_rollup_deals_changed() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM report_rollup_mark('deal', (OLD.created_at AT TIME ZONE 'UTC')::date);
+        -- Лид мог перестать считаться сконвертированным
+        PERFORM report_rollup_mark('lead', l.created_at::date) FROM leads l WHERE l.id = OLD.lead_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM report_rollup_mark('deal', (NEW.created_at AT TIME ZONE 'UTC')::date);
+        PERFORM report_rollup_mark('lead', l.created_at::date) FROM leads l WHERE l.id = NEW.lead_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS report_rollup_leads ON leads;
+CREATE TRIGGER report_rollup_leads
+    AFTER INSERT OR DELETE OR UPDATE OF owner_id, source, status, created_at ON leads
+    FOR EACH ROW EXECUTE FUNCTION report_rollup_leads_changed();
+
+DROP TRIGGER IF EXISTS report_rollup_deals ON deals;
+CREATE TRIGGER report_rollup_deals
+    AFTER INSERT OR DELETE OR UPDATE OF lead_id, amount, currency, status, created_at ON deals
+    FOR EACH ROW EXECUTE FUNCTION report_rollup_deals_changed();
+
+-- Существующие данные попадают в сводки при первых обновлениях
+INSERT INTO report_rollup_queue (entity, day)
+SELECT DISTINCT 'lead', created_at::date FROM leads WHERE created_at IS NOT NULL
+ON CONFLICT DO NOTHING;
+INSERT INTO report_rollup_queue (entity, day)
+SELECT DISTINCT 'deal', (created_at AT TIME ZONE 'UTC')::date FROM deals
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Пауза между кодами по документу считается по журналу sms_messages
+CREATE INDEX IF NOT EXISTS sms_messages_document_idx ON sms_messages (document_id, created_at DESC);
+
+-- Суточный лимит на номер больше не считается по sms_confirmations
+DROP INDEX IF EXISTS sms_confirmations_phone_sent_at_idx;
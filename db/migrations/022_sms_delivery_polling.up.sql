@@ -0,0 +1,6 @@
+-- Время последнего опроса статуса: трекер доставки идёт по кругу,
+-- а не опрашивает одни и те же старые сообщения
+ALTER TABLE sms_messages ADD COLUMN IF NOT EXISTS polled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS sms_messages_pending_idx ON sms_messages (polled_at NULLS FIRST, created_at)
+    WHERE status IN ('queued', 'sent') AND message_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Причина, по которой отчёт перестал рассылаться по расписанию (например, у расписания
+-- нет следующего запуска); очищается при сохранении отчёта
+ALTER TABLE scheduled_reports ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
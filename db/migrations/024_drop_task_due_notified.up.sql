@@ -0,0 +1,4 @@
+-- Напоминания о сроках задач ведёт task_reminders (013); отметка в самой задаче
+-- больше не используется
+DROP INDEX IF EXISTS tasks_due_notify_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_notified_at;
@@ -1,17 +1,29 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"turcompany/internal/config"
 	"turcompany/internal/handlers"
+	"turcompany/internal/mailbox"
+	"turcompany/internal/models"
+	"turcompany/internal/realtime"
 	"turcompany/internal/repositories"
 	"turcompany/internal/routes"
 	"turcompany/internal/services"
-	"turcompany/internal/utils"
+	"turcompany/internal/smsprovider"
+	"turcompany/internal/storage"
 
 	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	_ "github.com/lib/pq" // Подключение базы данных PostgreSQL
 
 	swaggerFiles "github.com/swaggo/files" // Импорт файлов для Swagger с alias
@@ -19,6 +31,9 @@ import (
 	_ "turcompany/docs"                    // Сгенерированная документация Swagger
 )
 
+// shutdownTimeout — сколько ждать завершения запросов и очереди бота при остановке
+const shutdownTimeout = 30 * time.Second
+
 func Run() {
 	cfg := config.LoadConfig()
 
@@ -39,9 +54,48 @@ func Run() {
 	leadRepo := repositories.NewLeadRepository(db)
 	dealRepo := repositories.NewDealRepository(db)
 	documentRepo := repositories.NewDocumentRepository(db)
+	documentNumberRepo := repositories.NewDocumentNumberRepository(db)
+	documentSignatureRepo := repositories.NewDocumentSignatureRepository(db)
 	taskRepo := repositories.NewTaskRepository(db)
+	taskChecklistRepo := repositories.NewTaskChecklistRepository(db)
+	taskCommentRepo := repositories.NewTaskCommentRepository(db)
+	taskActivityRepo := repositories.NewTaskActivityRepository(db)
+	taskTemplateRepo := repositories.NewTaskTemplateRepository(db)
+	activityRepo := repositories.NewActivityRepository(db)
 	messageRepo := repositories.NewMessageRepository(db)
+	conversationRepo := repositories.NewConversationRepository(db)
+	attachmentRepo := repositories.NewMessageAttachmentRepository(db)
 	smsRepo := repositories.NewSMSConfirmationRepository(db)
+	smsMessageRepo := repositories.NewSMSMessageRepository(db)
+	telegramLinkRepo := repositories.NewTelegramLinkRepository(db)
+	inboxRepo := repositories.NewInboxRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	leaseRepo := repositories.NewLeaseRepository(db)
+	reportRepo := repositories.NewReportRepository(db)
+	scheduledReportRepo := repositories.NewScheduledReportRepository(db)
+	reportBuilderRepo := repositories.NewReportBuilderRepository(db)
+
+	// Чат в реальном времени (события между экземплярами через LISTEN/NOTIFY)
+	chatHub := realtime.NewHub(realtime.NewPGBroker(db, cfg.Database.DSN, cfg.Chat.NotifyChannel), messageRepo)
+	if err := chatHub.Run(context.Background()); err != nil {
+		log.Printf("Чат работает без синхронизации между экземплярами: %v", err)
+	}
+
+	// Файловое хранилище документов и вложений
+	fileStorage := storage.NewLocal("placeholder-secret")
+
+	// Уведомления сотрудникам в Telegram; без токена бота отключены
+	var telegramBot *services.TelegramBotService
+	var botAPI *tgbotapi.BotAPI
+	if token := cfg.TelegramToken(); token != "" {
+		telegramBot, err = services.NewTelegramBotService(token, cfg.Telegram.Debug)
+		if err != nil {
+			log.Printf("Уведомления в Telegram отключены: %v", err)
+		} else {
+			botAPI = telegramBot.Bot
+		}
+	}
+	staffNotifier := services.NewTelegramNotifier(telegramLinkRepo, botAPI)
 
 	// Сервисы
 	authService := services.NewAuthService()
@@ -54,16 +108,69 @@ func Run() {
 	)
 	roleService := services.NewRoleService(roleRepo)
 	userService := services.NewUserService(userRepo, emailService, authService)
-	leadService := services.NewLeadService(leadRepo, dealRepo)
-	dealService := services.NewDealService(dealRepo)
-	documentService := services.NewDocumentService(documentRepo, leadRepo, dealRepo, smsRepo, "placeholder-secret")
-	taskService := services.NewTaskService(taskRepo)
-	messageService := services.NewMessageService(messageRepo)
-	mobizonClient := utils.NewClient("kzfaad0a91a4b498db593b78414dfdaa2c213b8b8996afa325a223543481efeb11dd11")
-	smsService := services.NewSMSService(smsRepo, mobizonClient)
+	activityService := services.NewActivityService(activityRepo, leadRepo, dealRepo, documentRepo)
+	taskTemplateService := services.NewTaskTemplateService(taskTemplateRepo, dealRepo, leadRepo)
+	leadService := services.NewLeadService(leadRepo, dealRepo, staffNotifier, taskTemplateService, activityService)
+	dealService := services.NewDealService(dealRepo, taskTemplateService, activityService)
+	documentNumberingService := services.NewDocumentNumberingService(documentNumberRepo, cfg.Documents.Numbering)
+	documentService := services.NewDocumentService(documentRepo, leadRepo, dealRepo, smsRepo, documentNumberingService, fileStorage, activityService)
+	taskService := services.NewTaskService(taskRepo, taskChecklistRepo, taskCommentRepo, taskActivityRepo, activityService)
+	messageService := services.NewMessageService(messageRepo, attachmentRepo, chatHub)
+	conversationService := services.NewConversationService(conversationRepo, attachmentRepo, chatHub, activityService)
+	attachmentPolicy := services.NewAttachmentPolicy(
+		cfg.Chat.Attachments.MaxSize,
+		cfg.Chat.Attachments.AllowedTypes,
+		cfg.Chat.Attachments.ThumbnailSize,
+		cfg.Chat.Attachments.PendingTTL,
+	)
+	attachmentService := services.NewAttachmentService(attachmentRepo, conversationRepo, fileStorage, attachmentPolicy)
+	smsProvider := newSMSProvider(cfg)
+	smsPolicy := services.NewSMSPolicy(
+		cfg.SMS.CodeTTL,
+		cfg.SMS.MaxAttempts,
+		cfg.SMS.ResendCooldown,
+		cfg.SMS.DailyLimitPerPhone,
+	)
+	smsService := services.NewSMSService(smsRepo, smsMessageRepo, smsProvider, smsPolicy, activityService)
+	smsDeliveryTracker := services.NewSMSDeliveryTracker(smsMessageRepo, smsProvider, cfg.SMS.DeliveryPoll.Window)
+	telegramLinkService := services.NewTelegramLinkService(telegramLinkRepo, cfg.Telegram.LinkCodeTTL, cfg.Telegram.BotUsername)
+	documentSigningService := services.NewDocumentSigningService(documentRepo, documentSignatureRepo, documentService, smsService, staffNotifier)
+
+	// Единый инбокс: ответы уходят в канал, из которого написал клиент
+	inboxSenders := map[string]services.InboxSender{
+		models.ChannelEmail: services.NewEmailInboxSender(emailService),
+	}
+	if botAPI != nil {
+		inboxSenders[models.ChannelTelegram] = services.NewTelegramInboxSender(botAPI)
+	}
+	inboxService := services.NewInboxService(inboxRepo, leadService, inboxSenders, staffNotifier, activityService, cfg.Inbox.DefaultOwnerID)
+	notificationService := services.NewNotificationService(notificationRepo)
+
+	// Напоминания о сроках задач и эскалация просроченных руководителю
+	taskScheduler := services.NewTaskScheduler(
+		taskRepo,
+		userRepo,
+		leaseRepo,
+		newTaskNotifier(cfg, staffNotifier, userRepo, emailService, notificationRepo, chatHub),
+		services.TaskReminderPolicy{
+			RemindBefore:  cfg.Tasks.Scheduler.RemindBefore,
+			EscalateAfter: cfg.Tasks.Scheduler.EscalateAfter,
+			LeaseTTL:      cfg.Tasks.Scheduler.LeaseTTL,
+		},
+		nil,
+	)
 
 	// Новый сервис для отчётов
-	reportService := services.NewReportService(leadRepo, dealRepo)
+	reportService := services.NewReportService(leadRepo, dealRepo, reportRepo, services.RevenuePolicy{
+		BaseCurrency:       cfg.Reports.BaseCurrency,
+		ExchangeRates:      cfg.Reports.ExchangeRates,
+		StageProbabilities: cfg.Reports.StageProbabilities,
+		DefaultProbability: cfg.Reports.DefaultProbability,
+	})
+	scheduledReportService := services.NewScheduledReportService(scheduledReportRepo, reportService, emailService, cfg.Reports.PDFFont)
+	reportScheduler := services.NewReportScheduler(scheduledReportService, leaseRepo, cfg.Reports.Scheduler.LeaseTTL)
+	reportRollupRefresher := services.NewReportRollupRefresher(reportRepo, leaseRepo, cfg.Reports.Rollups.Batch, cfg.Reports.Rollups.LeaseTTL)
+	reportBuilderService := services.NewReportBuilderService(reportBuilderRepo)
 
 	// Обработчики
 	authHandler := handlers.NewAuthHandler(userService, authService)
@@ -71,13 +178,23 @@ func Run() {
 	userHandler := handlers.NewUserHandler(userService, authService)
 	leadHandler := handlers.NewLeadHandler(leadService)
 	dealHandler := handlers.NewDealHandler(dealService)
-	documentHandler := handlers.NewDocumentHandler(documentService)
+	documentHandler := handlers.NewDocumentHandler(documentService, documentSigningService)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	taskTemplateHandler := handlers.NewTaskTemplateHandler(taskTemplateService)
 	messageHandler := handlers.NewMessageHandler(messageService)
+	chatHandler := handlers.NewChatHandler(chatHub)
+	conversationHandler := handlers.NewConversationHandler(conversationService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, attachmentPolicy.MaxSize)
 	smsHandler := handlers.NewSMSHandler(smsService)
+	telegramLinkHandler := handlers.NewTelegramLinkHandler(telegramLinkService)
+	inboxHandler := handlers.NewInboxHandler(inboxService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	activityHandler := handlers.NewActivityHandler(activityService)
 
 	// Новый обработчик для отчётов
-	reportHandler := handlers.NewReportHandler(reportService)
+	reportHandler := handlers.NewReportHandler(reportService, cfg.Reports.PDFFont)
+	scheduledReportHandler := handlers.NewScheduledReportHandler(scheduledReportService)
+	reportBuilderHandler := handlers.NewReportBuilderHandler(reportBuilderService)
 
 	// Настройка маршрутов и middleware
 	router := gin.Default()
@@ -94,19 +211,90 @@ func Run() {
 		authHandler,
 		documentHandler,
 		taskHandler,
+		taskTemplateHandler,
 		messageHandler,
+		chatHandler,
+		conversationHandler,
+		attachmentHandler,
 		smsHandler,
+		telegramLinkHandler,
+		inboxHandler,
+		notificationHandler,
+		activityHandler,
 		reportHandler, // Передаём reportHandler здесь
+		scheduledReportHandler,
+		reportBuilderHandler,
 	)
 
+	// Фоновый опрос статусов доставки SMS
+	go smsDeliveryTracker.Run(context.Background(), cfg.SMS.DeliveryPoll.Interval)
+
+	// Удаление загруженных, но не отправленных вложений
+	go services.RunAttachmentCleanup(context.Background(), attachmentService, time.Hour)
+
+	// Напоминания и эскалации по срокам задач
+	go taskScheduler.Run(context.Background(), cfg.Tasks.Scheduler.Interval)
+
+	// Рассылка отчётов по расписанию
+	go reportScheduler.Run(context.Background(), cfg.Reports.Scheduler.Interval)
+
+	// Обновление дневных сводок дашборда
+	go reportRollupRefresher.Run(context.Background(), cfg.Reports.Rollups.Interval)
+
+	// Письма клиентов в инбокс
+	if dir := cfg.Inbox.Mailbox.Dir; dir != "" {
+		mailSource, err := mailbox.NewDir(dir)
+		if err != nil {
+			log.Fatal("Ошибка открытия почтового ящика инбокса: ", err)
+		}
+		go services.RunInboxMailPoll(context.Background(), mailSource, inboxService, cfg.Inbox.Mailbox.PollInterval)
+	}
+
 	// Swagger UI
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Telegram-бот в режиме webhook обслуживается этим сервером по секретному пути
+	var telegramHandlers *handlers.TelegramHandlers
+	if cfg.Telegram.Mode == TelegramModeWebhook {
+		if telegramBot == nil {
+			log.Fatal("Режим webhook требует токен Telegram-бота")
+		}
+		webhookURL, err := telegramWebhookURL(cfg)
+		if err != nil {
+			log.Fatal("Настройки webhook Telegram: ", err)
+		}
+		telegramHandlers = NewTelegramHandlers(cfg, db, telegramBot, staffNotifier)
+		router.POST(cfg.Telegram.Webhook.Path, telegramHandlers.Webhook)
+		if err := telegramBot.SetWebhook(webhookURL, cfg.Telegram.Webhook.SecretToken, cfg.Telegram.Webhook.MaxConnections); err != nil {
+			log.Fatal("Ошибка регистрации webhook Telegram: ", err)
+		}
+	}
+
 	// Запуск сервера
 	listenAddr := fmt.Sprintf(":%d", cfg.Server.Port)
-	log.Printf("Сервер запущен на %s", listenAddr)
-	if err := router.Run(listenAddr); err != nil {
-		log.Fatal("Ошибка запуска сервера: ", err)
+	server := &http.Server{Addr: listenAddr, Handler: router}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("Сервер запущен на %s", listenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Ошибка запуска сервера: ", err)
+		}
+	}()
+	<-ctx.Done()
+
+	// Остановка: сначала перестаём принимать запросы, затем дорабатываем очередь обновлений бота
+	log.Println("Остановка сервера...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Ошибка остановки сервера: %v", err)
+	}
+	if telegramHandlers != nil {
+		if err := telegramHandlers.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Не все обновления Telegram обработаны: %v", err)
+		}
 	}
 }
 
@@ -122,3 +310,51 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// newTaskNotifier собирает каналы уведомлений планировщика задач из конфига.
+func newTaskNotifier(
+	cfg *config.Config,
+	telegram services.StaffNotifier,
+	userRepo repositories.UserRepository,
+	emailService services.EmailService,
+	notificationRepo repositories.NotificationRepository,
+	chatHub *realtime.Hub,
+) services.StaffNotifier {
+	var notifiers []services.StaffNotifier
+	for _, channel := range cfg.Tasks.Scheduler.Channels {
+		switch channel {
+		case "telegram":
+			notifiers = append(notifiers, telegram)
+		case "email":
+			notifiers = append(notifiers, services.NewEmailNotifier(userRepo, emailService))
+		case "in_app":
+			notifiers = append(notifiers, services.NewInAppNotifier(notificationRepo, chatHub))
+		default:
+			log.Fatalf("Неизвестный канал уведомлений о задачах: %s", channel)
+		}
+	}
+	return services.NewMultiNotifier(notifiers...)
+}
+
+// newSMSProvider собирает провайдера SMS из конфига, при необходимости
+// оборачивая его в переключение на резервного провайдера.
+func newSMSProvider(cfg *config.Config) smsprovider.Provider {
+	build := func(name string) smsprovider.Provider {
+		switch name {
+		case "console":
+			return smsprovider.NewConsole(cfg.SMS.Console.File)
+		case "mobizon", "":
+			m := cfg.SMS.Mobizon
+			return smsprovider.NewMobizon(m.APIURL, cfg.MobizonAPIKey(), m.Sender, m.Timeout)
+		default:
+			log.Fatalf("Неизвестный SMS-провайдер: %s", name)
+			return nil
+		}
+	}
+
+	provider := build(cfg.SMS.Provider)
+	if cfg.SMS.Fallback != "" && cfg.SMS.Fallback != cfg.SMS.Provider {
+		provider = smsprovider.NewFailover(provider, build(cfg.SMS.Fallback))
+	}
+	return provider
+}
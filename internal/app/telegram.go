@@ -0,0 +1,75 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"turcompany/internal/config"
+	"turcompany/internal/handlers"
+	"turcompany/internal/messaging"
+	"turcompany/internal/repositories"
+	"turcompany/internal/services"
+)
+
+// Режимы получения обновлений Telegram
+const (
+	TelegramModePolling = "polling"
+	TelegramModeWebhook = "webhook"
+)
+
+// Telegram допускает в secret_token только такие символы
+var telegramSecretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,256}$`)
+
+// NewTelegramHandlers собирает бота для клиентов и сотрудников. Используется и отдельным
+// процессом cmd/bot (long polling), и основным сервером в режиме webhook.
+func NewTelegramHandlers(
+	cfg *config.Config,
+	db *sql.DB,
+	botService *services.TelegramBotService,
+	staffNotifier services.StaffNotifier,
+) *handlers.TelegramHandlers {
+	telegramLinkRepo := repositories.NewTelegramLinkRepository(db)
+	leadRepo := repositories.NewLeadRepository(db)
+	dealRepo := repositories.NewDealRepository(db)
+	// Лиды из бота и сообщения клиентов попадают в ленту событий
+	activityService := services.NewActivityService(repositories.NewActivityRepository(db), leadRepo, dealRepo, repositories.NewDocumentRepository(db))
+	leadService := services.NewLeadService(leadRepo, dealRepo, staffNotifier, nil, activityService)
+	tourService := services.NewTourService(repositories.NewTourRepository(db))
+	telegramLinkService := services.NewTelegramLinkService(telegramLinkRepo, cfg.Telegram.LinkCodeTTL, cfg.Telegram.BotUsername)
+	taskService := services.NewTaskService(
+		repositories.NewTaskRepository(db),
+		repositories.NewTaskChecklistRepository(db),
+		repositories.NewTaskCommentRepository(db),
+		repositories.NewTaskActivityRepository(db),
+		activityService,
+	)
+	// Боту нужен только приём сообщений; ответы менеджеров отправляет основной сервер
+	inboxService := services.NewInboxService(repositories.NewInboxRepository(db), leadService, nil, staffNotifier, activityService, cfg.Inbox.DefaultOwnerID)
+
+	tourBot := messaging.NewTourBot(
+		tourService,
+		leadService,
+		repositories.NewTelegramSessionRepository(db),
+		messaging.NewStaffCommands(telegramLinkService, taskService),
+		inboxService,
+		cfg.Telegram.LeadOwnerID,
+		cfg.Telegram.SessionTTL,
+	)
+	return handlers.NewTelegramHandlers(tourBot, botService.Bot, cfg.Telegram.Workers, cfg.Telegram.Webhook.SecretToken)
+}
+
+// telegramWebhookURL проверяет настройки webhook и возвращает адрес для setWebhook.
+func telegramWebhookURL(cfg *config.Config) (string, error) {
+	w := cfg.Telegram.Webhook
+	if !strings.HasPrefix(w.URL, "https://") {
+		return "", fmt.Errorf("telegram.webhook.url должен начинаться с https://")
+	}
+	if !strings.HasPrefix(w.Path, "/") || len(w.Path) < 16 {
+		return "", fmt.Errorf("telegram.webhook.path должен быть секретным путём не короче 16 символов")
+	}
+	if !telegramSecretTokenPattern.MatchString(w.SecretToken) {
+		return "", fmt.Errorf("telegram.webhook.secret_token: от 16 до 256 символов A-Z, a-z, 0-9, _ и -")
+	}
+	return strings.TrimSuffix(w.URL, "/") + w.Path, nil
+}
@@ -0,0 +1,62 @@
+// Package benchdb общие помощники бенчмарков на заполненной базе: подключение
+// к базе из переменной окружения и пошаговое заполнение синтетическими данными.
+package benchdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// EnvDSN переменная окружения со строкой подключения к базе для бенчмарков.
+const EnvDSN = "BENCH_DSN"
+
+// Open подключается к базе из BENCH_DSN. Без переменной бенчмарк пропускается,
+// поэтому обычный go test ./... не требует базы.
+func Open(tb testing.TB) *sql.DB {
+	tb.Helper()
+	dsn := os.Getenv(EnvDSN)
+	if dsn == "" {
+		tb.Skipf("%s не задана, бенчмарк на базе пропущен", EnvDSN)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		tb.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Execer база или транзакция, в которой выполняются шаги заполнения.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Step один SQL-шаг заполнения.
+type Step struct {
+	Name  string
+	Query string
+	Args  []interface{}
+}
+
+// Exec выполняет шаги по порядку и пишет в лог время каждого.
+func Exec(ctx context.Context, db Execer, steps []Step) error {
+	for _, step := range steps {
+		start := time.Now()
+		if _, err := db.ExecContext(ctx, step.Query, step.Args...); err != nil {
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+		log.Printf("%s: %s", step.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
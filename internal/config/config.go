@@ -3,6 +3,7 @@ package config
 import (
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 type Config struct {
@@ -19,6 +20,113 @@ type Config struct {
 		SMTPPassword string `yaml:"smtp_password"`
 		FromEmail    string `yaml:"from_email"`
 	} `yaml:"email"`
+	SMS struct {
+		Provider string `yaml:"provider"` // mobizon или console
+		Fallback string `yaml:"fallback"` // резервный провайдер, пусто — без резерва
+		Mobizon  struct {
+			APIURL  string        `yaml:"api_url"`
+			APIKey  string        `yaml:"api_key"`
+			Sender  string        `yaml:"sender"`
+			Timeout time.Duration `yaml:"timeout"`
+		} `yaml:"mobizon"`
+		Console struct {
+			File string `yaml:"file"` // пусто — печать в stdout
+		} `yaml:"console"`
+		CodeTTL            time.Duration `yaml:"code_ttl"`
+		MaxAttempts        int           `yaml:"max_attempts"`
+		ResendCooldown     time.Duration `yaml:"resend_cooldown"`
+		DailyLimitPerPhone int           `yaml:"daily_limit_per_phone"`
+		DeliveryPoll       struct {
+			Interval time.Duration `yaml:"interval"`
+			Window   time.Duration `yaml:"window"`
+		} `yaml:"delivery_poll"`
+	} `yaml:"sms"`
+	Chat struct {
+		NotifyChannel string `yaml:"notify_channel"` // канал LISTEN/NOTIFY для событий чата
+		Attachments   struct {
+			MaxSize       int64         `yaml:"max_size"`       // байт
+			AllowedTypes  []string      `yaml:"allowed_types"`  // MIME-типы, определяемые по содержимому
+			ThumbnailSize int           `yaml:"thumbnail_size"` // сторона превью в пикселях
+			PendingTTL    time.Duration `yaml:"pending_ttl"`    // неотправленные вложения старше удаляются
+		} `yaml:"attachments"`
+	} `yaml:"chat"`
+	Telegram struct {
+		Mode    string `yaml:"mode"`    // polling (cmd/bot) или webhook (основной сервер)
+		Workers int    `yaml:"workers"` // параллельная обработка, по порядку внутри чата
+		Webhook struct {
+			URL            string `yaml:"url"`             // публичный адрес сервера, например https://crm.example.com
+			Path           string `yaml:"path"`            // секретный путь, например /telegram/webhook/<случайная строка>
+			SecretToken    string `yaml:"secret_token"`    // сверяется с X-Telegram-Bot-Api-Secret-Token
+			MaxConnections int    `yaml:"max_connections"` // одновременных запросов от Telegram, 0 — по умолчанию
+		} `yaml:"webhook"`
+		Token       string        `yaml:"token"`         // переменная окружения TELEGRAM_APITOKEN имеет приоритет
+		Debug       bool          `yaml:"debug"`         // логировать запросы к Bot API
+		LeadOwnerID int           `yaml:"lead_owner_id"` // менеджер, которому назначаются лиды из бота
+		SessionTTL  time.Duration `yaml:"session_ttl"`   // диалог, неактивный дольше, начинается заново
+		BotUsername string        `yaml:"bot_username"`  // для ссылок t.me на привязку аккаунта
+		LinkCodeTTL time.Duration `yaml:"link_code_ttl"` // срок действия кода /link
+	} `yaml:"telegram"`
+	Tasks struct {
+		Scheduler struct {
+			Interval      time.Duration   `yaml:"interval"`       // период проверки сроков задач
+			RemindBefore  []time.Duration `yaml:"remind_before"`  // напоминания исполнителю до срока
+			EscalateAfter time.Duration   `yaml:"escalate_after"` // через сколько после срока сообщать руководителю
+			LeaseTTL      time.Duration   `yaml:"lease_ttl"`      // аренда планировщика одним экземпляром
+			Channels      []string        `yaml:"channels"`       // telegram, email, in_app
+		} `yaml:"scheduler"`
+	} `yaml:"tasks"`
+	Inbox struct {
+		DefaultOwnerID int `yaml:"default_owner_id"` // менеджер для лидов из писем и сообщений новых клиентов
+		Mailbox        struct {
+			Dir          string        `yaml:"dir"`           // каталог входящих писем (.eml в new/), пусто — почта отключена
+			PollInterval time.Duration `yaml:"poll_interval"` // период проверки почты
+		} `yaml:"mailbox"`
+	} `yaml:"inbox"`
+	Documents struct {
+		Numbering map[string]DocumentNumberFormat `yaml:"numbering"`
+	} `yaml:"documents"`
+	Reports struct {
+		BaseCurrency       string             `yaml:"base_currency"`       // валюта отчётов по выручке и прогнозу
+		ExchangeRates      map[string]float64 `yaml:"exchange_rates"`      // сколько единиц базовой валюты в единице валюты
+		StageProbabilities map[string]float64 `yaml:"stage_probabilities"` // вероятность закрытия сделки по статусу, 0..1
+		DefaultProbability float64            `yaml:"default_probability"` // для статусов, которых нет в списке
+		PDFFont            string             `yaml:"pdf_font"`            // TTF-шрифт выгрузок в PDF с казахскими буквами, пусто — встроенный
+		Scheduler          struct {
+			Interval time.Duration `yaml:"interval"`  // период проверки отчётов по расписанию
+			LeaseTTL time.Duration `yaml:"lease_ttl"` // аренда планировщика одним экземпляром
+		} `yaml:"scheduler"`
+		Rollups struct {
+			Interval time.Duration `yaml:"interval"`  // период обновления дневных сводок дашборда
+			Batch    int           `yaml:"batch"`     // дней за одну транзакцию
+			LeaseTTL time.Duration `yaml:"lease_ttl"` // аренда обновления одним экземпляром
+		} `yaml:"rollups"`
+	} `yaml:"reports"`
+}
+
+// DocumentNumberFormat задаёт формат номера для одного типа документа.
+// В Pattern поддерживаются подстановки {year} и {seq}; {seq} дополняется
+// нулями слева до Width символов.
+type DocumentNumberFormat struct {
+	Pattern string `yaml:"pattern"`
+	Width   int    `yaml:"width"`
+}
+
+// TelegramToken возвращает токен бота; переменная окружения TELEGRAM_APITOKEN
+// имеет приоритет над конфигом.
+func (c *Config) TelegramToken() string {
+	if token := os.Getenv("TELEGRAM_APITOKEN"); token != "" {
+		return token
+	}
+	return c.Telegram.Token
+}
+
+// MobizonAPIKey возвращает ключ API Mobizon; переменная окружения MOBIZON_API_KEY
+// имеет приоритет над конфигом.
+func (c *Config) MobizonAPIKey() string {
+	if key := os.Getenv("MOBIZON_API_KEY"); key != "" {
+		return key
+	}
+	return c.SMS.Mobizon.APIKey
 }
 
 func LoadConfig() *Config {
@@ -0,0 +1,211 @@
+// Package cron разбирает расписания в формате cron из пяти полей
+// (минута, час, день месяца, месяц, день недели) и вычисляет следующий запуск.
+//
+// Поддерживаются *, списки (1,15), диапазоны (1-5), шаги (*/15, 8-18/2), имена
+// месяцев и дней недели (JAN, MON), воскресенье как 0 или 7 и сокращения @hourly,
+// @daily, @weekly, @monthly, @yearly. Если заданы и день месяца, и день недели,
+// подходит любой из них, как в классическом cron.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid cron schedule")
+
+// maxSearchYears ограничивает поиск для расписаний, которые не срабатывают
+// (например, 30 февраля).
+const maxSearchYears = 5
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// Schedule — разобранное расписание. Биты масок соответствуют допустимым значениям полей.
+type Schedule struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	anyDom   bool // день месяца задан как *
+	anyDow   bool // день недели задан как *
+	location *time.Location
+}
+
+// Parse разбирает расписание вроде "0 8 * * MON"; время считается в часовом поясе loc
+// (nil — локальный пояс сервера).
+func Parse(spec string, loc *time.Location) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	expr := spec
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		expr = d
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidSchedule, len(parts))
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	s := &Schedule{spec: spec, location: loc}
+	masks := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, part := range parts {
+		mask, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSchedule, fields[i].name, err)
+		}
+		*masks[i] = mask
+	}
+	// 7 — тоже воскресенье
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.anyDom = parts[2] == "*"
+	s.anyDow = parts[4] == "*"
+	return s, nil
+}
+
+// String возвращает расписание в том виде, в каком оно было задано.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next возвращает первый запуск строго после after; ok == false, если расписание
+// не срабатывает в ближайшие годы.
+func (s *Schedule) Next(after time.Time) (next time.Time, ok bool) {
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	}
+	return dom || dow
+}
+
+// parseField разбирает одно поле в битовую маску допустимых значений.
+func parseField(expr string, f field) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepExpr)
+			}
+			step = n
+		}
+
+		var low, high int
+		switch {
+		case rangeExpr == "*":
+			low, high = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			a, b, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if low, err = parseValue(a, f); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(b, f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", rangeExpr)
+			}
+		default:
+			v, err := parseValue(rangeExpr, f)
+			if err != nil {
+				return 0, err
+			}
+			low, high = v, v
+			if hasStep {
+				high = f.max
+			}
+		}
+		for v := low; v <= high; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%d is out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,123 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func bits(values ...int) uint64 {
+	var mask uint64
+	for _, v := range values {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+func span(low, high, step int) uint64 {
+	var mask uint64
+	for v := low; v <= high; v += step {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec                          string
+		minute, hour, dom, month, dow uint64
+		anyDom, anyDow                bool
+	}{
+		{"* * * * *", span(0, 59, 1), span(0, 23, 1), span(1, 31, 1), span(1, 12, 1), span(0, 7, 1), true, true},
+		{"0 8 * * MON", bits(0), bits(8), span(1, 31, 1), span(1, 12, 1), bits(1), true, false},
+		{"1,15 8-18/2 */10 JAN-MAR *", bits(1, 15), span(8, 18, 2), bits(1, 11, 21, 31), bits(1, 2, 3), span(0, 7, 1), false, true},
+		{"5/20 0 1 dec sun", bits(5, 25, 45), bits(0), bits(1), bits(12), bits(0), false, false},
+		// 7 — тоже воскресенье.
+		{"0 0 * * 7", bits(0), bits(0), span(1, 31, 1), span(1, 12, 1), bits(0, 7), true, false},
+		{"0 0 * * 5-7", bits(0), bits(0), span(1, 31, 1), span(1, 12, 1), bits(0, 5, 6, 7), true, false},
+		{"@hourly", bits(0), span(0, 23, 1), span(1, 31, 1), span(1, 12, 1), span(0, 7, 1), true, true},
+		{"@Weekly", bits(0), bits(0), span(1, 31, 1), span(1, 12, 1), bits(0), true, false},
+		{"@yearly", bits(0), bits(0), bits(1), bits(1), span(0, 7, 1), false, true},
+	}
+	for _, tc := range tests {
+		s, err := Parse(tc.spec, time.UTC)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.spec, err)
+			continue
+		}
+		got := [5]uint64{s.minute, s.hour, s.dom, s.month, s.dow}
+		want := [5]uint64{tc.minute, tc.hour, tc.dom, tc.month, tc.dow}
+		if got != want || s.anyDom != tc.anyDom || s.anyDow != tc.anyDow {
+			t.Errorf("Parse(%q) = %b, any %v/%v; want %b, any %v/%v", tc.spec, got, s.anyDom, s.anyDow, want, tc.anyDom, tc.anyDow)
+		}
+		if s.String() != tc.spec {
+			t.Errorf("Parse(%q).String() = %q", tc.spec, s.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1,,2 * * * *",
+		"* * * FOO *",
+		"* * * * MON-",
+		"@every 5m",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec, time.UTC); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Parse(%q): got %v, want ErrInvalidSchedule", spec, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	almaty := time.FixedZone("Almaty", 5*60*60)
+	// Пятница, 1 марта 2024, 10:00 UTC.
+	after := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		spec   string
+		loc    *time.Location
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"*/15 * * * *", time.UTC, after.Add(7 * time.Minute), time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.UTC, after.Add(15 * time.Minute), time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), true},
+		{"0 8 * * MON", time.UTC, after, time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC), true},
+		{"0 0 * * 7", time.UTC, after, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), true},
+		{"@monthly", time.UTC, after, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), true},
+		{"0 9 1 JAN *", time.UTC, after, time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), true},
+		// День месяца и день недели: подходит любой из них.
+		{"0 0 13 * FRI", time.UTC, after, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 2 * FRI", time.UTC, after, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 */10 * MON", time.UTC, after, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 29 2 *", time.UTC, after, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 30 2 *", time.UTC, after, time.Time{}, false},
+		// 10:00 UTC — это 15:00 в Алматы, поэтому 9:00 уже прошло.
+		{"0 9 * * *", almaty, after, time.Date(2024, 3, 2, 9, 0, 0, 0, almaty), true},
+		{"0 16 * * FRI", almaty, after, time.Date(2024, 3, 1, 16, 0, 0, 0, almaty), true},
+	}
+	for _, tc := range tests {
+		s, err := Parse(tc.spec, tc.loc)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.spec, err)
+		}
+		got, ok := s.Next(tc.after)
+		if ok != tc.wantOK || !got.Equal(tc.want) {
+			t.Errorf("%q.Next(%s) = %s, %v; want %s, %v", tc.spec, tc.after, got, ok, tc.want, tc.wantOK)
+		}
+		if ok && got.Location() != tc.loc {
+			t.Errorf("%q.Next(%s) is in %s, want %s", tc.spec, tc.after, got.Location(), tc.loc)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Package export выгружает отчёты в CSV, XLSX и PDF. Строки пишутся по одной, так что
+// большие выборки не собираются в памяти целиком: CSV уходит клиенту по мере записи,
+// XLSX excelize сбрасывает во временный файл, PDF ограничен MaxPDFRows.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"turcompany/internal/pdf"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Форматы выгрузки.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+// Языки заголовков.
+const (
+	LangRU = "ru"
+	LangKK = "kk"
+)
+
+// MaxPDFRows ограничивает PDF, который строится в памяти.
+const MaxPDFRows = 10000
+
+var (
+	ErrUnknownFormat = errors.New("format must be csv, xlsx or pdf")
+	ErrTooManyRows   = fmt.Errorf("too many rows for pdf, the limit is %d; use csv or xlsx", MaxPDFRows)
+)
+
+// Text строка на русском и казахском языках.
+type Text struct {
+	RU string
+	KK string
+}
+
+// In возвращает текст на языке lang; русский, если перевода нет.
+func (t Text) In(lang string) string {
+	if lang == LangKK && t.KK != "" {
+		return t.KK
+	}
+	return t.RU
+}
+
+// Titles переводит заголовки колонок на язык lang.
+func Titles(columns []Text, lang string) []string {
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = c.In(lang)
+	}
+	return titles
+}
+
+// Language выбирает язык по параметру lang или заголовку Accept-Language; по умолчанию русский.
+func Language(query, acceptLanguage string) string {
+	for _, value := range []string{query, acceptLanguage} {
+		for _, part := range strings.Split(value, ",") {
+			tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+			switch {
+			case strings.HasPrefix(tag, LangKK):
+				return LangKK
+			case strings.HasPrefix(tag, LangRU):
+				return LangRU
+			}
+		}
+	}
+	return LangRU
+}
+
+// ValidFormat проверяет формат выгрузки.
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatXLSX || format == FormatPDF
+}
+
+// ContentType возвращает MIME-тип файла выгрузки.
+func ContentType(format string) string {
+	switch format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName возвращает имя файла выгрузки отчёта name.
+func FileName(name, format string, now time.Time) string {
+	return fmt.Sprintf("%s_%s.%s", name, now.Format("2006-01-02"), format)
+}
+
+// Writer пишет строки отчёта. Значения ячеек — строки, числа, time.Time или указатели
+// на них; nil выводится пустой ячейкой.
+type Writer interface {
+	WriteRow(cells ...interface{}) error
+	// Close дописывает файл; без него выгрузка неполная.
+	Close() error
+}
+
+// Options настройки выгрузки.
+type Options struct {
+	Title   string // заголовок PDF
+	PDFFont string // TTF-шрифт для PDF, см. pdf.NewReportTable
+}
+
+// NewWriter создаёт Writer формата format, пишущий в w, и записывает заголовки колонок.
+func NewWriter(format string, w io.Writer, headers []string, opts Options) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, headers)
+	case FormatXLSX:
+		return newXLSXWriter(w, headers)
+	case FormatPDF:
+		return &pdfWriter{w: w, table: pdf.NewReportTable(opts.Title, headers, opts.PDFFont)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// csvFlushRows через сколько строк CSV отправляется клиенту.
+const csvFlushRows = 500
+
+// csvWriter пишет CSV для Excel: UTF-8 с BOM и точкой с запятой, как ожидает Excel с
+// русскими региональными настройками.
+type csvWriter struct {
+	buf  *bufio.Writer
+	csv  *csv.Writer
+	rows int
+}
+
+func newCSVWriter(w io.Writer, headers []string) (*csvWriter, error) {
+	buf := bufio.NewWriter(w)
+	if _, err := buf.WriteString("\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(buf)
+	cw.Comma = ';'
+	if err := cw.Write(headers); err != nil {
+		return nil, err
+	}
+	return &csvWriter{buf: buf, csv: cw}, nil
+}
+
+func (w *csvWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		if text, ok := xlsxValue(cell).(string); ok {
+			record[i] = escapeFormula(text)
+		} else {
+			record[i] = formatCell(cell)
+		}
+	}
+	if err := w.csv.Write(record); err != nil {
+		return err
+	}
+	w.rows++
+	if w.rows%csvFlushRows == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// escapeFormula добавляет апостроф к тексту, который Excel выполнил бы как формулу
+// (=, +, -, @, а также табуляция и возврат каретки в начале ячейки).
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func (w *csvWriter) Close() error {
+	return w.flush()
+}
+
+func (w *csvWriter) flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// xlsxWriter пишет лист через потоковый writer excelize; числа и даты остаются числами
+// и датами Excel. Текст записывается строкой (inlineStr), а не формулой, поэтому
+// значения вида "=..." не выполняются и апостроф им не нужен.
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer, headers []string) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	xw := &xlsxWriter{w: w, file: file, stream: stream}
+	cells := make([]interface{}, len(headers))
+	for i, h := range headers {
+		cells[i] = h
+	}
+	if err := xw.WriteRow(cells...); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (w *xlsxWriter) WriteRow(cells ...interface{}) error {
+	w.row++
+	values := make([]interface{}, len(cells))
+	for i, cell := range cells {
+		values[i] = xlsxValue(cell)
+	}
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.w)
+	return err
+}
+
+// pdfWriter собирает таблицу и записывает её при Close.
+type pdfWriter struct {
+	w     io.Writer
+	table *pdf.ReportTable
+}
+
+func (w *pdfWriter) WriteRow(cells ...interface{}) error {
+	if w.table.Rows() >= MaxPDFRows {
+		return ErrTooManyRows
+	}
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = formatCell(cell)
+	}
+	w.table.AddRow(record)
+	return nil
+}
+
+func (w *pdfWriter) Close() error {
+	return w.table.Output(w.w)
+}
+
+// xlsxValue разыменовывает указатели, чтобы excelize записал число или дату.
+func xlsxValue(cell interface{}) interface{} {
+	switch v := cell.(type) {
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	return cell
+}
+
+func formatCell(cell interface{}) string {
+	switch v := xlsxValue(cell).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"turcompany/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var formulaCells = []string{"=HYPERLINK(\"http://evil\")", "+7 701", "-1+2", "@SUM(A1)", "\t=1"}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf, []string{"a"}, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, text := range formulaCells {
+		if err := w.WriteRow(text); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	amount := -15.5
+	if err := w.WriteRow(&amount); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff")))
+	r.Comma = ';'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	for i, text := range formulaCells {
+		if got := records[i+1][0]; got != "'"+text {
+			t.Errorf("row %d = %q, want %q", i+1, got, "'"+text)
+		}
+	}
+	// Числа остаются числами.
+	if got := records[len(records)-1][0]; got != "-15.5" {
+		t.Errorf("negative number = %q, want -15.5", got)
+	}
+}
+
+func TestXLSXWritesFormulasAsText(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf, []string{"a"}, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, text := range formulaCells {
+		if err := w.WriteRow(text); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("OpenReader: %v", err)
+	}
+	defer file.Close()
+	for i, text := range formulaCells {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if formula, _ := file.GetCellFormula("Sheet1", cell); formula != "" {
+			t.Errorf("%s has formula %q", cell, formula)
+		}
+		if value, _ := file.GetCellValue("Sheet1", cell); value != text {
+			t.Errorf("%s = %q, want %q", cell, value, text)
+		}
+		if typ, _ := file.GetCellType("Sheet1", cell); typ != excelize.CellTypeInlineString {
+			t.Errorf("%s has type %v, want an inline string", cell, typ)
+		}
+	}
+}
+
+var dealAmounts = []struct {
+	amount string
+	csv    string
+	number bool
+}{
+	{"-100", "-100", true},
+	{" 2500.50 ", "2500.5", true},
+	{"", "", false},
+	{"=1+1", "'=1+1", false},
+}
+
+func writeDealAmounts(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, []string{"id", "lead", "amount"}, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for i, a := range dealAmounts {
+		if err := WriteDeal(w.WriteRow, &models.Deals{ID: i + 1, Amount: a.amount}); err != nil {
+			t.Fatalf("WriteDeal: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return &buf
+}
+
+func TestDealAmountsExportedAsNumbers(t *testing.T) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(writeDealAmounts(t, FormatCSV).String(), "\ufeff")))
+	r.Comma = ';'
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	for i, a := range dealAmounts {
+		if got := records[i+1][2]; got != a.csv {
+			t.Errorf("CSV amount %q = %q, want %q", a.amount, got, a.csv)
+		}
+	}
+
+	file, err := excelize.OpenReader(writeDealAmounts(t, FormatXLSX))
+	if err != nil {
+		t.Fatalf("OpenReader: %v", err)
+	}
+	defer file.Close()
+	// Числовые суммы — числа Excel (ячейка без типа), нечисловые остаются текстом.
+	for i, a := range dealAmounts {
+		cell, _ := excelize.CoordinatesToCellName(3, i+2)
+		typ, _ := file.GetCellType("Sheet1", cell)
+		value, _ := file.GetCellValue("Sheet1", cell)
+		if number := typ == excelize.CellTypeUnset && value != ""; number != a.number {
+			t.Errorf("%s (%q) has type %v and value %q, number = %v", cell, a.amount, typ, value, a.number)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"turcompany/internal/models"
+)
+
+// Report описывает файл отчёта: имя, заголовок и колонки на двух языках.
+type Report struct {
+	Name    string
+	Title   Text
+	Columns []Text
+}
+
+// Rows пишет строки отчёта на языке lang через write.
+type Rows func(lang string, write func(cells ...interface{}) error) error
+
+// Write выгружает отчёт report в формате format: заголовки, строки rows и конец файла.
+func Write(format string, w io.Writer, report Report, lang string, opts Options, rows Rows) error {
+	opts.Title = report.Title.In(lang)
+	writer, err := NewWriter(format, w, Titles(report.Columns, lang), opts)
+	if err != nil {
+		return err
+	}
+	if err := rows(lang, writer.WriteRow); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+var (
+	colGroup    = Text{RU: "Группа", KK: "Топ"}
+	colStatus   = Text{RU: "Статус", KK: "Мәртебе"}
+	colPeriod   = Text{RU: "Период", KK: "Кезең"}
+	colDeals    = Text{RU: "Сделок", KK: "Мәмілелер"}
+	colCreated  = Text{RU: "Создан", KK: "Құрылған"}
+	colAmount   = Text{RU: "Сумма", KK: "Сома"}
+	colCurrency = Text{RU: "Валюта", KK: "Валюта"}
+	colChange   = Text{RU: "Изменение", KK: "Өзгеріс"}
+	textUndated = Text{RU: "Без даты", KK: "Күні жоқ"}
+)
+
+// Выгружаемые отчёты.
+var (
+	SummaryReport = Report{
+		Name:  "summary",
+		Title: Text{RU: "Сводный отчёт", KK: "Жиынтық есеп"},
+		Columns: []Text{
+			{RU: "Показатель", KK: "Көрсеткіш"},
+			{RU: "Значение", KK: "Мәні"},
+		},
+	}
+	summaryRowTitles = map[string]Text{
+		"totalLeads": {RU: "Всего лидов", KK: "Барлық лидтер"},
+		"totalDeals": {RU: "Всего сделок", KK: "Барлық мәмілелер"},
+	}
+	LeadsReport = Report{
+		Name:  "leads",
+		Title: Text{RU: "Лиды", KK: "Лидтер"},
+		Columns: []Text{
+			{RU: "ID", KK: "ID"},
+			{RU: "Название", KK: "Атауы"},
+			{RU: "Описание", KK: "Сипаттамасы"},
+			colStatus,
+			{RU: "Владелец", KK: "Иесі"},
+			{RU: "Источник", KK: "Дереккөз"},
+			{RU: "Телефон", KK: "Телефон"},
+			{RU: "Email", KK: "Email"},
+			colCreated,
+		},
+	}
+	DealsReport = Report{
+		Name:  "deals",
+		Title: Text{RU: "Сделки", KK: "Мәмілелер"},
+		Columns: []Text{
+			{RU: "ID", KK: "ID"},
+			{RU: "Лид", KK: "Лид"},
+			colAmount,
+			colCurrency,
+			colStatus,
+			{RU: "Направление", KK: "Бағыт"},
+			{RU: "Дата вылета", KK: "Ұшу күні"},
+			{RU: "Причина проигрыша", KK: "Ұтылу себебі"},
+			colCreated,
+		},
+	}
+	FunnelReport = Report{
+		Name:    "funnel",
+		Title:   Text{RU: "Воронка лидов", KK: "Лидтер воронкасы"},
+		Columns: []Text{colGroup, colStatus, {RU: "Лидов", KK: "Лидтер"}},
+	}
+	ConversionReport = Report{
+		Name:  "conversion",
+		Title: Text{RU: "Конверсия лидов в сделки", KK: "Лидтердің мәмілеге айналуы"},
+		Columns: []Text{
+			colGroup,
+			{RU: "Лидов", KK: "Лидтер"},
+			{RU: "Стали сделками", KK: "Мәмілеге айналды"},
+			{RU: "Конверсия", KK: "Конверсия"},
+			{RU: "Среднее время до сделки, ч", KK: "Мәмілеге дейінгі орташа уақыт, сағ"},
+		},
+	}
+	StageDurationsReport = Report{
+		Name:  "stage_durations",
+		Title: Text{RU: "Время на этапах", KK: "Кезеңдердегі уақыт"},
+		Columns: []Text{
+			colGroup,
+			colStatus,
+			{RU: "Завершено", KK: "Аяқталды"},
+			{RU: "Среднее время, ч", KK: "Орташа уақыт, сағ"},
+			{RU: "Сейчас на этапе", KK: "Қазір кезеңде"},
+		},
+	}
+	WinLossReport = Report{
+		Name:  "win_loss",
+		Title: Text{RU: "Выигранные и проигранные сделки", KK: "Ұтқан және ұтылған мәмілелер"},
+		Columns: []Text{
+			colGroup,
+			{RU: "Выиграно", KK: "Ұтқан"},
+			{RU: "Проиграно", KK: "Ұтылған"},
+			{RU: "Открыто", KK: "Ашық"},
+			{RU: "Доля побед", KK: "Жеңіс үлесі"},
+			{RU: "Причины проигрыша", KK: "Ұтылу себептері"},
+		},
+	}
+	RevenueReport = Report{
+		Name:  "revenue",
+		Title: Text{RU: "Выручка", KK: "Түсім"},
+		Columns: []Text{
+			colGroup,
+			colPeriod,
+			colDeals,
+			colAmount,
+			{RU: "Предыдущий период", KK: "Алдыңғы кезең"},
+			colChange,
+			colCurrency,
+		},
+	}
+	ForecastReport = Report{
+		Name:  "forecast",
+		Title: Text{RU: "Прогноз продаж", KK: "Сату болжамы"},
+		Columns: []Text{
+			colGroup,
+			colPeriod,
+			colDeals,
+			colAmount,
+			{RU: "Взвешенная сумма", KK: "Өлшенген сома"},
+			colChange,
+			colCurrency,
+		},
+	}
+)
+
+// SummaryRows строки сводного отчёта.
+func SummaryRows(data map[string]int) Rows {
+	return func(lang string, write func(...interface{}) error) error {
+		for _, key := range []string{"totalLeads", "totalDeals"} {
+			if err := write(summaryRowTitles[key].In(lang), data[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WriteLead пишет строку лида.
+func WriteLead(write func(...interface{}) error, lead *models.Leads) error {
+	return write(lead.ID, lead.Title, lead.Description, lead.Status, lead.OwnerID, lead.Source, lead.Phone, lead.Email, lead.CreatedAt)
+}
+
+// WriteDeal пишет строку сделки.
+func WriteDeal(write func(...interface{}) error, deal *models.Deals) error {
+	return write(deal.ID, deal.LeadID, amountCell(deal.Amount), deal.Currency, deal.Status, deal.Destination, deal.DepartureDate, deal.LossReason, deal.CreatedAt)
+}
+
+// amountCell переводит сумму сделки, которая хранится текстом, в число, чтобы в таблице
+// она считалась и не экранировалась как формула. Пустая сумма — пустая ячейка, нечисловая
+// выводится как есть.
+func amountCell(amount string) interface{} {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return nil
+	}
+	if value, err := strconv.ParseFloat(amount, 64); err == nil && !math.IsNaN(value) && !math.IsInf(value, 0) {
+		return value
+	}
+	return amount
+}
+
+// FunnelRows строки воронки: группа, статус, число лидов.
+func FunnelRows(report *models.FunnelReport) Rows {
+	return func(_ string, write func(...interface{}) error) error {
+		for _, g := range report.Groups {
+			statuses := make([]string, 0, len(g.Statuses))
+			for status := range g.Statuses {
+				statuses = append(statuses, status)
+			}
+			sort.Strings(statuses)
+			for _, status := range statuses {
+				if err := write(g.Key, status, g.Statuses[status]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// ConversionRows строки конверсии по группам.
+func ConversionRows(report *models.ConversionReport) Rows {
+	return func(_ string, write func(...interface{}) error) error {
+		for _, g := range report.Groups {
+			if err := write(g.Key, g.Leads, g.Converted, g.Rate, g.AvgHoursToDeal); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StageDurationRows строки времени на этапах.
+func StageDurationRows(report *models.StageDurationReport) Rows {
+	return func(_ string, write func(...interface{}) error) error {
+		for _, st := range report.Stages {
+			if err := write(st.Key, st.Status, st.Completed, st.AvgHours, st.Current); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WinLossRows строки выигранных и проигранных сделок; причины проигрыша в одной ячейке.
+func WinLossRows(report *models.WinLossReport) Rows {
+	return func(_ string, write func(...interface{}) error) error {
+		for _, g := range report.Groups {
+			reasons := make([]string, len(g.LossReasons))
+			for i, r := range g.LossReasons {
+				reasons[i] = fmt.Sprintf("%s: %d", r.Reason, r.Count)
+			}
+			if err := write(g.Key, g.Won, g.Lost, g.Open, g.WinRate, strings.Join(reasons, "; ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RevenueRows строки выручки: по одной на группу и период.
+func RevenueRows(report *models.RevenueReport) Rows {
+	return func(_ string, write func(...interface{}) error) error {
+		for _, s := range report.Series {
+			for _, p := range s.Points {
+				if err := write(s.Key, p.Period, p.Deals, p.Amount, p.Previous, p.Change, report.Currency); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// ForecastRows строки прогноза; сделки без даты вылета — отдельной строкой группы.
+func ForecastRows(report *models.ForecastReport) Rows {
+	return func(lang string, write func(...interface{}) error) error {
+		for _, s := range report.Series {
+			for _, p := range s.Points {
+				if err := write(s.Key, p.Period, p.Deals, p.Amount, p.Weighted, p.Change, report.Currency); err != nil {
+					return err
+				}
+			}
+			if s.Undated.Deals > 0 {
+				u := s.Undated
+				if err := write(s.Key, textUndated.In(lang), u.Deals, u.Amount, u.Weighted, nil, report.Currency); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityHandler handles HTTP requests for the lead and deal timelines.
+type ActivityHandler struct {
+	service services.ActivityService
+}
+
+// NewActivityHandler creates a new ActivityHandler.
+func NewActivityHandler(service services.ActivityService) *ActivityHandler {
+	return &ActivityHandler{service: service}
+}
+
+// LeadTimeline handles GET /leads/:id/timeline?type=...&from=...&to=...&page=...&size=...
+func (h *ActivityHandler) LeadTimeline(c *gin.Context) {
+	h.timeline(c, h.service.LeadTimeline)
+}
+
+// DealTimeline handles GET /deals/:id/timeline?type=...&from=...&to=...&page=...&size=...
+func (h *ActivityHandler) DealTimeline(c *gin.Context) {
+	h.timeline(c, h.service.DealTimeline)
+}
+
+type timelineFunc func(ctx context.Context, id int64, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error)
+
+func (h *ActivityHandler) timeline(c *gin.Context, list timelineFunc) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	filter, err := activityFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	activities, err := list(c.Request.Context(), id, filter, page, size)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTimelineNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidActivityFilter):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve timeline"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, activities)
+}
+
+// activityFilterFromQuery reads the event types (repeated or comma-separated) and the
+// RFC3339 time range.
+func activityFilterFromQuery(c *gin.Context) (models.ActivityFilter, error) {
+	var filter models.ActivityFilter
+	for _, value := range c.QueryArray("type") {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value, ok := c.GetQuery(name); ok {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s format, use RFC3339", name)
+			}
+			*target = &t
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for multipart headers on top of the file size limit.
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler handles HTTP requests for message attachments.
+type AttachmentHandler struct {
+	service services.AttachmentService
+	maxSize int64
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler. maxSize caps the request body.
+func NewAttachmentHandler(service services.AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{service: service, maxSize: maxSize}
+}
+
+// Upload handles POST /attachments (multipart form, field "file")
+// The returned ID is passed in attachment_ids when sending a message.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.service.Upload(c.Request.Context(), middleware.CurrentUserID(c), fileHeader.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAttachmentType):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// Download handles GET /attachments/:id
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	h.serve(c, false)
+}
+
+// Thumbnail handles GET /attachments/:id/thumbnail
+func (h *AttachmentHandler) Thumbnail(c *gin.Context) {
+	h.serve(c, true)
+}
+
+func (h *AttachmentHandler) serve(c *gin.Context, thumbnail bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, file, err := h.service.Open(c.Request.Context(), middleware.CurrentUserID(c), id, thumbnail)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttachmentNotFound), errors.Is(err, services.ErrAttachmentNoThumbnail):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNotConversationMember):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open attachment"})
+		}
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open attachment"})
+		return
+	}
+
+	disposition, contentType := "attachment", attachment.ContentType
+	if thumbnail {
+		disposition, contentType = "inline", "image/jpeg"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, attachment.FileName, info.ModTime(), file)
+}
+
+// isAttachmentInputError reports whether err is caused by the attachments of a message being sent.
+func isAttachmentInputError(err error) bool {
+	return errors.Is(err, services.ErrEmptyMessage) ||
+		errors.Is(err, services.ErrTooManyAttachments) ||
+		errors.Is(err, services.ErrAttachmentUnavailable)
+}
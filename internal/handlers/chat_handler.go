@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"turcompany/internal/middleware"
+	"turcompany/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// ChatHandler обслуживает WebSocket-подключения чата.
+type ChatHandler struct {
+	hub      *realtime.Hub
+	upgrader websocket.Upgrader
+}
+
+// NewChatHandler creates a new ChatHandler.
+func NewChatHandler(hub *realtime.Hub) *ChatHandler {
+	return &ChatHandler{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// CORS для API открыт (см. corsMiddleware), доступ ограничен токеном
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// @Summary      WebSocket чата
+// @Description  Открывает WebSocket для получения событий чата: message, typing, presence. Клиент может отправлять {"type":"typing","to":<user_id>,"typing":true}. Токен передаётся в заголовке Authorization или параметре token.
+// @Tags         Messages
+// @Param        token  query  string  false  "Access-токен (если нельзя передать заголовок)"
+// @Success      101  "Switching Protocols"
+// @Failure      401  {object}  map[string]string
+// @Router       /ws/chat [get]
+// Connect handles GET /ws/chat
+func (h *ChatHandler) Connect(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Chat upgrade: %v", err)
+		return
+	}
+	h.hub.Serve(conn, userID)
+}
+
+// @Summary      Пользователи в сети
+// @Description  Возвращает ID пользователей, подключённых к чату
+// @Tags         Messages
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string][]int64
+// @Failure      401  {object}  map[string]string
+// @Router       /messages/online [get]
+// OnlineUsers handles GET /messages/online
+func (h *ChatHandler) OnlineUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"user_ids": h.hub.OnlineUsers()})
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConversationHandler handles HTTP requests for group conversations.
+type ConversationHandler struct {
+	service services.ConversationService
+}
+
+// NewConversationHandler creates a new ConversationHandler.
+func NewConversationHandler(service services.ConversationService) *ConversationHandler {
+	return &ConversationHandler{service: service}
+}
+
+// Create handles POST /conversations
+func (h *ConversationHandler) Create(c *gin.Context) {
+	var req struct {
+		Title          string  `json:"title" binding:"required,max=255"`
+		EntityType     string  `json:"entity_type"`
+		EntityID       int64   `json:"entity_id"`
+		ParticipantIDs []int64 `json:"participant_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv := &models.GroupConversation{
+		Title:      req.Title,
+		EntityType: req.EntityType,
+		EntityID:   req.EntityID,
+		CreatedBy:  middleware.CurrentUserID(c),
+	}
+
+	created, err := h.service.Create(c.Request.Context(), conv, req.ParticipantIDs)
+	if err != nil {
+		conversationError(c, err, "Failed to create conversation")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// List handles GET /conversations
+// With entity_type and entity_id it lists the threads linked to that lead, deal or document.
+func (h *ConversationHandler) List(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	if entityType := c.Query("entity_type"); entityType != "" {
+		entityID, err := strconv.ParseInt(c.Query("entity_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity ID"})
+			return
+		}
+		conversations, err := h.service.ListByEntity(c.Request.Context(), userID, entityType, entityID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversations"})
+			return
+		}
+		c.JSON(http.StatusOK, conversations)
+		return
+	}
+
+	conversations, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversations"})
+		return
+	}
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetByID handles GET /conversations/:id
+func (h *ConversationHandler) GetByID(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	conv, err := h.service.Get(c.Request.Context(), middleware.CurrentUserID(c), id)
+	if err != nil {
+		conversationError(c, err, "Failed to retrieve conversation")
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// AddParticipants handles POST /conversations/:id/participants
+func (h *ConversationHandler) AddParticipants(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserIDs []int64 `json:"user_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	participants, err := h.service.AddParticipants(c.Request.Context(), middleware.CurrentUserID(c), id, req.UserIDs)
+	if err != nil {
+		conversationError(c, err, "Failed to add participants")
+		return
+	}
+	c.JSON(http.StatusOK, participants)
+}
+
+// RemoveParticipant handles DELETE /conversations/:id/participants/:user_id
+func (h *ConversationHandler) RemoveParticipant(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+	participantID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.service.RemoveParticipant(c.Request.Context(), middleware.CurrentUserID(c), id, participantID); err != nil {
+		conversationError(c, err, "Failed to remove participant")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Send handles POST /conversations/:id/messages
+func (h *ConversationHandler) Send(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content       string  `json:"content" binding:"max=2000"`
+		Mentions      []int64 `json:"mentions"`
+		AttachmentIDs []int64 `json:"attachment_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.service.Send(c.Request.Context(), middleware.CurrentUserID(c), id, req.Content, req.Mentions, req.AttachmentIDs)
+	if err != nil {
+		conversationError(c, err, "Failed to send message")
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+// GetHistory handles GET /conversations/:id/messages
+// Query params: before or after (message ID, exclusive) and limit (default 50, max 200).
+func (h *ConversationHandler) GetHistory(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var cursor models.MessageCursor
+	var err error
+	if cursor.Before, err = queryInt64(c, "before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+		return
+	}
+	if cursor.After, err = queryInt64(c, "after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after"})
+		return
+	}
+	limit, err := queryInt64(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	cursor.Limit = int(limit)
+
+	page, err := h.service.GetHistory(c.Request.Context(), middleware.CurrentUserID(c), id, cursor)
+	if err != nil {
+		conversationError(c, err, "Failed to retrieve history")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// MarkRead handles POST /conversations/:id/read
+func (h *ConversationHandler) MarkRead(c *gin.Context) {
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MessageID int64 `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.MarkRead(c.Request.Context(), middleware.CurrentUserID(c), id, req.MessageID); err != nil {
+		conversationError(c, err, "Failed to mark conversation as read")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetMentions handles GET /conversations/mentions?page=...&size=...
+func (h *ConversationHandler) GetMentions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 50
+	}
+
+	mentions, err := h.service.GetMentions(c.Request.Context(), middleware.CurrentUserID(c), size, (page-1)*size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mentions"})
+		return
+	}
+	c.JSON(http.StatusOK, mentions)
+}
+
+func conversationID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// conversationError maps conversation service errors to HTTP responses.
+func conversationError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrConversationNotFound), errors.Is(err, services.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotConversationMember), errors.Is(err, services.ErrNotConversationOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidEntity),
+		errors.Is(err, services.ErrMentionNotParticipant),
+		errors.Is(err, services.ErrTooManyParticipants),
+		errors.Is(err, services.ErrEmptyConversationTitle),
+		errors.Is(err, services.ErrInvalidCursor),
+		isAttachmentInputError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
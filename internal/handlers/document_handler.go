@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 	"turcompany/internal/models"
 	"turcompany/internal/services"
@@ -9,11 +10,12 @@ import (
 )
 
 type DocumentHandler struct {
-	Service *services.DocumentService
+	Service        *services.DocumentService
+	SigningService *services.DocumentSigningService
 }
 
-func NewDocumentHandler(service *services.DocumentService) *DocumentHandler {
-	return &DocumentHandler{Service: service}
+func NewDocumentHandler(service *services.DocumentService, signingService *services.DocumentSigningService) *DocumentHandler {
+	return &DocumentHandler{Service: service, SigningService: signingService}
 }
 
 // @Summary      Создание документа
@@ -184,3 +186,118 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 
 	c.JSON(200, docs)
 }
+
+// @Summary      Запросить подписание документа
+// @Description  Отправляет на телефон подписанта SMS с кодом для подписания документа
+// @Tags         Documents
+// @Accept       json
+// @Produce      json
+// @Param        id     path  int64                true  "ID документа"
+// @Param        input  body  object{phone=string}  true  "Телефон подписанта"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /documents/{id}/sign/request [post]
+func (h *DocumentHandler) RequestSignature(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.SigningService.RequestSignature(id, input.Phone); err != nil {
+		c.JSON(signingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Код подтверждения отправлен"})
+}
+
+// @Summary      Подписать документ
+// @Description  Подтверждает код из SMS и подписывает документ: добавляет лист подписи в PDF и сохраняет SHA-256 файла
+// @Tags         Documents
+// @Accept       json
+// @Produce      json
+// @Param        id     path  int64               true  "ID документа"
+// @Param        input  body  object{code=string}  true  "Код из SMS"
+// @Success      200  {object}  models.DocumentSignature
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /documents/{id}/sign/confirm [post]
+func (h *DocumentHandler) ConfirmSignature(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sig, err := h.SigningService.Sign(id, input.Code, services.SignerInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		c.JSON(signingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, sig)
+}
+
+// @Summary      Проверить подпись документа
+// @Description  Возвращает сведения о подписи и сверяет SHA-256 подписанного файла с сохранённым
+// @Tags         Documents
+// @Produce      json
+// @Param        id   path  int64  true  "ID документа"
+// @Success      200  {object}  services.SignatureCheck
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /documents/{id}/signature [get]
+func (h *DocumentHandler) GetSignature(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	check, err := h.SigningService.Verify(id)
+	if err != nil {
+		c.JSON(signingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, check)
+}
+
+func signingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrDocumentNotFound):
+		return 404
+	case errors.Is(err, services.ErrDocumentAlreadySigned):
+		return 409
+	case errors.Is(err, services.ErrInvalidSignCode):
+		return 400
+	default:
+		return smsErrorStatus(err)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InboxHandler handles HTTP requests for the unified inbox of client messages.
+type InboxHandler struct {
+	service services.InboxService
+}
+
+// NewInboxHandler creates a new InboxHandler.
+func NewInboxHandler(service services.InboxService) *InboxHandler {
+	return &InboxHandler{service: service}
+}
+
+// ListThreads handles GET /inbox/threads?mine=true&channel=...&unread=true&page=...&size=...
+func (h *InboxHandler) ListThreads(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 50
+	}
+
+	filter := models.InboxThreadFilter{
+		Channel:    c.Query("channel"),
+		UnreadOnly: c.Query("unread") == "true",
+		Limit:      size,
+		Offset:     (page - 1) * size,
+	}
+	if c.Query("mine") == "true" {
+		userID := middleware.CurrentUserID(c)
+		filter.OwnerID = &userID
+	}
+
+	threads, err := h.service.ListThreads(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve inbox"})
+		return
+	}
+	c.JSON(http.StatusOK, threads)
+}
+
+// GetThread handles GET /inbox/threads/:id
+func (h *InboxHandler) GetThread(c *gin.Context) {
+	id, ok := inboxThreadID(c)
+	if !ok {
+		return
+	}
+	thread, err := h.service.GetThread(c.Request.Context(), id)
+	if err != nil {
+		inboxError(c, err, "Failed to retrieve thread")
+		return
+	}
+	c.JSON(http.StatusOK, thread)
+}
+
+// GetThreadByLead handles GET /inbox/leads/:lead_id
+func (h *InboxHandler) GetThreadByLead(c *gin.Context) {
+	leadID, err := strconv.ParseInt(c.Param("lead_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return
+	}
+	thread, err := h.service.GetThreadByLead(c.Request.Context(), leadID)
+	if err != nil {
+		inboxError(c, err, "Failed to retrieve thread")
+		return
+	}
+	c.JSON(http.StatusOK, thread)
+}
+
+// GetMessages handles GET /inbox/threads/:id/messages?before=...&after=...&limit=...
+func (h *InboxHandler) GetMessages(c *gin.Context) {
+	id, ok := inboxThreadID(c)
+	if !ok {
+		return
+	}
+
+	var cursor models.MessageCursor
+	var err error
+	if cursor.Before, err = queryInt64(c, "before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+		return
+	}
+	if cursor.After, err = queryInt64(c, "after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after"})
+		return
+	}
+	limit, err := queryInt64(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	cursor.Limit = int(limit)
+
+	page, err := h.service.GetMessages(c.Request.Context(), id, cursor)
+	if err != nil {
+		inboxError(c, err, "Failed to retrieve messages")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Reply handles POST /inbox/threads/:id/reply
+// Without a channel the reply goes where the client wrote last; "internal" adds a note
+// visible only in the CRM. A failed delivery is returned with 502 and status failed.
+func (h *InboxHandler) Reply(c *gin.Context) {
+	id, ok := inboxThreadID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Channel string `json:"channel"`
+		Body    string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.service.Reply(c.Request.Context(), middleware.CurrentUserID(c), id, req.Channel, req.Body)
+	if err != nil {
+		inboxError(c, err, "Failed to send reply")
+		return
+	}
+	if msg.Status == models.InboxStatusFailed {
+		c.JSON(http.StatusBadGateway, msg)
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+// MarkRead handles POST /inbox/threads/:id/read
+func (h *InboxHandler) MarkRead(c *gin.Context) {
+	id, ok := inboxThreadID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.MarkRead(c.Request.Context(), id); err != nil {
+		inboxError(c, err, "Failed to mark thread as read")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func inboxThreadID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// inboxError maps inbox service errors to HTTP responses.
+func inboxError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInboxThreadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownChannel),
+		errors.Is(err, services.ErrNoChannelAddress),
+		errors.Is(err, services.ErrNoReplyChannel),
+		errors.Is(err, services.ErrEmptyInboxMessage),
+		errors.Is(err, services.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChannelUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"turcompany/internal/middleware"
 	"turcompany/internal/models"
 	"turcompany/internal/services"
 )
@@ -19,10 +21,12 @@ func NewMessageHandler(service services.MessageService) *MessageHandler {
 }
 
 // Send handles POST /messages
+// Files are uploaded first via POST /attachments and referenced by attachment_ids.
 func (h *MessageHandler) Send(c *gin.Context) {
 	var req struct {
-		ReceiverID int64  `json:"receiver_id" binding:"required"`
-		Content    string `json:"content" binding:"required"`
+		ReceiverID    int64   `json:"receiver_id" binding:"required"`
+		Content       string  `json:"content" binding:"max=2000"`
+		AttachmentIDs []int64 `json:"attachment_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -30,8 +34,7 @@ func (h *MessageHandler) Send(c *gin.Context) {
 		return
 	}
 
-	// senderID := c.MustGet("userID").(int64)
-	senderID := int64(1) // Placeholder
+	senderID := middleware.CurrentUserID(c)
 
 	msg := &models.Message{
 		SenderID:   senderID,
@@ -39,8 +42,12 @@ func (h *MessageHandler) Send(c *gin.Context) {
 		Content:    req.Content,
 	}
 
-	sentMsg, err := h.service.Send(c.Request.Context(), msg)
+	sentMsg, err := h.service.Send(c.Request.Context(), msg, req.AttachmentIDs)
 	if err != nil {
+		if isAttachmentInputError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
 		return
 	}
@@ -48,6 +55,7 @@ func (h *MessageHandler) Send(c *gin.Context) {
 }
 
 // GetConversationHistory handles GET /messages/history/:partner_id
+// Query params: before or after (message ID, exclusive) and limit (default 50, max 200).
 func (h *MessageHandler) GetConversationHistory(c *gin.Context) {
 	partnerID, err := strconv.ParseInt(c.Param("partner_id"), 10, 64)
 	if err != nil {
@@ -55,21 +63,39 @@ func (h *MessageHandler) GetConversationHistory(c *gin.Context) {
 		return
 	}
 
-	// userID := c.MustGet("userID").(int64)
-	userID := int64(1) // Placeholder
+	var cursor models.MessageCursor
+	if cursor.Before, err = queryInt64(c, "before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+		return
+	}
+	if cursor.After, err = queryInt64(c, "after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after"})
+		return
+	}
+	limit, err := queryInt64(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	cursor.Limit = int(limit)
+
+	userID := middleware.CurrentUserID(c)
 
-	history, err := h.service.GetConversationHistory(c.Request.Context(), userID, partnerID)
+	page, err := h.service.GetConversationHistory(c.Request.Context(), userID, partnerID, cursor)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
 		return
 	}
-	c.JSON(http.StatusOK, history)
+	c.JSON(http.StatusOK, page)
 }
 
 // GetConversations handles GET /messages/conversations
 func (h *MessageHandler) GetConversations(c *gin.Context) {
-	// userID := c.MustGet("userID").(int64)
-	userID := int64(1) // Placeholder
+	userID := middleware.CurrentUserID(c)
 
 	conversations, err := h.service.GetConversations(c.Request.Context(), userID)
 	if err != nil {
@@ -78,3 +104,87 @@ func (h *MessageHandler) GetConversations(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, conversations)
 }
+
+// MarkRead handles POST /messages/read
+func (h *MessageHandler) MarkRead(c *gin.Context) {
+	var req struct {
+		MessageID int64 `json:"message_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.CurrentUserID(c)
+
+	marked, err := h.service.MarkRead(c.Request.Context(), userID, req.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case errors.Is(err, services.ErrNotConversationMember):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this conversation"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// GetUnreadCount handles GET /messages/unread-count
+func (h *MessageHandler) GetUnreadCount(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	count, err := h.service.GetUnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// Search handles GET /messages/search?q=...&partner_id=...&page=...&size=...
+func (h *MessageHandler) Search(c *gin.Context) {
+	partnerID, err := queryInt64(c, "partner_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 50
+	}
+
+	userID := middleware.CurrentUserID(c)
+
+	results, err := h.service.Search(c.Request.Context(), userID, partnerID, c.Query("q"), size, (page-1)*size)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// queryInt64 parses an optional non-negative integer query parameter; missing means 0.
+func queryInt64(c *gin.Context, name string) (int64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || v < 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	return v, nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles HTTP requests for the in-app notifications of the current user.
+type NotificationHandler struct {
+	service services.NotificationService
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(service services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// List handles GET /notifications?unread=true&page=...&size=...
+func (h *NotificationHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 50
+	}
+
+	notifications, err := h.service.List(c.Request.Context(), middleware.CurrentUserID(c), c.Query("unread") == "true", size, (page-1)*size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, notifications)
+}
+
+// GetUnreadCount handles GET /notifications/unread-count
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	count, err := h.service.UnreadCount(c.Request.Context(), middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkRead handles POST /notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+	if err := h.service.MarkRead(c.Request.Context(), middleware.CurrentUserID(c), id); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead handles POST /notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	if err := h.service.MarkAllRead(c.Request.Context(), middleware.CurrentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/querydsl"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportBuilderHandler handles HTTP requests for the report builder.
+type ReportBuilderHandler struct {
+	service services.ReportBuilderService
+}
+
+// NewReportBuilderHandler creates a new ReportBuilderHandler.
+func NewReportBuilderHandler(service services.ReportBuilderService) *ReportBuilderHandler {
+	return &ReportBuilderHandler{service: service}
+}
+
+type reportBuilderRequest struct {
+	querydsl.Query
+	Page int `json:"page"`
+	Size int `json:"size"`
+}
+
+// @Summary Поля конструктора отчётов
+// @Description Поля лидов, сделок, задач и документов, доступные для фильтров, сортировки и группировки.
+// @Tags Reports
+// @Produce json
+// @Success 200 {object} map[string][]querydsl.FieldInfo
+// @Router /reports/builder [get]
+func (h *ReportBuilderHandler) Schemas(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Schemas())
+}
+
+// @Summary Конструктор отчётов (строка запроса)
+// @Description Фильтры filter=поле:оператор:значение (eq, ne, in, range, like, is_null), sort=-поле,поле, group_by=поле или поле:интервал, agg=count,sum:поле, fields=поле,поле.
+// @Tags Reports
+// @Produce json
+// @Param entity path string true "Сущность (leads, deals, tasks, documents)"
+// @Param filter query []string false "Фильтр, например status:in:new,won или created_at:range:2024-01-01,2024-03-31"
+// @Param sort query string false "Сортировка, например -created_at"
+// @Param group_by query string false "Группировка, например created_at:month,status"
+// @Param agg query string false "Агрегаты, например count,sum:amount"
+// @Param fields query string false "Поля строк без группировки"
+// @Param page query int false "Страница"
+// @Param size query int false "Размер страницы"
+// @Success 200 {object} models.BuilderResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/builder/{entity} [get]
+func (h *ReportBuilderHandler) Query(c *gin.Context) {
+	q, err := querydsl.ParseValues(c.Request.URL.Query())
+	if err != nil {
+		reportBuilderError(c, err)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size"))
+	h.run(c, q, page, size)
+}
+
+// @Summary Конструктор отчётов (JSON)
+// @Description Тот же запрос в теле: {"filters": [{"field": "status", "op": "in", "value": ["new", "won"]}], "sort": ["-created_at"], "group_by": ["created_at:month"], "aggregates": ["count"], "page": 1, "size": 100}.
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param entity path string true "Сущность (leads, deals, tasks, documents)"
+// @Success 200 {object} models.BuilderResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/builder/{entity} [post]
+func (h *ReportBuilderHandler) QueryJSON(c *gin.Context) {
+	var req reportBuilderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.run(c, req.Query, req.Page, req.Size)
+}
+
+func (h *ReportBuilderHandler) run(c *gin.Context, q querydsl.Query, page, size int) {
+	result, err := h.service.Run(c.Request.Context(), c.Param("entity"), q, page, size)
+	if err != nil {
+		reportBuilderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func reportBuilderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownBuilderEntity):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, querydsl.ErrInvalidQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"turcompany/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFormat returns the file format asked for with ?format=; empty means JSON. It writes
+// the error response itself.
+func exportFormat(c *gin.Context) (string, bool) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" || format == "json" {
+		return "", true
+	}
+	if !export.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": export.ErrUnknownFormat.Error()})
+		return "", false
+	}
+	return format, true
+}
+
+// writeExport streams a report file; rows writes the rows with write. Errors before the
+// first byte is sent get a JSON response, later ones can only cut the file short.
+func (h *ReportHandler) writeExport(c *gin.Context, format string, report export.Report, rows export.Rows) {
+	lang := export.Language(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(report.Name, format, time.Now())))
+
+	err := export.Write(format, c.Writer, report, lang, export.Options{PDFFont: h.PDFFont}, rows)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		log.Printf("Export of report %s (%s) interrupted: %v", report.Name, format, err)
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	if errors.Is(err, export.ErrTooManyRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export report"})
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	"turcompany/internal/export"
+	"turcompany/internal/models"
 	"turcompany/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -10,25 +15,37 @@ import (
 
 type ReportHandler struct {
 	Service *services.ReportService
+	PDFFont string // TTF-шрифт для выгрузки в PDF, пусто — встроенный
 }
 
-func NewReportHandler(service *services.ReportService) *ReportHandler {
-	return &ReportHandler{Service: service}
+func NewReportHandler(service *services.ReportService, pdfFont string) *ReportHandler {
+	return &ReportHandler{Service: service, PDFFont: pdfFont}
 }
 
 // @Summary Сводный отчет
 // @Description Выводит общее количество лидов и сделок.
 // @Tags Reports
 // @Produce json
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
 // @Success 200 {object} map[string]int
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports/summary [get]
 func (h *ReportHandler) GetSummary(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
 	data, err := h.Service.GetSummary()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if format != "" {
+		h.writeExport(c, format, export.SummaryReport, export.SummaryRows(data))
+		return
+	}
 	c.JSON(http.StatusOK, data)
 }
 
@@ -42,15 +59,29 @@ func (h *ReportHandler) GetSummary(c *gin.Context) {
 // @Param order query string false "Порядок сортировки (asc, desc)"
 // @Param page query int false "Номер страницы"
 // @Param size query int false "Размер страницы"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON; выгружаются все страницы"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
 // @Success 200 {array} models.Leads
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports/leads/filter [get]
 func (h *ReportHandler) FilterLeads(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
 	status := c.Query("status")
 	ownerID, _ := strconv.Atoi(c.DefaultQuery("owner_id", "0"))
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	order := c.DefaultQuery("order", "desc")
+	if format != "" {
+		h.writeExport(c, format, export.LeadsReport, func(_ string, write func(...interface{}) error) error {
+			return h.Service.EachFilteredLead(status, ownerID, sortBy, order, func(lead *models.Leads) error {
+				return export.WriteLead(write, lead)
+			})
+		})
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "100"))
 	if page < 1 {
@@ -83,11 +114,17 @@ func (h *ReportHandler) FilterLeads(c *gin.Context) {
 // @Param order query string false "Порядок сортировки (asc, desc)"
 // @Param page query int false "Номер страницы"
 // @Param size query int false "Размер страницы"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON; выгружаются все страницы"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
 // @Success 200 {array} models.Deals
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports/deals/filter [get]
 func (h *ReportHandler) FilterDeals(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
 	status := c.Query("status")
 	from := c.Query("from")
 	to := c.Query("to")
@@ -99,6 +136,14 @@ func (h *ReportHandler) FilterDeals(c *gin.Context) {
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "100"))
 	amountMin, _ := strconv.ParseFloat(c.DefaultQuery("amount_min", "0"), 64)
 	amountMax, _ := strconv.ParseFloat(c.DefaultQuery("amount_max", "0"), 64)
+	if format != "" {
+		h.writeExport(c, format, export.DealsReport, func(_ string, write func(...interface{}) error) error {
+			return h.Service.EachFilteredDeal(status, from, to, currency, amountMin, amountMax, sortBy, order, func(deal *models.Deals) error {
+				return export.WriteDeal(write, deal)
+			})
+		})
+		return
+	}
 
 	if page < 1 {
 		page = 1
@@ -115,3 +160,296 @@ func (h *ReportHandler) FilterDeals(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, deals)
 }
+
+// @Summary Воронка лидов
+// @Description Количество лидов в каждом статусе с разбивкой по владельцу, источнику или периоду.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата создания с (yyyy-mm-dd)"
+// @Param to query string false "Дата создания по (yyyy-mm-dd), включительно"
+// @Param owner_id query int false "ID владельца"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, period)"
+// @Param interval query string false "Период для group_by=period (day, week, month)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.FunnelReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/funnel [get]
+func (h *ReportHandler) Funnel(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Funnel(c.Request.Context(), filter)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.FunnelReport, export.FunnelRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Конверсия лидов в сделки
+// @Description Доля лидов, ставших сделками, и среднее время до первой сделки.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата создания лида с (yyyy-mm-dd)"
+// @Param to query string false "Дата создания лида по (yyyy-mm-dd), включительно"
+// @Param owner_id query int false "ID владельца"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, period)"
+// @Param interval query string false "Период для group_by=period (day, week, month)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.ConversionReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/conversion [get]
+func (h *ReportHandler) Conversion(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Conversion(c.Request.Context(), filter)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.ConversionReport, export.ConversionRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Время на этапах
+// @Description Среднее время, которое лиды или сделки проводят в каждом статусе.
+// @Tags Reports
+// @Produce json
+// @Param entity query string false "lead или deal (по умолчанию lead)"
+// @Param from query string false "Начало этапа с (yyyy-mm-dd)"
+// @Param to query string false "Начало этапа по (yyyy-mm-dd), включительно"
+// @Param owner_id query int false "ID владельца лида"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, period)"
+// @Param interval query string false "Период для group_by=period (day, week, month)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.StageDurationReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/stage-durations [get]
+func (h *ReportHandler) StageDurations(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	entity := c.DefaultQuery("entity", models.EntityTypeLead)
+	report, err := h.Service.StageDurations(c.Request.Context(), entity, filter)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.StageDurationsReport, export.StageDurationRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Выигранные и проигранные сделки
+// @Description Число выигранных, проигранных и открытых сделок, доля побед и причины проигрыша.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата создания сделки с (yyyy-mm-dd)"
+// @Param to query string false "Дата создания сделки по (yyyy-mm-dd), включительно"
+// @Param owner_id query int false "ID владельца лида"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, period)"
+// @Param interval query string false "Период для group_by=period (day, week, month)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.WinLossReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/win-loss [get]
+func (h *ReportHandler) WinLoss(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.WinLoss(c.Request.Context(), filter)
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.WinLossReport, export.WinLossRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Выручка
+// @Description Сумма выигранных сделок по периодам с нулями для пустых периодов, в одной валюте, со сравнением с предыдущим периодом.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата с (yyyy-mm-dd), по умолчанию 12 периодов до текущего включительно"
+// @Param to query string false "Дата по (yyyy-mm-dd), включительно"
+// @Param interval query string false "Период (day, week, month), по умолчанию month"
+// @Param currency query string false "Валюта отчёта, по умолчанию базовая"
+// @Param owner_id query int false "ID владельца лида"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, destination)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.RevenueReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/revenue [get]
+func (h *ReportHandler) Revenue(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Revenue(c.Request.Context(), filter, c.Query("currency"))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.RevenueReport, export.RevenueRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Прогноз продаж
+// @Description Открытые сделки по дате вылета, взвешенные по вероятности этапа, в одной валюте.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата вылета с (yyyy-mm-dd), по умолчанию начало текущего периода"
+// @Param to query string false "Дата вылета по (yyyy-mm-dd), включительно"
+// @Param interval query string false "Период (day, week, month), по умолчанию month"
+// @Param currency query string false "Валюта отчёта, по умолчанию базовая"
+// @Param owner_id query int false "ID владельца лида"
+// @Param source query string false "Источник лида"
+// @Param group_by query string false "Разбивка (owner, source, destination, status)"
+// @Param format query string false "Формат выгрузки (csv, xlsx, pdf), по умолчанию JSON"
+// @Param lang query string false "Язык заголовков выгрузки (ru, kk), по умолчанию из Accept-Language"
+// @Success 200 {object} models.ForecastReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/forecast [get]
+func (h *ReportHandler) Forecast(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Forecast(c.Request.Context(), filter, c.Query("currency"))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	if format != "" {
+		h.writeExport(c, format, export.ForecastReport, export.ForecastRows(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Дашборд
+// @Description Ключевые показатели по лидам и сделкам, созданным за период, и открытая воронка. Строится по дневным сводкам, которые обновляются в фоне.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "Дата с (yyyy-mm-dd), по умолчанию 12 периодов до текущего включительно"
+// @Param to query string false "Дата по (yyyy-mm-dd), включительно"
+// @Param interval query string false "Период ряда (day, week, month), по умолчанию month"
+// @Param currency query string false "Валюта отчёта, по умолчанию базовая"
+// @Param owner_id query int false "ID владельца лида"
+// @Param source query string false "Источник лида"
+// @Success 200 {object} models.DashboardReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/dashboard [get]
+func (h *ReportHandler) Dashboard(c *gin.Context) {
+	filter, ok := reportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	report, err := h.Service.Dashboard(c.Request.Context(), filter, c.Query("currency"))
+	if err != nil {
+		reportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// reportFilterFromQuery reads the report filter; the to date is inclusive. It writes the
+// error response itself.
+func reportFilterFromQuery(c *gin.Context) (models.ReportFilter, bool) {
+	filter := models.ReportFilter{
+		Source:   c.Query("source"),
+		GroupBy:  c.Query("group_by"),
+		Interval: c.Query("interval"),
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s date, use yyyy-mm-dd", name)})
+				return filter, false
+			}
+			if name == "to" {
+				t = t.AddDate(0, 0, 1)
+			}
+			*target = &t
+		}
+	}
+	if value := c.Query("owner_id"); value != "" {
+		ownerID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+			return filter, false
+		}
+		filter.OwnerID = &ownerID
+	}
+	return filter, true
+}
+
+func reportError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidReportFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+}
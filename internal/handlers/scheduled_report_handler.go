@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduledReportHandler handles HTTP requests for reports emailed on a schedule.
+type ScheduledReportHandler struct {
+	service services.ScheduledReportService
+}
+
+// NewScheduledReportHandler creates a new ScheduledReportHandler.
+func NewScheduledReportHandler(service services.ScheduledReportService) *ScheduledReportHandler {
+	return &ScheduledReportHandler{service: service}
+}
+
+type scheduledReportRequest struct {
+	Name       string                       `json:"name" binding:"required"`
+	ReportType string                       `json:"report_type" binding:"required"`
+	Params     models.ScheduledReportParams `json:"params"`
+	Format     string                       `json:"format" binding:"required"`
+	Lang       string                       `json:"lang"`
+	Recipients []string                     `json:"recipients" binding:"required"`
+	Schedule   string                       `json:"schedule" binding:"required"`
+	Timezone   string                       `json:"timezone"`
+	Active     *bool                        `json:"active"`
+}
+
+func (r *scheduledReportRequest) report() *models.ScheduledReport {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &models.ScheduledReport{
+		Name:       r.Name,
+		ReportType: r.ReportType,
+		Params:     r.Params,
+		Format:     r.Format,
+		Lang:       r.Lang,
+		Recipients: r.Recipients,
+		Schedule:   r.Schedule,
+		Timezone:   r.Timezone,
+		Active:     active,
+	}
+}
+
+// Create handles POST /scheduled-reports
+func (h *ScheduledReportHandler) Create(c *gin.Context) {
+	var req scheduledReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r := req.report()
+	r.CreatedBy = middleware.CurrentUserID(c)
+	if err := h.service.Create(c.Request.Context(), r); err != nil {
+		scheduledReportError(c, err, "Failed to create scheduled report")
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// List handles GET /scheduled-reports
+func (h *ScheduledReportHandler) List(c *gin.Context) {
+	reports, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve scheduled reports"})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetByID handles GET /scheduled-reports/:id
+func (h *ScheduledReportHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	r, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		scheduledReportError(c, err, "Failed to retrieve scheduled report")
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// Update handles PUT /scheduled-reports/:id
+func (h *ScheduledReportHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req scheduledReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r := req.report()
+	r.ID = id
+	if err := h.service.Update(c.Request.Context(), r); err != nil {
+		scheduledReportError(c, err, "Failed to update scheduled report")
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// Delete handles DELETE /scheduled-reports/:id
+func (h *ScheduledReportHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		scheduledReportError(c, err, "Failed to delete scheduled report")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SendNow handles POST /scheduled-reports/:id/send
+func (h *ScheduledReportHandler) SendNow(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	delivery, err := h.service.SendNow(c.Request.Context(), middleware.CurrentUserID(c), id)
+	if err != nil {
+		scheduledReportError(c, err, "Failed to send scheduled report")
+		return
+	}
+	if delivery.Status == models.DeliveryStatusFailed {
+		c.JSON(http.StatusBadGateway, delivery)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Deliveries handles GET /scheduled-reports/:id/deliveries?page=...&size=...
+func (h *ScheduledReportHandler) Deliveries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size"))
+	deliveries, err := h.service.Deliveries(c.Request.Context(), id, page, size)
+	if err != nil {
+		scheduledReportError(c, err, "Failed to retrieve report deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func scheduledReportError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrScheduledReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidScheduledReport):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
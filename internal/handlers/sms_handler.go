@@ -1,9 +1,10 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/services"
 
@@ -15,7 +16,6 @@ type SMSHandler struct {
 }
 
 func NewSMSHandler(service *services.SMS_Service) *SMSHandler {
-	_ = models.SMSConfirmation{}
 	return &SMSHandler{Service: service}
 }
 
@@ -41,8 +41,7 @@ func (h *SMSHandler) SendSMSHandler(c *gin.Context) {
 	}
 
 	if err := h.Service.SendSMS(input.DocumentID, input.Phone); err != nil {
-		fmt.Printf("❌ Failed to send SMS: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(smsErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -68,6 +67,10 @@ func (h *SMSHandler) ResendSMSHandler(c *gin.Context) {
 	}
 
 	if err := h.Service.ResendSMS(documentID, ""); err != nil {
+		if status := smsErrorStatus(err); status != http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend SMS"})
 		return
 	}
@@ -75,40 +78,6 @@ func (h *SMSHandler) ResendSMSHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "SMS resent"})
 }
 
-// ConfirmSMSHandler — подтверждение кода
-// @Summary      Подтвердить SMS-код
-// @Description  Подтверждает введённый код по ID документа
-// @Tags         SMS
-// @Accept       json
-// @Produce      json
-// @Param        input  body  object{document_id=int64,code=string}  true  "ID документа и код подтверждения"
-// @Success      200  {object}  map[string]string
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
-// @Router       /sms/confirm [post]
-func (h *SMSHandler) ConfirmSMSHandler(c *gin.Context) {
-	var input struct {
-		DocumentID int64  `json:"document_id"`
-		Code       string `json:"code"`
-	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-		return
-	}
-
-	ok, err := h.Service.ConfirmCode(input.DocumentID, input.Code)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Confirmation failed"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Code confirmed"})
-}
-
 // GetLatestSMSHandler — получить последнее SMS
 // @Summary      Получить последнее SMS
 // @Description  Возвращает последнее SMS по документу
@@ -141,28 +110,92 @@ func (h *SMSHandler) GetLatestSMSHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, sms)
 }
 
-// DeleteSMSHandler — удалить подтверждения
-// @Summary      Удалить SMS-подтверждения
-// @Description  Удаляет все SMS-подтверждения по документу
+// smsErrorStatus сопоставляет ограничения SMS-сервиса с HTTP-статусами
+func smsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSMSResendCooldown),
+		errors.Is(err, services.ErrSMSDailyLimit),
+		errors.Is(err, services.ErrSMSTooManyAttempts):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListMessagesHandler — журнал исходящих SMS
+// @Summary      Журнал SMS
+// @Description  Возвращает исходящие SMS со статусами доставки (только для администраторов)
 // @Tags         SMS
 // @Produce      json
-// @Param        document_id  path  int64  true  "ID документа"
-// @Success      200  {object}  map[string]string
+// @Security     BearerAuth
+// @Param        recipient    query  string  false  "Номер получателя"
+// @Param        document_id  query  int64   false  "ID документа"
+// @Param        provider     query  string  false  "Провайдер (mobizon, console)"
+// @Param        status       query  string  false  "Статус (queued, sent, delivered, failed)"
+// @Param        from         query  string  false  "Дата с (yyyy-mm-dd)"
+// @Param        to           query  string  false  "Дата по (yyyy-mm-dd)"
+// @Param        page         query  int     false  "Номер страницы"
+// @Param        size         query  int     false  "Размер страницы"
+// @Success      200  {array}   models.SMSMessage
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
-// @Router       /sms/{document_id} [delete]
-func (h *SMSHandler) DeleteSMSHandler(c *gin.Context) {
-	documentIDStr := c.Param("document_id")
-	documentID, err := strconv.ParseInt(documentIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document_id"})
-		return
+// @Router       /admin/sms/messages [get]
+func (h *SMSHandler) ListMessagesHandler(c *gin.Context) {
+	var filter models.SMSMessageFilter
+
+	if recipient := c.Query("recipient"); recipient != "" {
+		filter.Recipient = &recipient
+	}
+	if provider := c.Query("provider"); provider != "" {
+		filter.Provider = &provider
+	}
+	if status := c.Query("status"); status != "" {
+		s := models.SMSMessageStatus(status)
+		filter.Status = &s
+	}
+	if documentIDStr := c.Query("document_id"); documentIDStr != "" {
+		documentID, err := strconv.ParseInt(documentIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document_id"})
+			return
+		}
+		filter.DocumentID = &documentID
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, use yyyy-mm-dd"})
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, use yyyy-mm-dd"})
+			return
+		}
+		// Включаем весь день "по"
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filter.To = &t
 	}
 
-	if err := h.Service.DeleteConfirmation(documentID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete confirmations"})
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "100"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 100
+	}
+	offset := (page - 1) * size
+
+	messages, err := h.Service.ListMessages(filter, size, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SMS messages"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Confirmations deleted"})
+	c.JSON(http.StatusOK, messages)
 }
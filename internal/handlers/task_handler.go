@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"turcompany/internal/middleware"
 	"turcompany/internal/models"
 	"turcompany/internal/services"
 )
@@ -26,7 +30,7 @@ func NewTaskHandler(service services.TaskService) *TaskHandler {
 // @Tags         tasks
 // @Accept       json
 // @Produce      json
-// @Param        task  body  object  true  "Task info (assignee_id, title, description, due_date in RFC3339)"
+// @Param        task  body  object  true  "Task info (assignee_id, title, description, due_date in RFC3339, recurrence as RRULE)"
 // @Success      201   {object}  models.Task
 // @Failure      400   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
@@ -40,6 +44,7 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		Title       string `json:"title" binding:"required"`
 		Description string `json:"description"`
 		DueDate     string `json:"due_date"`
+		Recurrence  string `json:"recurrence"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -47,18 +52,16 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Assume creatorID is set by auth middleware
-	// creatorID := c.MustGet("userID").(int64)
-	creatorID := int64(1) // Placeholder
+	creatorID := middleware.CurrentUserID(c)
 
-	var dueDate time.Time
+	var dueDate *time.Time
 	if req.DueDate != "" {
 		parsedDate, err := time.Parse(time.RFC3339, req.DueDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due_date format, use RFC3339"})
 			return
 		}
-		dueDate = parsedDate
+		dueDate = &parsedDate
 	}
 
 	task := &models.Task{
@@ -68,23 +71,24 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		EntityType:  req.EntityType,
 		Title:       req.Title,
 		Description: req.Description,
-		DueDate:     &dueDate,
+		DueDate:     dueDate,
+		Recurrence:  req.Recurrence,
 	}
 
 	createdTask, err := h.service.Create(c.Request.Context(), task)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		taskError(c, err, "Failed to create task")
 		return
 	}
 	c.JSON(http.StatusCreated, createdTask)
 }
 
 // @Summary      Get task by ID
-// @Description  Получить задачу по ID
+// @Description  Получить задачу по ID с чек-листом, комментариями и историей изменений
 // @Tags         tasks
 // @Produce      json
 // @Param        id   path      int  true  "Task ID"
-// @Success      200  {object}  models.Task
+// @Success      200  {object}  models.TaskDetails
 // @Failure      400  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Router       /tasks/{id} [get]
@@ -96,40 +100,97 @@ func (h *TaskHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	task, err := h.service.GetByID(c.Request.Context(), id)
+	task, err := h.service.GetDetails(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		taskError(c, err, "Failed to retrieve task")
 		return
 	}
 	c.JSON(http.StatusOK, task)
 }
 
 // @Summary      Get all tasks
-// @Description  Получить список всех задач (с фильтрацией по assignee_id)
+// @Description  Получить страницу задач с фильтрами и сортировкой
 // @Tags         tasks
 // @Produce      json
-// @Param        assignee_id  query     int  false  "Assignee ID"
-// @Success      200  {array}   models.Task
+// @Param        assignee_id  query     int     false  "Assignee ID"
+// @Param        creator_id   query     int     false  "Creator ID"
+// @Param        entity_type  query     string  false  "lead, deal or document"
+// @Param        entity_id    query     int     false  "Entity ID"
+// @Param        status       query     string  false  "new, in_progress, done or cancelled"
+// @Param        due_after    query     string  false  "Due at or after, RFC3339"
+// @Param        due_before   query     string  false  "Due before, RFC3339"
+// @Param        overdue      query     bool    false  "Only open tasks past their due date"
+// @Param        q            query     string  false  "Search in the title"
+// @Param        sort         query     string  false  "created_at, updated_at, due_date, title or status; prefix - for descending"
+// @Param        page         query     int     false  "Page number (default 1)"
+// @Param        size         query     int     false  "Page size (default 50, max 200)"
+// @Success      200  {object}  models.TaskPage
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /tasks [get]
 // GetAll handles GET /tasks
 func (h *TaskHandler) GetAll(c *gin.Context) {
-	var filter models.TaskFilter
-
-	if assigneeIDStr, ok := c.GetQuery("assignee_id"); ok {
-		if id, err := strconv.ParseInt(assigneeIDStr, 10, 64); err == nil {
-			filter.AssigneeID = &id
-		}
+	filter, err := taskFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size"))
 
-	tasks, err := h.service.GetAll(c.Request.Context(), filter)
+	tasks, err := h.service.List(c.Request.Context(), filter, page, size)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTaskFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
 	}
 	c.JSON(http.StatusOK, tasks)
 }
 
+func taskFilterFromQuery(c *gin.Context) (models.TaskFilter, error) {
+	filter := models.TaskFilter{
+		Search:  strings.TrimSpace(c.Query("q")),
+		Sort:    c.Query("sort"),
+		Overdue: c.Query("overdue") == "true",
+	}
+	for name, target := range map[string]**int64{
+		"assignee_id": &filter.AssigneeID,
+		"creator_id":  &filter.CreatorID,
+		"entity_id":   &filter.EntityID,
+	} {
+		if value, ok := c.GetQuery(name); ok {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			*target = &id
+		}
+	}
+	for name, target := range map[string]**time.Time{
+		"due_after":  &filter.DueAfter,
+		"due_before": &filter.DueBefore,
+	} {
+		if value, ok := c.GetQuery(name); ok {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s format, use RFC3339", name)
+			}
+			*target = &t
+		}
+	}
+	if value, ok := c.GetQuery("entity_type"); ok {
+		filter.EntityType = &value
+	}
+	if value, ok := c.GetQuery("status"); ok {
+		status := models.TaskStatus(value)
+		filter.Status = &status
+	}
+	return filter, nil
+}
+
 // @Summary      Update task
 // @Description  Обновить задачу по ID
 // @Tags         tasks
@@ -155,9 +216,9 @@ func (h *TaskHandler) Update(c *gin.Context) {
 		return
 	}
 
-	updatedTask, err := h.service.Update(c.Request.Context(), id, &req)
+	updatedTask, err := h.service.Update(c.Request.Context(), middleware.CurrentUserID(c), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		taskError(c, err, "Failed to update task")
 		return
 	}
 	c.JSON(http.StatusOK, updatedTask)
@@ -185,3 +246,157 @@ func (h *TaskHandler) Delete(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// AddChecklistItem handles POST /tasks/:id/checklist
+func (h *TaskHandler) AddChecklistItem(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req struct {
+		Title string `json:"title" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.AddChecklistItem(c.Request.Context(), id, req.Title)
+	if err != nil {
+		taskError(c, err, "Failed to add checklist item")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// UpdateChecklistItem handles PUT /tasks/:id/checklist/:item_id
+func (h *TaskHandler) UpdateChecklistItem(c *gin.Context) {
+	id, itemID, ok := taskChildIDs(c, "item_id")
+	if !ok {
+		return
+	}
+	var req models.TaskChecklistUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.UpdateChecklistItem(c.Request.Context(), id, itemID, req)
+	if err != nil {
+		taskError(c, err, "Failed to update checklist item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// DeleteChecklistItem handles DELETE /tasks/:id/checklist/:item_id
+func (h *TaskHandler) DeleteChecklistItem(c *gin.Context) {
+	id, itemID, ok := taskChildIDs(c, "item_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteChecklistItem(c.Request.Context(), id, itemID); err != nil {
+		taskError(c, err, "Failed to delete checklist item")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddComment handles POST /tasks/:id/comments
+func (h *TaskHandler) AddComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req struct {
+		Content  string `json:"content" binding:"required"`
+		ParentID *int64 `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment := &models.TaskComment{
+		TaskID:   id,
+		ParentID: req.ParentID,
+		AuthorID: middleware.CurrentUserID(c),
+		Content:  req.Content,
+	}
+	if err := h.service.AddComment(c.Request.Context(), comment); err != nil {
+		taskError(c, err, "Failed to add comment")
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment handles PUT /tasks/:id/comments/:comment_id
+func (h *TaskHandler) UpdateComment(c *gin.Context) {
+	id, commentID, ok := taskChildIDs(c, "comment_id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.UpdateComment(c.Request.Context(), middleware.CurrentUserID(c), id, commentID, req.Content)
+	if err != nil {
+		taskError(c, err, "Failed to update comment")
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /tasks/:id/comments/:comment_id
+func (h *TaskHandler) DeleteComment(c *gin.Context) {
+	id, commentID, ok := taskChildIDs(c, "comment_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteComment(c.Request.Context(), middleware.CurrentUserID(c), id, commentID); err != nil {
+		taskError(c, err, "Failed to delete comment")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// taskChildIDs parses the task ID and the ID of its checklist item or comment.
+func taskChildIDs(c *gin.Context, child string) (int64, int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return 0, 0, false
+	}
+	childID, err := strconv.ParseInt(c.Param(child), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + child})
+		return 0, 0, false
+	}
+	return id, childID, true
+}
+
+func taskError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound),
+		errors.Is(err, services.ErrChecklistItemNotFound),
+		errors.Is(err, services.ErrTaskCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCommentAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidRecurrence),
+		errors.Is(err, services.ErrRecurrenceNoDue),
+		errors.Is(err, services.ErrEmptyChecklistItem),
+		errors.Is(err, services.ErrEmptyTaskComment),
+		errors.Is(err, services.ErrInvalidCommentParent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"turcompany/internal/middleware"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskTemplateHandler handles HTTP requests for task templates.
+type TaskTemplateHandler struct {
+	service services.TaskTemplateService
+}
+
+// NewTaskTemplateHandler creates a new TaskTemplateHandler.
+func NewTaskTemplateHandler(service services.TaskTemplateService) *TaskTemplateHandler {
+	return &TaskTemplateHandler{service: service}
+}
+
+type taskTemplateRequest struct {
+	Name       string                    `json:"name" binding:"required"`
+	DealStatus string                    `json:"deal_status" binding:"required"`
+	Active     *bool                     `json:"active"`
+	Items      []models.TaskTemplateItem `json:"items" binding:"required"`
+}
+
+func (r *taskTemplateRequest) template() *models.TaskTemplate {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &models.TaskTemplate{Name: r.Name, DealStatus: r.DealStatus, Active: active, Items: r.Items}
+}
+
+// Create handles POST /task-templates
+func (h *TaskTemplateHandler) Create(c *gin.Context) {
+	var req taskTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t := req.template()
+	t.CreatedBy = middleware.CurrentUserID(c)
+	if err := h.service.Create(c.Request.Context(), t); err != nil {
+		taskTemplateError(c, err, "Failed to create task template")
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// List handles GET /task-templates?deal_status=...
+func (h *TaskTemplateHandler) List(c *gin.Context) {
+	templates, err := h.service.List(c.Request.Context(), c.Query("deal_status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetByID handles GET /task-templates/:id
+func (h *TaskTemplateHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	t, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		taskTemplateError(c, err, "Failed to retrieve task template")
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// Update handles PUT /task-templates/:id
+func (h *TaskTemplateHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req taskTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t := req.template()
+	t.ID = id
+	if err := h.service.Update(c.Request.Context(), t); err != nil {
+		taskTemplateError(c, err, "Failed to update task template")
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// Delete handles DELETE /task-templates/:id
+func (h *TaskTemplateHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		taskTemplateError(c, err, "Failed to delete task template")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Apply handles POST /task-templates/:id/apply/:deal_id
+func (h *TaskTemplateHandler) Apply(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	dealID, err := strconv.ParseInt(c.Param("deal_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+	tasks, err := h.service.Apply(c.Request.Context(), id, dealID)
+	if err != nil {
+		taskTemplateError(c, err, "Failed to apply task template")
+		return
+	}
+	c.JSON(http.StatusCreated, tasks)
+}
+
+func taskTemplateError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTaskTemplateNotFound), errors.Is(err, services.ErrTemplateDealNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTaskTemplate), errors.Is(err, services.ErrNoDepartureDate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTemplateAlreadyApplied):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,39 +1,110 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"turcompany/internal/messaging"
+
+	"github.com/gin-gonic/gin"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	// Make sure you import your services package if you haven't
-	"turcompany/internal/services"
 )
 
+// telegramSecretHeader carries the secret_token given to setWebhook.
+const telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookQueueTimeout bounds how long a webhook request waits for a full worker queue;
+// after that Telegram gets 503 and redelivers the update later.
+const webhookQueueTimeout = 5 * time.Second
+
+// TelegramHandlers passes Telegram updates to the tour bot and sends its answers. Updates are
+// processed by a worker pool in order per chat, both in long polling and webhook mode.
 type TelegramHandlers struct {
-	// You will use this later to get tour data
-	tourService *services.TourService
+	tourBot     *messaging.TourBot
+	bot         *tgbotapi.BotAPI
+	secretToken string
+	dispatcher  *messaging.Dispatcher
+}
+
+// NewTelegramHandlers creates a new TelegramHandlers with the given number of workers.
+// secretToken is checked on webhook requests.
+func NewTelegramHandlers(tourBot *messaging.TourBot, bot *tgbotapi.BotAPI, workers int, secretToken string) *TelegramHandlers {
+	h := &TelegramHandlers{
+		tourBot:     tourBot,
+		bot:         bot,
+		secretToken: secretToken,
+	}
+	h.dispatcher = messaging.NewDispatcher(workers, h.HandleUpdate)
+	return h
+}
+
+// Dispatch queues an update received by long polling. It blocks while the workers are busy.
+func (h *TelegramHandlers) Dispatch(update tgbotapi.Update) error {
+	return h.dispatcher.Dispatch(context.Background(), update)
+}
+
+// Shutdown stops accepting updates and waits for the queued ones until ctx is done.
+func (h *TelegramHandlers) Shutdown(ctx context.Context) error {
+	return h.dispatcher.Shutdown(ctx)
 }
 
-// FIX: Add the parameter to the function definition here.
-func NewTelegramHandlers(tourService *services.TourService) *TelegramHandlers {
-	return &TelegramHandlers{tourService: tourService}
+// Webhook handles POST requests from Telegram at the secret webhook path.
+func (h *TelegramHandlers) Webhook(c *gin.Context) {
+	token := c.GetHeader(telegramSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secretToken)) != 1 {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(c.Request.Body).Decode(&update); err != nil {
+		// Telegram would redeliver a malformed update forever, so it is acknowledged
+		log.Printf("Telegram webhook: invalid update: %v", err)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), webhookQueueTimeout)
+	defer cancel()
+	if err := h.dispatcher.Dispatch(ctx, update); err != nil {
+		if !errors.Is(err, messaging.ErrDispatcherClosed) {
+			log.Printf("Telegram webhook: update %d: %v", update.UpdateID, err)
+		}
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	c.Status(http.StatusOK)
 }
 
-func (h *TelegramHandlers) HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	msg := tgbotapi.NewMessage(message.Chat.ID, "")
-	switch message.Command() {
-	case "start":
-		msg.Text = "Welcome to the Tour Company Bot!"
-	case "tours":
-		// Here you would call your tourService to get the list of tours
-		// tours := h.tourService.GetAllTours()
-		// msg.Text = formatTours(tours)
-		msg.Text = "Here are our available tours:\n1. Mountain Adventure\n2. City Exploration"
-	default:
-		msg.Text = "Unknown command."
-	}
-	bot.Send(msg)
+// HandleUpdate processes one update. Errors are logged and the customer gets an apology,
+// so a failing update never stops the bot.
+func (h *TelegramHandlers) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
+	responses, err := h.tourBot.Handle(ctx, update)
+	if err != nil {
+		log.Printf("Telegram update %d: %v", update.UpdateID, err)
+		responses = apology(update)
+	}
+
+	for _, r := range responses {
+		// Answers to callback queries return a bool, not a message, so Request is used for all of them
+		if _, err := h.bot.Request(r); err != nil {
+			log.Printf("Telegram update %d: send: %v", update.UpdateID, err)
+		}
+	}
 }
 
-func (h *TelegramHandlers) HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	// Handle regular messages
-	msg := tgbotapi.NewMessage(message.Chat.ID, "Please use commands like /tours to interact with me.")
-	bot.Send(msg)
+func apology(update tgbotapi.Update) []tgbotapi.Chattable {
+	chat := update.FromChat()
+	if chat == nil {
+		return nil
+	}
+	var responses []tgbotapi.Chattable
+	if update.CallbackQuery != nil {
+		responses = append(responses, tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+	}
+	return append(responses, tgbotapi.NewMessage(chat.ID, "Sorry, something went wrong. Please try again later."))
 }
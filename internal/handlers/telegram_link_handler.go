@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"turcompany/internal/middleware"
+	"turcompany/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TelegramLinkHandler handles HTTP requests for linking the current user's Telegram account.
+type TelegramLinkHandler struct {
+	service services.TelegramLinkService
+}
+
+// NewTelegramLinkHandler creates a new TelegramLinkHandler.
+func NewTelegramLinkHandler(service services.TelegramLinkService) *TelegramLinkHandler {
+	return &TelegramLinkHandler{service: service}
+}
+
+// GenerateCode handles POST /telegram/link-code
+// The returned code is sent to the bot as /link CODE (or opened via url).
+func (h *TelegramLinkHandler) GenerateCode(c *gin.Context) {
+	code, err := h.service.GenerateCode(c.Request.Context(), middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate link code"})
+		return
+	}
+	c.JSON(http.StatusCreated, code)
+}
+
+// GetLink handles GET /telegram/link
+func (h *TelegramLinkHandler) GetLink(c *gin.Context) {
+	link, err := h.service.GetLink(c.Request.Context(), middleware.CurrentUserID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrTelegramNotLinked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve Telegram link"})
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// Unlink handles DELETE /telegram/link
+func (h *TelegramLinkHandler) Unlink(c *gin.Context) {
+	if err := h.service.Unlink(c.Request.Context(), middleware.CurrentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Telegram"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
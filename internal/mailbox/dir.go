@@ -0,0 +1,86 @@
+package mailbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxFetch ограничивает число писем за один проход.
+const maxFetch = 100
+
+// Dir — локальная замена IMAP для разработки и интеграций: письма .eml кладутся
+// в <path>/new, обработанные переносятся в <path>/cur, неразобранные — в <path>/bad.
+type Dir struct {
+	path string
+}
+
+// NewDir создаёт источник писем из каталога и при необходимости создаёт подкаталоги.
+func NewDir(path string) (*Dir, error) {
+	for _, sub := range []string{"new", "cur", "bad"} {
+		if err := os.MkdirAll(filepath.Join(path, sub), 0755); err != nil {
+			return nil, fmt.Errorf("каталог почты: %w", err)
+		}
+	}
+	return &Dir{path: path}, nil
+}
+
+func (d *Dir) Fetch(ctx context.Context) ([]Email, error) {
+	entries, err := os.ReadDir(filepath.Join(d.path, "new"))
+	if err != nil {
+		return nil, fmt.Errorf("чтение каталога почты: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		// Файлы, которые ещё дописываются, должны иметь другое расширение
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".eml") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	if len(names) > maxFetch {
+		names = names[:maxFetch]
+	}
+
+	emails := make([]Email, 0, len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return emails, ctx.Err()
+		}
+		email, err := d.parseFile(name)
+		if err != nil {
+			log.Printf("Письмо %s не разобрано: %v", name, err)
+			_ = os.Rename(filepath.Join(d.path, "new", name), filepath.Join(d.path, "bad", name))
+			continue
+		}
+		emails = append(emails, *email)
+	}
+	return emails, nil
+}
+
+func (d *Dir) Ack(ctx context.Context, id string) error {
+	return os.Rename(filepath.Join(d.path, "new", id), filepath.Join(d.path, "cur", id))
+}
+
+func (d *Dir) parseFile(name string) (*Email, error) {
+	f, err := os.Open(filepath.Join(d.path, "new", name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	email, err := Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	email.ID = name
+	if email.MessageID == "" {
+		// Без Message-ID повтор определяется по имени файла
+		email.MessageID = "<" + name + "@mailbox.local>"
+	}
+	return email, nil
+}
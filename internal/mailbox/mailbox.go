@@ -0,0 +1,154 @@
+// Package mailbox получает входящие письма клиентов для единого инбокса.
+package mailbox
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Email — входящее письмо, приведённое к тексту.
+type Email struct {
+	ID        string // идентификатор в источнике, передаётся в Ack
+	MessageID string // заголовок Message-ID, по нему отсекаются повторы
+	From      string // адрес отправителя в нижнем регистре
+	FromName  string
+	Subject   string
+	Text      string
+	Date      time.Time
+}
+
+// Source — почтовый ящик, из которого забираются письма (IMAP или локальная замена).
+type Source interface {
+	// Fetch возвращает ещё не подтверждённые письма.
+	Fetch(ctx context.Context) ([]Email, error)
+	// Ack отмечает письмо обработанным, чтобы оно не вернулось при следующем Fetch.
+	Ack(ctx context.Context, id string) error
+}
+
+var (
+	wordDecoder = new(mime.WordDecoder)
+	htmlTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
+// Parse разбирает письмо в формате RFC 5322. Из multipart берётся text/plain,
+// при его отсутствии — text/html без разметки.
+func Parse(r io.Reader) (*Email, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("разбор письма: %w", err)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("адрес отправителя: %w", err)
+	}
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	date, err := msg.Header.Date()
+	if err != nil {
+		date = time.Now()
+	}
+
+	plain, html, err := readBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	text := plain
+	if strings.TrimSpace(text) == "" {
+		text = htmlTag.ReplaceAllString(html, "")
+	}
+
+	return &Email{
+		MessageID: strings.TrimSpace(msg.Header.Get("Message-Id")),
+		From:      strings.ToLower(from.Address),
+		FromName:  from.Name,
+		Subject:   strings.TrimSpace(subject),
+		Text:      cleanText(text),
+		Date:      date,
+	}, nil
+}
+
+// readBody возвращает текстовую и HTML-версии тела, обходя вложенные multipart.
+func readBody(contentType, encoding string, body io.Reader) (plain, html string, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return plain, html, nil
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("разбор multipart: %w", err)
+			}
+			p, h, err := readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", "", err
+			}
+			if plain == "" {
+				plain = p
+			}
+			if html == "" {
+				html = h
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(encoding, body))
+	if err != nil {
+		return "", "", fmt.Errorf("чтение тела письма: %w", err)
+	}
+	switch mediaType {
+	case "text/plain":
+		return string(data), "", nil
+	case "text/html":
+		return "", string(data), nil
+	}
+	// Вложения и прочие части пропускаются
+	return "", "", nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		// Декодер base64 сам пропускает переводы строк
+		return base64.NewDecoder(base64.StdEncoding, r)
+	}
+	return r
+}
+
+// cleanText нормализует переводы строк и отрезает цитату предыдущего письма.
+func cleanText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			lines = lines[:i]
+			// Строка вида «... wrote:» перед цитатой тоже лишняя
+			if i > 0 && strings.HasSuffix(strings.TrimSpace(lines[i-1]), ":") {
+				lines = lines[:i-1]
+			}
+			break
+		}
+	}
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
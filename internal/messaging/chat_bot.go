@@ -1 +1,528 @@
-package messaging
\ No newline at end of file
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+	"turcompany/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Dialog states stored in telegram_sessions.
+const (
+	StateBrowsing   = "browsing"
+	StateAwaitName  = "await_name"
+	StateAwaitPhone = "await_phone"
+	StateConfirm    = "confirm"
+)
+
+// Callback data of the inline buttons. IDs are appended to the prefixes.
+const (
+	callbackMenu      = "menu"
+	callbackTours     = "tours"
+	callbackTour      = "tour:"
+	callbackDeparture = "dep:"
+	callbackBook      = "book:"
+	callbackConfirm   = "confirm"
+	callbackCancel    = "cancel"
+)
+
+const (
+	maxDeparturesShown = 10
+	maxNameLength      = 100
+	dateLayout         = "02.01.2006"
+)
+
+// TourBot is the customer-facing dialog of the Telegram bot: browse tours, pick a departure,
+// leave a name and phone and get a lead created in the CRM.
+type TourBot struct {
+	tours       *services.TourService
+	leads       *services.LeadService
+	sessions    repositories.TelegramSessionRepository
+	staff       *StaffCommands
+	inbox       services.InboxService
+	leadOwnerID int
+	sessionTTL  time.Duration
+	now         func() time.Time
+}
+
+// NewTourBot creates a TourBot. Leads are assigned to leadOwnerID; a dialog idle for longer
+// than sessionTTL (24h by default) starts over. Commands for CRM users go to staff; free
+// messages outside the booking dialog go to the inbox, where managers answer them.
+func NewTourBot(
+	tours *services.TourService,
+	leads *services.LeadService,
+	sessions repositories.TelegramSessionRepository,
+	staff *StaffCommands,
+	inbox services.InboxService,
+	leadOwnerID int,
+	sessionTTL time.Duration,
+) *TourBot {
+	if sessionTTL <= 0 {
+		sessionTTL = 24 * time.Hour
+	}
+	return &TourBot{
+		tours:       tours,
+		leads:       leads,
+		sessions:    sessions,
+		staff:       staff,
+		inbox:       inbox,
+		leadOwnerID: leadOwnerID,
+		sessionTTL:  sessionTTL,
+		now:         time.Now,
+	}
+}
+
+// reply is the text and buttons of one bot answer.
+type reply struct {
+	text     string
+	keyboard *tgbotapi.InlineKeyboardMarkup
+}
+
+// Handle processes one update and returns what has to be sent back to Telegram.
+func (b *TourBot) Handle(ctx context.Context, update tgbotapi.Update) ([]tgbotapi.Chattable, error) {
+	switch {
+	case update.CallbackQuery != nil:
+		return b.handleCallback(ctx, update.CallbackQuery)
+	case update.Message != nil:
+		return b.handleMessage(ctx, update.Message)
+	}
+	return nil, nil
+}
+
+func (b *TourBot) handleMessage(ctx context.Context, msg *tgbotapi.Message) ([]tgbotapi.Chattable, error) {
+	// Leads are collected only in private chats with the customer
+	if msg.Chat == nil || !msg.Chat.IsPrivate() {
+		return nil, nil
+	}
+
+	session, err := b.session(ctx, msg.Chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var r reply
+	if msg.IsCommand() {
+		r, err = b.handleCommand(ctx, session, msg)
+	} else {
+		r, err = b.handleInput(ctx, session, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := b.sessions.Save(ctx, session); err != nil {
+		return nil, err
+	}
+
+	out := tgbotapi.NewMessage(msg.Chat.ID, r.text)
+	if r.keyboard != nil {
+		out.ReplyMarkup = *r.keyboard
+	}
+	return []tgbotapi.Chattable{out}, nil
+}
+
+func (b *TourBot) handleCommand(ctx context.Context, session *models.TelegramSession, msg *tgbotapi.Message) (reply, error) {
+	command := msg.Command()
+	if b.staff != nil && b.staff.handles(command) {
+		return b.staff.handle(ctx, msg)
+	}
+
+	switch command {
+	case "start":
+		reset(session)
+		// Deep link t.me/<bot>?start=link_CODE from the CRM
+		if payload := msg.CommandArguments(); b.staff != nil && services.IsLinkCodeStart(payload) {
+			return b.staff.link(ctx, msg, payload)
+		}
+		return menuReply("Welcome to the Tour Company Bot! Browse our tours, choose a date and leave your contacts — a manager will call you back."), nil
+	case "tours":
+		reset(session)
+		return b.tourList(ctx)
+	case "cancel":
+		reset(session)
+		return menuReply("Cancelled."), nil
+	default:
+		return menuReply("Unknown command. Use the buttons below or /tours."), nil
+	}
+}
+
+// handleInput handles text (and shared contacts) while the bot is collecting contact details.
+func (b *TourBot) handleInput(ctx context.Context, session *models.TelegramSession, msg *tgbotapi.Message) (reply, error) {
+	switch session.State {
+	case StateAwaitName:
+		name := strings.Join(strings.Fields(msg.Text), " ")
+		if name == "" || len([]rune(name)) > maxNameLength {
+			return promptReply("Please send your name as text."), nil
+		}
+		session.Data.Name = name
+		session.State = StateAwaitPhone
+		return promptReply("Thank you, " + name + "! Now send your phone number, e.g. +7 701 123 45 67."), nil
+
+	case StateAwaitPhone:
+		text := msg.Text
+		if msg.Contact != nil {
+			text = msg.Contact.PhoneNumber
+		}
+		phone, ok := normalizePhone(text)
+		if !ok {
+			return promptReply("This does not look like a phone number. Please send it in the format +7 701 123 45 67."), nil
+		}
+		session.Data.Phone = phone
+		session.State = StateConfirm
+		return b.confirmation(ctx, session)
+
+	case StateConfirm:
+		return b.confirmation(ctx, session)
+
+	default:
+		return b.toInbox(ctx, msg)
+	}
+}
+
+// toInbox passes a free message of the customer to the managers' inbox.
+func (b *TourBot) toInbox(ctx context.Context, msg *tgbotapi.Message) (reply, error) {
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	phone := ""
+	if msg.Contact != nil {
+		phone, _ = normalizePhone(msg.Contact.PhoneNumber)
+		if text == "" {
+			text = "Contact: " + msg.Contact.PhoneNumber
+		}
+	}
+	if b.inbox == nil || strings.TrimSpace(text) == "" {
+		return menuReply("Use the buttons below to browse our tours."), nil
+	}
+
+	name := ""
+	if msg.From != nil {
+		name = strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName)
+		if name == "" && msg.From.UserName != "" {
+			name = "@" + msg.From.UserName
+		}
+	}
+	_, err := b.inbox.Receive(ctx, &models.InboundMessage{
+		Channel:        models.ChannelTelegram,
+		ExternalID:     services.TelegramExternalID(msg.Chat.ID, msg.MessageID),
+		Name:           name,
+		Phone:          phone,
+		TelegramChatID: msg.Chat.ID,
+		Body:           text,
+		ReceivedAt:     msg.Time(),
+	})
+	if err != nil {
+		return unavailable(err)
+	}
+	return menuReply("Thank you! A manager will answer you here."), nil
+}
+
+func (b *TourBot) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery) ([]tgbotapi.Chattable, error) {
+	// The callback is always answered so the client stops showing the loading state
+	answer := tgbotapi.NewCallback(query.ID, "")
+	if query.Message == nil || query.Message.Chat == nil || !query.Message.Chat.IsPrivate() {
+		return []tgbotapi.Chattable{answer}, nil
+	}
+	chatID := query.Message.Chat.ID
+
+	session, err := b.session(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := b.route(ctx, session, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.sessions.Save(ctx, session); err != nil {
+		return nil, err
+	}
+
+	var edit tgbotapi.EditMessageTextConfig
+	if r.keyboard != nil {
+		edit = tgbotapi.NewEditMessageTextAndMarkup(chatID, query.Message.MessageID, r.text, *r.keyboard)
+	} else {
+		edit = tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, r.text)
+	}
+	return []tgbotapi.Chattable{answer, edit}, nil
+}
+
+func (b *TourBot) route(ctx context.Context, session *models.TelegramSession, query *tgbotapi.CallbackQuery) (reply, error) {
+	data := query.Data
+	switch {
+	case data == callbackMenu:
+		reset(session)
+		return menuReply("What would you like to do?"), nil
+
+	case data == callbackTours:
+		reset(session)
+		return b.tourList(ctx)
+
+	case strings.HasPrefix(data, callbackTour):
+		reset(session)
+		return b.tourDetails(ctx, callbackID(data, callbackTour))
+
+	case strings.HasPrefix(data, callbackDeparture):
+		reset(session)
+		return b.departureDetails(ctx, callbackID(data, callbackDeparture))
+
+	case strings.HasPrefix(data, callbackBook):
+		departure, _, err := b.tours.GetDeparture(ctx, callbackID(data, callbackBook))
+		if err != nil {
+			return unavailable(err)
+		}
+		if departure.Seats <= 0 {
+			return menuReply("Sorry, there are no seats left on this date."), nil
+		}
+		reset(session)
+		session.State = StateAwaitName
+		session.Data.TourID = departure.TourID
+		session.Data.DepartureID = departure.ID
+		return promptReply("Great choice! Please send your name."), nil
+
+	case data == callbackConfirm:
+		if session.State != StateConfirm {
+			return menuReply("This request has expired. Please choose a tour again."), nil
+		}
+		lead, err := b.createLead(ctx, session, query.From)
+		if err != nil {
+			return unavailable(err)
+		}
+		r := menuReply(fmt.Sprintf("Thank you! Your request #%d has been sent. A manager will contact you at %s soon.", lead.ID, session.Data.Phone))
+		reset(session)
+		return r, nil
+
+	case data == callbackCancel:
+		reset(session)
+		return menuReply("Cancelled. What would you like to do?"), nil
+	}
+	return menuReply("What would you like to do?"), nil
+}
+
+func (b *TourBot) tourList(ctx context.Context) (reply, error) {
+	tours, err := b.tours.ListTours(ctx)
+	if err != nil {
+		return reply{}, err
+	}
+	if len(tours) == 0 {
+		return menuReply("There are no tours available right now. Please check back later."), nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(tours))
+	for _, t := range tours {
+		label := fmt.Sprintf("%s — from %s", t.Title, formatPrice(t.Price, t.Currency))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackTour+strconv.FormatInt(t.ID, 10)),
+		))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return reply{text: "Our tours:", keyboard: &keyboard}, nil
+}
+
+func (b *TourBot) tourDetails(ctx context.Context, tourID int64) (reply, error) {
+	tour, err := b.tours.GetTour(ctx, tourID)
+	if err != nil {
+		return unavailable(err)
+	}
+	departures, err := b.tours.ListDepartures(ctx, tourID)
+	if err != nil {
+		return reply{}, err
+	}
+
+	var text strings.Builder
+	text.WriteString(tour.Title)
+	if tour.Description != "" {
+		text.WriteString("\n\n" + tour.Description)
+	}
+	text.WriteString("\n\nFrom " + formatPrice(tour.Price, tour.Currency))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(departures) == 0 {
+		text.WriteString("\n\nNo upcoming departures yet.")
+	} else {
+		text.WriteString("\n\nChoose a departure date:")
+		for i, d := range departures {
+			if i == maxDeparturesShown {
+				break
+			}
+			label := d.DepartsAt.Format(dateLayout) + " – " + d.ReturnsAt.Format(dateLayout)
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(label, callbackDeparture+strconv.FormatInt(d.ID, 10)),
+			))
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("« All tours", callbackTours),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return reply{text: text.String(), keyboard: &keyboard}, nil
+}
+
+func (b *TourBot) departureDetails(ctx context.Context, departureID int64) (reply, error) {
+	departure, tour, err := b.tours.GetDeparture(ctx, departureID)
+	if err != nil {
+		return unavailable(err)
+	}
+
+	text := fmt.Sprintf("%s\n\n%s – %s\nPrice: %s\n",
+		tour.Title,
+		departure.DepartsAt.Format(dateLayout), departure.ReturnsAt.Format(dateLayout),
+		formatPrice(departure.Price, tour.Currency),
+	)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if departure.Seats > 0 {
+		text += fmt.Sprintf("Seats left: %d", departure.Seats)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Leave a request", callbackBook+strconv.FormatInt(departure.ID, 10)),
+		))
+	} else {
+		text += "No seats left on this date."
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("« Back", callbackTour+strconv.FormatInt(tour.ID, 10)),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return reply{text: text, keyboard: &keyboard}, nil
+}
+
+func (b *TourBot) confirmation(ctx context.Context, session *models.TelegramSession) (reply, error) {
+	departure, tour, err := b.tours.GetDeparture(ctx, session.Data.DepartureID)
+	if err != nil {
+		reset(session)
+		return unavailable(err)
+	}
+
+	text := fmt.Sprintf("Please check your request:\n\nTour: %s\nDates: %s – %s\nPrice: %s\nName: %s\nPhone: %s",
+		tour.Title,
+		departure.DepartsAt.Format(dateLayout), departure.ReturnsAt.Format(dateLayout),
+		formatPrice(departure.Price, tour.Currency),
+		session.Data.Name, session.Data.Phone,
+	)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Confirm", callbackConfirm),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", callbackCancel),
+	))
+	return reply{text: text, keyboard: &keyboard}, nil
+}
+
+func (b *TourBot) createLead(ctx context.Context, session *models.TelegramSession, from *tgbotapi.User) (*models.Leads, error) {
+	departure, tour, err := b.tours.GetDeparture(ctx, session.Data.DepartureID)
+	if err != nil {
+		return nil, err
+	}
+
+	contact := fmt.Sprintf("chat %d", session.ChatID)
+	if from != nil && from.UserName != "" {
+		contact = "@" + from.UserName + ", " + contact
+	}
+
+	title := tour.Title
+	if len([]rune(title)) > 200 {
+		title = string([]rune(title)[:200])
+	}
+
+	lead := &models.Leads{
+		Title: fmt.Sprintf("%s, %s (Telegram)", title, departure.DepartsAt.Format(dateLayout)),
+		Description: fmt.Sprintf("Name: %s\nPhone: %s\nTelegram: %s\nTour: %s (#%d)\nDates: %s – %s (departure #%d)\nPrice: %s",
+			session.Data.Name, session.Data.Phone, contact,
+			tour.Title, tour.ID,
+			departure.DepartsAt.Format(dateLayout), departure.ReturnsAt.Format(dateLayout), departure.ID,
+			formatPrice(departure.Price, tour.Currency),
+		),
+		CreatedAt: b.now(),
+		OwnerID:   b.leadOwnerID,
+		Source:    models.LeadSourceTelegram,
+		Phone:     session.Data.Phone,
+	}
+	chatID := session.ChatID
+	lead.TelegramChatID = &chatID
+	if err := b.leads.Create(lead); err != nil {
+		return nil, fmt.Errorf("create telegram lead: %w", err)
+	}
+	return lead, nil
+}
+
+// session returns the stored dialog of the chat, or a fresh one if it is missing or stale.
+func (b *TourBot) session(ctx context.Context, chatID int64) (*models.TelegramSession, error) {
+	session, err := b.sessions.Find(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || b.now().Sub(session.UpdatedAt) > b.sessionTTL {
+		return &models.TelegramSession{ChatID: chatID, State: StateBrowsing}, nil
+	}
+	return session, nil
+}
+
+func reset(session *models.TelegramSession) {
+	session.State = StateBrowsing
+	session.Data = models.TelegramSessionData{}
+}
+
+func menuReply(text string) reply {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Browse tours", callbackTours),
+	))
+	return reply{text: text, keyboard: &keyboard}
+}
+
+func promptReply(text string) reply {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", callbackCancel),
+	))
+	return reply{text: text, keyboard: &keyboard}
+}
+
+// unavailable turns a tour that was removed or has already departed into a message for the customer.
+func unavailable(err error) (reply, error) {
+	if errors.Is(err, services.ErrTourNotFound) || errors.Is(err, services.ErrDepartureNotFound) {
+		return menuReply("Sorry, this tour or date is no longer available."), nil
+	}
+	return reply{}, err
+}
+
+func callbackID(data, prefix string) int64 {
+	id, _ := strconv.ParseInt(strings.TrimPrefix(data, prefix), 10, 64)
+	return id
+}
+
+func formatPrice(price float64, currency string) string {
+	if price == math.Trunc(price) {
+		return fmt.Sprintf("%.0f %s", price, currency)
+	}
+	return fmt.Sprintf("%.2f %s", price, currency)
+}
+
+// normalizePhone accepts digits with spaces, dashes, brackets and a leading plus and returns
+// the number as +<digits>. The local Kazakhstan prefix 8 is replaced with 7.
+func normalizePhone(s string) (string, bool) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return "", false
+		}
+	}
+	number := digits.String()
+	if len(number) == 11 && number[0] == '8' {
+		number = "7" + number[1:]
+	}
+	if len(number) < 10 || len(number) > 15 {
+		return "", false
+	}
+	return "+" + number, true
+}
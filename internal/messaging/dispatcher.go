@@ -0,0 +1,114 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrDispatcherClosed is returned by Dispatch after Shutdown.
+var ErrDispatcherClosed = errors.New("update dispatcher is shut down")
+
+const dispatcherQueueSize = 64
+
+// Dispatcher processes Telegram updates on a pool of workers. Updates of one chat always go to
+// the same worker, so a dialog is handled in order while different chats run concurrently.
+type Dispatcher struct {
+	handle func(ctx context.Context, update tgbotapi.Update)
+	queues []chan tgbotapi.Update
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	// ctx is passed to handle and is cancelled only if draining takes too long
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDispatcher starts workers (at least one) that call handle for every dispatched update.
+func NewDispatcher(workers int, handle func(ctx context.Context, update tgbotapi.Update)) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		handle: handle,
+		queues: make([]chan tgbotapi.Update, workers),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, dispatcherQueueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// Dispatch queues the update for its chat's worker. It blocks while the queue is full,
+// until ctx is done.
+func (d *Dispatcher) Dispatch(ctx context.Context, update tgbotapi.Update) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	select {
+	case d.queues[d.worker(update)] <- update:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting updates and waits until the queued ones are processed.
+// If ctx ends first, the handlers in progress are cancelled.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, q := range d.queues {
+			close(q)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) work(queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+	for update := range queue {
+		d.handle(d.ctx, update)
+	}
+}
+
+// worker picks the queue by chat; updates without a chat are spread by their ID.
+func (d *Dispatcher) worker(update tgbotapi.Update) int {
+	key := int64(update.UpdateID)
+	if chat := update.FromChat(); chat != nil {
+		key = chat.ID
+	}
+	if key < 0 {
+		// Group chat IDs are negative
+		key = -key
+	}
+	return int(key % int64(len(d.queues)))
+}
@@ -0,0 +1,139 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const maxTasksShown = 20
+
+// StaffCommands handles the bot commands for CRM users: linking the chat to their account
+// with a code from the CRM and viewing their tasks.
+type StaffCommands struct {
+	links services.TelegramLinkService
+	tasks services.TaskService
+	now   func() time.Time
+}
+
+// NewStaffCommands creates a new StaffCommands.
+func NewStaffCommands(links services.TelegramLinkService, tasks services.TaskService) *StaffCommands {
+	return &StaffCommands{links: links, tasks: tasks, now: time.Now}
+}
+
+// handles reports whether the command belongs to the staff commands.
+func (s *StaffCommands) handles(command string) bool {
+	switch command {
+	case "link", "unlink", "mytasks", "today":
+		return true
+	}
+	return false
+}
+
+func (s *StaffCommands) handle(ctx context.Context, msg *tgbotapi.Message) (reply, error) {
+	switch msg.Command() {
+	case "link":
+		return s.link(ctx, msg, msg.CommandArguments())
+	case "unlink":
+		if err := s.links.UnlinkChat(ctx, msg.Chat.ID); err != nil {
+			return reply{}, err
+		}
+		return reply{text: "This chat is no longer linked to your CRM account."}, nil
+	case "mytasks":
+		return s.taskList(ctx, msg.Chat.ID, false)
+	case "today":
+		return s.taskList(ctx, msg.Chat.ID, true)
+	}
+	return reply{}, nil
+}
+
+func (s *StaffCommands) link(ctx context.Context, msg *tgbotapi.Message, code string) (reply, error) {
+	if strings.TrimSpace(code) == "" {
+		return reply{text: "Generate a code in the CRM and send it here as /link CODE."}, nil
+	}
+	username := ""
+	if msg.From != nil {
+		username = msg.From.UserName
+	}
+	if _, err := s.links.Link(ctx, code, msg.Chat.ID, username); err != nil {
+		if errors.Is(err, services.ErrInvalidLinkCode) {
+			return reply{text: "The code is invalid or expired. Generate a new one in the CRM."}, nil
+		}
+		return reply{}, err
+	}
+	return reply{text: "Your CRM account is linked. You will get notifications here.\n\n/mytasks — your open tasks\n/today — tasks due today\n/unlink — stop notifications"}, nil
+}
+
+// taskList shows the open tasks of the linked user; dueToday keeps only overdue and due today.
+func (s *StaffCommands) taskList(ctx context.Context, chatID int64, dueToday bool) (reply, error) {
+	link, err := s.links.FindByChat(ctx, chatID)
+	if err != nil {
+		return reply{}, err
+	}
+	if link == nil {
+		return reply{text: "This chat is not linked to a CRM account. Generate a code in the CRM and send /link CODE."}, nil
+	}
+
+	userID := link.UserID
+	all, err := s.tasks.GetAll(ctx, models.TaskFilter{AssigneeID: &userID})
+	if err != nil {
+		return reply{}, err
+	}
+
+	now := s.now()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	var tasks []models.Task
+	for _, t := range all {
+		if t.Status != models.StatusNew && t.Status != models.StatusInProgress {
+			continue
+		}
+		if dueToday && (t.DueDate == nil || !t.DueDate.Before(endOfDay)) {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	// Tasks with a due date first, the earliest on top
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].DueDate, tasks[j].DueDate
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+
+	if len(tasks) == 0 {
+		if dueToday {
+			return reply{text: "Nothing is due today."}, nil
+		}
+		return reply{text: "You have no open tasks."}, nil
+	}
+
+	var text strings.Builder
+	if dueToday {
+		text.WriteString("Due today:\n")
+	} else {
+		text.WriteString("Your open tasks:\n")
+	}
+	for i, t := range tasks {
+		if i == maxTasksShown {
+			fmt.Fprintf(&text, "\n…and %d more in the CRM", len(tasks)-maxTasksShown)
+			break
+		}
+		fmt.Fprintf(&text, "\n#%d %s", t.ID, t.Title)
+		if t.DueDate != nil {
+			due := t.DueDate.In(now.Location())
+			fmt.Fprintf(&text, " — due %s", due.Format("02.01 15:04"))
+			if due.Before(now) {
+				text.WriteString(" (overdue)")
+			}
+		}
+	}
+	return reply{text: text.String()}, nil
+}
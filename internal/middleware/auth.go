@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -17,6 +18,25 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// AdminRoleID роль администратора; при регистрации пользователи получают роль 2.
+const AdminRoleID = 1
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// ParseToken проверяет подпись и срок действия access-токена.
+func ParseToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return JWTKey, nil
+	})
+
+	if err != nil || !token.Valid || claims.ExpiresAt == nil || claims.ExpiresAt.Time.Before(time.Now()) {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -25,14 +45,34 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &Claims{}
+		claims, err := ParseToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("role_id", claims.RoleID)
+
+		c.Next()
+	}
+}
 
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return JWTKey, nil
-		})
+// WebSocketAuthMiddleware как AuthMiddleware, но дополнительно принимает токен
+// из параметра ?token=: браузерный WebSocket не умеет передавать заголовки.
+func WebSocketAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenStr == "" {
+			tokenStr = c.Query("token")
+		}
+		if tokenStr == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
+			return
+		}
 
-		if err != nil || !token.Valid || claims.ExpiresAt.Time.Before(time.Now()) {
+		claims, err := ParseToken(tokenStr)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
@@ -43,3 +83,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// CurrentUserID возвращает ID пользователя, установленный middleware авторизации.
+func CurrentUserID(c *gin.Context) int64 {
+	return int64(c.GetInt("user_id"))
+}
+
+// RequireRole пропускает только пользователей с одной из ролей; ставится после AuthMiddleware.
+func RequireRole(roleIDs ...int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetInt("role_id")
+		for _, id := range roleIDs {
+			if role == id {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Activity types shown in the lead and deal timelines.
+const (
+	ActivityLeadCreated       = "lead_created"
+	ActivityLeadStatusChanged = "lead_status_changed"
+	ActivityDealCreated       = "deal_created"
+	ActivityDealConverted     = "deal_converted"
+	ActivityDealStatusChanged = "deal_status_changed"
+	ActivityDocumentGenerated = "document_generated"
+	ActivitySMSSent           = "sms_sent"
+	ActivitySMSConfirmed      = "sms_confirmed"
+	ActivityTaskCompleted     = "task_completed"
+	ActivityMessageSent       = "message_sent"
+	ActivityMessageReceived   = "message_received"
+)
+
+// Activity is one event in the history of a lead or deal. EntityType and EntityID name the
+// lead, deal or document the event happened to; LeadID and DealID are resolved from it so
+// the event shows in both timelines.
+type Activity struct {
+	ID         int64             `json:"id"`
+	Type       string            `json:"type"`
+	EntityType string            `json:"entity_type"`
+	EntityID   int64             `json:"entity_id"`
+	LeadID     *int64            `json:"lead_id,omitempty"`
+	DealID     *int64            `json:"deal_id,omitempty"`
+	ActorID    int64             `json:"actor_id,omitempty"` // zero for the system or the client
+	Summary    string            `json:"summary"`
+	Details    map[string]string `json:"details,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ActivityFilter selects the events of one timeline.
+type ActivityFilter struct {
+	LeadID *int64
+	DealID *int64
+	Types  []string
+	From   *time.Time // at or after
+	To     *time.Time // before
+	Limit  int
+	Offset int
+}
+
+// ActivityPage is a page of a timeline, newest first, with the total number of matches.
+type ActivityPage struct {
+	Activities []Activity `json:"activities"`
+	Total      int        `json:"total"`
+	Page       int        `json:"page"`
+	Size       int        `json:"size"`
+}
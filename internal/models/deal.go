@@ -11,4 +11,16 @@ type Deals struct {
 	Currency  string    `json:"currency"`
 	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
+	// Дата вылета тура; от неё считаются сроки задач из шаблонов
+	DepartureDate *time.Time `json:"departure_date,omitempty"`
+	// Причина проигрыша для сделок в статусе lost
+	LossReason string `json:"loss_reason,omitempty"`
+	// Направление тура (страна или курорт) для отчётов по выручке
+	Destination string `json:"destination,omitempty"`
 }
+
+// Итоговые статусы сделки
+const (
+	DealStatusWon  = "won"
+	DealStatusLost = "lost"
+)
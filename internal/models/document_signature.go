@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DocumentSignature фиксирует факт подписания документа кодом из SMS.
+type DocumentSignature struct {
+	ID                int64     `json:"id"`
+	DocumentID        int64     `json:"document_id"`
+	SMSConfirmationID int64     `json:"sms_confirmation_id"`
+	Phone             string    `json:"phone"`
+	IP                string    `json:"ip"`
+	UserAgent         string    `json:"user_agent"`
+	FilePath          string    `json:"file_path"`
+	FileHash          string    `json:"file_hash"` // SHA-256 подписанного PDF в hex
+	SignedAt          time.Time `json:"signed_at"`
+}
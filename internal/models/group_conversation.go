@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Entity types a group conversation can be linked to.
+const (
+	EntityTypeLead     = "lead"
+	EntityTypeDeal     = "deal"
+	EntityTypeDocument = "document"
+)
+
+// Participant roles in a group conversation.
+const (
+	ParticipantRoleOwner  = "owner"
+	ParticipantRoleMember = "member"
+)
+
+// GroupConversation is a conversation with several participants,
+// optionally linked to a lead, deal or document.
+type GroupConversation struct {
+	ID           int64                     `json:"id"`
+	Title        string                    `json:"title"`
+	EntityType   string                    `json:"entity_type,omitempty"`
+	EntityID     int64                     `json:"entity_id,omitempty"`
+	CreatedBy    int64                     `json:"created_by"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	Participants []ConversationParticipant `json:"participants,omitempty"`
+}
+
+// ConversationParticipant is a member of a group conversation with their read position.
+type ConversationParticipant struct {
+	ConversationID    int64      `json:"conversation_id"`
+	UserID            int64      `json:"user_id"`
+	CompanyName       string     `json:"company_name"`
+	Email             string     `json:"email"`
+	Role              string     `json:"role"`
+	LastReadMessageID int64      `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+	JoinedAt          time.Time  `json:"joined_at"`
+}
+
+// GroupConversationSummary is a group conversation as seen by one participant in their list.
+type GroupConversationSummary struct {
+	GroupConversation
+	LastMessage       *Message `json:"last_message,omitempty"`
+	UnreadCount       int      `json:"unread_count"`
+	LastReadMessageID int64    `json:"last_read_message_id"`
+}
+
+// Mention is a message in which the user was @mentioned.
+type Mention struct {
+	UserID  int64   `json:"user_id"`
+	Message Message `json:"message"`
+}
+
+// ConversationReadReceipt tells participants that ReaderID has read the conversation up to UpToMessageID.
+type ConversationReadReceipt struct {
+	ConversationID int64     `json:"conversation_id"`
+	ReaderID       int64     `json:"reader_id"`
+	UpToMessageID  int64     `json:"up_to_message_id"`
+	ReadAt         time.Time `json:"read_at"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Inbox channels.
+const (
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelInternal = "internal" // agent notes, never delivered to the client
+)
+
+// Inbox message directions and statuses.
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+
+	InboxStatusReceived = "received"
+	InboxStatusPending  = "pending"
+	InboxStatusSent     = "sent"
+	InboxStatusFailed   = "failed"
+)
+
+// InboxThread is the conversation with the client of one lead across all channels.
+type InboxThread struct {
+	ID            int64     `json:"id"`
+	LeadID        int64     `json:"lead_id"`
+	LeadTitle     string    `json:"lead_title"`
+	OwnerID       int64     `json:"owner_id"`
+	LastChannel   string    `json:"last_channel"`
+	LastMessage   string    `json:"last_message"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	UnreadCount   int       `json:"unread_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// InboxMessage is one message in a thread.
+type InboxMessage struct {
+	ID         int64     `json:"id"`
+	ThreadID   int64     `json:"thread_id"`
+	Channel    string    `json:"channel"`
+	Direction  string    `json:"direction"`
+	AuthorID   *int64    `json:"author_id,omitempty"`
+	Address    string    `json:"address,omitempty"` // client's address: email or Telegram name
+	Subject    string    `json:"subject,omitempty"`
+	Body       string    `json:"body"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// InboxMessagePage is a page of thread history in ascending order.
+type InboxMessagePage struct {
+	Messages []InboxMessage `json:"messages"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// InboundMessage is a client message received from an external channel, with the contacts
+// used to find the lead.
+type InboundMessage struct {
+	Channel        string
+	ExternalID     string // Telegram chat:message ID or email Message-ID, for deduplication
+	Name           string
+	Phone          string
+	Email          string
+	TelegramChatID int64
+	Subject        string
+	Body           string
+	ReceivedAt     time.Time
+}
+
+// InboxThreadFilter defines the parameters for listing threads.
+type InboxThreadFilter struct {
+	OwnerID    *int64
+	Channel    string
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
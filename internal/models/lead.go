@@ -11,4 +11,16 @@ type Leads struct {
 	CreatedAt   time.Time `json:"created_at"`
 	OwnerID     int       `json:"owner_id"`
 	Status      string    `json:"status"`
+	Source      string    `json:"source"`
+	Phone       string    `json:"phone,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	// TelegramChatID заполняется ботом и не меняется при обновлении лида
+	TelegramChatID *int64 `json:"telegram_chat_id,omitempty"`
 }
+
+// Источники лидов
+const (
+	LeadSourceCRM      = "crm"
+	LeadSourceTelegram = "telegram"
+	LeadSourceEmail    = "email"
+)
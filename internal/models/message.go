@@ -2,14 +2,18 @@ package models
 
 import "time"
 
-// Message represents a single message between two users.
+// Message represents a single message between two users or in a group conversation.
 type Message struct {
-	ID         int64      `json:"id"`
-	SenderID   int64      `json:"sender_id"`
-	ReceiverID int64      `json:"receiver_id"`
-	Content    string     `json:"content"`
-	SentAt     time.Time  `json:"sent_at"`
-	ReadAt     *time.Time `json:"read_at,omitempty"` // Time the message was read, null if unread
+	ID             int64      `json:"id"`
+	SenderID       int64      `json:"sender_id"`
+	ReceiverID     int64      `json:"receiver_id,omitempty"` // Zero for group conversation messages
+	ConversationID *int64     `json:"conversation_id,omitempty"`
+	Content        string     `json:"content"`
+	SentAt         time.Time  `json:"sent_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`  // Time the message was read, null if unread (direct messages only)
+	Mentions       []int64    `json:"mentions,omitempty"` // IDs of users mentioned in a group message
+
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
 }
 
 // Conversation represents a summary of a chat between the current user and a partner.
@@ -18,3 +22,34 @@ type Conversation struct {
 	LastMessage Message `json:"last_message"`
 	UnreadCount int     `json:"unread_count"`
 }
+
+// MessageCursor selects a page of conversation history by message ID.
+// Before and After are exclusive; without either the latest messages are returned.
+type MessageCursor struct {
+	Before int64
+	After  int64
+	Limit  int
+}
+
+// MessagePage is a page of conversation history in ascending order.
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"` // More messages exist beyond the page in the requested direction
+}
+
+// MessageSearchResult is a message matched by full-text search.
+// PartnerID is set for direct messages, Message.ConversationID for group ones.
+type MessageSearchResult struct {
+	Message   Message `json:"message"`
+	PartnerID int64   `json:"partner_id,omitempty"`
+	Headline  string  `json:"headline"` // Content fragment with matches wrapped in <b></b>
+	Rank      float64 `json:"rank"`
+}
+
+// ReadReceipt tells the partner that ReaderID has read their messages up to UpToMessageID.
+type ReadReceipt struct {
+	ReaderID      int64     `json:"reader_id"`
+	PartnerID     int64     `json:"partner_id"`
+	UpToMessageID int64     `json:"up_to_message_id"`
+	ReadAt        time.Time `json:"read_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// MessageAttachment is a file sent with a message. It is uploaded first and
+// linked to a message when the message is sent; until then MessageID is nil.
+type MessageAttachment struct {
+	ID            int64     `json:"id"`
+	MessageID     *int64    `json:"message_id,omitempty"`
+	UploaderID    int64     `json:"uploader_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	FilePath      string    `json:"-"`
+	ThumbnailPath string    `json:"-"`
+	HasThumbnail  bool      `json:"has_thumbnail"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AttachmentAccess is an attachment together with the message fields that decide who may download it.
+type AttachmentAccess struct {
+	Attachment     MessageAttachment
+	SenderID       int64
+	ReceiverID     int64
+	ConversationID *int64
+}
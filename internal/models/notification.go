@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Notification is an in-app notification of a CRM user.
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
@@ -0,0 +1,249 @@
+package models
+
+import "time"
+
+// Report breakdowns.
+const (
+	ReportGroupOwner       = "owner"
+	ReportGroupSource      = "source"
+	ReportGroupPeriod      = "period"
+	ReportGroupDestination = "destination"
+	ReportGroupStatus      = "status"
+)
+
+// Report time buckets.
+const (
+	ReportIntervalDay   = "day"
+	ReportIntervalWeek  = "week"
+	ReportIntervalMonth = "month"
+)
+
+// ReportFilter narrows a funnel report and sets its breakdown. Without GroupBy the report
+// has one group with the key "all".
+type ReportFilter struct {
+	From     *time.Time // created at or after
+	To       *time.Time // created before
+	OwnerID  *int
+	Source   string
+	GroupBy  string // owner, source or period; destination and status for revenue reports
+	Interval string // day, week or month when grouping by period or for a time series
+}
+
+// FunnelCount is the number of leads of one group in one status.
+type FunnelCount struct {
+	Key    string
+	Status string
+	Count  int
+}
+
+// FunnelGroup holds lead counts per status for one group.
+type FunnelGroup struct {
+	Key      string         `json:"key"`
+	Total    int            `json:"total"`
+	Statuses map[string]int `json:"statuses"`
+}
+
+// FunnelReport is the number of leads in each status.
+type FunnelReport struct {
+	GroupBy string        `json:"group_by,omitempty"`
+	Groups  []FunnelGroup `json:"groups"`
+}
+
+// ConversionGroup is the share of leads of one group that became deals.
+type ConversionGroup struct {
+	Key            string   `json:"key"`
+	Leads          int      `json:"leads"`
+	Converted      int      `json:"converted"`
+	Rate           float64  `json:"rate"`                        // converted / leads, 0..1
+	AvgHoursToDeal *float64 `json:"avg_hours_to_deal,omitempty"` // from lead creation to its first deal
+}
+
+// ConversionReport is the lead to deal conversion.
+type ConversionReport struct {
+	GroupBy string            `json:"group_by,omitempty"`
+	Groups  []ConversionGroup `json:"groups"`
+}
+
+// StageDuration is how long leads or deals of one group stay in a status. Stays that have not
+// ended yet are only counted in Current.
+type StageDuration struct {
+	Key       string   `json:"key"`
+	Status    string   `json:"status"`
+	Completed int      `json:"completed"`
+	AvgHours  *float64 `json:"avg_hours,omitempty"`
+	Current   int      `json:"current"`
+}
+
+// StageDurationReport is the average time in each stage of leads or deals.
+type StageDurationReport struct {
+	Entity  string          `json:"entity"`
+	GroupBy string          `json:"group_by,omitempty"`
+	Stages  []StageDuration `json:"stages"`
+}
+
+// LossReasonCount is the number of lost deals with one reason; an empty reason was not given.
+type LossReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// WinLossGroup holds the closed deals of one group.
+type WinLossGroup struct {
+	Key         string            `json:"key"`
+	Won         int               `json:"won"`
+	Lost        int               `json:"lost"`
+	Open        int               `json:"open"`
+	WinRate     float64           `json:"win_rate"` // won / (won + lost), 0..1
+	LossReasons []LossReasonCount `json:"loss_reasons"`
+}
+
+// WinLossReport is the won and lost deals with loss reasons.
+type WinLossReport struct {
+	GroupBy string         `json:"group_by,omitempty"`
+	Groups  []WinLossGroup `json:"groups"`
+}
+
+// DealAmount is the sum of the deal amounts of one group, bucket, status and currency.
+// Period is empty for deals without a date.
+type DealAmount struct {
+	Key      string
+	Period   string
+	Status   string
+	Currency string
+	Deals    int
+	Amount   float64
+}
+
+// RevenuePoint is the revenue of one bucket, compared with the bucket before it.
+type RevenuePoint struct {
+	Period   string   `json:"period"` // start of the bucket, yyyy-mm-dd
+	Deals    int      `json:"deals"`
+	Amount   float64  `json:"amount"`
+	Previous float64  `json:"previous"`
+	Change   *float64 `json:"change,omitempty"` // (amount - previous) / previous
+}
+
+// RevenueSeries is the revenue time series of one group.
+type RevenueSeries struct {
+	Key           string         `json:"key"`
+	Points        []RevenuePoint `json:"points"`
+	Total         float64        `json:"total"`
+	PreviousTotal float64        `json:"previous_total"` // the same number of buckets before from
+	Change        *float64       `json:"change,omitempty"`
+}
+
+// RevenueReport is the amount of won deals by the time they were won, in one currency.
+type RevenueReport struct {
+	Currency string          `json:"currency"`
+	Interval string          `json:"interval"`
+	GroupBy  string          `json:"group_by,omitempty"`
+	From     string          `json:"from"`
+	To       string          `json:"to"` // exclusive
+	Series   []RevenueSeries `json:"series"`
+	Total    float64         `json:"total"`
+	Previous float64         `json:"previous_total"`
+	Change   *float64        `json:"change,omitempty"`
+	// Currencies without an exchange rate; their deals are left out.
+	SkippedCurrencies []string `json:"skipped_currencies,omitempty"`
+}
+
+// ForecastPoint is the pipeline expected to close in one bucket.
+type ForecastPoint struct {
+	Period   string   `json:"period"`
+	Deals    int      `json:"deals"`
+	Amount   float64  `json:"amount"`
+	Weighted float64  `json:"weighted"`         // amounts multiplied by the stage probability
+	Change   *float64 `json:"change,omitempty"` // of the weighted amount against the bucket before
+}
+
+// ForecastTotal sums open deals.
+type ForecastTotal struct {
+	Deals    int     `json:"deals"`
+	Amount   float64 `json:"amount"`
+	Weighted float64 `json:"weighted"`
+}
+
+// ForecastSeries is the forecast time series of one group.
+type ForecastSeries struct {
+	Key     string          `json:"key"`
+	Points  []ForecastPoint `json:"points"`
+	Total   ForecastTotal   `json:"total"`
+	Undated ForecastTotal   `json:"undated"` // open deals without a departure date
+}
+
+// ForecastReport is the weighted pipeline of open deals by departure date, in one currency.
+type ForecastReport struct {
+	Currency      string             `json:"currency"`
+	Interval      string             `json:"interval"`
+	GroupBy       string             `json:"group_by,omitempty"`
+	From          string             `json:"from"`
+	To            string             `json:"to"` // exclusive
+	Probabilities map[string]float64 `json:"probabilities"`
+	Series        []ForecastSeries   `json:"series"`
+	Total         ForecastTotal      `json:"total"`
+	Undated       ForecastTotal      `json:"undated"`
+	// Currencies without an exchange rate; their deals are left out.
+	SkippedCurrencies []string `json:"skipped_currencies,omitempty"`
+}
+
+// RollupCount is the number of leads created in one bucket in one status, from the daily
+// rollup.
+type RollupCount struct {
+	Period    string
+	Status    string
+	Leads     int
+	Converted int
+}
+
+// RollupState tells how fresh the daily rollups are.
+type RollupState struct {
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	PendingDays int        `json:"pending_days"` // days changed since and not yet refreshed
+}
+
+// DashboardPoint is the activity of one bucket of the dashboard.
+type DashboardPoint struct {
+	Period    string  `json:"period"`
+	Leads     int     `json:"leads"`
+	Converted int     `json:"converted"`
+	Deals     int     `json:"deals"`
+	WonDeals  int     `json:"won_deals"`
+	WonAmount float64 `json:"won_amount"`
+}
+
+// DashboardReport holds the key figures of the leads and deals created in a period, by
+// their current status, and of the whole open pipeline. It is read from the daily rollups,
+// so it lags the live data by up to one refresh.
+type DashboardReport struct {
+	Currency       string           `json:"currency"`
+	Interval       string           `json:"interval"`
+	From           string           `json:"from"`
+	To             string           `json:"to"` // exclusive
+	Leads          int              `json:"leads"`
+	ConvertedLeads int              `json:"converted_leads"`
+	ConversionRate float64          `json:"conversion_rate"` // converted / leads, 0..1
+	LeadsByStatus  map[string]int   `json:"leads_by_status"`
+	Deals          int              `json:"deals"`
+	DealsByStatus  map[string]int   `json:"deals_by_status"`
+	WonDeals       int              `json:"won_deals"`
+	LostDeals      int              `json:"lost_deals"`
+	WinRate        float64          `json:"win_rate"` // won / (won + lost), 0..1
+	WonAmount      float64          `json:"won_amount"`
+	AvgWonAmount   float64          `json:"avg_won_amount"`
+	Pipeline       ForecastTotal    `json:"pipeline"` // open deals, whenever they were created
+	Series         []DashboardPoint `json:"series"`
+	Rollup         RollupState      `json:"rollup"`
+	// Currencies without an exchange rate; their deals are left out.
+	SkippedCurrencies []string `json:"skipped_currencies,omitempty"`
+}
+
+// BuilderResult is the result of a report builder query: rows of the selected fields, or
+// one row per group with the group keys followed by the aggregates.
+type BuilderResult struct {
+	Entity  string          `json:"entity"`
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Total   int             `json:"total"` // rows or groups matching, without paging
+	Page    int             `json:"page"`
+	Size    int             `json:"size"`
+}
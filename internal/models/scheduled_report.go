@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Report types that can be scheduled.
+const (
+	ReportTypeSummary        = "summary"
+	ReportTypeLeads          = "leads"
+	ReportTypeDeals          = "deals"
+	ReportTypeFunnel         = "funnel"
+	ReportTypeConversion     = "conversion"
+	ReportTypeStageDurations = "stage_durations"
+	ReportTypeWinLoss        = "win_loss"
+	ReportTypeRevenue        = "revenue"
+	ReportTypeForecast       = "forecast"
+)
+
+// Delivery triggers and statuses.
+const (
+	DeliveryTriggerSchedule = "schedule"
+	DeliveryTriggerManual   = "manual"
+
+	DeliveryStatusSent   = "sent"
+	DeliveryStatusFailed = "failed"
+)
+
+// ScheduledReportParams are the filters of a saved report. Period makes the dates relative
+// to the run: the last full day, week or month before it. Without it reports use their
+// own defaults and the deal list is not limited by date. The lead list takes only Status
+// and OwnerID, the deal list only Status and Period.
+type ScheduledReportParams struct {
+	Period   string `json:"period,omitempty"` // day, week or month
+	OwnerID  *int   `json:"owner_id,omitempty"`
+	Source   string `json:"source,omitempty"`
+	GroupBy  string `json:"group_by,omitempty"`
+	Interval string `json:"interval,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Entity   string `json:"entity,omitempty"` // lead or deal for stage durations
+	Status   string `json:"status,omitempty"` // for the lead and deal lists
+}
+
+// ScheduledReport is a saved report emailed as an attachment on a cron schedule.
+type ScheduledReport struct {
+	ID         int64                 `json:"id"`
+	Name       string                `json:"name"`
+	ReportType string                `json:"report_type"`
+	Params     ScheduledReportParams `json:"params"`
+	Format     string                `json:"format"` // csv, xlsx or pdf
+	Lang       string                `json:"lang"`   // ru or kk
+	Recipients []string              `json:"recipients"`
+	Schedule   string                `json:"schedule"` // cron, e.g. "0 8 * * MON"
+	Timezone   string                `json:"timezone,omitempty"`
+	Active     bool                  `json:"active"`
+	CreatedBy  int64                 `json:"created_by"`
+	NextRunAt  *time.Time            `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time            `json:"last_run_at,omitempty"`
+	LastError  string                `json:"last_error,omitempty"` // why the schedule was deactivated
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// ReportDelivery is one sending of a scheduled report.
+type ReportDelivery struct {
+	ID          int64     `json:"id"`
+	ReportID    int64     `json:"report_id"`
+	Trigger     string    `json:"trigger"`
+	Status      string    `json:"status"`
+	Recipients  []string  `json:"recipients"`
+	FileName    string    `json:"file_name"`
+	FileSize    int       `json:"file_size"`
+	Error       string    `json:"error,omitempty"`
+	TriggeredBy int64     `json:"triggered_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReportDeliveryPage is one page of a delivery history.
+type ReportDeliveryPage struct {
+	Deliveries []ReportDelivery `json:"deliveries"`
+	Total      int              `json:"total"`
+	Page       int              `json:"page"`
+	Size       int              `json:"size"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// SMSMessageStatus статус доставки исходящего SMS.
+type SMSMessageStatus string
+
+const (
+	SMSStatusQueued    SMSMessageStatus = "queued"
+	SMSStatusSent      SMSMessageStatus = "sent"
+	SMSStatusDelivered SMSMessageStatus = "delivered"
+	SMSStatusFailed    SMSMessageStatus = "failed"
+)
+
+// SMSMessage запись журнала исходящих SMS. Template хранит текст без кода
+// подтверждения, чтобы журнал не раскрывал одноразовые коды.
+type SMSMessage struct {
+	ID         int64            `json:"id"`
+	DocumentID *int64           `json:"document_id,omitempty"`
+	Provider   string           `json:"provider"`
+	MessageID  string           `json:"message_id"`
+	Recipient  string           `json:"recipient"`
+	Template   string           `json:"template"`
+	Cost       *float64         `json:"cost,omitempty"`
+	Status     SMSMessageStatus `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// SMSMessageFilter параметры выборки журнала SMS.
+type SMSMessageFilter struct {
+	Recipient  *string
+	DocumentID *int64
+	Provider   *string
+	Status     *SMSMessageStatus
+	From       *time.Time
+	To         *time.Time
+}
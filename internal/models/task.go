@@ -25,6 +25,11 @@ type Task struct {
 	Status      TaskStatus `json:"status"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	OverdueAt   *time.Time `json:"overdue_at,omitempty"`   // set by the scheduler once the due date has passed
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"` // set when the overdue task was escalated to the manager
+	Recurrence  string     `json:"recurrence,omitempty"`   // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO; the next task is created when this one is closed
+	SeriesID    *int64     `json:"series_id,omitempty"`    // first task of the recurring series
+	SeriesStart *time.Time `json:"-"`                      // due date of the first task, the rule counts from it
 }
 
 // TaskFilter defines the available parameters for filtering tasks.
@@ -34,4 +39,22 @@ type TaskFilter struct {
 	EntityID   *int64
 	EntityType *string
 	Status     *TaskStatus
+	DueAfter   *time.Time // due at or after this time
+	DueBefore  *time.Time // due before this time
+	Overdue    bool       // only open tasks past their due date
+	Search     string     // substring of the title, case-insensitive
+	Sort       string     // one of TaskSortFields, "-" prefix for descending; newest first by default
+	Limit      int        // zero means no limit
+	Offset     int
+}
+
+// TaskSortFields are the fields tasks can be sorted by.
+var TaskSortFields = []string{"created_at", "updated_at", "due_date", "title", "status"}
+
+// TaskPage is a page of filtered tasks with the total number of matches.
+type TaskPage struct {
+	Tasks []Task `json:"tasks"`
+	Total int    `json:"total"`
+	Page  int    `json:"page"`
+	Size  int    `json:"size"`
 }
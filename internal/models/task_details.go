@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TaskChecklistItem is one subtask of a task's checklist.
+type TaskChecklistItem struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	Position  int        `json:"position"`
+	Title     string     `json:"title"`
+	Done      bool       `json:"done"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TaskChecklistUpdate holds the checklist item fields to change; nil fields are kept.
+type TaskChecklistUpdate struct {
+	Title *string `json:"title"`
+	Done  *bool   `json:"done"`
+}
+
+// TaskComment is a comment on a task; ParentID is set for replies.
+type TaskComment struct {
+	ID        int64         `json:"id"`
+	TaskID    int64         `json:"task_id"`
+	ParentID  *int64        `json:"parent_id,omitempty"`
+	AuthorID  int64         `json:"author_id"`
+	Content   string        `json:"content"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Replies   []TaskComment `json:"replies,omitempty"`
+}
+
+// Task fields tracked in the activity log.
+const (
+	TaskFieldStatus   = "status"
+	TaskFieldAssignee = "assignee_id"
+	TaskFieldDueDate  = "due_date"
+)
+
+// TaskActivity is one change of a tracked task field. Values are stored as text; due dates
+// in RFC3339, an empty value means none.
+type TaskActivity struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	ActorID   int64     `json:"actor_id,omitempty"` // zero if the change was not made by a signed-in user
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskDetails is a task with its checklist, comment threads and activity log.
+type TaskDetails struct {
+	Task
+	Checklist []TaskChecklistItem `json:"checklist"`
+	Comments  []TaskComment       `json:"comments"`
+	Activity  []TaskActivity      `json:"activity"`
+}
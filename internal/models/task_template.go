@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TaskTemplate is a set of tasks created for a deal when it enters DealStatus.
+type TaskTemplate struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	DealStatus string             `json:"deal_status"`
+	Active     bool               `json:"active"`
+	CreatedBy  int64              `json:"created_by"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Items      []TaskTemplateItem `json:"items"`
+}
+
+// TaskTemplateItem is one task of a template. The task is due OffsetDays after the tour
+// departure date (negative — before it) and goes to AssigneeID or, if empty, the lead owner.
+type TaskTemplateItem struct {
+	ID          int64  `json:"id"`
+	TemplateID  int64  `json:"template_id"`
+	Position    int    `json:"position"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	AssigneeID  *int64 `json:"assignee_id,omitempty"`
+	OffsetDays  int    `json:"offset_days"`
+}
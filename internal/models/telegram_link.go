@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TelegramLink binds a CRM user to the Telegram chat that receives their notifications.
+type TelegramLink struct {
+	UserID   int64     `json:"user_id"`
+	ChatID   int64     `json:"chat_id"`
+	Username string    `json:"username,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// TelegramLinkCode is a one-time code the user sends to the bot with /link.
+type TelegramLinkCode struct {
+	Code      string    `json:"code"`
+	Command   string    `json:"command"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TelegramSession is the state of the bot dialog in one chat.
+type TelegramSession struct {
+	ChatID    int64               `json:"chat_id"`
+	State     string              `json:"state"`
+	Data      TelegramSessionData `json:"data"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// TelegramSessionData holds what the customer has chosen and entered so far.
+type TelegramSessionData struct {
+	TourID      int64  `json:"tour_id,omitempty"`
+	DepartureID int64  `json:"departure_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Tour is a tour offered to customers in the Telegram bot.
+type Tour struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+	Currency    string    `json:"currency"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TourDeparture is a scheduled date of a tour. Price is the tour price unless overridden.
+type TourDeparture struct {
+	ID        int64     `json:"id"`
+	TourID    int64     `json:"tour_id"`
+	DepartsAt time.Time `json:"departs_at"`
+	ReturnsAt time.Time `json:"returns_at"`
+	Seats     int       `json:"seats"`
+	Price     float64   `json:"price"`
+}
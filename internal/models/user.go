@@ -7,6 +7,7 @@ type User struct {
 	Email        string `json:"email"`
 	PasswordHash string `json:"password_hash"`
 	RoleID       int    `json:"role_id"`
+	ManagerID    *int   `json:"manager_id,omitempty"` // receives escalations of the user's overdue tasks
 }
 
 type LoginRequest struct {
@@ -19,22 +19,35 @@ type DocumentGenerator struct {
 
 // ContractData структура данных для контракта
 type ContractData struct {
+	Number       string
 	LeadTitle    string
 	DealID       int
 	Amount       string
 	Currency     string
 	CreatedAt    time.Time
 	DocumentPath string
+	Signature    *SignatureData // Если задано, добавляется лист подписи
 }
 
 // InvoiceData структура данных для счета
 type InvoiceData struct {
+	Number       string
 	LeadTitle    string
 	DealID       int
 	Amount       string
 	Currency     string
 	CreatedAt    time.Time
 	DocumentPath string
+	Signature    *SignatureData // Если задано, добавляется лист подписи
+}
+
+// SignatureData сведения о подписании документа кодом из SMS
+type SignatureData struct {
+	DocumentNumber string
+	Phone          string
+	IP             string
+	UserAgent      string
+	SignedAt       time.Time
 }
 
 // NewDocumentGenerator создает новый генератор PDF
@@ -63,6 +76,9 @@ func (g *DocumentGenerator) GenerateContract(data ContractData) error {
 
 	// Информация о контракте
 	g.addContractInfo(data)
+	if data.Signature != nil {
+		g.addSignaturePage(*data.Signature)
+	}
 
 	return pdf.OutputFileAndClose(data.DocumentPath)
 }
@@ -76,7 +92,7 @@ func (g *DocumentGenerator) addContractInfo(data ContractData) {
 
 	// Добавляем информацию построчно
 	lines := []string{
-		fmt.Sprintf("Номер договора: %d", data.DealID),
+		fmt.Sprintf("Номер договора: %s", data.Number),
 		fmt.Sprintf("Клиент: %s", data.LeadTitle),
 		fmt.Sprintf("Сумма: %s %s", data.Amount, data.Currency),
 		fmt.Sprintf("Дата создания: %s", data.CreatedAt.Format("02.01.2006")),
@@ -107,6 +123,9 @@ func (g *DocumentGenerator) GenerateInvoice(data InvoiceData) error {
 	pdf.Ln(20)
 
 	g.addInvoiceInfo(data)
+	if data.Signature != nil {
+		g.addSignaturePage(*data.Signature)
+	}
 
 	return pdf.OutputFileAndClose(data.DocumentPath)
 }
@@ -118,7 +137,7 @@ func (g *DocumentGenerator) addInvoiceInfo(data InvoiceData) {
 	g.pdf.SetX(leftMargin)
 
 	lines := []string{
-		fmt.Sprintf("Номер счета: %d", data.DealID),
+		fmt.Sprintf("Номер счета: %s", data.Number),
 		fmt.Sprintf("Клиент: %s", data.LeadTitle),
 		fmt.Sprintf("Сумма к оплате: %s %s", data.Amount, data.Currency),
 		fmt.Sprintf("Дата выставления: %s", data.CreatedAt.Format("02.01.2006")),
@@ -130,3 +149,36 @@ func (g *DocumentGenerator) addInvoiceInfo(data InvoiceData) {
 		g.pdf.Ln(15)
 	}
 }
+
+// addSignaturePage добавляет отдельный лист с реквизитами электронной подписи
+func (g *DocumentGenerator) addSignaturePage(sig SignatureData) {
+	g.pdf.AddPage()
+
+	g.pdf.SetFont("Arial", "B", 16)
+	g.pdf.SetY(20)
+	title := "ЛИСТ ПОДПИСИ"
+	g.pdf.SetX((210 - g.pdf.GetStringWidth(title)) / 2)
+	g.pdf.Cell(40, 10, title)
+	g.pdf.Ln(20)
+
+	g.pdf.SetFont("Arial", "", 12)
+	leftMargin := 20.0
+
+	lines := []string{
+		fmt.Sprintf("Документ: %s", sig.DocumentNumber),
+		"Способ подписания: одноразовый код из SMS",
+		fmt.Sprintf("Телефон подписанта: %s", sig.Phone),
+		fmt.Sprintf("IP-адрес: %s", sig.IP),
+		fmt.Sprintf("Дата и время подписания: %s", sig.SignedAt.Format("02.01.2006 15:04:05 MST")),
+	}
+
+	for _, line := range lines {
+		g.pdf.SetX(leftMargin)
+		g.pdf.Cell(0, 10, line)
+		g.pdf.Ln(15)
+	}
+
+	// User-Agent бывает длинным, поэтому выводим его с переносом строк
+	g.pdf.SetX(leftMargin)
+	g.pdf.MultiCell(170, 8, fmt.Sprintf("Клиент: %s", sig.UserAgent), "", "L", false)
+}
@@ -0,0 +1,108 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const (
+	reportFont        = "report"
+	reportPageWidth   = 277.0 // ширина A4 в альбомной ориентации без полей, мм
+	reportRowHeight   = 7.0
+	reportHeaderColor = 230
+)
+
+// ReportTable таблица отчёта в PDF. Строки добавляются по одной, документ записывается
+// в Output целиком, поэтому gofpdf держит его в памяти до конца выгрузки.
+type ReportTable struct {
+	pdf     *gofpdf.Fpdf
+	headers []string
+	widths  []float64
+	rows    int
+}
+
+// NewReportTable создаёт таблицу с заголовком title и колонками headers. fontPath — путь
+// к TTF-шрифту с нужными символами (например, казахскими буквами); если пусто, используется
+// встроенный шрифт Go, в котором есть только кириллица и латиница.
+func NewReportTable(title string, headers []string, fontPath string) *ReportTable {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	if fontPath != "" {
+		pdf.AddUTF8Font(reportFont, "", fontPath)
+		pdf.AddUTF8Font(reportFont, "B", fontPath)
+	} else {
+		pdf.AddUTF8FontFromBytes(reportFont, "", goregular.TTF)
+		pdf.AddUTF8FontFromBytes(reportFont, "B", gobold.TTF)
+	}
+
+	t := &ReportTable{pdf: pdf, headers: headers, widths: make([]float64, len(headers))}
+	for i := range t.widths {
+		t.widths[i] = reportPageWidth / float64(len(headers))
+	}
+
+	// Шапка таблицы повторяется на каждой странице
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() == 1 {
+			pdf.SetFont(reportFont, "B", 14)
+			pdf.CellFormat(0, 10, title, "", 1, "L", false, 0, "")
+			pdf.SetFont(reportFont, "", 9)
+			pdf.CellFormat(0, 6, time.Now().Format("02.01.2006 15:04"), "", 1, "L", false, 0, "")
+			pdf.Ln(2)
+		}
+		pdf.SetFont(reportFont, "B", 9)
+		pdf.SetFillColor(reportHeaderColor, reportHeaderColor, reportHeaderColor)
+		for i, header := range t.headers {
+			pdf.CellFormat(t.widths[i], reportRowHeight, t.fit(header, t.widths[i]), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(reportFont, "", 9)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(reportFont, "", 8)
+		pdf.CellFormat(0, 8, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+	return t
+}
+
+// AddRow добавляет строку; текст, не помещающийся в колонку, обрезается.
+func (t *ReportTable) AddRow(cells []string) {
+	for i := range t.widths {
+		var cell string
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		t.pdf.CellFormat(t.widths[i], reportRowHeight, t.fit(cell, t.widths[i]), "1", 0, "L", false, 0, "")
+	}
+	t.pdf.Ln(-1)
+	t.rows++
+}
+
+// Rows возвращает число добавленных строк.
+func (t *ReportTable) Rows() int {
+	return t.rows
+}
+
+// Output записывает документ в w.
+func (t *ReportTable) Output(w io.Writer) error {
+	return t.pdf.Output(w)
+}
+
+// fit обрезает текст по ширине колонки с учётом отступов ячейки.
+func (t *ReportTable) fit(text string, width float64) string {
+	width -= 2 * t.pdf.GetCellMargin()
+	if t.pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && t.pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,602 @@
+// Package querydsl собирает параметризованные SQL-запросы из описания фильтров,
+// сортировки и группировки, пришедшего от клиента (JSON или строка запроса).
+//
+// Клиент ссылается только на имена полей схемы; SQL-выражения полей, таблицы,
+// операторы, интервалы группировки и агрегатные функции берутся из кода, а все
+// значения передаются параметрами $n. Поэтому текст запроса зависит от ввода
+// только выбором из заранее заданных фрагментов.
+//
+// Операторы фильтров: eq, ne, in, range, like, is_null. Группировка по полю даты
+// задаётся с интервалом (created_at:month), агрегаты — count, sum, avg, min, max.
+package querydsl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Ограничения размера запроса.
+const (
+	MaxFilters    = 20
+	MaxInValues   = 500
+	MaxGroups     = 3
+	MaxAggregates = 5
+	MaxFields     = 50
+	MaxLikeLength = 200
+)
+
+// Операторы фильтров.
+const (
+	OpEq     = "eq"
+	OpNe     = "ne"
+	OpIn     = "in"
+	OpRange  = "range"
+	OpLike   = "like"
+	OpIsNull = "is_null"
+)
+
+// Type — тип значения поля; значения фильтров приводятся к нему.
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeFloat  Type = "float"
+	TypeTime   Type = "time"
+	TypeBool   Type = "bool"
+)
+
+// buckets — интервалы группировки дат для date_trunc.
+var buckets = map[string]string{
+	"day":     "day",
+	"week":    "week",
+	"month":   "month",
+	"quarter": "quarter",
+	"year":    "year",
+}
+
+// aggregates — агрегатные функции и типы полей, к которым они применимы.
+var aggregates = map[string]struct {
+	sql   string
+	types []Type
+}{
+	"sum": {"SUM(%s)::float8", []Type{TypeInt, TypeFloat}},
+	"avg": {"AVG(%s)::float8", []Type{TypeInt, TypeFloat}},
+	"min": {"MIN(%s)", []Type{TypeInt, TypeFloat, TypeTime}},
+	"max": {"MAX(%s)", []Type{TypeInt, TypeFloat, TypeTime}},
+}
+
+// Field — поле схемы. Column — SQL-выражение, задаётся только в коде.
+type Field struct {
+	Name   string
+	Column string
+	Type   Type
+	Sort   bool // можно сортировать
+	Group  bool // можно группировать
+}
+
+// FieldInfo описывает поле для клиента.
+type FieldInfo struct {
+	Name  string `json:"name"`
+	Type  Type   `json:"type"`
+	Sort  bool   `json:"sort"`
+	Group bool   `json:"group"`
+}
+
+// Schema — разрешённые поля одной сущности.
+type Schema struct {
+	from        string
+	defaultSort string
+	fields      []Field
+	byName      map[string]Field
+}
+
+// NewSchema создаёт схему; from — таблица с JOIN, defaultSort — ORDER BY для строк без
+// сортировки от клиента.
+func NewSchema(from, defaultSort string, fields ...Field) *Schema {
+	s := &Schema{from: from, defaultSort: defaultSort, fields: fields, byName: make(map[string]Field, len(fields))}
+	for _, f := range fields {
+		if _, ok := s.byName[f.Name]; ok {
+			panic("querydsl: duplicate field " + f.Name)
+		}
+		s.byName[f.Name] = f
+	}
+	return s
+}
+
+// Fields возвращает поля схемы в порядке объявления.
+func (s *Schema) Fields() []FieldInfo {
+	info := make([]FieldInfo, len(s.fields))
+	for i, f := range s.fields {
+		info[i] = FieldInfo{Name: f.Name, Type: f.Type, Sort: f.Sort, Group: f.Group}
+	}
+	return info
+}
+
+// Filter — условие на поле. Value — значение для eq, ne и like, список для in, пара
+// [от, до] для range (null — граница не задана), true или false для is_null.
+type Filter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Query — запрос клиента. Sort — имена полей (с "-" — по убыванию); при группировке —
+// имена столбцов результата. GroupBy — "поле" или "поле:интервал"; Aggregates — "count"
+// или "функция:поле". Без группировки и агрегатов возвращаются строки с полями Fields
+// (все поля, если не заданы).
+type Query struct {
+	Filters    []Filter `json:"filters"`
+	Sort       []string `json:"sort"`
+	GroupBy    []string `json:"group_by"`
+	Aggregates []string `json:"aggregates"`
+	Fields     []string `json:"fields"`
+	Limit      int      `json:"-"`
+	Offset     int      `json:"-"`
+}
+
+// Grouped сообщает, возвращает ли запрос группы вместо строк.
+func (q Query) Grouped() bool {
+	return len(q.GroupBy) > 0 || len(q.Aggregates) > 0
+}
+
+// Statement — собранный запрос. CountSQL считает строки или группы без LIMIT и OFFSET.
+type Statement struct {
+	SQL       string
+	Args      []interface{}
+	CountSQL  string
+	CountArgs []interface{}
+	Columns   []string
+}
+
+// ParseValues читает запрос из строки запроса:
+//
+//	filter=status:in:new,won&filter=created_at:range:2024-01-01,&filter=email:is_null
+//	sort=-created_at,title&group_by=created_at:month,status&agg=count,sum:amount&fields=id,title
+//
+// Значения in и range разделяются запятыми; значения с запятыми передаются в JSON.
+func ParseValues(values url.Values) (Query, error) {
+	q := Query{
+		Sort:       splitList(values.Get("sort")),
+		GroupBy:    splitList(values.Get("group_by")),
+		Aggregates: splitList(values.Get("agg")),
+		Fields:     splitList(values.Get("fields")),
+	}
+	for _, raw := range values["filter"] {
+		parts := strings.SplitN(raw, ":", 3)
+		if len(parts) < 2 {
+			return q, fmt.Errorf("%w: filter %q must be field:op:value", ErrInvalidQuery, raw)
+		}
+		f := Filter{Field: parts[0], Op: normalizeOp(parts[1])}
+		value := ""
+		if len(parts) == 3 {
+			value = parts[2]
+		}
+		switch f.Op {
+		case OpIn:
+			list := []interface{}{}
+			for _, v := range strings.Split(value, ",") {
+				list = append(list, v)
+			}
+			f.Value = list
+		case OpRange:
+			from, to, ok := strings.Cut(value, ",")
+			if !ok {
+				return q, fmt.Errorf("%w: range of %s must be from,to", ErrInvalidQuery, f.Field)
+			}
+			f.Value = []interface{}{emptyToNil(from), emptyToNil(to)}
+		case OpIsNull:
+			f.Value = emptyToNil(value)
+		default:
+			f.Value = value
+		}
+		q.Filters = append(q.Filters, f)
+	}
+	return q, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func emptyToNil(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// normalizeOp допускает написание через дефис: is-null.
+func normalizeOp(op string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(op)), "-", "_")
+}
+
+// Compile проверяет запрос по схеме и собирает SQL.
+func (s *Schema) Compile(q Query) (*Statement, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return nil, fmt.Errorf("%w: negative limit or offset", ErrInvalidQuery)
+	}
+	if len(q.Fields) > MaxFields {
+		return nil, fmt.Errorf("%w: at most %d fields", ErrInvalidQuery, MaxFields)
+	}
+	where, args, err := s.where(q.Filters)
+	if err != nil {
+		return nil, err
+	}
+	if q.Grouped() {
+		return s.compileGroups(q, where, args)
+	}
+	return s.compileRows(q, where, args)
+}
+
+func (s *Schema) compileRows(q Query, where string, args []interface{}) (*Statement, error) {
+	if len(q.Fields) == 0 {
+		for _, f := range s.fields {
+			q.Fields = append(q.Fields, f.Name)
+		}
+	}
+	st := &Statement{}
+	columns := make([]string, 0, len(q.Fields))
+	for _, name := range q.Fields {
+		f, err := s.field(name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, f.Column)
+		st.Columns = append(st.Columns, f.Name)
+	}
+
+	order := s.defaultSort
+	if len(q.Sort) > 0 {
+		terms := make([]string, 0, len(q.Sort))
+		for _, key := range q.Sort {
+			name, desc := sortKey(key)
+			f, err := s.field(name)
+			if err != nil {
+				return nil, err
+			}
+			if !f.Sort {
+				return nil, fmt.Errorf("%w: cannot sort by %s", ErrInvalidQuery, name)
+			}
+			terms = append(terms, f.Column+direction(desc))
+		}
+		order = strings.Join(terms, ", ")
+	}
+
+	body := ` FROM ` + s.from + ` WHERE ` + where
+	st.CountSQL = `SELECT COUNT(*)` + body
+	st.CountArgs = args
+	st.SQL, st.Args = paginate(`SELECT `+strings.Join(columns, ", ")+body+` ORDER BY `+order, args, q)
+	return st, nil
+}
+
+func (s *Schema) compileGroups(q Query, where string, args []interface{}) (*Statement, error) {
+	if len(q.GroupBy) > MaxGroups {
+		return nil, fmt.Errorf("%w: at most %d groups", ErrInvalidQuery, MaxGroups)
+	}
+	if len(q.Aggregates) > MaxAggregates {
+		return nil, fmt.Errorf("%w: at most %d aggregates", ErrInvalidQuery, MaxAggregates)
+	}
+	if len(q.Aggregates) == 0 {
+		q.Aggregates = []string{"count"}
+	}
+
+	st := &Statement{}
+	var groups, selects []string
+	for _, spec := range q.GroupBy {
+		name, bucket, _ := strings.Cut(spec, ":")
+		f, err := s.field(name)
+		if err != nil {
+			return nil, err
+		}
+		if !f.Group {
+			return nil, fmt.Errorf("%w: cannot group by %s", ErrInvalidQuery, name)
+		}
+		expr, column := f.Column, f.Name
+		switch {
+		case f.Type == TypeTime:
+			if bucket == "" {
+				bucket = "day"
+			}
+			trunc, ok := buckets[bucket]
+			if !ok {
+				return nil, fmt.Errorf("%w: interval of %s must be day, week, month, quarter or year", ErrInvalidQuery, name)
+			}
+			expr = fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD')", trunc, f.Column)
+			column = f.Name + "_" + trunc
+		case bucket != "":
+			return nil, fmt.Errorf("%w: %s is not a date", ErrInvalidQuery, name)
+		}
+		if slices.Contains(st.Columns, column) {
+			return nil, fmt.Errorf("%w: %s is grouped twice", ErrInvalidQuery, column)
+		}
+		groups = append(groups, expr)
+		selects = append(selects, expr)
+		st.Columns = append(st.Columns, column)
+	}
+	for _, spec := range q.Aggregates {
+		expr, column, err := s.aggregate(spec)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(st.Columns, column) {
+			return nil, fmt.Errorf("%w: %s is requested twice", ErrInvalidQuery, column)
+		}
+		selects = append(selects, expr)
+		st.Columns = append(st.Columns, column)
+	}
+
+	// Сортировка по номерам столбцов результата
+	var terms []string
+	for _, key := range q.Sort {
+		name, desc := sortKey(key)
+		i := slices.Index(st.Columns, name)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: cannot sort by %s, use a result column", ErrInvalidQuery, name)
+		}
+		terms = append(terms, strconv.Itoa(i+1)+direction(desc))
+	}
+	if len(terms) == 0 {
+		for i := range groups {
+			terms = append(terms, strconv.Itoa(i+1))
+		}
+	}
+
+	body := ` FROM ` + s.from + ` WHERE ` + where
+	query := `SELECT ` + strings.Join(selects, ", ") + body
+	// Без группировки агрегаты дают одну строку
+	st.CountSQL = `SELECT 1`
+	if len(groups) > 0 {
+		query += ` GROUP BY ` + strings.Join(groups, ", ")
+		st.CountSQL = `SELECT COUNT(*) FROM (SELECT 1` + body + ` GROUP BY ` + strings.Join(groups, ", ") + `) g`
+		st.CountArgs = args
+	}
+	if len(terms) > 0 {
+		query += ` ORDER BY ` + strings.Join(terms, ", ")
+	}
+	st.SQL, st.Args = paginate(query, args, q)
+	return st, nil
+}
+
+func (s *Schema) aggregate(spec string) (expr, column string, err error) {
+	fn, name, _ := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+	if fn == "count" {
+		if name != "" {
+			return "", "", fmt.Errorf("%w: count takes no field", ErrInvalidQuery)
+		}
+		return "COUNT(*)", "count", nil
+	}
+	agg, ok := aggregates[fn]
+	if !ok {
+		return "", "", fmt.Errorf("%w: aggregate must be count, sum, avg, min or max", ErrInvalidQuery)
+	}
+	f, err := s.field(name)
+	if err != nil {
+		return "", "", err
+	}
+	if !slices.Contains(agg.types, f.Type) {
+		return "", "", fmt.Errorf("%w: cannot %s %s", ErrInvalidQuery, fn, name)
+	}
+	return fmt.Sprintf(agg.sql, f.Column), fn + "_" + f.Name, nil
+}
+
+// where собирает условия фильтров; значения добавляются в args.
+func (s *Schema) where(filters []Filter) (string, []interface{}, error) {
+	if len(filters) > MaxFilters {
+		return "", nil, fmt.Errorf("%w: at most %d filters", ErrInvalidQuery, MaxFilters)
+	}
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, filter := range filters {
+		f, err := s.field(filter.Field)
+		if err != nil {
+			return "", nil, err
+		}
+		switch normalizeOp(filter.Op) {
+		case OpEq, OpNe:
+			v, err := coerce(f, filter.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			op := " = "
+			if normalizeOp(filter.Op) == OpNe {
+				op = " IS DISTINCT FROM "
+			}
+			conditions = append(conditions, f.Column+op+param(v))
+		case OpIn:
+			list, ok := filter.Value.([]interface{})
+			if !ok || len(list) == 0 {
+				return "", nil, fmt.Errorf("%w: in of %s needs a list of values", ErrInvalidQuery, f.Name)
+			}
+			if len(list) > MaxInValues {
+				return "", nil, fmt.Errorf("%w: at most %d values in in", ErrInvalidQuery, MaxInValues)
+			}
+			params := make([]string, len(list))
+			for i, item := range list {
+				v, err := coerce(f, item)
+				if err != nil {
+					return "", nil, err
+				}
+				params[i] = param(v)
+			}
+			conditions = append(conditions, f.Column+" IN ("+strings.Join(params, ", ")+")")
+		case OpRange:
+			bounds, ok := filter.Value.([]interface{})
+			if !ok || len(bounds) != 2 || (bounds[0] == nil && bounds[1] == nil) {
+				return "", nil, fmt.Errorf("%w: range of %s needs [from, to]", ErrInvalidQuery, f.Name)
+			}
+			if f.Type == TypeString || f.Type == TypeBool {
+				return "", nil, fmt.Errorf("%w: range needs a number or a date", ErrInvalidQuery)
+			}
+			if bounds[0] != nil {
+				v, err := coerce(f, bounds[0])
+				if err != nil {
+					return "", nil, err
+				}
+				conditions = append(conditions, f.Column+" >= "+param(v))
+			}
+			if bounds[1] != nil {
+				v, err := coerce(f, bounds[1])
+				if err != nil {
+					return "", nil, err
+				}
+				// Дата без времени включает весь день
+				if date, ok := bounds[1].(string); ok && f.Type == TypeTime && isDate(date) {
+					conditions = append(conditions, f.Column+" < "+param(v.(time.Time).AddDate(0, 0, 1)))
+				} else {
+					conditions = append(conditions, f.Column+" <= "+param(v))
+				}
+			}
+		case OpLike:
+			if f.Type != TypeString {
+				return "", nil, fmt.Errorf("%w: like needs a text field", ErrInvalidQuery)
+			}
+			v, err := coerce(f, filter.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			text := v.(string)
+			if text == "" || len([]rune(text)) > MaxLikeLength {
+				return "", nil, fmt.Errorf("%w: like of %s needs 1 to %d characters", ErrInvalidQuery, f.Name, MaxLikeLength)
+			}
+			conditions = append(conditions, f.Column+" ILIKE "+param("%"+escapeLike(text)+"%"))
+		case OpIsNull:
+			isNull := true
+			if filter.Value != nil {
+				v, err := coerce(Field{Name: f.Name, Type: TypeBool}, filter.Value)
+				if err != nil {
+					return "", nil, err
+				}
+				isNull = v.(bool)
+			}
+			if isNull {
+				conditions = append(conditions, f.Column+" IS NULL")
+			} else {
+				conditions = append(conditions, f.Column+" IS NOT NULL")
+			}
+		default:
+			return "", nil, fmt.Errorf("%w: op must be eq, ne, in, range, like or is_null", ErrInvalidQuery)
+		}
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+func (s *Schema) field(name string) (Field, error) {
+	f, ok := s.byName[name]
+	if !ok {
+		return Field{}, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, name)
+	}
+	return f, nil
+}
+
+// coerce приводит значение из JSON или строки запроса к типу поля.
+func coerce(f Field, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%w: invalid %s value for %s", ErrInvalidQuery, f.Type, f.Name)
+	if n, ok := value.(json.Number); ok {
+		value = n.String()
+	}
+	switch f.Type {
+	case TypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case TypeInt:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case TypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+				return n, nil
+			}
+		}
+	case TypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+	case TypeTime:
+		if v, ok := value.(string); ok {
+			v = strings.TrimSpace(v)
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t, nil
+			}
+			if t, err := time.Parse("2006-01-02", v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, invalid
+}
+
+func isDate(value string) bool {
+	_, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	return err == nil
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы значение искалось как подстрока.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func paginate(query string, args []interface{}, q Query) (string, []interface{}) {
+	args = append([]interface{}{}, args...)
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if q.Offset > 0 {
+		args = append(args, q.Offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
+
+func sortKey(key string) (name string, desc bool) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "-") {
+		return key[1:], true
+	}
+	return key, false
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
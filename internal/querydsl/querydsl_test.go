@@ -0,0 +1,199 @@
+package querydsl
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSchema повторяет схему сделок конструктора отчётов: с JOIN и полями-выражениями.
+var testSchema = NewSchema("deals d LEFT JOIN leads l ON l.id = d.lead_id", "d.id DESC",
+	Field{Name: "id", Column: "d.id", Type: TypeInt, Sort: true},
+	Field{Name: "amount", Column: "(NULLIF(d.amount, '')::numeric)::float8", Type: TypeFloat, Sort: true},
+	Field{Name: "currency", Column: "UPPER(TRIM(d.currency))", Type: TypeString, Sort: true, Group: true},
+	Field{Name: "status", Column: "d.status", Type: TypeString, Sort: true, Group: true},
+	Field{Name: "paid", Column: "d.paid", Type: TypeBool},
+	Field{Name: "lead_title", Column: "l.title", Type: TypeString},
+	Field{Name: "created_at", Column: "d.created_at", Type: TypeTime, Sort: true, Group: true},
+)
+
+// sqlToken — всё, что может остаться в запросе после удаления фрагментов схемы.
+var sqlToken = regexp.MustCompile(`^(?:\s+|\$\d+|\d+|::|[(),*=<>]|'(?:day|week|month|quarter|year|YYYY-MM-DD)'|[A-Za-z_][A-Za-z_0-9]*)`)
+
+var sqlWords = []string{
+	"SELECT", "FROM", "WHERE", "AND", "TRUE", "ORDER", "BY", "ASC", "DESC", "GROUP", "LIMIT", "OFFSET",
+	"IN", "IS", "NOT", "NULL", "DISTINCT", "ILIKE", "COUNT", "SUM", "AVG", "MIN", "MAX", "float8",
+	"to_char", "date_trunc", "g",
+}
+
+var paramRef = regexp.MustCompile(`\$(\d+)`)
+
+// checkSQL проверяет, что в тексте запроса нет ничего, кроме фрагментов схемы, ключевых
+// слов и параметров.
+func checkSQL(t *testing.T, query string) {
+	t.Helper()
+	fragments := []string{testSchema.from, testSchema.defaultSort}
+	for _, f := range testSchema.fields {
+		fragments = append(fragments, f.Column)
+	}
+	slices.SortFunc(fragments, func(a, b string) int { return len(b) - len(a) })
+	rest := query
+	for _, fragment := range fragments {
+		rest = strings.ReplaceAll(rest, fragment, " ")
+	}
+	for rest != "" {
+		token := sqlToken.FindString(rest)
+		if token == "" {
+			t.Fatalf("unexpected text %q in %q", rest, query)
+		}
+		if c := token[0]; (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '_') && !slices.Contains(sqlWords, token) {
+			t.Fatalf("unexpected word %q in %q", token, query)
+		}
+		rest = rest[len(token):]
+	}
+}
+
+// checkParams проверяет, что запрос ссылается на каждый параметр ровно один раз по порядку.
+func checkParams(t *testing.T, query string, args []interface{}) {
+	t.Helper()
+	refs := paramRef.FindAllStringSubmatch(query, -1)
+	if len(refs) != len(args) {
+		t.Fatalf("%d parameters in %q, %d args", len(refs), query, len(args))
+	}
+	for i, ref := range refs {
+		if n, _ := strconv.Atoi(ref[1]); n != i+1 {
+			t.Fatalf("parameter %d is $%d in %q", i+1, n, query)
+		}
+	}
+}
+
+// wantArgs возвращает значения фильтров в том виде, в каком они должны попасть в Args.
+func wantArgs(filters []Filter) []interface{} {
+	var want []interface{}
+	for _, filter := range filters {
+		f := testSchema.byName[filter.Field]
+		value := func(v interface{}) interface{} {
+			c, _ := coerce(f, v)
+			return c
+		}
+		switch normalizeOp(filter.Op) {
+		case OpEq, OpNe:
+			want = append(want, value(filter.Value))
+		case OpIn:
+			for _, item := range filter.Value.([]interface{}) {
+				want = append(want, value(item))
+			}
+		case OpRange:
+			bounds := filter.Value.([]interface{})
+			if bounds[0] != nil {
+				want = append(want, value(bounds[0]))
+			}
+			if bounds[1] != nil {
+				to := value(bounds[1])
+				if date, ok := bounds[1].(string); ok && f.Type == TypeTime && isDate(date) {
+					to = to.(time.Time).AddDate(0, 0, 1)
+				}
+				want = append(want, to)
+			}
+		case OpLike:
+			want = append(want, "%"+escapeLike(filter.Value.(string))+"%")
+		}
+	}
+	return want
+}
+
+// checkCompiled собирает запрос и проверяет инварианты; ошибка допустима только ErrInvalidQuery.
+func checkCompiled(t *testing.T, q Query) {
+	t.Helper()
+	st, err := testSchema.Compile(q)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Fatalf("Compile: %v is not ErrInvalidQuery", err)
+		}
+		return
+	}
+	checkSQL(t, st.SQL)
+	checkSQL(t, st.CountSQL)
+	checkParams(t, st.SQL, st.Args)
+	checkParams(t, st.CountSQL, st.CountArgs)
+
+	want := wantArgs(q.Filters)
+	if q.Limit > 0 {
+		want = append(want, q.Limit)
+	}
+	if q.Offset > 0 {
+		want = append(want, q.Offset)
+	}
+	if len(st.Args) != len(want) || len(want) > 0 && !reflect.DeepEqual(st.Args, want) {
+		t.Fatalf("Args = %#v, want %#v", st.Args, want)
+	}
+}
+
+func FuzzParseValues(f *testing.F) {
+	for _, seed := range []string{
+		"filter=status:in:new,won&filter=created_at:range:2024-01-01,&filter=lead_title:is_null&sort=-created_at,id",
+		"group_by=created_at:month,status&agg=count,sum:amount&sort=-count",
+		"filter=amount:range:,1500.5&filter=paid:eq:true&fields=id,currency",
+		"filter=lead_title:like:50%25_off&filter=currency:ne:KZT",
+		"filter=id:eq:1;DROP TABLE deals&sort=id;--",
+		"group_by=created_at:day'),(SELECT 1&agg=max:created_at",
+	} {
+		f.Add(seed, 50, 0)
+	}
+	f.Fuzz(func(t *testing.T, raw string, limit, offset int) {
+		values, err := url.ParseQuery(raw)
+		if err != nil {
+			return
+		}
+		q, err := ParseValues(values)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("ParseValues: %v is not ErrInvalidQuery", err)
+			}
+			return
+		}
+		q.Limit, q.Offset = limit, offset
+		checkCompiled(t, q)
+	})
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, seed := range []string{
+		`{"filters": [{"field": "status", "op": "in", "value": ["new", "won"]}], "sort": ["-created_at"]}`,
+		`{"filters": [{"field": "amount", "op": "range", "value": [100, null]}, {"field": "id", "op": "ne", "value": 7}]}`,
+		`{"group_by": ["created_at:quarter", "currency"], "aggregates": ["count", "avg:amount"], "sort": ["-avg_amount"]}`,
+		`{"filters": [{"field": "lead_title", "op": "like", "value": "' OR 1=1 --"}], "fields": ["id", "lead_title"]}`,
+		`{"filters": [{"field": "created_at", "op": "is-null", "value": false}], "aggregates": ["min:created_at"]}`,
+		`{"fields": ["d.id; DROP TABLE deals"], "sort": ["id DESC, (SELECT 1)"]}`,
+	} {
+		f.Add(seed, 100, 200)
+	}
+	f.Fuzz(func(t *testing.T, body string, limit, offset int) {
+		var q Query
+		if err := json.Unmarshal([]byte(body), &q); err != nil {
+			return
+		}
+		q.Limit, q.Offset = limit, offset
+		checkCompiled(t, q)
+	})
+}
+
+func TestCompileLimitsFields(t *testing.T) {
+	fields := make([]string, MaxFields+1)
+	for i := range fields {
+		fields[i] = "id"
+	}
+	if _, err := testSchema.Compile(Query{Fields: fields}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("Compile with %d fields: got %v, want ErrInvalidQuery", len(fields), err)
+	}
+	if _, err := testSchema.Compile(Query{Fields: fields[:MaxFields]}); err != nil {
+		t.Fatalf("Compile with %d fields: %v", MaxFields, err)
+	}
+}
@@ -0,0 +1,91 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxNotifyPayload предел размера payload в NOTIFY (8000 байт) с запасом.
+const maxNotifyPayload = 7900
+
+var ErrPayloadTooLarge = errors.New("событие не помещается в NOTIFY")
+
+// Broker пересылает события между экземплярами приложения.
+type Broker interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe вызывает handler для каждого события, опубликованного любым
+	// экземпляром, до отмены ctx.
+	Subscribe(ctx context.Context, handler func(Event)) error
+}
+
+// PGBroker реализует Broker через Postgres LISTEN/NOTIFY.
+type PGBroker struct {
+	db      *sql.DB
+	dsn     string
+	channel string
+}
+
+func NewPGBroker(db *sql.DB, dsn, channel string) *PGBroker {
+	if channel == "" {
+		channel = "chat_events"
+	}
+	return &PGBroker{db: db, dsn: dsn, channel: channel}
+}
+
+func (b *PGBroker) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+
+	if _, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, string(payload)); err != nil {
+		return fmt.Errorf("pg_notify: %w", err)
+	}
+	return nil
+}
+
+func (b *PGBroker) Subscribe(ctx context.Context, handler func(Event)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Chat LISTEN: %v", err)
+		}
+	})
+	if err := listener.Listen(b.channel); err != nil {
+		listener.Close()
+		return fmt.Errorf("listen %s: %w", b.channel, err)
+	}
+
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// nil приходит после переподключения: часть событий могла потеряться
+				if n == nil {
+					continue
+				}
+				var event Event
+				if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+					log.Printf("Chat NOTIFY: некорректное событие: %v", err)
+					continue
+				}
+				handler(event)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,98 @@
+package realtime
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxFrameSize   = 4096
+	sendBufferSize = 64
+)
+
+// Client одно WebSocket-подключение пользователя.
+type Client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID int64
+	send   chan []byte
+}
+
+// Serve регистрирует подключение в хабе и обслуживает его до разрыва.
+func (h *Hub) Serve(conn *websocket.Conn, userID int64) {
+	c := &Client{
+		hub:    h,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan []byte, sendBufferSize),
+	}
+	h.register(c)
+
+	go c.writePump()
+	c.readPump()
+}
+
+// readPump читает кадры клиента: индикатор набора текста и служебные ping.
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxFrameSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame clientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			continue
+		}
+		switch frame.Type {
+		case EventTyping:
+			if frame.To > 0 && frame.To != c.userID {
+				c.hub.typing(c.userID, frame.To, frame.Typing)
+			}
+		}
+	}
+}
+
+// writePump отправляет клиенту события из очереди и поддерживает соединение ping'ами.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package realtime доставляет события чата пользователям по WebSocket:
+// новые сообщения, индикатор набора текста, присутствие в сети, отметки
+// о прочтении, упоминания в групповых беседах и уведомления CRM.
+package realtime
+
+import "encoding/json"
+
+// Типы событий, которые получает клиент.
+const (
+	EventMessage  = "message"
+	EventTyping   = "typing"
+	EventPresence = "presence"
+	EventRead     = "read"
+
+	EventConversationRead = "conversation_read"
+	EventMention          = "mention"
+
+	EventNotification = "notification"
+)
+
+// Служебные события между экземплярами; клиентам они не доставляются.
+const (
+	// eventPresenceSync периодический список пользователей, подключённых к экземпляру.
+	eventPresenceSync = "presence_sync"
+	// eventPresenceRequest просьба к остальным экземплярам сразу прислать presence_sync.
+	eventPresenceRequest = "presence_request"
+)
+
+// Event событие чата. Recipients — пользователи, которым событие адресовано;
+// пустой список означает рассылку всем подключённым пользователям.
+type Event struct {
+	Type       string          `json:"type"`
+	Recipients []int64         `json:"recipients,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+
+	// Instance идентификатор экземпляра приложения, опубликовавшего событие.
+	Instance string `json:"instance,omitempty"`
+	// MessageID ссылка на сообщение чата. Если сообщение не поместилось в NOTIFY,
+	// Payload пуст и получатель загружает сообщение из базы.
+	MessageID int64 `json:"message_id,omitempty"`
+}
+
+// TypingPayload пользователь From начал или закончил набирать сообщение для To.
+type TypingPayload struct {
+	From   int64 `json:"from"`
+	To     int64 `json:"to"`
+	Typing bool  `json:"typing"`
+}
+
+// PresencePayload пользователь появился в сети или вышел из неё.
+type PresencePayload struct {
+	UserID int64 `json:"user_id"`
+	Online bool  `json:"online"`
+}
+
+// presenceSyncPayload пользователи, подключённые к экземпляру-отправителю.
+type presenceSyncPayload struct {
+	Users []int64 `json:"users"`
+}
+
+// clientFrame сообщение, которое клиент присылает по WebSocket.
+type clientFrame struct {
+	Type   string `json:"type"`
+	To     int64  `json:"to"`
+	Typing bool   `json:"typing"`
+}
@@ -0,0 +1,440 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"turcompany/internal/models"
+)
+
+const (
+	// presenceInterval как часто экземпляр подтверждает своих пользователей остальным.
+	presenceInterval = 30 * time.Second
+	// presenceTTL через сколько без подтверждения пользователь другого экземпляра
+	// считается отключённым (например, если тот экземпляр упал).
+	presenceTTL = 3 * presenceInterval
+	// presenceChunk пользователей в одном presence_sync, чтобы событие помещалось в NOTIFY.
+	presenceChunk = 300
+)
+
+// MessageLoader загружает сообщение чата, которое другой экземпляр прислал только ссылкой.
+type MessageLoader interface {
+	FindWithDetails(ctx context.Context, id int64) (*models.Message, error)
+}
+
+// Hub хранит WebSocket-подключения пользователей этого экземпляра и доставляет
+// им события. Если задан Broker, события также рассылаются другим экземплярам,
+// а присутствие пользователей учитывается по всему кластеру.
+type Hub struct {
+	instance string
+	broker   Broker
+	messages MessageLoader
+	now      func() time.Time
+
+	mu      sync.RWMutex
+	clients map[int64]map[*Client]struct{}
+	// remote пользователи, подключённые к другим экземплярам:
+	// user -> instance -> время последнего подтверждения
+	remote map[int64]map[string]time.Time
+}
+
+func NewHub(broker Broker, messages MessageLoader) *Hub {
+	return &Hub{
+		instance: newInstanceID(),
+		broker:   broker,
+		messages: messages,
+		now:      time.Now,
+		clients:  map[int64]map[*Client]struct{}{},
+		remote:   map[int64]map[string]time.Time{},
+	}
+}
+
+// Run подписывает хаб на события других экземпляров, запрашивает у них текущее
+// присутствие и до отмены ctx периодически подтверждает своих пользователей.
+func (h *Hub) Run(ctx context.Context) error {
+	if h.broker == nil {
+		return nil
+	}
+	if err := h.broker.Subscribe(ctx, h.handleRemote); err != nil {
+		return err
+	}
+	h.broadcast(Event{Type: eventPresenceRequest})
+	go h.heartbeat(ctx, presenceInterval)
+	return nil
+}
+
+// heartbeat рассылает присутствие и снимает пользователей экземпляров, которые перестали его подтверждать.
+func (h *Hub) heartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.syncPresence()
+			h.expireRemote()
+		}
+	}
+}
+
+// NotifyMessage доставляет новое сообщение отправителю и получателю.
+func (h *Hub) NotifyMessage(msg *models.Message) {
+	h.publishMessage([]int64{msg.SenderID, msg.ReceiverID}, msg)
+}
+
+// NotifyRead сообщает собеседнику (и другим вкладкам читателя), что сообщения прочитаны.
+func (h *Hub) NotifyRead(receipt models.ReadReceipt) {
+	h.publish(EventRead, []int64{receipt.PartnerID, receipt.ReaderID}, receipt)
+}
+
+// NotifyConversationMessage доставляет сообщение групповой беседы всем её участникам.
+// Пустой список получателей означал бы рассылку всем, поэтому такие события отбрасываются.
+func (h *Hub) NotifyConversationMessage(msg *models.Message, participantIDs []int64) {
+	if len(participantIDs) == 0 {
+		return
+	}
+	h.publishMessage(participantIDs, msg)
+}
+
+// NotifyConversationRead сообщает участникам беседы, докуда её прочитал участник.
+func (h *Hub) NotifyConversationRead(receipt models.ConversationReadReceipt, participantIDs []int64) {
+	if len(participantIDs) == 0 {
+		return
+	}
+	h.publish(EventConversationRead, participantIDs, receipt)
+}
+
+// NotifyMention уведомляет пользователя, что его упомянули в сообщении.
+func (h *Hub) NotifyMention(mention models.Mention) {
+	h.publish(EventMention, []int64{mention.UserID}, mention)
+}
+
+// NotifyNotification доставляет пользователю новое уведомление CRM.
+func (h *Hub) NotifyNotification(n *models.Notification) {
+	h.publish(EventNotification, []int64{n.UserID}, n)
+}
+
+// IsOnline сообщает, подключён ли пользователь к какому-либо экземпляру.
+func (h *Hub) IsOnline(userID int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0 || len(h.remote[userID]) > 0
+}
+
+// OnlineUsers возвращает отсортированный список пользователей в сети.
+func (h *Hub) OnlineUsers() []int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := map[int64]struct{}{}
+	for id := range h.clients {
+		seen[id] = struct{}{}
+	}
+	for id := range h.remote {
+		seen[id] = struct{}{}
+	}
+
+	users := make([]int64, 0, len(seen))
+	for id := range seen {
+		users = append(users, id)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users
+}
+
+func (h *Hub) register(c *Client) {
+	h.mu.Lock()
+	wasOnline := len(h.clients[c.userID]) > 0 || len(h.remote[c.userID]) > 0
+	if h.clients[c.userID] == nil {
+		h.clients[c.userID] = map[*Client]struct{}{}
+	}
+	h.clients[c.userID][c] = struct{}{}
+	firstLocal := len(h.clients[c.userID]) == 1
+	h.mu.Unlock()
+
+	if firstLocal {
+		h.announcePresence(c.userID, true, !wasOnline)
+	}
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	conns, ok := h.clients[c.userID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if _, ok := conns[c]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(conns, c)
+	close(c.send)
+
+	lastLocal := len(conns) == 0
+	if lastLocal {
+		delete(h.clients, c.userID)
+	}
+	stillOnline := len(h.remote[c.userID]) > 0
+	h.mu.Unlock()
+
+	if lastLocal {
+		h.announcePresence(c.userID, false, !stillOnline)
+	}
+}
+
+// typing пересылает индикатор набора текста собеседнику.
+func (h *Hub) typing(from, to int64, typing bool) {
+	h.publish(EventTyping, []int64{to}, TypingPayload{From: from, To: to, Typing: typing})
+}
+
+// announcePresence сообщает другим экземплярам о подключениях этого экземпляра,
+// а локальным клиентам — только об изменении общего статуса пользователя.
+func (h *Hub) announcePresence(userID int64, online, changed bool) {
+	event, err := newEvent(EventPresence, nil, PresencePayload{UserID: userID, Online: online})
+	if err != nil {
+		log.Printf("Chat presence: %v", err)
+		return
+	}
+	if changed {
+		h.deliver(event)
+	}
+	h.broadcast(event)
+}
+
+// syncPresence отправляет остальным экземплярам список своих пользователей частями.
+func (h *Hub) syncPresence() {
+	h.mu.RLock()
+	users := make([]int64, 0, len(h.clients))
+	for userID := range h.clients {
+		users = append(users, userID)
+	}
+	h.mu.RUnlock()
+
+	for start := 0; start < len(users); start += presenceChunk {
+		end := min(start+presenceChunk, len(users))
+		event, err := newEvent(eventPresenceSync, nil, presenceSyncPayload{Users: users[start:end]})
+		if err != nil {
+			log.Printf("Chat presence sync: %v", err)
+			return
+		}
+		h.broadcast(event)
+	}
+}
+
+// expireRemote забывает подключения, которые другие экземпляры не подтверждали дольше presenceTTL.
+func (h *Hub) expireRemote() {
+	deadline := h.now().Add(-presenceTTL)
+	var offline []int64
+
+	h.mu.Lock()
+	for userID, instances := range h.remote {
+		for instance, seen := range instances {
+			if seen.Before(deadline) {
+				delete(instances, instance)
+			}
+		}
+		if len(instances) == 0 {
+			delete(h.remote, userID)
+			if len(h.clients[userID]) == 0 {
+				offline = append(offline, userID)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, userID := range offline {
+		h.deliverPresence(userID, false)
+	}
+}
+
+// deliverPresence сообщает локальным клиентам об изменении статуса пользователя.
+func (h *Hub) deliverPresence(userID int64, online bool) {
+	event, err := newEvent(EventPresence, nil, PresencePayload{UserID: userID, Online: online})
+	if err != nil {
+		log.Printf("Chat presence: %v", err)
+		return
+	}
+	h.deliver(event)
+}
+
+// publishMessage доставляет сообщение чата. Если оно не помещается в NOTIFY,
+// другие экземпляры получают только его ID и загружают сообщение из базы.
+func (h *Hub) publishMessage(recipients []int64, msg *models.Message) {
+	event, err := newEvent(EventMessage, recipients, msg)
+	if err != nil {
+		log.Printf("Chat event %s: %v", EventMessage, err)
+		return
+	}
+	h.deliver(event)
+	event.MessageID = msg.ID
+	h.broadcast(event)
+}
+
+func (h *Hub) publish(eventType string, recipients []int64, payload interface{}) {
+	event, err := newEvent(eventType, recipients, payload)
+	if err != nil {
+		log.Printf("Chat event %s: %v", eventType, err)
+		return
+	}
+	h.deliver(event)
+	h.broadcast(event)
+}
+
+// broadcast отправляет событие остальным экземплярам.
+func (h *Hub) broadcast(event Event) {
+	if h.broker == nil {
+		return
+	}
+	event.Instance = h.instance
+	err := h.broker.Publish(context.Background(), event)
+	if errors.Is(err, ErrPayloadTooLarge) && event.MessageID != 0 {
+		event.Payload = nil
+		err = h.broker.Publish(context.Background(), event)
+	}
+	if err != nil {
+		log.Printf("Chat broker publish %s: %v", event.Type, err)
+	}
+}
+
+// handleRemote обрабатывает событие, пришедшее от брокера.
+func (h *Hub) handleRemote(event Event) {
+	if event.Instance == h.instance {
+		return
+	}
+
+	switch event.Type {
+	case eventPresenceRequest:
+		h.syncPresence()
+		return
+	case eventPresenceSync:
+		var p presenceSyncPayload
+		if err := json.Unmarshal(event.Payload, &p); err != nil {
+			return
+		}
+		for _, userID := range p.Users {
+			if h.trackRemote(userID, event.Instance, true) {
+				h.deliverPresence(userID, true)
+			}
+		}
+		return
+	case EventPresence:
+		var p PresencePayload
+		if err := json.Unmarshal(event.Payload, &p); err != nil {
+			return
+		}
+		if !h.trackRemote(p.UserID, event.Instance, p.Online) {
+			return
+		}
+	case EventMessage:
+		if len(event.Payload) == 0 {
+			payload, err := h.loadMessage(event.MessageID)
+			if err != nil {
+				log.Printf("Chat: сообщение %d от другого экземпляра: %v", event.MessageID, err)
+				return
+			}
+			event.Payload = payload
+		}
+	}
+	h.deliver(event)
+}
+
+// loadMessage загружает из базы сообщение, присланное другим экземпляром по ID.
+func (h *Hub) loadMessage(id int64) (json.RawMessage, error) {
+	if h.messages == nil || id == 0 {
+		return nil, errors.New("сообщение пришло без содержимого")
+	}
+	msg, err := h.messages.FindWithDetails(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("сообщение %d не найдено", id)
+	}
+	return json.Marshal(msg)
+}
+
+// trackRemote учитывает подключения на другом экземпляре и возвращает true,
+// если общий статус пользователя изменился.
+func (h *Hub) trackRemote(userID int64, instance string, online bool) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	wasOnline := len(h.clients[userID]) > 0 || len(h.remote[userID]) > 0
+	if online {
+		if h.remote[userID] == nil {
+			h.remote[userID] = map[string]time.Time{}
+		}
+		h.remote[userID][instance] = h.now()
+	} else {
+		delete(h.remote[userID], instance)
+		if len(h.remote[userID]) == 0 {
+			delete(h.remote, userID)
+		}
+	}
+	isOnline := len(h.clients[userID]) > 0 || len(h.remote[userID]) > 0
+	return wasOnline != isOnline
+}
+
+// deliver отправляет событие локальным клиентам-получателям.
+func (h *Hub) deliver(event Event) {
+	event.Instance = ""
+	event.MessageID = 0
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(event.Recipients) == 0 {
+		for _, conns := range h.clients {
+			h.sendAll(conns, data)
+		}
+		return
+	}
+
+	seen := map[int64]struct{}{}
+	for _, userID := range event.Recipients {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		h.sendAll(h.clients[userID], data)
+	}
+}
+
+// sendAll кладёт данные в очереди клиентов; медленные клиенты пропускают событие.
+func (h *Hub) sendAll(conns map[*Client]struct{}, data []byte) {
+	for c := range conns {
+		select {
+		case c.send <- data:
+		default:
+			log.Printf("Chat: очередь клиента %d переполнена, событие пропущено", c.userID)
+		}
+	}
+}
+
+func newEvent(eventType string, recipients []int64, payload interface{}) (Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, Recipients: recipients, Payload: raw}, nil
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,157 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"turcompany/internal/models"
+)
+
+// memBroker рассылает события подписчикам синхронно и, как NOTIFY, отклоняет большие события.
+type memBroker struct {
+	mu       sync.Mutex
+	handlers []func(Event)
+}
+
+func (b *memBroker) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+	b.mu.Lock()
+	handlers := append([]func(Event){}, b.handlers...)
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *memBroker) Subscribe(ctx context.Context, handler func(Event)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+type memMessages map[int64]*models.Message
+
+func (m memMessages) FindWithDetails(ctx context.Context, id int64) (*models.Message, error) {
+	return m[id], nil
+}
+
+func startHub(t *testing.T, broker Broker, messages MessageLoader) *Hub {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	h := NewHub(broker, messages)
+	if err := h.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return h
+}
+
+func connect(h *Hub, userID int64) *Client {
+	c := &Client{hub: h, userID: userID, send: make(chan []byte, sendBufferSize)}
+	h.register(c)
+	return c
+}
+
+// received разбирает события, накопившиеся в очереди клиента.
+func received(t *testing.T, c *Client) []Event {
+	t.Helper()
+	var events []Event
+	for {
+		select {
+		case data := <-c.send:
+			var event Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				t.Fatalf("client got invalid event %s: %v", data, err)
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestPresenceSnapshotOnStart(t *testing.T) {
+	broker := &memBroker{}
+	first := startHub(t, broker, nil)
+	connect(first, 1)
+
+	second := startHub(t, broker, nil)
+	if !second.IsOnline(1) {
+		t.Fatal("user of the running instance is offline for a new instance")
+	}
+}
+
+func TestRemotePresenceExpires(t *testing.T) {
+	broker := &memBroker{}
+	first := startHub(t, broker, nil)
+	second := startHub(t, broker, nil)
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	second.now = func() time.Time { return clock }
+
+	watcher := connect(second, 2)
+	connect(first, 1)
+	if !second.IsOnline(1) {
+		t.Fatal("remote user is offline after connecting")
+	}
+	received(t, watcher)
+
+	// Подтверждения продлевают присутствие.
+	clock = clock.Add(presenceTTL - time.Second)
+	first.syncPresence()
+	clock = clock.Add(presenceTTL - time.Second)
+	second.expireRemote()
+	if !second.IsOnline(1) {
+		t.Fatal("remote user expired despite heartbeats")
+	}
+
+	// Экземпляр пропал, не успев сообщить об отключении.
+	clock = clock.Add(2 * time.Second)
+	second.expireRemote()
+	if second.IsOnline(1) {
+		t.Fatal("remote user is still online after the TTL")
+	}
+	events := received(t, watcher)
+	if len(events) != 1 || events[0].Type != EventPresence || !strings.Contains(string(events[0].Payload), `"online":false`) {
+		t.Fatalf("watcher got %+v, want one offline presence event", events)
+	}
+
+	first.syncPresence()
+	if !second.IsOnline(1) {
+		t.Fatal("heartbeat did not bring the remote user back online")
+	}
+}
+
+func TestLargeMessageIsSentByID(t *testing.T) {
+	msg := &models.Message{ID: 42, SenderID: 1, ReceiverID: 2, Content: strings.Repeat("я", maxNotifyPayload)}
+	broker := &memBroker{}
+	sender := startHub(t, broker, nil)
+	receiver := startHub(t, broker, memMessages{msg.ID: msg})
+	recipient := connect(receiver, 2)
+	received(t, recipient)
+
+	sender.NotifyMessage(msg)
+
+	events := received(t, recipient)
+	if len(events) != 1 || events[0].Type != EventMessage {
+		t.Fatalf("recipient got %+v, want one message event", events)
+	}
+	if events[0].MessageID != 0 {
+		t.Fatalf("message_id leaked to the client: %d", events[0].MessageID)
+	}
+	var got models.Message
+	if err := json.Unmarshal(events[0].Payload, &got); err != nil || got.Content != msg.Content {
+		t.Fatalf("recipient got a different message (err %v)", err)
+	}
+}
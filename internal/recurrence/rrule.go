@@ -0,0 +1,399 @@
+// Package recurrence разбирает правила повторения в формате RRULE (RFC 5545)
+// и вычисляет даты следующих повторений задач.
+//
+// Поддерживаются FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY (для MONTHLY с порядковым номером: 1MO — первый понедельник, -1FR —
+// последняя пятница) и BYMONTHDAY (отрицательные значения — с конца месяца).
+// Первое повторение — всегда дата начала серии.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency — базовый период повторения.
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods ограничивает перебор периодов для правил, которые редко дают даты
+// (например, BYMONTHDAY=31 с FREQ=DAILY и большим INTERVAL).
+const maxPeriods = 5000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// WeekdayNum — значение BYDAY: день недели и, для MONTHLY, его номер в месяце
+// (0 — каждый такой день, 1 — первый, -1 — последний).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule — разобранное правило повторения.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	Count      int       // 0 — без ограничения
+	Until      time.Time // нулевое — без ограничения
+}
+
+// Parse разбирает правило вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10";
+// префикс "RRULE:" допускается.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidRule)
+	}
+
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			switch r.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		default:
+			err = fmt.Errorf("unsupported %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	case r.Freq == Yearly && len(r.ByDay) > 0:
+		return nil, fmt.Errorf("%w: BYDAY is not supported with FREQ=YEARLY", ErrInvalidRule)
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return nil, fmt.Errorf("%w: numbered BYDAY requires FREQ=MONTHLY", ErrInvalidRule)
+		}
+	}
+	return r, nil
+}
+
+// String возвращает правило в каноническом виде.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayCode(d.Day)
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает первое повторение серии, начатой в start, строго после after.
+// ok == false, если серия закончилась (COUNT или UNTIL).
+func (r *Rule) Next(start, after time.Time) (next time.Time, ok bool) {
+	index := 1 // start — первое повторение
+	if start.After(after) {
+		return start, true
+	}
+	// Перебор начинается рядом с after; для COUNT повторения в предыдущих периодах
+	// только подсчитываются, все они не позже after
+	from := r.periodsBetween(start, after)
+	if r.Count > 0 {
+		for period := 0; period < from; period++ {
+			for _, c := range r.candidates(start, period) {
+				if c.After(start) {
+					index++
+				}
+			}
+			if index > r.Count {
+				return time.Time{}, false
+			}
+		}
+	}
+	for period := from; period < from+maxPeriods; period++ {
+		for _, c := range r.candidates(start, period) {
+			if !c.After(start) {
+				continue
+			}
+			index++
+			if r.Count > 0 && index > r.Count {
+				return time.Time{}, false
+			}
+			if !r.Until.IsZero() && c.After(r.Until) {
+				return time.Time{}, false
+			}
+			if c.After(after) {
+				return c, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodsBetween оценивает снизу номер периода, в котором лежит after.
+func (r *Rule) periodsBetween(start, after time.Time) int {
+	var n int
+	switch r.Freq {
+	case Daily:
+		n = int(after.Sub(start).Hours() / 24)
+	case Weekly:
+		n = int(after.Sub(start).Hours() / (24 * 7))
+	case Monthly:
+		n = (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+	case Yearly:
+		n = after.Year() - start.Year()
+	}
+	// Запас в один период покрывает переходы на летнее время и неполные недели
+	n = n/r.Interval - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// candidates возвращает даты повторения в порядке возрастания внутри периода с номером
+// period, считая от периода, в котором лежит start. Время суток берётся из start.
+func (r *Rule) candidates(start time.Time, period int) []time.Time {
+	y, m, d := start.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+	step := period * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		c := at(y, m, d+step)
+		if r.matchesWeekday(c) && r.matchesMonthDay(c) {
+			return []time.Time{c}
+		}
+		return nil
+
+	case Weekly:
+		// Неделя начинается с понедельника (WKST=MO)
+		monday := d - (int(start.Weekday())+6)%7 + step*7
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Day: start.Weekday()}}
+		}
+		var out []time.Time
+		for offset := 0; offset < 7; offset++ {
+			c := at(y, m, monday+offset)
+			if containsWeekday(days, c.Weekday()) && r.matchesMonthDay(c) {
+				out = append(out, c)
+			}
+		}
+		return out
+
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		year, month := first.Year(), first.Month()
+		var out []time.Time
+		if len(r.ByDay) > 0 {
+			for day := 1; day <= daysIn(year, month); day++ {
+				c := at(year, month, day)
+				if r.matchesNumberedWeekday(c) && r.matchesMonthDay(c) {
+					out = append(out, c)
+				}
+			}
+			return out
+		}
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{d}
+		}
+		for _, day := range resolveMonthDays(days, year, month) {
+			out = append(out, at(year, month, day))
+		}
+		return out
+
+	case Yearly:
+		year := y + step
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{d}
+		}
+		var out []time.Time
+		for _, day := range resolveMonthDays(days, year, m) {
+			out = append(out, at(year, m, day))
+		}
+		return out
+	}
+	return nil
+}
+
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	return len(r.ByDay) == 0 || containsWeekday(r.ByDay, t.Weekday())
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	for _, day := range resolveMonthDays(r.ByMonthDay, t.Year(), t.Month()) {
+		if day == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNumberedWeekday проверяет BYDAY с номером дня недели в месяце.
+func (r *Rule) matchesNumberedWeekday(t time.Time) bool {
+	fromStart := (t.Day()-1)/7 + 1
+	fromEnd := -((daysIn(t.Year(), t.Month())-t.Day())/7 + 1)
+	for _, wd := range r.ByDay {
+		if wd.Day == t.Weekday() && (wd.N == 0 || wd.N == fromStart || wd.N == fromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWeekday(days []WeekdayNum, wd time.Weekday) bool {
+	for _, d := range days {
+		if d.Day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMonthDays переводит BYMONTHDAY в дни месяца по возрастанию, пропуская
+// несуществующие (31 в апреле).
+func resolveMonthDays(days []int, year int, month time.Month) []int {
+	n := daysIn(year, month)
+	var out []int
+	for _, day := range days {
+		if day < 0 {
+			day = n + day + 1
+		}
+		if day >= 1 && day <= n {
+			out = append(out, day)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q must be a positive number", value)
+	}
+	return n, nil
+}
+
+// parseUntil принимает дату (20261231, включительно) или момент в UTC (20261231T235959Z).
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("UNTIL %q: use YYYYMMDD or YYYYMMDDTHHMMSSZ", value)
+	}
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(strings.ToUpper(value), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("BYDAY %q", item)
+		}
+		day, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("BYDAY %q", item)
+		}
+		wd := WeekdayNum{Day: day}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("BYDAY %q", item)
+			}
+			wd.N = n
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -31 || n > 31 {
+			return nil, fmt.Errorf("BYMONTHDAY %q", item)
+		}
+		days = append(days, n)
+	}
+	return days, nil
+}
+
+func weekdayCode(wd time.Weekday) string {
+	for code, day := range weekdayCodes {
+		if day == wd {
+			return code
+		}
+	}
+	return ""
+}
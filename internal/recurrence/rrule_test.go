@@ -0,0 +1,106 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	valid := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;interval=2;byday=mo,th", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=5", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=5"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"FREQ=YEARLY;UNTIL=20301231", "FREQ=YEARLY;UNTIL=20301231T235959Z"},
+		{"FREQ=DAILY;INTERVAL=1;UNTIL=20300101T120000Z", "FREQ=DAILY;UNTIL=20300101T120000Z"},
+	}
+	for _, tc := range valid {
+		r, err := Parse(tc.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.rule, err)
+			continue
+		}
+		if got := r.String(); got != tc.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tc.rule, got, tc.want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20300101",
+		"FREQ=DAILY;UNTIL=2030-01-01",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT",
+	}
+	for _, rule := range invalid {
+		if _, err := Parse(rule); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q): got %v, want ErrInvalidRule", rule, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	monday := date(2024, 1, 1)
+	tests := []struct {
+		name   string
+		rule   string
+		start  time.Time
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"start not reached", "FREQ=DAILY", monday, monday.Add(-time.Hour), monday, true},
+		{"daily", "FREQ=DAILY", monday, monday, date(2024, 1, 2), true},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", monday, date(2024, 1, 5), date(2024, 1, 7), true},
+		{"daily weekend", "FREQ=DAILY;BYDAY=SA,SU", monday, monday, date(2024, 1, 6), true},
+		{"weekly days", "FREQ=WEEKLY;BYDAY=MO,TH", monday, monday, date(2024, 1, 4), true},
+		{"weekly interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", monday, date(2024, 1, 2), date(2024, 1, 15), true},
+		{"weekly start day", "FREQ=WEEKLY", monday, date(2024, 1, 8), date(2024, 1, 15), true},
+		{"monthly skips short months", "FREQ=MONTHLY", date(2024, 1, 31), date(2024, 1, 31), date(2024, 3, 31), true},
+		{"monthly last day", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2024, 1, 31), date(2024, 1, 31), date(2024, 2, 29), true},
+		{"monthly month days", "FREQ=MONTHLY;BYMONTHDAY=15,1", monday, monday, date(2024, 1, 15), true},
+		{"monthly first monday", "FREQ=MONTHLY;BYDAY=1MO", monday, monday, date(2024, 2, 5), true},
+		{"monthly last friday", "FREQ=MONTHLY;BYDAY=-1FR", date(2024, 1, 26), date(2024, 1, 26), date(2024, 2, 23), true},
+		{"yearly leap day", "FREQ=YEARLY", date(2024, 2, 29), date(2024, 2, 29), date(2028, 2, 29), true},
+		{"count", "FREQ=DAILY;COUNT=3", monday, date(2024, 1, 2), date(2024, 1, 3), true},
+		{"count exhausted", "FREQ=DAILY;COUNT=3", monday, date(2024, 1, 3), time.Time{}, false},
+		{"until date inclusive", "FREQ=DAILY;UNTIL=20240105", monday, date(2024, 1, 4), date(2024, 1, 5), true},
+		{"until passed", "FREQ=DAILY;UNTIL=20240105", monday, date(2024, 1, 5), time.Time{}, false},
+		{"far after start", "FREQ=DAILY", monday, monday.AddDate(0, 0, 6000), monday.AddDate(0, 0, 6001), true},
+		// Повторения до after учитываются в COUNT, даже если их больше maxPeriods.
+		{"count far after start", "FREQ=DAILY;COUNT=10000", monday, monday.AddDate(0, 0, 6000), monday.AddDate(0, 0, 6001), true},
+		{"count last far after start", "FREQ=DAILY;COUNT=10000", monday, monday.AddDate(0, 0, 9998), monday.AddDate(0, 0, 9999), true},
+		{"count exhausted far after start", "FREQ=DAILY;COUNT=10000", monday, monday.AddDate(0, 0, 9999), time.Time{}, false},
+		{"weekly count far after start", "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=20000", monday, monday.AddDate(0, 0, 7*6000), monday.AddDate(0, 0, 7*6000+3), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.rule, err)
+			}
+			got, ok := r.Next(tc.start, tc.after)
+			if ok != tc.wantOK || !got.Equal(tc.want) {
+				t.Fatalf("Next(%s, %s) = %s, %v; want %s, %v", tc.start, tc.after, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ActivityRepository defines the interface for database operations on timeline events.
+type ActivityRepository interface {
+	Create(ctx context.Context, a *models.Activity) error
+	List(ctx context.Context, filter models.ActivityFilter) ([]models.Activity, error)
+	Count(ctx context.Context, filter models.ActivityFilter) (int, error)
+}
+
+type activityRepository struct {
+	db *sql.DB
+}
+
+// NewActivityRepository creates a new instance of ActivityRepository.
+func NewActivityRepository(db *sql.DB) ActivityRepository {
+	return &activityRepository{db: db}
+}
+
+func (r *activityRepository) Create(ctx context.Context, a *models.Activity) error {
+	details, err := json.Marshal(a.Details)
+	if err != nil {
+		return err
+	}
+	if a.Details == nil {
+		details = []byte("{}")
+	}
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO activities (type, entity_type, entity_id, lead_id, deal_id, actor_id, summary, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8, $9)
+		RETURNING id`,
+		a.Type, a.EntityType, a.EntityID, a.LeadID, a.DealID, a.ActorID, a.Summary, details, a.CreatedAt,
+	).Scan(&a.ID)
+}
+
+// List returns the matching events, newest first.
+func (r *activityRepository) List(ctx context.Context, filter models.ActivityFilter) ([]models.Activity, error) {
+	where, args := activityFilterConditions(filter)
+	query := `
+		SELECT id, type, entity_type, entity_id, lead_id, deal_id, COALESCE(actor_id, 0), summary, details, created_at
+		FROM activities` + where + ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := []models.Activity{}
+	for rows.Next() {
+		var a models.Activity
+		var details []byte
+		if err := rows.Scan(&a.ID, &a.Type, &a.EntityType, &a.EntityID, &a.LeadID, &a.DealID, &a.ActorID,
+			&a.Summary, &details, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &a.Details); err != nil {
+			return nil, fmt.Errorf("activity %d details: %w", a.ID, err)
+		}
+		activities = append(activities, a)
+	}
+	return activities, rows.Err()
+}
+
+// Count returns the number of matching events, ignoring the limit and offset.
+func (r *activityRepository) Count(ctx context.Context, filter models.ActivityFilter) (int, error) {
+	where, args := activityFilterConditions(filter)
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM activities`+where, args...).Scan(&total)
+	return total, err
+}
+
+func activityFilterConditions(filter models.ActivityFilter) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.LeadID != nil {
+		add("lead_id = $%d", *filter.LeadID)
+	}
+	if filter.DealID != nil {
+		add("deal_id = $%d", *filter.DealID)
+	}
+	if len(filter.Types) > 0 {
+		add("type = ANY($%d)", pq.Array(filter.Types))
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
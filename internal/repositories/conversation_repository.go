@@ -0,0 +1,453 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ConversationRepository defines the interface for database operations on group conversations.
+type ConversationRepository interface {
+	Create(ctx context.Context, conv *models.GroupConversation, participantIDs []int64) error
+	FindByID(ctx context.Context, id int64) (*models.GroupConversation, error)
+	FindForUser(ctx context.Context, userID int64) ([]models.GroupConversationSummary, error)
+	FindByEntity(ctx context.Context, userID int64, entityType string, entityID int64) ([]models.GroupConversation, error)
+	EntityExists(ctx context.Context, entityType string, entityID int64) (bool, error)
+
+	FindParticipants(ctx context.Context, conversationID int64) ([]models.ConversationParticipant, error)
+	FindParticipant(ctx context.Context, conversationID, userID int64) (*models.ConversationParticipant, error)
+	AddParticipants(ctx context.Context, conversationID int64, userIDs []int64) (int64, error)
+	RemoveParticipant(ctx context.Context, conversationID, userID int64) (bool, error)
+
+	StoreMessage(ctx context.Context, msg *models.Message) error
+	FindMessage(ctx context.Context, conversationID, messageID int64) (*models.Message, error)
+	FindHistory(ctx context.Context, conversationID int64, cursor models.MessageCursor) (*models.MessagePage, error)
+	MarkRead(ctx context.Context, conversationID, userID, upToID int64, readAt time.Time) (bool, error)
+	FindMentions(ctx context.Context, userID int64, limit, offset int) ([]models.Mention, error)
+}
+
+type conversationRepository struct {
+	db *sql.DB
+}
+
+// NewConversationRepository creates a new instance of ConversationRepository.
+func NewConversationRepository(db *sql.DB) ConversationRepository {
+	return &conversationRepository{db: db}
+}
+
+// entityTables maps the entity types a conversation can be linked to onto their tables.
+var entityTables = map[string]string{
+	models.EntityTypeLead:     "leads",
+	models.EntityTypeDeal:     "deals",
+	models.EntityTypeDocument: "documents",
+}
+
+const conversationColumns = `c.id, c.title, COALESCE(c.entity_type, ''), COALESCE(c.entity_id, 0), c.created_by, c.created_at, c.updated_at`
+
+func scanConversation(row rowScanner, conv *models.GroupConversation) error {
+	return row.Scan(&conv.ID, &conv.Title, &conv.EntityType, &conv.EntityID, &conv.CreatedBy, &conv.CreatedAt, &conv.UpdatedAt)
+}
+
+// Create stores the conversation with its creator as owner and the other users as members.
+func (r *conversationRepository) Create(ctx context.Context, conv *models.GroupConversation, participantIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO conversations (title, entity_type, entity_id, created_by, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, 0), $4, $5, $5)
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, query, conv.Title, conv.EntityType, conv.EntityID, conv.CreatedBy, conv.CreatedAt).Scan(&conv.ID)
+	if err != nil {
+		return fmt.Errorf("create conversation: %w", err)
+	}
+	conv.UpdatedAt = conv.CreatedAt
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO conversation_participants (conversation_id, user_id, role, joined_at)
+		VALUES ($1, $2, $3, $4)`,
+		conv.ID, conv.CreatedBy, models.ParticipantRoleOwner, conv.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("add conversation owner: %w", err)
+	}
+	if _, err := addParticipants(ctx, tx, conv.ID, participantIDs, conv.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *conversationRepository) FindByID(ctx context.Context, id int64) (*models.GroupConversation, error) {
+	query := `SELECT ` + conversationColumns + ` FROM conversations c WHERE c.id = $1`
+
+	var conv models.GroupConversation
+	if err := scanConversation(r.db.QueryRowContext(ctx, query, id), &conv); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// FindForUser lists the user's group conversations with the last message and the number of
+// messages from others after the user's read position, most recently active first.
+func (r *conversationRepository) FindForUser(ctx context.Context, userID int64) ([]models.GroupConversationSummary, error) {
+	query := `
+		SELECT ` + conversationColumns + `, p.last_read_message_id,
+			lm.id, lm.sender_id, lm.content, lm.sent_at,
+			(SELECT COUNT(*) FROM messages um
+			 WHERE um.conversation_id = c.id AND um.id > p.last_read_message_id AND um.sender_id <> $1) AS unread_count
+		FROM conversation_participants p
+		JOIN conversations c ON c.id = p.conversation_id
+		LEFT JOIN LATERAL (
+			SELECT id, sender_id, content, sent_at FROM messages
+			WHERE conversation_id = c.id
+			ORDER BY id DESC LIMIT 1
+		) lm ON true
+		WHERE p.user_id = $1
+		ORDER BY COALESCE(lm.sent_at, c.created_at) DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.GroupConversationSummary{}
+	for rows.Next() {
+		var sum models.GroupConversationSummary
+		var lastID, lastSenderID sql.NullInt64
+		var lastContent sql.NullString
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(
+			&sum.ID, &sum.Title, &sum.EntityType, &sum.EntityID, &sum.CreatedBy, &sum.CreatedAt, &sum.UpdatedAt,
+			&sum.LastReadMessageID, &lastID, &lastSenderID, &lastContent, &lastSentAt, &sum.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		if lastID.Valid {
+			conversationID := sum.ID
+			sum.LastMessage = &models.Message{
+				ID:             lastID.Int64,
+				SenderID:       lastSenderID.Int64,
+				ConversationID: &conversationID,
+				Content:        lastContent.String,
+				SentAt:         lastSentAt.Time,
+			}
+		}
+		summaries = append(summaries, sum)
+	}
+	return summaries, rows.Err()
+}
+
+// FindByEntity lists conversations linked to an entity that the user takes part in.
+func (r *conversationRepository) FindByEntity(ctx context.Context, userID int64, entityType string, entityID int64) ([]models.GroupConversation, error) {
+	query := `
+		SELECT ` + conversationColumns + `
+		FROM conversations c
+		JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = $1
+		WHERE c.entity_type = $2 AND c.entity_id = $3
+		ORDER BY c.id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []models.GroupConversation{}
+	for rows.Next() {
+		var conv models.GroupConversation
+		if err := scanConversation(rows, &conv); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conv)
+	}
+	return conversations, rows.Err()
+}
+
+// EntityExists reports whether the lead, deal or document exists; unknown types never exist.
+func (r *conversationRepository) EntityExists(ctx context.Context, entityType string, entityID int64) (bool, error) {
+	table, ok := entityTables[entityType]
+	if !ok {
+		return false, nil
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, entityID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+const participantColumns = `p.conversation_id, p.user_id, COALESCE(u.company_name, ''), u.email, p.role,
+	p.last_read_message_id, p.last_read_at, p.joined_at`
+
+func scanParticipant(row rowScanner, p *models.ConversationParticipant) error {
+	return row.Scan(&p.ConversationID, &p.UserID, &p.CompanyName, &p.Email, &p.Role,
+		&p.LastReadMessageID, &p.LastReadAt, &p.JoinedAt)
+}
+
+func (r *conversationRepository) FindParticipants(ctx context.Context, conversationID int64) ([]models.ConversationParticipant, error) {
+	query := `
+		SELECT ` + participantColumns + `
+		FROM conversation_participants p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.conversation_id = $1
+		ORDER BY p.joined_at, p.user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := []models.ConversationParticipant{}
+	for rows.Next() {
+		var p models.ConversationParticipant
+		if err := scanParticipant(rows, &p); err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
+	}
+	return participants, rows.Err()
+}
+
+func (r *conversationRepository) FindParticipant(ctx context.Context, conversationID, userID int64) (*models.ConversationParticipant, error) {
+	query := `
+		SELECT ` + participantColumns + `
+		FROM conversation_participants p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.conversation_id = $1 AND p.user_id = $2`
+
+	var p models.ConversationParticipant
+	if err := scanParticipant(r.db.QueryRowContext(ctx, query, conversationID, userID), &p); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AddParticipants adds users as members, skipping those already in the conversation,
+// and returns how many were added.
+func (r *conversationRepository) AddParticipants(ctx context.Context, conversationID int64, userIDs []int64) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added, err := addParticipants(ctx, tx, conversationID, userIDs, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return added, tx.Commit()
+}
+
+func addParticipants(ctx context.Context, tx *sql.Tx, conversationID int64, userIDs []int64, joinedAt time.Time) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+	query := `
+		INSERT INTO conversation_participants (conversation_id, user_id, role, joined_at)
+		SELECT $1, u, $2, $3 FROM unnest($4::int[]) AS u
+		ON CONFLICT (conversation_id, user_id) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, conversationID, models.ParticipantRoleMember, joinedAt, pq.Array(userIDs))
+	if err != nil {
+		return 0, fmt.Errorf("add conversation participants: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RemoveParticipant removes a participant. When the owner leaves, ownership passes to the
+// remaining participant who joined first, so a conversation with members always has an owner.
+func (r *conversationRepository) RemoveParticipant(ctx context.Context, conversationID, userID int64) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Membership changes of one conversation are serialized so a successor cannot leave concurrently
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM conversations WHERE id = $1 FOR UPDATE`, conversationID); err != nil {
+		return false, err
+	}
+
+	var role string
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM conversation_participants WHERE conversation_id = $1 AND user_id = $2 RETURNING role`,
+		conversationID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if role == models.ParticipantRoleOwner {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE conversation_participants SET role = $2
+			WHERE conversation_id = $1 AND user_id = (
+				SELECT user_id FROM conversation_participants
+				WHERE conversation_id = $1
+				ORDER BY joined_at, user_id
+				LIMIT 1
+			)`, conversationID, models.ParticipantRoleOwner)
+		if err != nil {
+			return false, fmt.Errorf("transfer conversation ownership: %w", err)
+		}
+	}
+	return true, tx.Commit()
+}
+
+// StoreMessage stores a conversation message together with its mentions and attachments.
+// The sender's read position moves to their own message.
+func (r *conversationRepository) StoreMessage(ctx context.Context, msg *models.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO messages (sender_id, conversation_id, content, sent_at) VALUES ($1, $2, $3, $4) RETURNING id, sent_at`
+	if err := tx.QueryRowContext(ctx, query, msg.SenderID, msg.ConversationID, msg.Content, msg.SentAt).Scan(&msg.ID, &msg.SentAt); err != nil {
+		return fmt.Errorf("store conversation message: %w", err)
+	}
+
+	if len(msg.Mentions) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO message_mentions (message_id, user_id)
+			SELECT $1, u FROM unnest($2::int[]) AS u
+			ON CONFLICT DO NOTHING`,
+			msg.ID, pq.Array(msg.Mentions))
+		if err != nil {
+			return fmt.Errorf("store mentions: %w", err)
+		}
+	}
+	if err := linkAttachments(ctx, tx, msg); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE conversation_participants SET last_read_message_id = $3, last_read_at = $4
+		WHERE conversation_id = $1 AND user_id = $2`,
+		msg.ConversationID, msg.SenderID, msg.ID, msg.SentAt)
+	if err != nil {
+		return fmt.Errorf("update sender read position: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE conversations SET updated_at = $2 WHERE id = $1`, msg.ConversationID, msg.SentAt); err != nil {
+		return fmt.Errorf("touch conversation: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *conversationRepository) FindMessage(ctx context.Context, conversationID, messageID int64) (*models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE id = $1 AND conversation_id = $2`
+
+	var msg models.Message
+	if err := scanMessage(r.db.QueryRowContext(ctx, query, messageID, conversationID), &msg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// FindHistory returns a page of conversation messages with their mentions.
+func (r *conversationRepository) FindHistory(ctx context.Context, conversationID int64, cursor models.MessageCursor) (*models.MessagePage, error) {
+	page, err := findMessagePage(ctx, r.db, `conversation_id = $1`, []interface{}{conversationID}, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadMentions(ctx, r.db, page.Messages); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// loadMentions fills Mentions of the given messages.
+func loadMentions(ctx context.Context, db *sql.DB, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(messages))
+	index := make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+		index[msg.ID] = i
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT message_id, user_id FROM message_mentions WHERE message_id = ANY($1) ORDER BY message_id, user_id`,
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID, userID int64
+		if err := rows.Scan(&messageID, &userID); err != nil {
+			return err
+		}
+		msg := &messages[index[messageID]]
+		msg.Mentions = append(msg.Mentions, userID)
+	}
+	return rows.Err()
+}
+
+// MarkRead moves the participant's read position forward to upToID.
+// It returns false if the position was already at or past it.
+func (r *conversationRepository) MarkRead(ctx context.Context, conversationID, userID, upToID int64, readAt time.Time) (bool, error) {
+	query := `
+		UPDATE conversation_participants SET last_read_message_id = $3, last_read_at = $4
+		WHERE conversation_id = $1 AND user_id = $2 AND last_read_message_id < $3`
+
+	result, err := r.db.ExecContext(ctx, query, conversationID, userID, upToID, readAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// FindMentions lists messages that mention the user in conversations they still take part in, newest first.
+func (r *conversationRepository) FindMentions(ctx context.Context, userID int64, limit, offset int) ([]models.Mention, error) {
+	query := `
+		SELECT mm.user_id, m.id, m.sender_id, m.conversation_id, m.content, m.sent_at
+		FROM message_mentions mm
+		JOIN messages m ON m.id = mm.message_id
+		JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = mm.user_id
+		WHERE mm.user_id = $1
+		ORDER BY mm.message_id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.Mention{}
+	for rows.Next() {
+		var m models.Mention
+		if err := rows.Scan(&m.UserID, &m.Message.ID, &m.Message.SenderID, &m.Message.ConversationID,
+			&m.Message.Content, &m.Message.SentAt); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, rows.Err()
+}
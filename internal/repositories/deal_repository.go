@@ -17,8 +17,8 @@ func NewDealRepository(db *sql.DB) *DealRepository {
 // ✔ Возвращает ID новой сделки
 func (r *DealRepository) Create(deal *models.Deals) (int64, error) {
 	query := `
-        INSERT INTO deals (lead_id, amount, currency, status, created_at) 
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO deals (lead_id, amount, currency, status, created_at, departure_date, loss_reason, destination) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id
     `
 	var id int64
@@ -29,6 +29,9 @@ func (r *DealRepository) Create(deal *models.Deals) (int64, error) {
 		deal.Currency,
 		deal.Status,
 		deal.CreatedAt,
+		deal.DepartureDate,
+		deal.LossReason,
+		deal.Destination,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("создание сделки: %w", err)
@@ -39,7 +42,7 @@ func (r *DealRepository) Create(deal *models.Deals) (int64, error) {
 // ✔ Получение сделки по lead_id (нужен для document/lead service)
 func (r *DealRepository) GetByLeadID(leadID int) (*models.Deals, error) {
 	query := `
-        SELECT id, lead_id, amount, currency, status, created_at 
+        SELECT id, lead_id, amount, currency, status, created_at, departure_date, loss_reason, destination 
         FROM deals 
         WHERE lead_id = $1 
         ORDER BY created_at DESC 
@@ -53,6 +56,9 @@ func (r *DealRepository) GetByLeadID(leadID int) (*models.Deals, error) {
 		&deal.Currency,
 		&deal.Status,
 		&deal.CreatedAt,
+		&deal.DepartureDate,
+		&deal.LossReason,
+		&deal.Destination,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -67,10 +73,10 @@ func (r *DealRepository) GetByLeadID(leadID int) (*models.Deals, error) {
 func (r *DealRepository) Update(deal *models.Deals) error {
 	query := `
         UPDATE deals 
-        SET lead_id=$1, amount=$2, currency=$3, status=$4 
-        WHERE id=$5
+        SET lead_id=$1, amount=$2, currency=$3, status=$4, departure_date=$5, loss_reason=$7, destination=$8 
+        WHERE id=$6
     `
-	_, err := r.db.Exec(query, deal.LeadID, deal.Amount, deal.Currency, deal.Status, deal.ID)
+	_, err := r.db.Exec(query, deal.LeadID, deal.Amount, deal.Currency, deal.Status, deal.DepartureDate, deal.ID, deal.LossReason, deal.Destination)
 	if err != nil {
 		return fmt.Errorf("обновление сделки: %w", err)
 	}
@@ -80,7 +86,7 @@ func (r *DealRepository) Update(deal *models.Deals) error {
 // ✔ Поиск по ID (тип int!)
 func (r *DealRepository) GetByID(id int) (*models.Deals, error) {
 	query := `
-        SELECT id, lead_id, amount, currency, status, created_at 
+        SELECT id, lead_id, amount, currency, status, created_at, departure_date, loss_reason, destination 
         FROM deals 
         WHERE id=$1
     `
@@ -92,6 +98,9 @@ func (r *DealRepository) GetByID(id int) (*models.Deals, error) {
 		&deal.Currency,
 		&deal.Status,
 		&deal.CreatedAt,
+		&deal.DepartureDate,
+		&deal.LossReason,
+		&deal.Destination,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -128,6 +137,50 @@ func (r *DealRepository) CountDeals() (int, error) {
 }
 
 func (r *DealRepository) FilterDeals(status, fromDate, toDate, currency, sortBy, order string, amountMin, amountMax float64, limit, offset int) ([]models.Deals, error) {
+	query, args := filterDealsQuery(status, fromDate, toDate, currency, sortBy, order, amountMin, amountMax)
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deals []models.Deals
+	for rows.Next() {
+		var deal models.Deals
+		if err := rows.Scan(&deal.ID, &deal.LeadID, &deal.Amount, &deal.Currency, &deal.Status, &deal.CreatedAt, &deal.DepartureDate, &deal.LossReason, &deal.Destination); err != nil {
+			return nil, err
+		}
+		deals = append(deals, deal)
+	}
+	return deals, nil
+}
+
+// EachFilteredDeal calls fn for every deal matching the filter, one row at a time, so that
+// exports do not hold the whole result in memory.
+func (r *DealRepository) EachFilteredDeal(status, fromDate, toDate, currency, sortBy, order string, amountMin, amountMax float64, fn func(*models.Deals) error) error {
+	query, args := filterDealsQuery(status, fromDate, toDate, currency, sortBy, order, amountMin, amountMax)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deal models.Deals
+		if err := rows.Scan(&deal.ID, &deal.LeadID, &deal.Amount, &deal.Currency, &deal.Status, &deal.CreatedAt, &deal.DepartureDate, &deal.LossReason, &deal.Destination); err != nil {
+			return err
+		}
+		if err := fn(&deal); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func filterDealsQuery(status, fromDate, toDate, currency, sortBy, order string, amountMin, amountMax float64) (string, []interface{}) {
 	if sortBy == "" {
 		sortBy = "created_at"
 	}
@@ -145,7 +198,7 @@ func (r *DealRepository) FilterDeals(status, fromDate, toDate, currency, sortBy,
 		sortBy = "created_at"
 	}
 
-	query := "SELECT id, lead_id, amount, currency, status, created_at FROM deals WHERE 1=1"
+	query := "SELECT id, lead_id, amount, currency, status, created_at, departure_date, loss_reason, destination FROM deals WHERE 1=1"
 	args := []interface{}{}
 	i := 1
 
@@ -180,28 +233,12 @@ func (r *DealRepository) FilterDeals(status, fromDate, toDate, currency, sortBy,
 		i++
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s LIMIT $%d OFFSET $%d", sortBy, order, i, i+1)
-	args = append(args, limit, offset)
-
-	rows, err := r.db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var deals []models.Deals
-	for rows.Next() {
-		var deal models.Deals
-		if err := rows.Scan(&deal.ID, &deal.LeadID, &deal.Amount, &deal.Currency, &deal.Status, &deal.CreatedAt); err != nil {
-			return nil, err
-		}
-		deals = append(deals, deal)
-	}
-	return deals, nil
+	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, order)
+	return query, args
 }
 
 func (r *DealRepository) ListPaginated(limit, offset int) ([]*models.Deals, error) {
-	query := `SELECT id, lead_id, amount, currency, status, created_at 
+	query := `SELECT id, lead_id, amount, currency, status, created_at, departure_date, loss_reason, destination 
 	          FROM deals 
 	          ORDER BY created_at DESC 
 	          LIMIT $1 OFFSET $2`
@@ -215,7 +252,7 @@ func (r *DealRepository) ListPaginated(limit, offset int) ([]*models.Deals, erro
 	var deals []*models.Deals
 	for rows.Next() {
 		var deal models.Deals
-		if err := rows.Scan(&deal.ID, &deal.LeadID, &deal.Amount, &deal.Currency, &deal.Status, &deal.CreatedAt); err != nil {
+		if err := rows.Scan(&deal.ID, &deal.LeadID, &deal.Amount, &deal.Currency, &deal.Status, &deal.CreatedAt, &deal.DepartureDate, &deal.LossReason, &deal.Destination); err != nil {
 			return nil, fmt.Errorf("ошибка чтения: %w", err)
 		}
 		deals = append(deals, &deal)
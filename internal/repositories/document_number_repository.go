@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type DocumentNumberRepository struct {
+	db *sql.DB
+}
+
+func NewDocumentNumberRepository(db *sql.DB) *DocumentNumberRepository {
+	return &DocumentNumberRepository{db: db}
+}
+
+// NextValue увеличивает счётчик для типа документа и года внутри транзакции tx.
+// Строка счётчика остаётся заблокированной до завершения транзакции, поэтому
+// параллельные создания документов выстраиваются в очередь, а откат транзакции
+// возвращает значение обратно — номера не пропускаются и не повторяются.
+func (r *DocumentNumberRepository) NextValue(tx *sql.Tx, docType string, year int) (int64, error) {
+	query := `
+        INSERT INTO document_number_sequences (doc_type, year, last_value)
+        VALUES ($1, $2, 1)
+        ON CONFLICT (doc_type, year)
+        DO UPDATE SET last_value = document_number_sequences.last_value + 1
+        RETURNING last_value
+    `
+	var value int64
+	if err := tx.QueryRow(query, docType, year).Scan(&value); err != nil {
+		return 0, fmt.Errorf("выделение номера документа: %w", err)
+	}
+	return value, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"time"
 	"turcompany/internal/models"
 )
 
@@ -14,14 +15,24 @@ func NewDocumentRepository(db *sql.DB) *DocumentRepository {
 	return &DocumentRepository{db: db}
 }
 
-func (r *DocumentRepository) Create(doc *models.Document) (int64, error) {
-	query := `INSERT INTO documents (deal_id, doc_type, file_path, status, signed_at)
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
+// BeginTx открывает транзакцию для создания документа вместе с его номером.
+func (r *DocumentRepository) BeginTx() (*sql.Tx, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("начало транзакции документа: %w", err)
+	}
+	return tx, nil
+}
+
+func (r *DocumentRepository) Create(tx *sql.Tx, doc *models.Document) (int64, error) {
+	query := `INSERT INTO documents (deal_id, number, doc_type, file_path, status, signed_at)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 
 	var id int64
-	err := r.db.QueryRow(
+	err := tx.QueryRow(
 		query,
 		doc.DealID,
+		doc.Number,
 		doc.DocType,
 		doc.FilePath,
 		doc.Status,
@@ -36,10 +47,10 @@ func (r *DocumentRepository) Create(doc *models.Document) (int64, error) {
 }
 
 func (r *DocumentRepository) GetByID(id int64) (*models.Document, error) {
-	query := `SELECT id, deal_id, doc_type, file_path, status, signed_at FROM documents WHERE id = $1`
+	query := `SELECT id, deal_id, COALESCE(number, ''), doc_type, file_path, status, signed_at FROM documents WHERE id = $1`
 	row := r.db.QueryRow(query, id)
 	var doc models.Document
-	err := row.Scan(&doc.ID, &doc.DealID, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt)
+	err := row.Scan(&doc.ID, &doc.DealID, &doc.Number, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -68,7 +79,7 @@ func (r *DocumentRepository) Delete(id int64) error {
 }
 
 func (r *DocumentRepository) ListDocumentsByDeal(dealID int64) ([]*models.Document, error) {
-	query := `SELECT id, deal_id, doc_type, file_path, status, signed_at FROM documents WHERE deal_id = $1`
+	query := `SELECT id, deal_id, COALESCE(number, ''), doc_type, file_path, status, signed_at FROM documents WHERE deal_id = $1`
 	rows, err := r.db.Query(query, dealID)
 	if err != nil {
 		return nil, fmt.Errorf("get by deal: %w", err)
@@ -78,7 +89,7 @@ func (r *DocumentRepository) ListDocumentsByDeal(dealID int64) ([]*models.Docume
 	var docs []*models.Document
 	for rows.Next() {
 		var doc models.Document
-		err := rows.Scan(&doc.ID, &doc.DealID, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt)
+		err := rows.Scan(&doc.ID, &doc.DealID, &doc.Number, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -96,6 +107,22 @@ func (r *DocumentRepository) UpdateStatus(id int64, status string) error {
 	return nil
 }
 
+// MarkSigned переводит документ в статус "signed" в рамках транзакции tx.
+// Возвращает false, если документ уже был подписан ранее.
+func (r *DocumentRepository) MarkSigned(tx *sql.Tx, id int64, filePath string, signedAt time.Time) (bool, error) {
+	query := `UPDATE documents SET status = 'signed', signed_at = $1, file_path = $2
+              WHERE id = $3 AND status <> 'signed'`
+	result, err := tx.Exec(query, signedAt, filePath, id)
+	if err != nil {
+		return false, fmt.Errorf("mark document signed: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("mark document signed: %w", err)
+	}
+	return affected > 0, nil
+}
+
 // Добавим метод для проверки существования лида
 func (r *DocumentRepository) LeadExists(id int) (bool, error) {
 	var exists bool
@@ -107,7 +134,7 @@ func (r *DocumentRepository) LeadExists(id int) (bool, error) {
 	return exists, nil
 }
 func (r *DocumentRepository) ListDocuments(limit, offset int) ([]*models.Document, error) {
-	query := `SELECT id, deal_id, doc_type, file_path, status, signed_at 
+	query := `SELECT id, deal_id, COALESCE(number, ''), doc_type, file_path, status, signed_at 
 			  FROM documents 
 			  ORDER BY signed_at DESC 
 			  LIMIT $1 OFFSET $2`
@@ -121,7 +148,7 @@ func (r *DocumentRepository) ListDocuments(limit, offset int) ([]*models.Documen
 	var docs []*models.Document
 	for rows.Next() {
 		var doc models.Document
-		if err := rows.Scan(&doc.ID, &doc.DealID, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt); err != nil {
+		if err := rows.Scan(&doc.ID, &doc.DealID, &doc.Number, &doc.DocType, &doc.FilePath, &doc.Status, &doc.SignedAt); err != nil {
 			return nil, err
 		}
 		docs = append(docs, &doc)
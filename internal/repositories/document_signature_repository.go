@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"turcompany/internal/models"
+)
+
+type DocumentSignatureRepository struct {
+	db *sql.DB
+}
+
+func NewDocumentSignatureRepository(db *sql.DB) *DocumentSignatureRepository {
+	return &DocumentSignatureRepository{db: db}
+}
+
+func (r *DocumentSignatureRepository) Create(tx *sql.Tx, sig *models.DocumentSignature) (int64, error) {
+	query := `INSERT INTO document_signatures
+              (document_id, sms_confirmation_id, phone, ip, user_agent, file_path, file_hash, signed_at)
+              VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	err := tx.QueryRow(
+		query,
+		sig.DocumentID,
+		sig.SMSConfirmationID,
+		sig.Phone,
+		sig.IP,
+		sig.UserAgent,
+		sig.FilePath,
+		sig.FileHash,
+		sig.SignedAt,
+	).Scan(&sig.ID)
+	if err != nil {
+		return 0, fmt.Errorf("create document signature: %w", err)
+	}
+	return sig.ID, nil
+}
+
+func (r *DocumentSignatureRepository) GetByDocumentID(documentID int64) (*models.DocumentSignature, error) {
+	query := `SELECT id, document_id, COALESCE(sms_confirmation_id, 0), phone, COALESCE(ip, ''),
+                     COALESCE(user_agent, ''), file_path, file_hash, signed_at
+              FROM document_signatures WHERE document_id = $1`
+
+	var sig models.DocumentSignature
+	err := r.db.QueryRow(query, documentID).Scan(
+		&sig.ID,
+		&sig.DocumentID,
+		&sig.SMSConfirmationID,
+		&sig.Phone,
+		&sig.IP,
+		&sig.UserAgent,
+		&sig.FilePath,
+		&sig.FileHash,
+		&sig.SignedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get document signature: %w", err)
+	}
+	return &sig, nil
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"turcompany/internal/models"
+)
+
+// InboxRepository defines the interface for database operations on the client inbox.
+type InboxRepository interface {
+	FindThread(ctx context.Context, id int64) (*models.InboxThread, error)
+	FindThreadByLead(ctx context.Context, leadID int64) (*models.InboxThread, error)
+	ListThreads(ctx context.Context, filter models.InboxThreadFilter) ([]models.InboxThread, error)
+	StoreMessage(ctx context.Context, leadID int64, msg *models.InboxMessage) (bool, error)
+	UpdateStatus(ctx context.Context, id int64, status, externalID, errText string) error
+	FindMessages(ctx context.Context, threadID int64, cursor models.MessageCursor) (*models.InboxMessagePage, error)
+	FindLastInbound(ctx context.Context, threadID int64, channel string) (*models.InboxMessage, error)
+	MarkRead(ctx context.Context, threadID int64) error
+}
+
+type inboxRepository struct {
+	db *sql.DB
+}
+
+// NewInboxRepository creates a new instance of InboxRepository.
+func NewInboxRepository(db *sql.DB) InboxRepository {
+	return &inboxRepository{db: db}
+}
+
+const inboxThreadQuery = `
+	SELECT t.id, t.lead_id, l.title, l.owner_id, t.last_channel, COALESCE(last.body, ''),
+		t.last_message_at, t.unread_count, t.created_at
+	FROM inbox_threads t
+	JOIN leads l ON l.id = t.lead_id
+	LEFT JOIN LATERAL (
+		SELECT m.body FROM inbox_messages m WHERE m.thread_id = t.id ORDER BY m.id DESC LIMIT 1
+	) last ON TRUE`
+
+func scanInboxThread(row rowScanner, t *models.InboxThread) error {
+	return row.Scan(&t.ID, &t.LeadID, &t.LeadTitle, &t.OwnerID, &t.LastChannel, &t.LastMessage,
+		&t.LastMessageAt, &t.UnreadCount, &t.CreatedAt)
+}
+
+const inboxMessageColumns = `id, thread_id, channel, direction, author_id, address, subject, body,
+	COALESCE(external_id, ''), status, error, created_at`
+
+func scanInboxMessage(row rowScanner, m *models.InboxMessage) error {
+	return row.Scan(&m.ID, &m.ThreadID, &m.Channel, &m.Direction, &m.AuthorID, &m.Address, &m.Subject, &m.Body,
+		&m.ExternalID, &m.Status, &m.Error, &m.CreatedAt)
+}
+
+// FindThread returns the thread; nil if it does not exist.
+func (r *inboxRepository) FindThread(ctx context.Context, id int64) (*models.InboxThread, error) {
+	return r.findThread(ctx, `t.id = $1`, id)
+}
+
+// FindThreadByLead returns the thread of the lead; nil if the lead has no messages yet.
+func (r *inboxRepository) FindThreadByLead(ctx context.Context, leadID int64) (*models.InboxThread, error) {
+	return r.findThread(ctx, `t.lead_id = $1`, leadID)
+}
+
+func (r *inboxRepository) findThread(ctx context.Context, where string, arg int64) (*models.InboxThread, error) {
+	var t models.InboxThread
+	if err := scanInboxThread(r.db.QueryRowContext(ctx, inboxThreadQuery+` WHERE `+where, arg), &t); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListThreads returns threads with the latest activity first.
+func (r *inboxRepository) ListThreads(ctx context.Context, filter models.InboxThreadFilter) ([]models.InboxThread, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.OwnerID != nil {
+		args = append(args, *filter.OwnerID)
+		conditions = append(conditions, fmt.Sprintf("l.owner_id = $%d", len(args)))
+	}
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		conditions = append(conditions, fmt.Sprintf("t.last_channel = $%d", len(args)))
+	}
+	if filter.UnreadOnly {
+		conditions = append(conditions, "t.unread_count > 0")
+	}
+
+	query := inboxThreadQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY t.last_message_at DESC, t.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	threads := []models.InboxThread{}
+	for rows.Next() {
+		var t models.InboxThread
+		if err := scanInboxThread(rows, &t); err != nil {
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// StoreMessage adds the message to the lead's thread, creating the thread on the first message.
+// It returns false without storing anything if the inbound message was already received.
+func (r *inboxRepository) StoreMessage(ctx context.Context, leadID int64, msg *models.InboxMessage) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO inbox_threads (lead_id, last_channel, last_message_at) VALUES ($1, $2, $3)
+		ON CONFLICT (lead_id) DO UPDATE SET lead_id = EXCLUDED.lead_id
+		RETURNING id`, leadID, msg.Channel, msg.CreatedAt,
+	).Scan(&msg.ThreadID)
+	if err != nil {
+		return false, fmt.Errorf("store inbox thread: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO inbox_messages (thread_id, channel, direction, author_id, address, subject, body, external_id, status, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11)
+		ON CONFLICT (channel, external_id) WHERE direction = 'in' AND external_id IS NOT NULL DO NOTHING
+		RETURNING id`,
+		msg.ThreadID, msg.Channel, msg.Direction, msg.AuthorID, msg.Address, msg.Subject, msg.Body,
+		msg.ExternalID, msg.Status, msg.Error, msg.CreatedAt,
+	).Scan(&msg.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("store inbox message: %w", err)
+	}
+
+	unread := 0
+	if msg.Direction == models.DirectionIn {
+		unread = 1
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE inbox_threads SET last_channel = $2, last_message_at = GREATEST(last_message_at, $3),
+			unread_count = unread_count + $4
+		WHERE id = $1`, msg.ThreadID, msg.Channel, msg.CreatedAt, unread)
+	if err != nil {
+		return false, fmt.Errorf("touch inbox thread: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *inboxRepository) UpdateStatus(ctx context.Context, id int64, status, externalID, errText string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE inbox_messages SET status = $2, external_id = COALESCE(NULLIF($3, ''), external_id), error = $4
+		WHERE id = $1`, id, status, externalID, errText)
+	return err
+}
+
+// FindMessages returns a page of the thread history; see models.MessageCursor.
+func (r *inboxRepository) FindMessages(ctx context.Context, threadID int64, cursor models.MessageCursor) (*models.InboxMessagePage, error) {
+	query := `SELECT ` + inboxMessageColumns + ` FROM inbox_messages WHERE thread_id = $1`
+	args := []interface{}{threadID}
+	switch {
+	case cursor.After > 0:
+		args = append(args, cursor.After, cursor.Limit+1)
+		query += ` AND id > $2 ORDER BY id ASC LIMIT $3`
+	case cursor.Before > 0:
+		args = append(args, cursor.Before, cursor.Limit+1)
+		query += ` AND id < $2 ORDER BY id DESC LIMIT $3`
+	default:
+		args = append(args, cursor.Limit+1)
+		query += ` ORDER BY id DESC LIMIT $2`
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.InboxMessage{}
+	for rows.Next() {
+		var m models.InboxMessage
+		if err := scanInboxMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &models.InboxMessagePage{HasMore: len(messages) > cursor.Limit}
+	if page.HasMore {
+		messages = messages[:cursor.Limit]
+	}
+	if cursor.After <= 0 {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	page.Messages = messages
+	return page, nil
+}
+
+// FindLastInbound returns the latest client message in the thread, of the given channel if set;
+// nil if there is none.
+func (r *inboxRepository) FindLastInbound(ctx context.Context, threadID int64, channel string) (*models.InboxMessage, error) {
+	query := `SELECT ` + inboxMessageColumns + ` FROM inbox_messages
+		WHERE thread_id = $1 AND direction = 'in' AND ($2 = '' OR channel = $2)
+		ORDER BY id DESC LIMIT 1`
+	var m models.InboxMessage
+	if err := scanInboxMessage(r.db.QueryRowContext(ctx, query, threadID, channel), &m); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *inboxRepository) MarkRead(ctx context.Context, threadID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE inbox_threads SET unread_count = 0 WHERE id = $1`, threadID)
+	return err
+}
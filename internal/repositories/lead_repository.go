@@ -18,27 +18,39 @@ func NewLeadRepository(db *sql.DB) *LeadRepository {
 	return &LeadRepository{db: db}
 }
 
+const leadColumns = `id, title, description, created_at, owner_id, status, source,
+	COALESCE(phone, ''), COALESCE(email, ''), telegram_chat_id`
+
+func scanLead(row rowScanner, lead *models.Leads) error {
+	return row.Scan(&lead.ID, &lead.Title, &lead.Description, &lead.CreatedAt, &lead.OwnerID, &lead.Status, &lead.Source,
+		&lead.Phone, &lead.Email, &lead.TelegramChatID)
+}
+
 func (r *LeadRepository) Create(lead *models.Leads) error {
 
+	if lead.Source == "" {
+		lead.Source = models.LeadSourceCRM
+	}
 	query := `
-		INSERT INTO leads ( title, description, created_at, owner_id, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO leads ( title, description, created_at, owner_id, status, source, phone, email, telegram_chat_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9)
+		RETURNING id
 	`
-	_, err := r.db.Exec(query, lead.Title, lead.Description, lead.CreatedAt, lead.OwnerID, lead.Status)
-	return err
+	return r.db.QueryRow(query, lead.Title, lead.Description, lead.CreatedAt, lead.OwnerID, lead.Status, lead.Source,
+		lead.Phone, lead.Email, lead.TelegramChatID).Scan(&lead.ID)
 }
 
 func (r *LeadRepository) Update(lead *models.Leads) error {
-	query := `UPDATE leads SET title=$1, description=$2, created_at=$3, owner_id=$4, status=$5 WHERE id=$6`
-	_, err := r.db.Exec(query, lead.Title, lead.Description, lead.CreatedAt, lead.OwnerID, lead.Status, lead.ID)
+	query := `UPDATE leads SET title=$1, description=$2, created_at=$3, owner_id=$4, status=$5,
+		phone=NULLIF($6, ''), email=NULLIF($7, '') WHERE id=$8`
+	_, err := r.db.Exec(query, lead.Title, lead.Description, lead.CreatedAt, lead.OwnerID, lead.Status, lead.Phone, lead.Email, lead.ID)
 	return err
 }
 
 func (r *LeadRepository) GetByID(id int) (*models.Leads, error) {
-	query := `SELECT id, title, description, created_at, owner_id, status FROM leads WHERE id=$1`
-	row := r.db.QueryRow(query, id)
+	query := `SELECT ` + leadColumns + ` FROM leads WHERE id=$1`
 	lead := &models.Leads{}
-	err := row.Scan(&lead.ID, &lead.Title, &lead.Description, &lead.CreatedAt, &lead.OwnerID, &lead.Status)
+	err := scanLead(r.db.QueryRow(query, id), lead)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +70,50 @@ func (r *LeadRepository) CountLeads() (int, error) {
 
 // Файл: internal/repositories/lead_repository.go
 func (r *LeadRepository) FilterLeads(status string, ownerID int, sortBy, order string, limit, offset int) ([]models.Leads, error) {
+	query, args := filterLeadsQuery(status, ownerID, sortBy, order)
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leads []models.Leads
+	for rows.Next() {
+		var lead models.Leads
+		if err := scanLead(rows, &lead); err != nil {
+			return nil, err
+		}
+		leads = append(leads, lead)
+	}
+	return leads, nil
+}
+
+// EachFilteredLead calls fn for every lead matching the filter, one row at a time, so that
+// exports do not hold the whole result in memory.
+func (r *LeadRepository) EachFilteredLead(status string, ownerID int, sortBy, order string, fn func(*models.Leads) error) error {
+	query, args := filterLeadsQuery(status, ownerID, sortBy, order)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lead models.Leads
+		if err := scanLead(rows, &lead); err != nil {
+			return err
+		}
+		if err := fn(&lead); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func filterLeadsQuery(status string, ownerID int, sortBy, order string) (string, []interface{}) {
 	if sortBy == "" {
 		sortBy = "created_at"
 	}
@@ -74,7 +130,7 @@ func (r *LeadRepository) FilterLeads(status string, ownerID int, sortBy, order s
 		sortBy = "created_at"
 	}
 
-	query := "SELECT id, title, description, created_at, owner_id, status FROM leads WHERE 1=1"
+	query := "SELECT " + leadColumns + " FROM leads WHERE 1=1"
 	args := []interface{}{}
 	i := 1
 
@@ -89,28 +145,12 @@ func (r *LeadRepository) FilterLeads(status string, ownerID int, sortBy, order s
 		i++
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s LIMIT $%d OFFSET $%d", sortBy, order, i, i+1)
-	args = append(args, limit, offset)
-
-	rows, err := r.db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var leads []models.Leads
-	for rows.Next() {
-		var lead models.Leads
-		if err := rows.Scan(&lead.ID, &lead.Title, &lead.Description, &lead.CreatedAt, &lead.OwnerID, &lead.Status); err != nil {
-			return nil, err
-		}
-		leads = append(leads, lead)
-	}
-	return leads, nil
+	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, order)
+	return query, args
 }
 
 func (r *LeadRepository) ListPaginated(limit, offset int) ([]*models.Leads, error) {
-	query := `SELECT id, title, description, created_at, owner_id, status 
+	query := `SELECT ` + leadColumns + `
 	          FROM leads 
 	          ORDER BY created_at DESC 
 	          LIMIT $1 OFFSET $2`
@@ -123,10 +163,40 @@ func (r *LeadRepository) ListPaginated(limit, offset int) ([]*models.Leads, erro
 	var leads []*models.Leads
 	for rows.Next() {
 		var lead models.Leads
-		if err := rows.Scan(&lead.ID, &lead.Title, &lead.Description, &lead.CreatedAt, &lead.OwnerID, &lead.Status); err != nil {
+		if err := scanLead(rows, &lead); err != nil {
 			return nil, err
 		}
 		leads = append(leads, &lead)
 	}
 	return leads, nil
 }
+
+// FindByContact возвращает самый свежий лид с совпадающим Telegram-чатом, телефоном
+// или email (в этом порядке приоритета); nil, если совпадений нет.
+func (r *LeadRepository) FindByContact(telegramChatID int64, phone, email string) (*models.Leads, error) {
+	query := `SELECT ` + leadColumns + ` FROM leads
+		WHERE ($1::bigint <> 0 AND telegram_chat_id = $1::bigint)
+		   OR ($2::text <> '' AND phone = $2::text)
+		   OR ($3::text <> '' AND LOWER(email) = LOWER($3::text))
+		ORDER BY CASE
+			WHEN $1::bigint <> 0 AND telegram_chat_id = $1::bigint THEN 0
+			WHEN $2::text <> '' AND phone = $2::text THEN 1
+			ELSE 2
+		END, created_at DESC
+		LIMIT 1`
+	lead := &models.Leads{}
+	err := scanLead(r.db.QueryRow(query, telegramChatID, phone, email), lead)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("поиск лида по контактам: %w", err)
+	}
+	return lead, nil
+}
+
+// SetTelegramChat привязывает Telegram-чат к лиду, если он ещё не привязан.
+func (r *LeadRepository) SetTelegramChat(id int, chatID int64) error {
+	_, err := r.db.Exec(`UPDATE leads SET telegram_chat_id = $1 WHERE id = $2 AND telegram_chat_id IS NULL`, chatID, id)
+	return err
+}
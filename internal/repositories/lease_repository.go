@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LeaseRepository grants named leases so that a background job runs on one instance at a time.
+type LeaseRepository interface {
+	Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, holder string) error
+}
+
+type leaseRepository struct {
+	db *sql.DB
+}
+
+// NewLeaseRepository creates a new instance of LeaseRepository.
+func NewLeaseRepository(db *sql.DB) LeaseRepository {
+	return &leaseRepository{db: db}
+}
+
+// Acquire takes the lease for ttl, or extends it if holder already has it. It returns false
+// while another holder's lease has not expired.
+func (r *leaseRepository) Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	var current string
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO scheduler_leases (name, holder, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at <= $4
+		RETURNING holder`, name, holder, now.Add(ttl), now,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Release gives up the lease so another instance can take it without waiting for expiry.
+func (r *leaseRepository) Release(ctx context.Context, name, holder string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM scheduler_leases WHERE name = $1 AND holder = $2`, name, holder)
+	return err
+}
@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// MessageAttachmentRepository defines the interface for database operations on message attachments.
+type MessageAttachmentRepository interface {
+	Create(ctx context.Context, a *models.MessageAttachment) error
+	FindAccess(ctx context.Context, id int64) (*models.AttachmentAccess, error)
+	FindPending(ctx context.Context, uploaderID int64, ids []int64) ([]models.MessageAttachment, error)
+	DeletePendingBefore(ctx context.Context, before time.Time) ([]models.MessageAttachment, error)
+}
+
+type messageAttachmentRepository struct {
+	db *sql.DB
+}
+
+// NewMessageAttachmentRepository creates a new instance of MessageAttachmentRepository.
+func NewMessageAttachmentRepository(db *sql.DB) MessageAttachmentRepository {
+	return &messageAttachmentRepository{db: db}
+}
+
+const attachmentColumns = `a.id, a.message_id, a.uploader_id, a.file_name, a.content_type, a.size,
+	a.file_path, COALESCE(a.thumbnail_path, ''), a.created_at`
+
+func scanAttachment(row rowScanner, a *models.MessageAttachment) error {
+	err := row.Scan(&a.ID, &a.MessageID, &a.UploaderID, &a.FileName, &a.ContentType, &a.Size,
+		&a.FilePath, &a.ThumbnailPath, &a.CreatedAt)
+	a.HasThumbnail = a.ThumbnailPath != ""
+	return err
+}
+
+func (r *messageAttachmentRepository) Create(ctx context.Context, a *models.MessageAttachment) error {
+	query := `
+		INSERT INTO message_attachments (uploader_id, file_name, content_type, size, file_path, thumbnail_path, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id`
+	err := r.db.QueryRowContext(ctx, query,
+		a.UploaderID, a.FileName, a.ContentType, a.Size, a.FilePath, a.ThumbnailPath, a.CreatedAt,
+	).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("create message attachment: %w", err)
+	}
+	return nil
+}
+
+// FindAccess returns the attachment with the addressing of its message; nil if it does not exist.
+func (r *messageAttachmentRepository) FindAccess(ctx context.Context, id int64) (*models.AttachmentAccess, error) {
+	query := `
+		SELECT ` + attachmentColumns + `, COALESCE(m.sender_id, 0), COALESCE(m.receiver_id, 0), m.conversation_id
+		FROM message_attachments a
+		LEFT JOIN messages m ON m.id = a.message_id
+		WHERE a.id = $1`
+
+	var access models.AttachmentAccess
+	a := &access.Attachment
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&a.ID, &a.MessageID, &a.UploaderID, &a.FileName, &a.ContentType, &a.Size,
+		&a.FilePath, &a.ThumbnailPath, &a.CreatedAt,
+		&access.SenderID, &access.ReceiverID, &access.ConversationID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	a.HasThumbnail = a.ThumbnailPath != ""
+	return &access, nil
+}
+
+// FindPending returns the uploader's attachments with the given IDs that are not linked to a message yet.
+func (r *messageAttachmentRepository) FindPending(ctx context.Context, uploaderID int64, ids []int64) ([]models.MessageAttachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM message_attachments a
+		WHERE a.id = ANY($1) AND a.uploader_id = $2 AND a.message_id IS NULL
+		ORDER BY a.id`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), uploaderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAttachments(rows)
+}
+
+// DeletePendingBefore deletes attachments uploaded before the given time and never sent,
+// returning them so their files can be removed.
+func (r *messageAttachmentRepository) DeletePendingBefore(ctx context.Context, before time.Time) ([]models.MessageAttachment, error) {
+	query := `DELETE FROM message_attachments a
+		WHERE a.message_id IS NULL AND a.created_at < $1
+		RETURNING ` + attachmentColumns
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAttachments(rows)
+}
+
+func scanAttachments(rows *sql.Rows) ([]models.MessageAttachment, error) {
+	attachments := []models.MessageAttachment{}
+	for rows.Next() {
+		var a models.MessageAttachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// linkAttachments binds the sender's pending attachments listed in msg.Attachments to the stored
+// message and replaces them with the linked rows. It fails if any of them is no longer pending.
+func linkAttachments(ctx context.Context, tx *sql.Tx, msg *models.Message) error {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		ids[i] = a.ID
+	}
+
+	query := `
+		UPDATE message_attachments a SET message_id = $1
+		WHERE a.id = ANY($2) AND a.uploader_id = $3 AND a.message_id IS NULL
+		RETURNING ` + attachmentColumns
+	rows, err := tx.QueryContext(ctx, query, msg.ID, pq.Array(ids), msg.SenderID)
+	if err != nil {
+		return fmt.Errorf("link attachments: %w", err)
+	}
+	defer rows.Close()
+
+	linked, err := scanAttachments(rows)
+	if err != nil {
+		return fmt.Errorf("link attachments: %w", err)
+	}
+	if len(linked) != len(ids) {
+		return fmt.Errorf("link attachments: %d of %d attachments are no longer available", len(ids)-len(linked), len(ids))
+	}
+	msg.Attachments = linked
+	return nil
+}
+
+// loadAttachments fills Attachments of the given messages.
+func loadAttachments(ctx context.Context, db *sql.DB, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(messages))
+	index := make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+		index[msg.ID] = i
+	}
+
+	query := `SELECT ` + attachmentColumns + ` FROM message_attachments a
+		WHERE a.message_id = ANY($1)
+		ORDER BY a.id`
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		msg := &messages[index[*a.MessageID]]
+		msg.Attachments = append(msg.Attachments, a)
+	}
+	return nil
+}
@@ -3,14 +3,21 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 	"turcompany/internal/models"
 )
 
 // MessageRepository defines the interface for database operations on messages.
 type MessageRepository interface {
 	Store(ctx context.Context, msg *models.Message) error
-	FindConversationHistory(ctx context.Context, userID1, userID2 int64) ([]models.Message, error)
+	FindConversationHistory(ctx context.Context, userID1, userID2 int64, cursor models.MessageCursor) (*models.MessagePage, error)
 	FindConversations(ctx context.Context, userID int64) ([]models.Conversation, error)
+	SearchMessages(ctx context.Context, userID, partnerID int64, text string, limit, offset int) ([]models.MessageSearchResult, error)
+	FindByID(ctx context.Context, id int64) (*models.Message, error)
+	FindWithDetails(ctx context.Context, id int64) (*models.Message, error)
+	MarkRead(ctx context.Context, readerID, partnerID, upToID int64, readAt time.Time) (int64, error)
+	CountUnread(ctx context.Context, userID int64) (int, error)
 }
 
 type messageRepository struct {
@@ -22,53 +29,110 @@ func NewMessageRepository(db *sql.DB) MessageRepository {
 	return &messageRepository{db: db}
 }
 
+// Store stores a direct message and links the pending attachments listed in msg.Attachments to it.
 func (r *messageRepository) Store(ctx context.Context, msg *models.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO messages (sender_id, receiver_id, content, sent_at) VALUES ($1, $2, $3, $4) RETURNING id, sent_at`
-	return r.db.QueryRowContext(ctx, query, msg.SenderID, msg.ReceiverID, msg.Content, msg.SentAt).Scan(&msg.ID, &msg.SentAt)
+	if err := tx.QueryRowContext(ctx, query, msg.SenderID, msg.ReceiverID, msg.Content, msg.SentAt).Scan(&msg.ID, &msg.SentAt); err != nil {
+		return err
+	}
+	if err := linkAttachments(ctx, tx, msg); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *messageRepository) FindConversationHistory(ctx context.Context, userID1, userID2 int64) ([]models.Message, error) {
-	query := `
-		SELECT id, sender_id, receiver_id, content, sent_at, read_at FROM messages
-		WHERE (sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1)
-		ORDER BY sent_at ASC`
+// messageColumns is the column list read by scanMessage.
+const messageColumns = `id, sender_id, COALESCE(receiver_id, 0), conversation_id, content, sent_at, read_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	rows, err := r.db.QueryContext(ctx, query, userID1, userID2)
+func scanMessage(row rowScanner, msg *models.Message) error {
+	return row.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.ConversationID, &msg.Content, &msg.SentAt, &msg.ReadAt)
+}
+
+// FindConversationHistory returns up to cursor.Limit messages between two users in ascending order.
+func (r *messageRepository) FindConversationHistory(ctx context.Context, userID1, userID2 int64, cursor models.MessageCursor) (*models.MessagePage, error) {
+	conversation := `((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))`
+	return findMessagePage(ctx, r.db, conversation, []interface{}{userID1, userID2}, cursor)
+}
+
+// findMessagePage reads a keyset page of messages matching where, whose placeholders are args.
+// It fetches one extra row to tell whether more messages exist beyond the page.
+func findMessagePage(ctx context.Context, db *sql.DB, where string, args []interface{}, cursor models.MessageCursor) (*models.MessagePage, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE ` + where
+	switch {
+	case cursor.After > 0:
+		args = append(args, cursor.After, cursor.Limit+1)
+		query += fmt.Sprintf(` AND id > $%d ORDER BY id ASC LIMIT $%d`, len(args)-1, len(args))
+	case cursor.Before > 0:
+		args = append(args, cursor.Before, cursor.Limit+1)
+		query += fmt.Sprintf(` AND id < $%d ORDER BY id DESC LIMIT $%d`, len(args)-1, len(args))
+	default:
+		args = append(args, cursor.Limit+1)
+		query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []models.Message
+	messages := []models.Message{}
 	for rows.Next() {
 		var msg models.Message
-		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Content, &msg.SentAt, &msg.ReadAt); err != nil {
+		if err := scanMessage(rows, &msg); err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
 	}
-	return messages, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{HasMore: len(messages) > cursor.Limit}
+	if page.HasMore {
+		messages = messages[:cursor.Limit]
+	}
+	// Pages fetched backwards are read newest first; the client always gets ascending order
+	if cursor.After <= 0 {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	if err := loadAttachments(ctx, db, messages); err != nil {
+		return nil, err
+	}
+	page.Messages = messages
+	return page, nil
 }
 
-// FindConversations is a complex query. It finds all unique chat partners for a user,
-// gets the last message for each, and counts unread messages.
+// FindConversations finds all chat partners of a user with the last message and unread count for each.
+// Sent and received messages are read separately so each branch uses its own index.
 func (r *messageRepository) FindConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
-	// This query is complex and might need optimization for very large datasets (e.g., using window functions).
-	// For now, this is a straightforward approach.
 	query := `
-		WITH message_partners AS (
-			SELECT
-				CASE
-					WHEN sender_id = $1 THEN receiver_id
-					ELSE sender_id
-				END as partner_id,
-				MAX(sent_at) as max_sent_at
-			FROM messages
-			WHERE sender_id = $1 OR receiver_id = $1
-			GROUP BY partner_id
+		WITH user_messages AS (
+			SELECT id, sender_id, receiver_id, content, sent_at, read_at, receiver_id AS partner_id
+			FROM messages WHERE sender_id = $1 AND receiver_id IS NOT NULL
+			UNION ALL
+			SELECT id, sender_id, receiver_id, content, sent_at, read_at, sender_id AS partner_id
+			FROM messages WHERE receiver_id = $1 AND sender_id <> $1
+		), ranked AS (
+			SELECT *,
+				ROW_NUMBER() OVER (PARTITION BY partner_id ORDER BY id DESC) AS rn,
+				COUNT(*) FILTER (WHERE receiver_id = $1 AND read_at IS NULL) OVER (PARTITION BY partner_id) AS unread_count
+			FROM user_messages
 		)
 		SELECT
-			p.partner_id,
+			m.partner_id,
 			u.company_name,
 			u.email,
 			m.id,
@@ -77,14 +141,11 @@ func (r *messageRepository) FindConversations(ctx context.Context, userID int64)
 			m.content,
 			m.sent_at,
 			m.read_at,
-			(SELECT COUNT(*) FROM messages um WHERE um.sender_id = p.partner_id AND um.receiver_id = $1 AND um.read_at IS NULL) as unread_count
-		FROM message_partners p
-		JOIN messages m ON (
-			(m.sender_id = p.partner_id AND m.receiver_id = $1) OR
-			(m.sender_id = $1 AND m.receiver_id = p.partner_id)
-		) AND m.sent_at = p.max_sent_at
-		JOIN users u ON u.id = p.partner_id
-		ORDER BY m.sent_at DESC`
+			m.unread_count
+		FROM ranked m
+		JOIN users u ON u.id = m.partner_id
+		WHERE m.rn = 1
+		ORDER BY m.id DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -105,5 +166,104 @@ func (r *messageRepository) FindConversations(ctx context.Context, userID int64)
 		}
 		conversations = append(conversations, conv)
 	}
-	return conversations, nil
+	return conversations, rows.Err()
+}
+
+// SearchMessages runs a full-text search over the user's direct messages and the group conversations
+// they take part in, optionally limited to one direct partner (partnerID > 0).
+// Results are ordered by relevance, newest first.
+func (r *messageRepository) SearchMessages(ctx context.Context, userID, partnerID int64, text string, limit, offset int) ([]models.MessageSearchResult, error) {
+	query := `
+		WITH q AS (SELECT plainto_tsquery('simple', $2) AS query)
+		SELECT
+			m.id, m.sender_id, COALESCE(m.receiver_id, 0), m.conversation_id, m.content, m.sent_at, m.read_at,
+			CASE
+				WHEN m.receiver_id IS NULL THEN 0
+				WHEN m.sender_id = $1 THEN m.receiver_id
+				ELSE m.sender_id
+			END AS partner_id,
+			ts_headline('simple', m.content, q.query, 'StartSel=<b>, StopSel=</b>, MaxFragments=2'),
+			ts_rank(m.content_tsv, q.query) AS rank
+		FROM messages m, q
+		WHERE m.content_tsv @@ q.query
+			AND (
+				(m.receiver_id IS NOT NULL AND (m.sender_id = $1 OR m.receiver_id = $1))
+				OR m.conversation_id IN (SELECT conversation_id FROM conversation_participants WHERE user_id = $1)
+			)
+			AND ($3 = 0 OR (m.sender_id = $3 AND m.receiver_id = $1) OR (m.sender_id = $1 AND m.receiver_id = $3))
+		ORDER BY rank DESC, m.id DESC
+		LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, text, partnerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.MessageSearchResult{}
+	for rows.Next() {
+		var res models.MessageSearchResult
+		if err := rows.Scan(
+			&res.Message.ID, &res.Message.SenderID, &res.Message.ReceiverID, &res.Message.ConversationID,
+			&res.Message.Content, &res.Message.SentAt, &res.Message.ReadAt, &res.PartnerID, &res.Headline, &res.Rank,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+func (r *messageRepository) FindByID(ctx context.Context, id int64) (*models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE id = $1`
+
+	var msg models.Message
+	err := scanMessage(r.db.QueryRowContext(ctx, query, id), &msg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// FindWithDetails returns a message with its attachments and mentions; nil if it does not exist.
+func (r *messageRepository) FindWithDetails(ctx context.Context, id int64) (*models.Message, error) {
+	msg, err := r.FindByID(ctx, id)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+	messages := []models.Message{*msg}
+	if err := loadAttachments(ctx, r.db, messages); err != nil {
+		return nil, err
+	}
+	if err := loadMentions(ctx, r.db, messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+// MarkRead marks messages sent by partnerID to readerID with id <= upToID as read
+// and returns the number of messages that were unread before the call.
+func (r *messageRepository) MarkRead(ctx context.Context, readerID, partnerID, upToID int64, readAt time.Time) (int64, error) {
+	query := `
+		UPDATE messages SET read_at = $1
+		WHERE receiver_id = $2 AND sender_id = $3 AND id <= $4 AND read_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, readAt, readerID, partnerID, upToID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *messageRepository) CountUnread(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM messages WHERE receiver_id = $1 AND read_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
 }
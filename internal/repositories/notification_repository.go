@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"turcompany/internal/models"
+)
+
+// NotificationRepository defines the interface for database operations on in-app notifications.
+type NotificationRepository interface {
+	Create(ctx context.Context, n *models.Notification) error
+	List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userID int64) (int, error)
+	MarkRead(ctx context.Context, userID, id int64) (bool, error)
+	MarkAllRead(ctx context.Context, userID int64) error
+}
+
+type notificationRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository creates a new instance of NotificationRepository.
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO notifications (user_id, text, created_at) VALUES ($1, $2, $3)
+		RETURNING id`, n.UserID, n.Text, n.CreatedAt,
+	).Scan(&n.ID)
+}
+
+// List returns the user's notifications, newest first.
+func (r *notificationRepository) List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, text, created_at, read_at FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY id DESC LIMIT $3 OFFSET $4`, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Text, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one notification of the user as read; false if the user has no such notification.
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	return err
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"turcompany/internal/querydsl"
+)
+
+// builderSchemas are the fields the report builder may filter, sort and group by. Contact
+// details of leads can be filtered but are not offered for grouping.
+var builderSchemas = map[string]*querydsl.Schema{
+	"leads": querydsl.NewSchema("leads l", "l.id DESC",
+		querydsl.Field{Name: "id", Column: "l.id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "title", Column: "l.title", Type: querydsl.TypeString, Sort: true},
+		querydsl.Field{Name: "owner_id", Column: "l.owner_id", Type: querydsl.TypeInt, Sort: true, Group: true},
+		querydsl.Field{Name: "status", Column: "l.status", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "source", Column: "l.source", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "phone", Column: "l.phone", Type: querydsl.TypeString},
+		querydsl.Field{Name: "email", Column: "l.email", Type: querydsl.TypeString},
+		querydsl.Field{Name: "created_at", Column: "l.created_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+	),
+	"deals": querydsl.NewSchema("deals d LEFT JOIN leads l ON l.id = d.lead_id", "d.id DESC",
+		querydsl.Field{Name: "id", Column: "d.id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "lead_id", Column: "d.lead_id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "owner_id", Column: "d.owner_id", Type: querydsl.TypeInt, Sort: true, Group: true},
+		querydsl.Field{Name: "amount", Column: "(" + dealAmountSQL + ")::float8", Type: querydsl.TypeFloat, Sort: true},
+		querydsl.Field{Name: "currency", Column: "UPPER(TRIM(d.currency))", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "status", Column: "d.status", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "destination", Column: "d.destination", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "loss_reason", Column: "d.loss_reason", Type: querydsl.TypeString, Group: true},
+		querydsl.Field{Name: "departure_date", Column: "d.departure_date", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "created_at", Column: "d.created_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "lead_owner_id", Column: "l.owner_id", Type: querydsl.TypeInt, Sort: true, Group: true},
+		querydsl.Field{Name: "lead_source", Column: "l.source", Type: querydsl.TypeString, Sort: true, Group: true},
+	),
+	"tasks": querydsl.NewSchema("tasks t", "t.id DESC",
+		querydsl.Field{Name: "id", Column: "t.id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "title", Column: "t.title", Type: querydsl.TypeString, Sort: true},
+		querydsl.Field{Name: "creator_id", Column: "t.creator_id", Type: querydsl.TypeInt, Sort: true, Group: true},
+		querydsl.Field{Name: "assignee_id", Column: "t.assignee_id", Type: querydsl.TypeInt, Sort: true, Group: true},
+		querydsl.Field{Name: "entity_type", Column: "t.entity_type", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "entity_id", Column: "t.entity_id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "status", Column: "t.status", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "due_date", Column: "t.due_date", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "overdue_at", Column: "t.overdue_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "escalated_at", Column: "t.escalated_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "created_at", Column: "t.created_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+		querydsl.Field{Name: "updated_at", Column: "t.updated_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+	),
+	"documents": querydsl.NewSchema("documents doc", "doc.id DESC",
+		querydsl.Field{Name: "id", Column: "doc.id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "deal_id", Column: "doc.deal_id", Type: querydsl.TypeInt, Sort: true},
+		querydsl.Field{Name: "number", Column: "doc.number", Type: querydsl.TypeString, Sort: true},
+		querydsl.Field{Name: "doc_type", Column: "doc.doc_type", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "status", Column: "doc.status", Type: querydsl.TypeString, Sort: true, Group: true},
+		querydsl.Field{Name: "signed_at", Column: "doc.signed_at", Type: querydsl.TypeTime, Sort: true, Group: true},
+	),
+}
+
+// ReportBuilderRepository runs the queries of the report builder.
+type ReportBuilderRepository interface {
+	// Schema returns the fields of an entity; nil if the entity is unknown.
+	Schema(entity string) *querydsl.Schema
+	Entities() []string
+	// Run returns the rows of a compiled query; numbers, text, times and booleans come as
+	// int64, float64, string, time.Time and bool.
+	Run(ctx context.Context, st *querydsl.Statement) ([][]interface{}, error)
+	Count(ctx context.Context, st *querydsl.Statement) (int, error)
+}
+
+type reportBuilderRepository struct {
+	db *sql.DB
+}
+
+// NewReportBuilderRepository creates a new instance of ReportBuilderRepository.
+func NewReportBuilderRepository(db *sql.DB) ReportBuilderRepository {
+	return &reportBuilderRepository{db: db}
+}
+
+func (r *reportBuilderRepository) Schema(entity string) *querydsl.Schema {
+	return builderSchemas[entity]
+}
+
+func (r *reportBuilderRepository) Entities() []string {
+	entities := make([]string, 0, len(builderSchemas))
+	for entity := range builderSchemas {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+	return entities
+}
+
+func (r *reportBuilderRepository) Run(ctx context.Context, st *querydsl.Statement) ([][]interface{}, error) {
+	rows, err := r.db.QueryContext(ctx, st.SQL, st.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := [][]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(st.Columns))
+		targets := make([]interface{}, len(values))
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result = append(result, values)
+	}
+	return result, rows.Err()
+}
+
+func (r *reportBuilderRepository) Count(ctx context.Context, st *querydsl.Statement) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, st.CountSQL, st.CountArgs...).Scan(&total)
+	return total, err
+}
@@ -0,0 +1,304 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+)
+
+// ReportRepository runs the aggregate queries of the funnel reports. Filters and groups use
+// the lead's owner and source; deals take them from their lead.
+type ReportRepository interface {
+	LeadStatusCounts(ctx context.Context, f models.ReportFilter) ([]models.FunnelCount, error)
+	Conversion(ctx context.Context, f models.ReportFilter) ([]models.ConversionGroup, error)
+	StageDurations(ctx context.Context, entity string, f models.ReportFilter) ([]models.StageDuration, error)
+	WinLoss(ctx context.Context, f models.ReportFilter) ([]models.WinLossGroup, error)
+	LossReasons(ctx context.Context, f models.ReportFilter) (map[string][]models.LossReasonCount, error)
+	// Revenue sums won deals by the time they were won, per group, bucket and currency.
+	Revenue(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error)
+	// Pipeline sums open deals by departure date, per group, bucket, status and currency.
+	// Deals without a departure date are included with an empty period; From and To apply
+	// to the departure date and are both required to take effect.
+	Pipeline(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error)
+
+	RefreshRollups(ctx context.Context, limit int, now time.Time) (int, error)
+	// RollupLeads counts leads from the daily rollup per bucket of Interval and status.
+	RollupLeads(ctx context.Context, f models.ReportFilter) ([]models.RollupCount, error)
+	// RollupDeals sums deals from the daily rollup per bucket of Interval, status and
+	// currency; the key is "all" and the period is empty without Interval.
+	RollupDeals(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error)
+	RollupState(ctx context.Context) (*models.RollupState, error)
+}
+
+type reportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository creates a new instance of ReportRepository.
+func NewReportRepository(db *sql.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+// reportGroupKey returns the SQL expression of the group key; timeColumn is bucketed when
+// grouping by period. GroupBy and Interval are validated by the service.
+func reportGroupKey(f models.ReportFilter, timeColumn string) string {
+	switch f.GroupBy {
+	case models.ReportGroupOwner:
+		return "l.owner_id::text"
+	case models.ReportGroupSource:
+		return "l.source"
+	case models.ReportGroupPeriod:
+		return fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD')", f.Interval, timeColumn)
+	case models.ReportGroupDestination:
+		return "d.destination"
+	case models.ReportGroupStatus:
+		return "d.status"
+	}
+	return "'all'"
+}
+
+// reportConditions filters by the period of timeColumn and the lead's owner and source. An
+// empty timeColumn leaves the period to the caller.
+func reportConditions(f models.ReportFilter, timeColumn string, args []interface{}) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if f.From != nil && timeColumn != "" {
+		add(timeColumn+" >= $%d", *f.From)
+	}
+	if f.To != nil && timeColumn != "" {
+		add(timeColumn+" < $%d", *f.To)
+	}
+	if f.OwnerID != nil {
+		add("l.owner_id = $%d", *f.OwnerID)
+	}
+	if f.Source != "" {
+		add("l.source = $%d", f.Source)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r *reportRepository) LeadStatusCounts(ctx context.Context, f models.ReportFilter) ([]models.FunnelCount, error) {
+	where, args := reportConditions(f, "l.created_at", nil)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reportGroupKey(f, "l.created_at")+` AS key, l.status, COUNT(*)
+		FROM leads l
+		WHERE `+where+`
+		GROUP BY 1, 2 ORDER BY 1, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FunnelCount{}
+	for rows.Next() {
+		var c models.FunnelCount
+		if err := rows.Scan(&c.Key, &c.Status, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (r *reportRepository) Conversion(ctx context.Context, f models.ReportFilter) ([]models.ConversionGroup, error) {
+	where, args := reportConditions(f, "l.created_at", nil)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reportGroupKey(f, "l.created_at")+` AS key, COUNT(*), COUNT(d.deal_at),
+			AVG(EXTRACT(EPOCH FROM d.deal_at - l.created_at) / 3600)
+		FROM leads l
+		LEFT JOIN LATERAL (SELECT MIN(created_at) AS deal_at FROM deals WHERE lead_id = l.id) d ON TRUE
+		WHERE `+where+`
+		GROUP BY 1 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.ConversionGroup{}
+	for rows.Next() {
+		var g models.ConversionGroup
+		var avg sql.NullFloat64
+		if err := rows.Scan(&g.Key, &g.Leads, &g.Converted, &avg); err != nil {
+			return nil, err
+		}
+		if avg.Valid {
+			g.AvgHoursToDeal = &avg.Float64
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// StageDurations rebuilds the stays in each status from the status change events. A lead or
+// deal starts in the status its first change left (its current status if it never changed)
+// at its creation time; each change ends one stay and starts the next.
+func (r *reportRepository) StageDurations(ctx context.Context, entity string, f models.ReportFilter) ([]models.StageDuration, error) {
+	table, activityType, join := "leads", models.ActivityLeadStatusChanged, "JOIN leads l ON l.id = s.id"
+	if entity == models.EntityTypeDeal {
+		table, activityType, join = "deals", models.ActivityDealStatusChanged,
+			"JOIN deals d ON d.id = s.id JOIN leads l ON l.id = d.lead_id"
+	}
+
+	where, args := reportConditions(f, "s.entered_at", []interface{}{activityType, entity})
+	rows, err := r.db.QueryContext(ctx, `
+		WITH changes AS (
+			SELECT a.entity_id AS id, a.created_at AS entered_at, a.details->>'to' AS status
+			FROM activities a
+			WHERE a.type = $1 AND a.entity_type = $2
+			UNION ALL
+			SELECT x.id, x.created_at, COALESCE((
+				SELECT a.details->>'from' FROM activities a
+				WHERE a.type = $1 AND a.entity_type = $2 AND a.entity_id = x.id
+				ORDER BY a.created_at, a.id LIMIT 1), x.status)
+			FROM `+table+` x
+		), stays AS (
+			SELECT id, status, entered_at, LEAD(entered_at) OVER (PARTITION BY id ORDER BY entered_at) AS left_at
+			FROM changes
+		)
+		SELECT `+reportGroupKey(f, "s.entered_at")+` AS key, s.status, COUNT(s.left_at),
+			AVG(EXTRACT(EPOCH FROM s.left_at - s.entered_at) / 3600),
+			COUNT(*) - COUNT(s.left_at)
+		FROM stays s `+join+`
+		WHERE `+where+`
+		GROUP BY 1, 2 ORDER BY 1, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stages := []models.StageDuration{}
+	for rows.Next() {
+		var st models.StageDuration
+		var avg sql.NullFloat64
+		if err := rows.Scan(&st.Key, &st.Status, &st.Completed, &avg, &st.Current); err != nil {
+			return nil, err
+		}
+		if avg.Valid {
+			st.AvgHours = &avg.Float64
+		}
+		stages = append(stages, st)
+	}
+	return stages, rows.Err()
+}
+
+func (r *reportRepository) WinLoss(ctx context.Context, f models.ReportFilter) ([]models.WinLossGroup, error) {
+	where, args := reportConditions(f, "d.created_at", []interface{}{models.DealStatusWon, models.DealStatusLost})
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reportGroupKey(f, "d.created_at")+` AS key,
+			COUNT(*) FILTER (WHERE d.status = $1),
+			COUNT(*) FILTER (WHERE d.status = $2),
+			COUNT(*) FILTER (WHERE d.status NOT IN ($1, $2))
+		FROM deals d JOIN leads l ON l.id = d.lead_id
+		WHERE `+where+`
+		GROUP BY 1 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.WinLossGroup{}
+	for rows.Next() {
+		var g models.WinLossGroup
+		if err := rows.Scan(&g.Key, &g.Won, &g.Lost, &g.Open); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// LossReasons returns the reasons of lost deals per group, the most frequent first.
+func (r *reportRepository) LossReasons(ctx context.Context, f models.ReportFilter) (map[string][]models.LossReasonCount, error) {
+	where, args := reportConditions(f, "d.created_at", []interface{}{models.DealStatusLost})
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reportGroupKey(f, "d.created_at")+` AS key, TRIM(d.loss_reason), COUNT(*)
+		FROM deals d JOIN leads l ON l.id = d.lead_id
+		WHERE d.status = $1 AND `+where+`
+		GROUP BY 1, 2 ORDER BY 1, 3 DESC, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := map[string][]models.LossReasonCount{}
+	for rows.Next() {
+		var key string
+		var rc models.LossReasonCount
+		if err := rows.Scan(&key, &rc.Reason, &rc.Count); err != nil {
+			return nil, err
+		}
+		reasons[key] = append(reasons[key], rc)
+	}
+	return reasons, rows.Err()
+}
+
+// dealAmountSQL reads the text amount of a deal; spaces and a decimal comma are allowed,
+// anything else counts as zero.
+const dealAmountSQL = `CASE WHEN replace(replace(d.amount, ' ', ''), ',', '.') ~ '^-?[0-9]+(\.[0-9]+)?$'
+	THEN replace(replace(d.amount, ' ', ''), ',', '.')::numeric ELSE 0 END`
+
+// reportBucket returns the start of the UTC time bucket of column as yyyy-mm-dd. The interval
+// is validated by the service.
+func reportBucket(interval, column string) string {
+	return fmt.Sprintf("to_char(date_trunc('%s', %s AT TIME ZONE 'UTC'), 'YYYY-MM-DD')", interval, column)
+}
+
+func (r *reportRepository) Revenue(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error) {
+	args := []interface{}{models.ActivityDealStatusChanged, models.EntityTypeDeal, models.DealStatusWon}
+	where, args := reportConditions(f, "w.won_at", args)
+	return r.dealAmounts(ctx, `
+		SELECT COALESCE(`+reportGroupKey(f, "w.won_at")+`, '') AS key, `+reportBucket(f.Interval, "w.won_at")+`,
+			d.status, UPPER(TRIM(d.currency)), COUNT(*), SUM(`+dealAmountSQL+`)::float8
+		FROM deals d
+		LEFT JOIN leads l ON l.id = d.lead_id
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(MAX(a.created_at), d.created_at) AS won_at FROM activities a
+			WHERE a.type = $1 AND a.entity_type = $2 AND a.entity_id = d.id AND a.details->>'to' = $3
+		) w
+		WHERE d.status = $3 AND `+where+`
+		GROUP BY 1, 2, 3, 4`, args...)
+}
+
+func (r *reportRepository) Pipeline(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error) {
+	where, args := reportConditions(f, "", []interface{}{models.DealStatusWon, models.DealStatusLost})
+	period := "TRUE"
+	if f.From != nil && f.To != nil {
+		args = append(args, *f.From, *f.To)
+		period = fmt.Sprintf("d.departure_date IS NULL OR (d.departure_date >= $%d AND d.departure_date < $%d)",
+			len(args)-1, len(args))
+	}
+	return r.dealAmounts(ctx, `
+		SELECT COALESCE(`+reportGroupKey(f, "d.departure_date")+`, '') AS key,
+			COALESCE(`+reportBucket(f.Interval, "d.departure_date")+`, ''),
+			d.status, UPPER(TRIM(d.currency)), COUNT(*), SUM(`+dealAmountSQL+`)::float8
+		FROM deals d
+		LEFT JOIN leads l ON l.id = d.lead_id
+		WHERE COALESCE(d.status, '') NOT IN ($1, $2) AND (`+period+`) AND `+where+`
+		GROUP BY 1, 2, 3, 4`, args...)
+}
+
+func (r *reportRepository) dealAmounts(ctx context.Context, query string, args ...interface{}) ([]models.DealAmount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amounts := []models.DealAmount{}
+	for rows.Next() {
+		var a models.DealAmount
+		var status sql.NullString
+		if err := rows.Scan(&a.Key, &a.Period, &status, &a.Currency, &a.Deals, &a.Amount); err != nil {
+			return nil, err
+		}
+		a.Status = status.String
+		amounts = append(amounts, a)
+	}
+	return amounts, rows.Err()
+}
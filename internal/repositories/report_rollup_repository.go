@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// The daily rollups (report_lead_daily, report_deal_daily) hold the leads and deals created
+// each day by owner, source and current status. Triggers on leads and deals queue the days
+// they change in report_rollup_queue; RefreshRollups rebuilds the queued days.
+
+// RefreshRollups rebuilds the rollups of up to limit queued days and returns their number.
+// Days queued by transactions that have not committed yet are left for the next call.
+func (r *reportRepository) RefreshRollups(ctx context.Context, limit int, now time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT entity, day FROM report_rollup_queue
+		ORDER BY day
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, err
+	}
+	days := map[string][]string{}
+	n := 0
+	for rows.Next() {
+		var entity string
+		var day time.Time
+		if err := rows.Scan(&entity, &day); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		days[entity] = append(days[entity], day.Format("2006-01-02"))
+		n++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	steps := []struct {
+		entity string
+		query  string
+	}{
+		{models.EntityTypeLead, `DELETE FROM report_lead_daily WHERE day = ANY($1::date[])`},
+		{models.EntityTypeLead, `
+			INSERT INTO report_lead_daily (day, owner_id, source, status, leads, converted)
+			SELECT q.day, l.owner_id, COALESCE(l.source, ''), COALESCE(l.status, ''), COUNT(*),
+				COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM deals d WHERE d.lead_id = l.id))
+			FROM unnest($1::date[]) AS q(day)
+			JOIN leads l ON l.created_at >= q.day AND l.created_at < q.day + 1
+			GROUP BY 1, 2, 3, 4`},
+		{models.EntityTypeDeal, `DELETE FROM report_deal_daily WHERE day = ANY($1::date[])`},
+		{models.EntityTypeDeal, `
+			INSERT INTO report_deal_daily (day, owner_id, source, status, currency, deals, amount)
+			SELECT q.day, COALESCE(l.owner_id, 0), COALESCE(l.source, ''), COALESCE(d.status, ''),
+				UPPER(TRIM(d.currency)), COUNT(*), SUM(` + dealAmountSQL + `)
+			FROM unnest($1::date[]) AS q(day)
+			JOIN deals d ON d.created_at >= q.day::timestamp AT TIME ZONE 'UTC'
+				AND d.created_at < (q.day + 1)::timestamp AT TIME ZONE 'UTC'
+			LEFT JOIN leads l ON l.id = d.lead_id
+			GROUP BY 1, 2, 3, 4, 5`},
+	}
+	for _, step := range steps {
+		if len(days[step.entity]) == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, step.query, pq.Array(days[step.entity])); err != nil {
+			return 0, fmt.Errorf("refresh %s rollup: %w", step.entity, err)
+		}
+	}
+	for entity, list := range days {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM report_rollup_queue WHERE entity = $1 AND day = ANY($2::date[])`,
+			entity, pq.Array(list)); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO report_rollup_state (id, refreshed_at) VALUES (TRUE, $1)
+		ON CONFLICT (id) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`, now); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// rollupConditions filters a rollup table by day, owner and source.
+func rollupConditions(f models.ReportFilter) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if f.From != nil {
+		add("r.day >= $%d::date", f.From.UTC().Format("2006-01-02"))
+	}
+	if f.To != nil {
+		add("r.day < $%d::date", f.To.UTC().Format("2006-01-02"))
+	}
+	if f.OwnerID != nil {
+		add("r.owner_id = $%d", *f.OwnerID)
+	}
+	if f.Source != "" {
+		add("r.source = $%d", f.Source)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// rollupBucket returns the bucket of the rollup day as yyyy-mm-dd; empty without an interval.
+func rollupBucket(interval string) string {
+	if interval == "" {
+		return "''"
+	}
+	return fmt.Sprintf("to_char(date_trunc('%s', r.day), 'YYYY-MM-DD')", interval)
+}
+
+func (r *reportRepository) RollupLeads(ctx context.Context, f models.ReportFilter) ([]models.RollupCount, error) {
+	where, args := rollupConditions(f)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+rollupBucket(f.Interval)+`, r.status, SUM(r.leads), SUM(r.converted)
+		FROM report_lead_daily r
+		WHERE `+where+`
+		GROUP BY 1, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.RollupCount{}
+	for rows.Next() {
+		var c models.RollupCount
+		if err := rows.Scan(&c.Period, &c.Status, &c.Leads, &c.Converted); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (r *reportRepository) RollupDeals(ctx context.Context, f models.ReportFilter) ([]models.DealAmount, error) {
+	where, args := rollupConditions(f)
+	return r.dealAmounts(ctx, `
+		SELECT 'all', `+rollupBucket(f.Interval)+`, r.status, r.currency, SUM(r.deals), SUM(r.amount)::float8
+		FROM report_deal_daily r
+		WHERE `+where+`
+		GROUP BY 2, 3, 4`, args...)
+}
+
+func (r *reportRepository) RollupState(ctx context.Context) (*models.RollupState, error) {
+	var state models.RollupState
+	var refreshed sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT (SELECT refreshed_at FROM report_rollup_state), (SELECT COUNT(*) FROM report_rollup_queue)`,
+	).Scan(&refreshed, &state.PendingDays)
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.Valid {
+		state.RefreshedAt = &refreshed.Time
+	}
+	return &state, nil
+}
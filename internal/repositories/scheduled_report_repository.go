@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ScheduledReportRepository stores saved reports and their delivery history.
+type ScheduledReportRepository interface {
+	Create(ctx context.Context, r *models.ScheduledReport) error
+	Update(ctx context.Context, r *models.ScheduledReport) error
+	FindByID(ctx context.Context, id int64) (*models.ScheduledReport, error)
+	List(ctx context.Context) ([]models.ScheduledReport, error)
+	Delete(ctx context.Context, id int64) error
+	// ListDue returns the active reports whose next run is not after now.
+	ListDue(ctx context.Context, now time.Time) ([]models.ScheduledReport, error)
+	// Advance moves the next run of a report from the one the caller saw to next; false
+	// means another pass already did it. A non-empty lastError deactivates the report.
+	Advance(ctx context.Context, id int64, seen time.Time, next *time.Time, ranAt time.Time, lastError string) (bool, error)
+	CreateDelivery(ctx context.Context, d *models.ReportDelivery) error
+	ListDeliveries(ctx context.Context, reportID int64, limit, offset int) ([]models.ReportDelivery, error)
+	CountDeliveries(ctx context.Context, reportID int64) (int, error)
+}
+
+type scheduledReportRepository struct {
+	db *sql.DB
+}
+
+// NewScheduledReportRepository creates a new instance of ScheduledReportRepository.
+func NewScheduledReportRepository(db *sql.DB) ScheduledReportRepository {
+	return &scheduledReportRepository{db: db}
+}
+
+const scheduledReportColumns = `id, name, report_type, params, format, lang, recipients, schedule, timezone,
+	active, COALESCE(created_by, 0), next_run_at, last_run_at, last_error, created_at, updated_at`
+
+func (r *scheduledReportRepository) Create(ctx context.Context, report *models.ScheduledReport) error {
+	params, err := json.Marshal(report.Params)
+	if err != nil {
+		return err
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_reports (name, report_type, params, format, lang, recipients, schedule, timezone,
+			active, created_by, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0), $11, $12, $13)
+		RETURNING id`,
+		report.Name, report.ReportType, params, report.Format, report.Lang, pq.Array(report.Recipients),
+		report.Schedule, report.Timezone, report.Active, report.CreatedBy, report.NextRunAt,
+		report.CreatedAt, report.UpdatedAt,
+	).Scan(&report.ID)
+	if err != nil {
+		return fmt.Errorf("store scheduled report: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduledReportRepository) Update(ctx context.Context, report *models.ScheduledReport) error {
+	params, err := json.Marshal(report.Params)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE scheduled_reports
+		SET name = $2, report_type = $3, params = $4, format = $5, lang = $6, recipients = $7, schedule = $8,
+			timezone = $9, active = $10, next_run_at = $11, last_error = $12, updated_at = $13
+		WHERE id = $1`,
+		report.ID, report.Name, report.ReportType, params, report.Format, report.Lang, pq.Array(report.Recipients),
+		report.Schedule, report.Timezone, report.Active, report.NextRunAt, report.LastError, report.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("update scheduled report: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the report; nil if it does not exist.
+func (r *scheduledReportRepository) FindByID(ctx context.Context, id int64) (*models.ScheduledReport, error) {
+	reports, err := r.find(ctx, `WHERE id = $1`, id)
+	if err != nil || len(reports) == 0 {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+func (r *scheduledReportRepository) List(ctx context.Context) ([]models.ScheduledReport, error) {
+	return r.find(ctx, `ORDER BY id`)
+}
+
+func (r *scheduledReportRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM scheduled_reports WHERE id = $1`, id)
+	return err
+}
+
+func (r *scheduledReportRepository) ListDue(ctx context.Context, now time.Time) ([]models.ScheduledReport, error) {
+	return r.find(ctx, `WHERE active AND next_run_at <= $1 ORDER BY next_run_at`, now)
+}
+
+func (r *scheduledReportRepository) Advance(ctx context.Context, id int64, seen time.Time, next *time.Time, ranAt time.Time, lastError string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE scheduled_reports
+		SET next_run_at = $3, last_run_at = $4, last_error = $5, active = active AND $5 = ''
+		WHERE id = $1 AND next_run_at = $2`, id, seen, next, ranAt, lastError)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *scheduledReportRepository) find(ctx context.Context, rest string, args ...interface{}) ([]models.ScheduledReport, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+scheduledReportColumns+` FROM scheduled_reports `+rest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.ScheduledReport{}
+	for rows.Next() {
+		var report models.ScheduledReport
+		var params []byte
+		var nextRun, lastRun sql.NullTime
+		if err := rows.Scan(&report.ID, &report.Name, &report.ReportType, &params, &report.Format, &report.Lang,
+			pq.Array(&report.Recipients), &report.Schedule, &report.Timezone, &report.Active, &report.CreatedBy,
+			&nextRun, &lastRun, &report.LastError, &report.CreatedAt, &report.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(params, &report.Params); err != nil {
+			return nil, err
+		}
+		if nextRun.Valid {
+			report.NextRunAt = &nextRun.Time
+		}
+		if lastRun.Valid {
+			report.LastRunAt = &lastRun.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func (r *scheduledReportRepository) CreateDelivery(ctx context.Context, d *models.ReportDelivery) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_report_deliveries (report_id, trigger, status, recipients, file_name, file_size,
+			error, triggered_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9)
+		RETURNING id`,
+		d.ReportID, d.Trigger, d.Status, pq.Array(d.Recipients), d.FileName, d.FileSize, d.Error,
+		d.TriggeredBy, d.CreatedAt,
+	).Scan(&d.ID)
+	if err != nil {
+		return fmt.Errorf("store report delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the deliveries of a report, the latest first.
+func (r *scheduledReportRepository) ListDeliveries(ctx context.Context, reportID int64, limit, offset int) ([]models.ReportDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, report_id, trigger, status, recipients, file_name, file_size, error,
+			COALESCE(triggered_by, 0), created_at
+		FROM scheduled_report_deliveries
+		WHERE report_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3`, reportID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.ReportDelivery{}
+	for rows.Next() {
+		var d models.ReportDelivery
+		if err := rows.Scan(&d.ID, &d.ReportID, &d.Trigger, &d.Status, pq.Array(&d.Recipients), &d.FileName,
+			&d.FileSize, &d.Error, &d.TriggeredBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *scheduledReportRepository) CountDeliveries(ctx context.Context, reportID int64) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM scheduled_report_deliveries WHERE report_id = $1`, reportID,
+	).Scan(&total)
+	return total, err
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"time"
 	"turcompany/internal/models"
 )
 
@@ -15,9 +16,9 @@ func NewSMSConfirmationRepository(db *sql.DB) *SMSConfirmationRepository {
 }
 
 func (r *SMSConfirmationRepository) Create(sms *models.SMSConfirmation) (int64, error) {
-	query := `INSERT INTO sms_confirmations (document_id, phone, sms_code, sent_at, confirmed, confirmed_at)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	err := r.DB.QueryRow(query, sms.DocumentID, sms.Phone, sms.SMSCode, sms.SentAt, sms.Confirmed, sms.ConfirmedAt).Scan(&sms.ID)
+	query := `INSERT INTO sms_confirmations (document_id, phone, sms_code, code_salt, attempts, sent_at, confirmed, confirmed_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err := r.DB.QueryRow(query, sms.DocumentID, sms.Phone, sms.SMSCode, sms.CodeSalt, sms.Attempts, sms.SentAt, sms.Confirmed, sms.ConfirmedAt).Scan(&sms.ID)
 	if err != nil {
 		return 0, fmt.Errorf("create sms confirmation: %w", err)
 	}
@@ -25,12 +26,12 @@ func (r *SMSConfirmationRepository) Create(sms *models.SMSConfirmation) (int64,
 }
 
 func (r *SMSConfirmationRepository) GetByID(id int64) (*models.SMSConfirmation, error) {
-	query := `SELECT id, document_id, phone, sms_code, sent_at, confirmed, confirmed_at
+	query := `SELECT id, document_id, phone, sms_code, COALESCE(code_salt, ''), attempts, sent_at, confirmed, confirmed_at
               FROM sms_confirmations WHERE id = $1`
 	row := r.DB.QueryRow(query, id)
 
 	var sms models.SMSConfirmation
-	err := row.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
+	err := row.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.CodeSalt, &sms.Attempts, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -42,14 +43,14 @@ func (r *SMSConfirmationRepository) GetByID(id int64) (*models.SMSConfirmation,
 }
 
 func (r *SMSConfirmationRepository) GetLatestByDocumentID(documentID int64) (*models.SMSConfirmation, error) {
-	query := `SELECT id, document_id, phone, sms_code, sent_at, confirmed, confirmed_at
+	query := `SELECT id, document_id, phone, sms_code, COALESCE(code_salt, ''), attempts, sent_at, confirmed, confirmed_at
               FROM sms_confirmations
               WHERE document_id = $1
               ORDER BY sent_at DESC LIMIT 1`
 	row := r.DB.QueryRow(query, documentID)
 
 	var sms models.SMSConfirmation
-	err := row.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
+	err := row.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.CodeSalt, &sms.Attempts, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -62,43 +63,62 @@ func (r *SMSConfirmationRepository) GetLatestByDocumentID(documentID int64) (*mo
 
 func (r *SMSConfirmationRepository) Update(sms *models.SMSConfirmation) error {
 	query := `UPDATE sms_confirmations
-              SET document_id = $1, phone = $2, sms_code = $3, sent_at = $4, confirmed = $5, confirmed_at = $6
-              WHERE id = $7`
-	_, err := r.DB.Exec(query, sms.DocumentID, sms.Phone, sms.SMSCode, sms.SentAt, sms.Confirmed, sms.ConfirmedAt, sms.ID)
+              SET document_id = $1, phone = $2, sms_code = $3, code_salt = $4, attempts = $5, sent_at = $6, confirmed = $7, confirmed_at = $8
+              WHERE id = $9`
+	_, err := r.DB.Exec(query, sms.DocumentID, sms.Phone, sms.SMSCode, sms.CodeSalt, sms.Attempts, sms.SentAt, sms.Confirmed, sms.ConfirmedAt, sms.ID)
 	if err != nil {
 		return fmt.Errorf("update sms confirmation: %w", err)
 	}
 	return nil
 }
 
-func (r *SMSConfirmationRepository) Delete(id int64) error {
-	query := `DELETE FROM sms_confirmations WHERE id = $1`
-	_, err := r.DB.Exec(query, id)
+// MarkConfirmed подтверждает код, только если он ещё не был подтверждён и попытка, в
+// которой его проверили, уложилась в maxAttempts (0 — без ограничения).
+// Возвращает false, если код успели использовать или заблокировать параллельно.
+func (r *SMSConfirmationRepository) MarkConfirmed(id int64, confirmedAt time.Time, maxAttempts int) (bool, error) {
+	query := `UPDATE sms_confirmations SET confirmed = TRUE, confirmed_at = $1
+              WHERE id = $2 AND confirmed = FALSE AND ($3 = 0 OR attempts <= $3)`
+	result, err := r.DB.Exec(query, confirmedAt, id, maxAttempts)
 	if err != nil {
-		return fmt.Errorf("delete sms confirmation: %w", err)
+		return false, fmt.Errorf("confirm sms: %w", err)
 	}
-	return nil
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("confirm sms: %w", err)
+	}
+	return affected > 0, nil
 }
 
-func (r *SMSConfirmationRepository) GetByDocumentIDAndCode(documentID int64, code string) (*models.SMSConfirmation, error) {
-	query := `SELECT id, document_id, phone, sms_code, sent_at, confirmed, confirmed_at
-	          FROM sms_confirmations
-	          WHERE document_id = $1 AND sms_code = $2`
-	row := r.DB.QueryRow(query, documentID, code)
+// ClaimAttempt засчитывает попытку ввода кода до сравнения и возвращает её номер.
+// false — попытки исчерпаны (maxAttempts, 0 — без ограничения) или код уже подтверждён;
+// проверка и увеличение счётчика выполняются одним запросом, поэтому параллельные
+// попытки не обходят ограничение.
+func (r *SMSConfirmationRepository) ClaimAttempt(id int64, maxAttempts int) (int, bool, error) {
+	query := `UPDATE sms_confirmations SET attempts = attempts + 1
+              WHERE id = $1 AND confirmed = FALSE AND ($2 = 0 OR attempts < $2)
+              RETURNING attempts`
+	var attempts int
+	err := r.DB.QueryRow(query, id, maxAttempts).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("claim sms attempt: %w", err)
+	}
+	return attempts, true, nil
+}
 
-	var sms models.SMSConfirmation
-	err := row.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
+func (r *SMSConfirmationRepository) Delete(id int64) error {
+	query := `DELETE FROM sms_confirmations WHERE id = $1`
+	_, err := r.DB.Exec(query, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("get sms by doc and code: %w", err)
+		return fmt.Errorf("delete sms confirmation: %w", err)
 	}
-	return &sms, nil
+	return nil
 }
 
 func (r *SMSConfirmationRepository) GetUnconfirmedByDocumentID(documentID int64) ([]*models.SMSConfirmation, error) {
-	query := `SELECT id, document_id, phone, sms_code, sent_at, confirmed, confirmed_at
+	query := `SELECT id, document_id, phone, sms_code, COALESCE(code_salt, ''), attempts, sent_at, confirmed, confirmed_at
 	          FROM sms_confirmations
 	          WHERE document_id = $1 AND confirmed = false`
 	rows, err := r.DB.Query(query, documentID)
@@ -110,7 +130,7 @@ func (r *SMSConfirmationRepository) GetUnconfirmedByDocumentID(documentID int64)
 	var confirmations []*models.SMSConfirmation
 	for rows.Next() {
 		var sms models.SMSConfirmation
-		err := rows.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
+		err := rows.Scan(&sms.ID, &sms.DocumentID, &sms.Phone, &sms.SMSCode, &sms.CodeSalt, &sms.Attempts, &sms.SentAt, &sms.Confirmed, &sms.ConfirmedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scan unconfirmed sms: %w", err)
 		}
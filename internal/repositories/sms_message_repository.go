@@ -0,0 +1,222 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSMSCooldown   = errors.New("sms cooldown is active for the document")
+	ErrSMSDailyLimit = errors.New("daily sms limit reached for the recipient")
+)
+
+// SMSSendLimits ограничения, которые CreateWithinLimits проверяет вместе с записью в журнал.
+// Нулевые значения отключают соответствующее ограничение.
+type SMSSendLimits struct {
+	// CooldownSince — по документу не должно быть SMS, созданных после этого момента.
+	CooldownSince time.Time
+	// DailyLimit — сколько SMS на номер допускается начиная с DailySince.
+	DailyLimit int
+	DailySince time.Time
+}
+
+type SMSMessageRepository struct {
+	DB *sql.DB
+}
+
+func NewSMSMessageRepository(db *sql.DB) *SMSMessageRepository {
+	return &SMSMessageRepository{DB: db}
+}
+
+const smsMessageColumns = `id, document_id, COALESCE(provider, ''), COALESCE(message_id, ''), recipient, template,
+              cost, status, COALESCE(error, ''), created_at, updated_at`
+
+const insertSMSMessageQuery = `INSERT INTO sms_messages (document_id, provider, message_id, recipient, template, cost, status, error, created_at, updated_at)
+              VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, ''), $9, $10) RETURNING id`
+
+func (r *SMSMessageRepository) Create(msg *models.SMSMessage) (int64, error) {
+	err := r.DB.QueryRow(insertSMSMessageQuery,
+		msg.DocumentID, msg.Provider, msg.MessageID, msg.Recipient, msg.Template,
+		msg.Cost, msg.Status, msg.Error, msg.CreatedAt, msg.UpdatedAt,
+	).Scan(&msg.ID)
+	if err != nil {
+		return 0, fmt.Errorf("create sms message: %w", err)
+	}
+	return msg.ID, nil
+}
+
+// CreateWithinLimits записывает сообщение в журнал, только если это не нарушает limits.
+// Журнал не редактируется задним числом, поэтому по нему и считаются лимиты. Проверка
+// и вставка идут в одной транзакции под advisory-блокировками документа и номера,
+// так что параллельные запросы не могут вместе превысить лимит.
+func (r *SMSMessageRepository) CreateWithinLimits(msg *models.SMSMessage, limits SMSSendLimits) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin sms message: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировки берутся всегда в одном порядке: сначала документ, потом номер.
+	if msg.DocumentID != nil {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('sms_document:' || $1::text))`, *msg.DocumentID); err != nil {
+			return 0, fmt.Errorf("lock sms document: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('sms_recipient:' || $1))`, msg.Recipient); err != nil {
+		return 0, fmt.Errorf("lock sms recipient: %w", err)
+	}
+
+	if msg.DocumentID != nil && !limits.CooldownSince.IsZero() {
+		var recent bool
+		query := `SELECT EXISTS (SELECT 1 FROM sms_messages WHERE document_id = $1 AND created_at > $2)`
+		if err := tx.QueryRow(query, *msg.DocumentID, limits.CooldownSince).Scan(&recent); err != nil {
+			return 0, fmt.Errorf("check sms cooldown: %w", err)
+		}
+		if recent {
+			return 0, ErrSMSCooldown
+		}
+	}
+
+	if limits.DailyLimit > 0 {
+		var sent int
+		query := `SELECT COUNT(*) FROM sms_messages WHERE recipient = $1 AND created_at >= $2`
+		if err := tx.QueryRow(query, msg.Recipient, limits.DailySince).Scan(&sent); err != nil {
+			return 0, fmt.Errorf("count sms by recipient: %w", err)
+		}
+		if sent >= limits.DailyLimit {
+			return 0, ErrSMSDailyLimit
+		}
+	}
+
+	err = tx.QueryRow(insertSMSMessageQuery,
+		msg.DocumentID, msg.Provider, msg.MessageID, msg.Recipient, msg.Template,
+		msg.Cost, msg.Status, msg.Error, msg.CreatedAt, msg.UpdatedAt,
+	).Scan(&msg.ID)
+	if err != nil {
+		return 0, fmt.Errorf("create sms message: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit sms message: %w", err)
+	}
+	return msg.ID, nil
+}
+
+// UpdateDelivery сохраняет результат отправки или опроса статуса.
+func (r *SMSMessageRepository) UpdateDelivery(msg *models.SMSMessage) error {
+	query := `UPDATE sms_messages
+              SET provider = NULLIF($1, ''), message_id = NULLIF($2, ''), cost = COALESCE($3, cost),
+                  status = $4, error = NULLIF($5, ''), updated_at = $6
+              WHERE id = $7`
+	_, err := r.DB.Exec(query, msg.Provider, msg.MessageID, msg.Cost, msg.Status, msg.Error, msg.UpdatedAt, msg.ID)
+	if err != nil {
+		return fmt.Errorf("update sms message: %w", err)
+	}
+	return nil
+}
+
+// ListPending возвращает сообщения, доставка которых ещё не завершена,
+// отправленные не раньше since. Первыми идут сообщения, которые дольше всех
+// не опрашивались, затем никогда не опрошенные по времени отправки.
+func (r *SMSMessageRepository) ListPending(since time.Time, limit int) ([]*models.SMSMessage, error) {
+	query := `SELECT ` + smsMessageColumns + `
+              FROM sms_messages
+              WHERE status IN ('queued', 'sent') AND message_id IS NOT NULL AND created_at >= $1
+              ORDER BY polled_at NULLS FIRST, created_at
+              LIMIT $2`
+	rows, err := r.DB.Query(query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending sms messages: %w", err)
+	}
+	defer rows.Close()
+	return scanSMSMessages(rows)
+}
+
+// MarkPolled запоминает время последнего опроса статуса сообщений.
+func (r *SMSMessageRepository) MarkPolled(ids []int64, at time.Time) error {
+	query := `UPDATE sms_messages SET polled_at = $1 WHERE id = ANY($2)`
+	if _, err := r.DB.Exec(query, at, pq.Array(ids)); err != nil {
+		return fmt.Errorf("mark sms messages polled: %w", err)
+	}
+	return nil
+}
+
+func (r *SMSMessageRepository) List(filter models.SMSMessageFilter, limit, offset int) ([]*models.SMSMessage, error) {
+	query := `SELECT ` + smsMessageColumns + ` FROM sms_messages`
+
+	conditions := []string{}
+	args := []interface{}{}
+	argID := 1
+
+	if filter.Recipient != nil {
+		conditions = append(conditions, fmt.Sprintf("recipient = $%d", argID))
+		args = append(args, *filter.Recipient)
+		argID++
+	}
+	if filter.DocumentID != nil {
+		conditions = append(conditions, fmt.Sprintf("document_id = $%d", argID))
+		args = append(args, *filter.DocumentID)
+		argID++
+	}
+	if filter.Provider != nil {
+		conditions = append(conditions, fmt.Sprintf("provider = $%d", argID))
+		args = append(args, *filter.Provider)
+		argID++
+	}
+	if filter.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argID))
+		args = append(args, *filter.Status)
+		argID++
+	}
+	if filter.From != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argID))
+		args = append(args, *filter.From)
+		argID++
+	}
+	if filter.To != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argID))
+		args = append(args, *filter.To)
+		argID++
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argID, argID+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list sms messages: %w", err)
+	}
+	defer rows.Close()
+	return scanSMSMessages(rows)
+}
+
+func scanSMSMessages(rows *sql.Rows) ([]*models.SMSMessage, error) {
+	messages := []*models.SMSMessage{}
+	for rows.Next() {
+		var msg models.SMSMessage
+		var documentID sql.NullInt64
+		var cost sql.NullFloat64
+		if err := rows.Scan(
+			&msg.ID, &documentID, &msg.Provider, &msg.MessageID, &msg.Recipient, &msg.Template,
+			&cost, &msg.Status, &msg.Error, &msg.CreatedAt, &msg.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan sms message: %w", err)
+		}
+		if documentID.Valid {
+			msg.DocumentID = &documentID.Int64
+		}
+		if cost.Valid {
+			msg.Cost = &cost.Float64
+		}
+		messages = append(messages, &msg)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"turcompany/internal/models"
+)
+
+// TaskActivityRepository reads the task activity log. Entries are written by
+// TaskRepository.Update together with the change they describe.
+type TaskActivityRepository interface {
+	ListByTask(ctx context.Context, taskID int64) ([]models.TaskActivity, error)
+}
+
+type taskActivityRepository struct {
+	db *sql.DB
+}
+
+// NewTaskActivityRepository creates a new instance of TaskActivityRepository.
+func NewTaskActivityRepository(db *sql.DB) TaskActivityRepository {
+	return &taskActivityRepository{db: db}
+}
+
+func insertTaskActivity(ctx context.Context, q queryRower, a *models.TaskActivity) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO task_activity (task_id, actor_id, field, old_value, new_value, created_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6)
+		RETURNING id`, a.TaskID, a.ActorID, a.Field, a.OldValue, a.NewValue, a.CreatedAt,
+	).Scan(&a.ID)
+}
+
+// ListByTask returns the task's activity, oldest first.
+func (r *taskActivityRepository) ListByTask(ctx context.Context, taskID int64) ([]models.TaskActivity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, task_id, COALESCE(actor_id, 0), field, old_value, new_value, created_at
+		FROM task_activity WHERE task_id = $1 ORDER BY id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := []models.TaskActivity{}
+	for rows.Next() {
+		var a models.TaskActivity
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.ActorID, &a.Field, &a.OldValue, &a.NewValue, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
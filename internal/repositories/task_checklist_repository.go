@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"turcompany/internal/models"
+)
+
+// TaskChecklistRepository defines the interface for database operations on task checklists.
+type TaskChecklistRepository interface {
+	Create(ctx context.Context, item *models.TaskChecklistItem) error
+	FindByID(ctx context.Context, id int64) (*models.TaskChecklistItem, error)
+	ListByTask(ctx context.Context, taskID int64) ([]models.TaskChecklistItem, error)
+	Update(ctx context.Context, item *models.TaskChecklistItem) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type taskChecklistRepository struct {
+	db *sql.DB
+}
+
+// NewTaskChecklistRepository creates a new instance of TaskChecklistRepository.
+func NewTaskChecklistRepository(db *sql.DB) TaskChecklistRepository {
+	return &taskChecklistRepository{db: db}
+}
+
+const taskChecklistColumns = `id, task_id, position, title, done, done_at, created_at`
+
+func scanTaskChecklistItem(row rowScanner, item *models.TaskChecklistItem) error {
+	return row.Scan(&item.ID, &item.TaskID, &item.Position, &item.Title, &item.Done, &item.DoneAt, &item.CreatedAt)
+}
+
+// Create appends the item to the end of the task's checklist.
+func (r *taskChecklistRepository) Create(ctx context.Context, item *models.TaskChecklistItem) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO task_checklist_items (task_id, position, title, created_at)
+		VALUES ($1, (SELECT COALESCE(MAX(position) + 1, 0) FROM task_checklist_items WHERE task_id = $1), $2, $3)
+		RETURNING id, position`, item.TaskID, item.Title, item.CreatedAt,
+	).Scan(&item.ID, &item.Position)
+}
+
+// FindByID returns the item; nil if it does not exist.
+func (r *taskChecklistRepository) FindByID(ctx context.Context, id int64) (*models.TaskChecklistItem, error) {
+	item := &models.TaskChecklistItem{}
+	err := scanTaskChecklistItem(r.db.QueryRowContext(ctx,
+		`SELECT `+taskChecklistColumns+` FROM task_checklist_items WHERE id = $1`, id), item)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *taskChecklistRepository) ListByTask(ctx context.Context, taskID int64) ([]models.TaskChecklistItem, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+taskChecklistColumns+` FROM task_checklist_items WHERE task_id = $1 ORDER BY position, id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.TaskChecklistItem{}
+	for rows.Next() {
+		var item models.TaskChecklistItem
+		if err := scanTaskChecklistItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *taskChecklistRepository) Update(ctx context.Context, item *models.TaskChecklistItem) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE task_checklist_items SET title = $2, done = $3, done_at = $4, position = $5 WHERE id = $1`,
+		item.ID, item.Title, item.Done, item.DoneAt, item.Position)
+	return err
+}
+
+func (r *taskChecklistRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_checklist_items WHERE id = $1`, id)
+	return err
+}
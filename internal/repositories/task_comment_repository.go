@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"turcompany/internal/models"
+)
+
+// TaskCommentRepository defines the interface for database operations on task comments.
+type TaskCommentRepository interface {
+	Create(ctx context.Context, comment *models.TaskComment) error
+	FindByID(ctx context.Context, id int64) (*models.TaskComment, error)
+	ListByTask(ctx context.Context, taskID int64) ([]models.TaskComment, error)
+	Update(ctx context.Context, comment *models.TaskComment) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type taskCommentRepository struct {
+	db *sql.DB
+}
+
+// NewTaskCommentRepository creates a new instance of TaskCommentRepository.
+func NewTaskCommentRepository(db *sql.DB) TaskCommentRepository {
+	return &taskCommentRepository{db: db}
+}
+
+const taskCommentColumns = `id, task_id, parent_id, author_id, content, created_at, updated_at`
+
+func scanTaskComment(row rowScanner, comment *models.TaskComment) error {
+	return row.Scan(&comment.ID, &comment.TaskID, &comment.ParentID, &comment.AuthorID,
+		&comment.Content, &comment.CreatedAt, &comment.UpdatedAt)
+}
+
+func (r *taskCommentRepository) Create(ctx context.Context, comment *models.TaskComment) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO task_comments (task_id, parent_id, author_id, content, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id, updated_at`, comment.TaskID, comment.ParentID, comment.AuthorID, comment.Content, comment.CreatedAt,
+	).Scan(&comment.ID, &comment.UpdatedAt)
+}
+
+// FindByID returns the comment; nil if it does not exist.
+func (r *taskCommentRepository) FindByID(ctx context.Context, id int64) (*models.TaskComment, error) {
+	comment := &models.TaskComment{}
+	err := scanTaskComment(r.db.QueryRowContext(ctx,
+		`SELECT `+taskCommentColumns+` FROM task_comments WHERE id = $1`, id), comment)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// ListByTask returns all comments of the task, oldest first, without building threads.
+func (r *taskCommentRepository) ListByTask(ctx context.Context, taskID int64) ([]models.TaskComment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+taskCommentColumns+` FROM task_comments WHERE task_id = $1 ORDER BY id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.TaskComment{}
+	for rows.Next() {
+		var comment models.TaskComment
+		if err := scanTaskComment(rows, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *taskCommentRepository) Update(ctx context.Context, comment *models.TaskComment) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE task_comments SET content = $2, updated_at = $3 WHERE id = $1`,
+		comment.ID, comment.Content, comment.UpdatedAt)
+	return err
+}
+
+// Delete removes the comment together with its replies.
+func (r *taskCommentRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_comments WHERE id = $1`, id)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 	"turcompany/internal/models"
 )
 
@@ -13,8 +14,14 @@ type TaskRepository interface {
 	Store(ctx context.Context, task *models.Task) error
 	FindByID(ctx context.Context, id int64) (*models.Task, error)
 	FindAll(ctx context.Context, filter models.TaskFilter) ([]models.Task, error)
-	Update(ctx context.Context, task *models.Task) error
+	Count(ctx context.Context, filter models.TaskFilter) (int, error)
+	Update(ctx context.Context, task *models.Task, activity []models.TaskActivity) error
 	Delete(ctx context.Context, id int64) error
+	ClaimReminders(ctx context.Context, stage string, now, from, until time.Time) ([]models.Task, error)
+	MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error)
+	ClaimEscalations(ctx context.Context, now, dueBefore time.Time) ([]models.Task, error)
+	// HasOpenOccurrence reports whether the series has an open task due after dueAfter.
+	HasOpenOccurrence(ctx context.Context, seriesID int64, dueAfter time.Time) (bool, error)
 }
 
 type taskRepository struct {
@@ -26,31 +33,53 @@ func NewTaskRepository(db *sql.DB) TaskRepository {
 	return &taskRepository{db: db}
 }
 
-func (r *taskRepository) Store(ctx context.Context, task *models.Task) error {
+const taskColumns = `id, creator_id, assignee_id, entity_id, entity_type, title, description, due_date, status,
+	created_at, updated_at, overdue_at, escalated_at, recurrence, series_id, series_start`
+
+// openTaskCondition selects tasks the scheduler still has to watch.
+const openTaskCondition = `status IN ('new', 'in_progress') AND due_date IS NOT NULL`
+
+func scanTask(row rowScanner, task *models.Task) error {
+	return row.Scan(
+		&task.ID, &task.CreatorID, &task.AssigneeID, &task.EntityID, &task.EntityType,
+		&task.Title, &task.Description, &task.DueDate, &task.Status,
+		&task.CreatedAt, &task.UpdatedAt, &task.OverdueAt, &task.EscalatedAt,
+		&task.Recurrence, &task.SeriesID, &task.SeriesStart,
+	)
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertTask(ctx context.Context, q queryRower, task *models.Task) error {
 	query := `
-		INSERT INTO tasks (creator_id, assignee_id, entity_id, entity_type, title, description, due_date, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO tasks (creator_id, assignee_id, entity_id, entity_type, title, description, due_date, status, created_at, updated_at,
+			recurrence, series_id, series_start)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRowContext(ctx, query,
+	return q.QueryRowContext(ctx, query,
 		task.CreatorID, task.AssigneeID, task.EntityID, task.EntityType,
 		task.Title, task.Description, task.DueDate, task.Status,
 		task.CreatedAt, task.UpdatedAt,
+		task.Recurrence, task.SeriesID, task.SeriesStart,
 	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
 }
 
+func (r *taskRepository) Store(ctx context.Context, task *models.Task) error {
+	return insertTask(ctx, r.db, task)
+}
+
 func (r *taskRepository) FindByID(ctx context.Context, id int64) (*models.Task, error) {
-	query := `SELECT id, creator_id, assignee_id, entity_id, entity_type, title, description, due_date, status, created_at, updated_at FROM tasks WHERE id = $1`
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
 
 	task := &models.Task{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&task.ID, &task.CreatorID, &task.AssigneeID, &task.EntityID, &task.EntityType,
-		&task.Title, &task.Description, &task.DueDate, &task.Status,
-		&task.CreatedAt, &task.UpdatedAt,
-	)
+	err := scanTask(r.db.QueryRowContext(ctx, query, id), task)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("task not found") // Or a custom error
+			return nil, nil
 		}
 		return nil, err
 	}
@@ -58,65 +87,114 @@ func (r *taskRepository) FindByID(ctx context.Context, id int64) (*models.Task,
 }
 
 func (r *taskRepository) FindAll(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
-	baseQuery := `SELECT id, creator_id, assignee_id, entity_id, entity_type, title, description, due_date, status, created_at, updated_at FROM tasks`
+	where, args := taskFilterConditions(filter)
+	query := `SELECT ` + taskColumns + ` FROM tasks` + where + ` ORDER BY ` + taskOrder(filter.Sort)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, filter.Limit, filter.Offset)
+	}
+	return r.queryTasks(ctx, query, args...)
+}
 
+// Count returns the number of tasks matching the filter, ignoring its limit and offset.
+func (r *taskRepository) Count(ctx context.Context, filter models.TaskFilter) (int, error) {
+	where, args := taskFilterConditions(filter)
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`+where, args...).Scan(&total)
+	return total, err
+}
+
+func taskFilterConditions(filter models.TaskFilter) (string, []interface{}) {
 	conditions := []string{}
 	args := []interface{}{}
-	argID := 1
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
 
 	if filter.AssigneeID != nil {
-		conditions = append(conditions, fmt.Sprintf("assignee_id = $%d", argID))
-		args = append(args, *filter.AssigneeID)
-		argID++
+		add("assignee_id = $%d", *filter.AssigneeID)
 	}
 	if filter.CreatorID != nil {
-		conditions = append(conditions, fmt.Sprintf("creator_id = $%d", argID))
-		args = append(args, *filter.CreatorID)
-		argID++
+		add("creator_id = $%d", *filter.CreatorID)
+	}
+	if filter.EntityID != nil {
+		add("entity_id = $%d", *filter.EntityID)
+	}
+	if filter.EntityType != nil {
+		add("entity_type = $%d", *filter.EntityType)
 	}
 	if filter.Status != nil {
-		conditions = append(conditions, fmt.Sprintf("status = $%d", argID))
-		args = append(args, *filter.Status)
-		argID++
+		add("status = $%d", *filter.Status)
 	}
-
-	if len(conditions) > 0 {
-		baseQuery += " WHERE " + strings.Join(conditions, " AND ")
+	if filter.DueAfter != nil {
+		add("due_date >= $%d", *filter.DueAfter)
+	}
+	if filter.DueBefore != nil {
+		add("due_date < $%d", *filter.DueBefore)
+	}
+	if filter.Overdue {
+		conditions = append(conditions, openTaskCondition+" AND due_date < NOW()")
+	}
+	if filter.Search != "" {
+		add(`title ILIKE '%%' || $%d || '%%'`, likeEscaper.Replace(filter.Search))
 	}
-	baseQuery += " ORDER BY created_at DESC"
 
-	rows, err := r.db.QueryContext(ctx, baseQuery, args...)
-	if err != nil {
-		return nil, err
+	if len(conditions) == 0 {
+		return "", args
 	}
-	defer rows.Close()
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
 
-	tasks := []models.Task{}
-	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(
-			&task.ID, &task.CreatorID, &task.AssigneeID, &task.EntityID, &task.EntityType,
-			&task.Title, &task.Description, &task.DueDate, &task.Status,
-			&task.CreatedAt, &task.UpdatedAt,
-		); err != nil {
-			return nil, err
+// likeEscaper escapes LIKE wildcards so the search matches them literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// taskOrder turns a sort option into an ORDER BY clause; unknown options mean newest first.
+func taskOrder(sort string) string {
+	direction := "ASC"
+	field := sort
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		field = sort[1:]
+	}
+	for _, allowed := range models.TaskSortFields {
+		if field == allowed {
+			// Tasks without a due date go last in both directions
+			return field + " " + direction + " NULLS LAST, id " + direction
 		}
-		tasks = append(tasks, task)
 	}
-	return tasks, nil
+	return "created_at DESC, id DESC"
 }
 
-func (r *taskRepository) Update(ctx context.Context, task *models.Task) error {
+// Update stores the task and the activity entries describing the change in one transaction.
+func (r *taskRepository) Update(ctx context.Context, task *models.Task, activity []models.TaskActivity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE tasks SET
-			assignee_id = $1, title = $2, description = $3, due_date = $4, status = $5, updated_at = $6
+			assignee_id = $1, title = $2, description = $3, due_date = $4, status = $5, updated_at = $6,
+			recurrence = $8, series_start = $9,
+			overdue_at = CASE WHEN due_date IS DISTINCT FROM $4 THEN NULL ELSE overdue_at END,
+			escalated_at = CASE WHEN due_date IS DISTINCT FROM $4 THEN NULL ELSE escalated_at END
 		WHERE id = $7`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		task.AssigneeID, task.Title, task.Description, task.DueDate, task.Status, task.UpdatedAt,
-		task.ID,
+		task.ID, task.Recurrence, task.SeriesStart,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	for i := range activity {
+		if err := insertTaskActivity(ctx, tx, &activity[i]); err != nil {
+			return fmt.Errorf("store task activity: %w", err)
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *taskRepository) Delete(ctx context.Context, id int64) error {
@@ -124,3 +202,67 @@ func (r *taskRepository) Delete(ctx context.Context, id int64) error {
 	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
+
+// ClaimReminders records the reminder stage for open tasks due in (from, until] and returns
+// the tasks recorded now. A reminder is sent once per stage and due date, even with several
+// instances running; moving the due date makes the task eligible again.
+func (r *taskRepository) ClaimReminders(ctx context.Context, stage string, now, from, until time.Time) ([]models.Task, error) {
+	query := `
+		WITH claimed AS (
+			INSERT INTO task_reminders (task_id, stage, due_date, sent_at)
+			SELECT id, $1, due_date, $2 FROM tasks
+			WHERE ` + openTaskCondition + ` AND due_date > $3 AND due_date <= $4
+			ON CONFLICT DO NOTHING
+			RETURNING task_id
+		)
+		SELECT ` + taskColumns + ` FROM tasks WHERE id IN (SELECT task_id FROM claimed)
+		ORDER BY due_date`
+	return r.queryTasks(ctx, query, stage, now, from, until)
+}
+
+// MarkOverdue marks open tasks past their due date as overdue and returns the newly marked ones.
+func (r *taskRepository) MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error) {
+	query := `
+		UPDATE tasks SET overdue_at = $1
+		WHERE ` + openTaskCondition + ` AND due_date <= $1 AND overdue_at IS NULL
+		RETURNING ` + taskColumns
+	return r.queryTasks(ctx, query, now)
+}
+
+// ClaimEscalations marks overdue open tasks due at or before dueBefore as escalated and returns them.
+func (r *taskRepository) ClaimEscalations(ctx context.Context, now, dueBefore time.Time) ([]models.Task, error) {
+	query := `
+		UPDATE tasks SET escalated_at = $1
+		WHERE ` + openTaskCondition + ` AND overdue_at IS NOT NULL AND escalated_at IS NULL AND due_date <= $2
+		RETURNING ` + taskColumns
+	return r.queryTasks(ctx, query, now, dueBefore)
+}
+
+func (r *taskRepository) HasOpenOccurrence(ctx context.Context, seriesID int64, dueAfter time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM tasks
+			WHERE (id = $1 OR series_id = $1) AND ` + openTaskCondition + ` AND due_date > $2
+		)`
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, seriesID, dueAfter).Scan(&exists)
+	return exists, err
+}
+
+func (r *taskRepository) queryTasks(ctx context.Context, query string, args ...interface{}) ([]models.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
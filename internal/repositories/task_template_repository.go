@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"turcompany/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// TaskTemplateRepository defines the interface for database operations on task templates.
+type TaskTemplateRepository interface {
+	Create(ctx context.Context, t *models.TaskTemplate) error
+	Update(ctx context.Context, t *models.TaskTemplate) error
+	FindByID(ctx context.Context, id int64) (*models.TaskTemplate, error)
+	List(ctx context.Context, dealStatus string, activeOnly bool) ([]models.TaskTemplate, error)
+	Delete(ctx context.Context, id int64) error
+	CreateTasks(ctx context.Context, templateID, dealID int64, tasks []*models.Task) (bool, error)
+}
+
+type taskTemplateRepository struct {
+	db *sql.DB
+}
+
+// NewTaskTemplateRepository creates a new instance of TaskTemplateRepository.
+func NewTaskTemplateRepository(db *sql.DB) TaskTemplateRepository {
+	return &taskTemplateRepository{db: db}
+}
+
+// Create stores the template with its items.
+func (r *taskTemplateRepository) Create(ctx context.Context, t *models.TaskTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO task_templates (name, deal_status, active, created_by, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5)
+		RETURNING id`, t.Name, t.DealStatus, t.Active, t.CreatedBy, t.CreatedAt,
+	).Scan(&t.ID)
+	if err != nil {
+		return fmt.Errorf("store task template: %w", err)
+	}
+	if err := insertTemplateItems(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update replaces the template fields and its items.
+func (r *taskTemplateRepository) Update(ctx context.Context, t *models.TaskTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE task_templates SET name = $2, deal_status = $3, active = $4 WHERE id = $1`,
+		t.ID, t.Name, t.DealStatus, t.Active)
+	if err != nil {
+		return fmt.Errorf("update task template: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_template_items WHERE template_id = $1`, t.ID); err != nil {
+		return fmt.Errorf("replace task template items: %w", err)
+	}
+	if err := insertTemplateItems(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertTemplateItems(ctx context.Context, tx *sql.Tx, t *models.TaskTemplate) error {
+	for i := range t.Items {
+		item := &t.Items[i]
+		item.TemplateID = t.ID
+		item.Position = i
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO task_template_items (template_id, position, title, description, assignee_id, offset_days)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`, item.TemplateID, item.Position, item.Title, item.Description, item.AssigneeID, item.OffsetDays,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("store task template item: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindByID returns the template with its items; nil if it does not exist.
+func (r *taskTemplateRepository) FindByID(ctx context.Context, id int64) (*models.TaskTemplate, error) {
+	templates, err := r.find(ctx, `WHERE id = $1`, id)
+	if err != nil || len(templates) == 0 {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+// List returns templates with their items, optionally only those of one deal status.
+func (r *taskTemplateRepository) List(ctx context.Context, dealStatus string, activeOnly bool) ([]models.TaskTemplate, error) {
+	return r.find(ctx, `WHERE ($1 = '' OR deal_status = $1) AND (NOT $2 OR active)`, dealStatus, activeOnly)
+}
+
+func (r *taskTemplateRepository) find(ctx context.Context, where string, args ...interface{}) ([]models.TaskTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, deal_status, active, COALESCE(created_by, 0), created_at
+		FROM task_templates `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.TaskTemplate{}
+	index := map[int64]int{}
+	ids := []int64{}
+	for rows.Next() {
+		t := models.TaskTemplate{Items: []models.TaskTemplateItem{}}
+		if err := rows.Scan(&t.ID, &t.Name, &t.DealStatus, &t.Active, &t.CreatedBy, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		index[t.ID] = len(templates)
+		ids = append(ids, t.ID)
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil || len(templates) == 0 {
+		return templates, err
+	}
+
+	itemRows, err := r.db.QueryContext(ctx, `
+		SELECT id, template_id, position, title, description, assignee_id, offset_days
+		FROM task_template_items WHERE template_id = ANY($1) ORDER BY template_id, position`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.TaskTemplateItem
+		if err := itemRows.Scan(&item.ID, &item.TemplateID, &item.Position, &item.Title, &item.Description,
+			&item.AssigneeID, &item.OffsetDays); err != nil {
+			return nil, err
+		}
+		t := &templates[index[item.TemplateID]]
+		t.Items = append(t.Items, item)
+	}
+	return templates, itemRows.Err()
+}
+
+func (r *taskTemplateRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_templates WHERE id = $1`, id)
+	return err
+}
+
+// CreateTasks stores the tasks generated from the template for the deal. It returns false
+// without storing anything if the template was already applied to the deal.
+func (r *taskTemplateRepository) CreateTasks(ctx context.Context, templateID, dealID int64, tasks []*models.Task) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO task_template_runs (template_id, deal_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, templateID, dealID)
+	if err != nil {
+		return false, fmt.Errorf("record task template run: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	for _, task := range tasks {
+		if err := insertTask(ctx, tx, task); err != nil {
+			return false, fmt.Errorf("store template task: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"turcompany/internal/models"
+)
+
+// TelegramLinkRepository defines the interface for database operations on Telegram links.
+type TelegramLinkRepository interface {
+	CreateCode(ctx context.Context, userID int64, codeHash string, expiresAt time.Time) error
+	LinkByCode(ctx context.Context, codeHash string, chatID int64, username string, now time.Time) (*models.TelegramLink, error)
+	FindByUser(ctx context.Context, userID int64) (*models.TelegramLink, error)
+	FindByChat(ctx context.Context, chatID int64) (*models.TelegramLink, error)
+	DeleteByUser(ctx context.Context, userID int64) error
+	DeleteByChat(ctx context.Context, chatID int64) error
+}
+
+type telegramLinkRepository struct {
+	db *sql.DB
+}
+
+// NewTelegramLinkRepository creates a new instance of TelegramLinkRepository.
+func NewTelegramLinkRepository(db *sql.DB) TelegramLinkRepository {
+	return &telegramLinkRepository{db: db}
+}
+
+// CreateCode stores a new code and drops the user's previous unused codes.
+func (r *telegramLinkRepository) CreateCode(ctx context.Context, userID int64, codeHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telegram_link_codes WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return fmt.Errorf("delete old link codes: %w", err)
+	}
+	query := `INSERT INTO telegram_link_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, codeHash, userID, expiresAt); err != nil {
+		return fmt.Errorf("create link code: %w", err)
+	}
+	return tx.Commit()
+}
+
+// LinkByCode spends an unexpired code and binds the chat to its user, replacing the user's
+// previous chat and any other user linked to this chat. Returns nil if the code is not valid.
+func (r *telegramLinkRepository) LinkByCode(ctx context.Context, codeHash string, chatID int64, username string, now time.Time) (*models.TelegramLink, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	link := &models.TelegramLink{ChatID: chatID, Username: username, LinkedAt: now}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE telegram_link_codes SET used_at = $2
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id`, codeHash, now,
+	).Scan(&link.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("use link code: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telegram_links WHERE chat_id = $1 AND user_id <> $2`, chatID, link.UserID); err != nil {
+		return nil, fmt.Errorf("unlink previous user: %w", err)
+	}
+	query := `
+		INSERT INTO telegram_links (user_id, chat_id, username, linked_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET chat_id = EXCLUDED.chat_id, username = EXCLUDED.username, linked_at = EXCLUDED.linked_at`
+	if _, err := tx.ExecContext(ctx, query, link.UserID, link.ChatID, link.Username, link.LinkedAt); err != nil {
+		return nil, fmt.Errorf("link telegram chat: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// FindByUser returns the user's link; nil if the user has not linked Telegram.
+func (r *telegramLinkRepository) FindByUser(ctx context.Context, userID int64) (*models.TelegramLink, error) {
+	return r.find(ctx, `WHERE user_id = $1`, userID)
+}
+
+// FindByChat returns the link of the chat; nil if the chat is not linked.
+func (r *telegramLinkRepository) FindByChat(ctx context.Context, chatID int64) (*models.TelegramLink, error) {
+	return r.find(ctx, `WHERE chat_id = $1`, chatID)
+}
+
+func (r *telegramLinkRepository) find(ctx context.Context, where string, arg int64) (*models.TelegramLink, error) {
+	query := `SELECT user_id, chat_id, username, linked_at FROM telegram_links ` + where
+	var link models.TelegramLink
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(&link.UserID, &link.ChatID, &link.Username, &link.LinkedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *telegramLinkRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM telegram_links WHERE user_id = $1`, userID)
+	return err
+}
+
+func (r *telegramLinkRepository) DeleteByChat(ctx context.Context, chatID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM telegram_links WHERE chat_id = $1`, chatID)
+	return err
+}
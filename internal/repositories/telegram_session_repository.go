@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"turcompany/internal/models"
+)
+
+// TelegramSessionRepository defines the interface for storing bot dialog state per chat.
+type TelegramSessionRepository interface {
+	Find(ctx context.Context, chatID int64) (*models.TelegramSession, error)
+	Save(ctx context.Context, session *models.TelegramSession) error
+	Delete(ctx context.Context, chatID int64) error
+}
+
+type telegramSessionRepository struct {
+	db *sql.DB
+}
+
+// NewTelegramSessionRepository creates a new instance of TelegramSessionRepository.
+func NewTelegramSessionRepository(db *sql.DB) TelegramSessionRepository {
+	return &telegramSessionRepository{db: db}
+}
+
+// Find returns the session of the chat; nil if the chat has none.
+func (r *telegramSessionRepository) Find(ctx context.Context, chatID int64) (*models.TelegramSession, error) {
+	query := `SELECT chat_id, state, data, updated_at FROM telegram_sessions WHERE chat_id = $1`
+
+	var s models.TelegramSession
+	var data []byte
+	err := r.db.QueryRowContext(ctx, query, chatID).Scan(&s.ChatID, &s.State, &data, &s.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.Data); err != nil {
+		return nil, fmt.Errorf("decode telegram session %d: %w", chatID, err)
+	}
+	return &s, nil
+}
+
+func (r *telegramSessionRepository) Save(ctx context.Context, s *models.TelegramSession) error {
+	data, err := json.Marshal(s.Data)
+	if err != nil {
+		return fmt.Errorf("encode telegram session %d: %w", s.ChatID, err)
+	}
+	query := `
+		INSERT INTO telegram_sessions (chat_id, state, data, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chat_id) DO UPDATE SET state = EXCLUDED.state, data = EXCLUDED.data, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+	if err := r.db.QueryRowContext(ctx, query, s.ChatID, s.State, data).Scan(&s.UpdatedAt); err != nil {
+		return fmt.Errorf("save telegram session %d: %w", s.ChatID, err)
+	}
+	return nil
+}
+
+func (r *telegramSessionRepository) Delete(ctx context.Context, chatID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM telegram_sessions WHERE chat_id = $1`, chatID)
+	return err
+}
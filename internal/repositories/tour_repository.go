@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"turcompany/internal/models"
+)
+
+// TourRepository defines the interface for reading the tour catalogue.
+type TourRepository interface {
+	FindActive(ctx context.Context) ([]models.Tour, error)
+	FindByID(ctx context.Context, id int64) (*models.Tour, error)
+	FindDepartures(ctx context.Context, tourID int64, from time.Time) ([]models.TourDeparture, error)
+	FindDeparture(ctx context.Context, id int64) (*models.TourDeparture, error)
+}
+
+type tourRepository struct {
+	db *sql.DB
+}
+
+// NewTourRepository creates a new instance of TourRepository.
+func NewTourRepository(db *sql.DB) TourRepository {
+	return &tourRepository{db: db}
+}
+
+const tourColumns = `id, title, description, price, currency, active, created_at`
+
+func scanTour(row rowScanner, t *models.Tour) error {
+	return row.Scan(&t.ID, &t.Title, &t.Description, &t.Price, &t.Currency, &t.Active, &t.CreatedAt)
+}
+
+// departureColumns falls back to the tour price when the departure has none.
+const departureColumns = `d.id, d.tour_id, d.departs_at, d.returns_at, d.seats, COALESCE(d.price, t.price)`
+
+func scanDeparture(row rowScanner, d *models.TourDeparture) error {
+	return row.Scan(&d.ID, &d.TourID, &d.DepartsAt, &d.ReturnsAt, &d.Seats, &d.Price)
+}
+
+func (r *tourRepository) FindActive(ctx context.Context) ([]models.Tour, error) {
+	query := `SELECT ` + tourColumns + ` FROM tours WHERE active ORDER BY title, id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tours := []models.Tour{}
+	for rows.Next() {
+		var t models.Tour
+		if err := scanTour(rows, &t); err != nil {
+			return nil, err
+		}
+		tours = append(tours, t)
+	}
+	return tours, rows.Err()
+}
+
+// FindByID returns the tour; nil if it does not exist.
+func (r *tourRepository) FindByID(ctx context.Context, id int64) (*models.Tour, error) {
+	query := `SELECT ` + tourColumns + ` FROM tours WHERE id = $1`
+	var t models.Tour
+	if err := scanTour(r.db.QueryRowContext(ctx, query, id), &t); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// FindDepartures returns the departures of the tour starting after from, earliest first.
+func (r *tourRepository) FindDepartures(ctx context.Context, tourID int64, from time.Time) ([]models.TourDeparture, error) {
+	query := `SELECT ` + departureColumns + `
+		FROM tour_departures d
+		JOIN tours t ON t.id = d.tour_id
+		WHERE d.tour_id = $1 AND d.departs_at > $2
+		ORDER BY d.departs_at, d.id`
+	rows, err := r.db.QueryContext(ctx, query, tourID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	departures := []models.TourDeparture{}
+	for rows.Next() {
+		var d models.TourDeparture
+		if err := scanDeparture(rows, &d); err != nil {
+			return nil, err
+		}
+		departures = append(departures, d)
+	}
+	return departures, rows.Err()
+}
+
+// FindDeparture returns the departure; nil if it does not exist.
+func (r *tourRepository) FindDeparture(ctx context.Context, id int64) (*models.TourDeparture, error) {
+	query := `SELECT ` + departureColumns + `
+		FROM tour_departures d
+		JOIN tours t ON t.id = d.tour_id
+		WHERE d.id = $1`
+	var d models.TourDeparture
+	if err := scanDeparture(r.db.QueryRowContext(ctx, query, id), &d); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
@@ -27,8 +27,8 @@ func NewUserRepository(db *sql.DB) UserRepository {
 
 func (r *userRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (company_name, bin_iin, email, password_hash, role_id)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (company_name, bin_iin, email, password_hash, role_id, manager_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
@@ -38,12 +38,13 @@ func (r *userRepository) Create(user *models.User) error {
 		user.Email,
 		user.PasswordHash,
 		user.RoleID,
+		user.ManagerID,
 	).Scan(&user.ID)
 }
 
 func (r *userRepository) GetByID(id int) (*models.User, error) {
 	query := `
-		SELECT id, company_name, bin_iin, email, role_id
+		SELECT id, company_name, bin_iin, email, role_id, manager_id
 		FROM users
 		WHERE id = $1
 	`
@@ -54,6 +55,7 @@ func (r *userRepository) GetByID(id int) (*models.User, error) {
 		&user.BinIin,
 		&user.Email,
 		&user.RoleID,
+		&user.ManagerID,
 	)
 	if err != nil {
 		return nil, err
@@ -64,8 +66,8 @@ func (r *userRepository) GetByID(id int) (*models.User, error) {
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET company_name = $1, bin_iin = $2, email = $3, password_hash = $4, role_id = $5
-		WHERE id = $6
+		SET company_name = $1, bin_iin = $2, email = $3, password_hash = $4, role_id = $5, manager_id = $6
+		WHERE id = $7
 	`
 	_, err := r.DB.Exec(query,
 		user.CompanyName,
@@ -73,6 +75,7 @@ func (r *userRepository) Update(user *models.User) error {
 		user.Email,
 		user.PasswordHash,
 		user.RoleID,
+		user.ManagerID,
 		user.ID,
 	)
 	return err
@@ -86,7 +89,7 @@ func (r *userRepository) Delete(id int) error {
 
 func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, company_name, bin_iin, email, role_id
+		SELECT id, company_name, bin_iin, email, role_id, manager_id
 		FROM users
 		ORDER BY id
 		LIMIT $1 OFFSET $2
@@ -106,6 +109,7 @@ func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
 			&u.BinIin,
 			&u.Email,
 			&u.RoleID,
+			&u.ManagerID,
 		); err != nil {
 			return nil, err
 		}
@@ -116,7 +120,7 @@ func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
 
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, company_name, bin_iin, email, password_hash, role_id
+		SELECT id, company_name, bin_iin, email, password_hash, role_id, manager_id
 		FROM users
 		WHERE email = $1
 	`
@@ -128,6 +132,7 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Email,
 		&user.PasswordHash,
 		&user.RoleID,
+		&user.ManagerID,
 	)
 	if err != nil {
 		return nil, err
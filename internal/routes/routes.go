@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"turcompany/internal/handlers"
+	"turcompany/internal/middleware"
 )
 
 func SetupRoutes(
@@ -14,9 +15,19 @@ func SetupRoutes(
 	authHandler *handlers.AuthHandler,
 	documentHandler *handlers.DocumentHandler,
 	taskHandler *handlers.TaskHandler,
+	taskTemplateHandler *handlers.TaskTemplateHandler,
 	messageHandler *handlers.MessageHandler,
+	chatHandler *handlers.ChatHandler,
+	conversationHandler *handlers.ConversationHandler,
+	attachmentHandler *handlers.AttachmentHandler,
 	smsHandler *handlers.SMSHandler,
+	telegramLinkHandler *handlers.TelegramLinkHandler,
+	inboxHandler *handlers.InboxHandler,
+	notificationHandler *handlers.NotificationHandler,
+	activityHandler *handlers.ActivityHandler,
 	reportHandler *handlers.ReportHandler,
+	scheduledReportHandler *handlers.ScheduledReportHandler,
+	reportBuilderHandler *handlers.ReportBuilderHandler,
 ) *gin.Engine {
 
 	// Аутентификация
@@ -52,21 +63,23 @@ func SetupRoutes(
 	// Маршруты для лидов
 	leads := r.Group("/leads")
 	{
-		leads.POST("/", leadHandler.Create)                  // Создание лида
-		leads.GET("/:id", leadHandler.GetByID)               // Получение лида по ID
-		leads.PUT("/:id", leadHandler.Update)                // Обновление лида
-		leads.DELETE("/:id", leadHandler.Delete)             // Удаление лида
-		leads.PUT("/:id/convert", leadHandler.ConvertToDeal) // Конвертация в сделку
+		leads.POST("/", leadHandler.Create)                                                   // Создание лида
+		leads.GET("/:id", leadHandler.GetByID)                                                // Получение лида по ID
+		leads.PUT("/:id", leadHandler.Update)                                                 // Обновление лида
+		leads.DELETE("/:id", leadHandler.Delete)                                              // Удаление лида
+		leads.PUT("/:id/convert", leadHandler.ConvertToDeal)                                  // Конвертация в сделку
+		leads.GET("/:id/timeline", middleware.AuthMiddleware(), activityHandler.LeadTimeline) // Лента событий лида
 		leads.GET("/", leadHandler.List)
 	}
 
 	// Маршруты для сделок
 	deals := r.Group("/deals")
 	{
-		deals.POST("/", dealHandler.Create)      // Создание сделки
-		deals.GET("/:id", dealHandler.GetByID)   // Получение сделки по ID
-		deals.PUT("/:id", dealHandler.Update)    // Обновление сделки
-		deals.DELETE("/:id", dealHandler.Delete) // Удаление сделки
+		deals.POST("/", dealHandler.Create)                                                   // Создание сделки
+		deals.GET("/:id", dealHandler.GetByID)                                                // Получение сделки по ID
+		deals.PUT("/:id", dealHandler.Update)                                                 // Обновление сделки
+		deals.DELETE("/:id", dealHandler.Delete)                                              // Удаление сделки
+		deals.GET("/:id/timeline", middleware.AuthMiddleware(), activityHandler.DealTimeline) // Лента событий сделки
 		deals.GET("/", dealHandler.List)
 	}
 
@@ -79,41 +92,151 @@ func SetupRoutes(
 		documents.DELETE("/:id", documentHandler.DeleteDocument)
 		documents.POST("/create-from-lead", documentHandler.CreateDocumentFromLead)
 		documents.GET("/deal/:dealid", documentHandler.ListDocumentsByDeal)
+		documents.POST("/:id/sign/request", documentHandler.RequestSignature) // Отправка кода подписи
+		documents.POST("/:id/sign/confirm", documentHandler.ConfirmSignature) // Подписание кодом из SMS
+		documents.GET("/:id/signature", documentHandler.GetSignature)         // Проверка подписи
 	}
 
 	// Маршруты для задач
-	tasks := r.Group("/tasks")
+	tasks := r.Group("/tasks", middleware.AuthMiddleware())
 	{
-		tasks.POST("/", taskHandler.Create)      // Создание задачи
-		tasks.GET("/", taskHandler.GetAll)       // Получение всех задач
-		tasks.GET("/:id", taskHandler.GetByID)   // Получение задачи по ID
-		tasks.PUT("/:id", taskHandler.Update)    // Обновление задачи
-		tasks.DELETE("/:id", taskHandler.Delete) // Удаление задачи
+		tasks.POST("/", taskHandler.Create)                                      // Создание задачи
+		tasks.GET("/", taskHandler.GetAll)                                       // Получение всех задач
+		tasks.GET("/:id", taskHandler.GetByID)                                   // Задача с чек-листом, комментариями и историей
+		tasks.PUT("/:id", taskHandler.Update)                                    // Обновление задачи
+		tasks.DELETE("/:id", taskHandler.Delete)                                 // Удаление задачи
+		tasks.POST("/:id/checklist", taskHandler.AddChecklistItem)               // Новый пункт чек-листа
+		tasks.PUT("/:id/checklist/:item_id", taskHandler.UpdateChecklistItem)    // Изменение или отметка пункта
+		tasks.DELETE("/:id/checklist/:item_id", taskHandler.DeleteChecklistItem) // Удаление пункта
+		tasks.POST("/:id/comments", taskHandler.AddComment)                      // Комментарий или ответ
+		tasks.PUT("/:id/comments/:comment_id", taskHandler.UpdateComment)        // Правка своего комментария
+		tasks.DELETE("/:id/comments/:comment_id", taskHandler.DeleteComment)     // Удаление своего комментария
+	}
+
+	// Шаблоны задач: создают задачи при переходе сделки на этап
+	taskTemplates := r.Group("/task-templates", middleware.AuthMiddleware())
+	{
+		taskTemplates.POST("/", taskTemplateHandler.Create)                  // Создание шаблона
+		taskTemplates.GET("/", taskTemplateHandler.List)                     // Список шаблонов
+		taskTemplates.GET("/:id", taskTemplateHandler.GetByID)               // Шаблон с задачами
+		taskTemplates.PUT("/:id", taskTemplateHandler.Update)                // Обновление шаблона
+		taskTemplates.DELETE("/:id", taskTemplateHandler.Delete)             // Удаление шаблона
+		taskTemplates.POST("/:id/apply/:deal_id", taskTemplateHandler.Apply) // Применение к сделке вручную
 	}
 
 	// Маршруты для сообщений
-	messages := r.Group("/messages")
+	messages := r.Group("/messages", middleware.AuthMiddleware())
 	{
 		messages.POST("/", messageHandler.Send)                                     // Отправка сообщения
 		messages.GET("/conversations", messageHandler.GetConversations)             // Список бесед
 		messages.GET("/history/:partner_id", messageHandler.GetConversationHistory) // История беседы
+		messages.GET("/search", messageHandler.Search)                              // Полнотекстовый поиск
+		messages.POST("/read", messageHandler.MarkRead)                             // Отметка о прочтении
+		messages.GET("/unread-count", messageHandler.GetUnreadCount)                // Количество непрочитанных
+		messages.GET("/online", chatHandler.OnlineUsers)                            // Пользователи в сети
+	}
+
+	// Групповые беседы, в том числе привязанные к лидам, сделкам и документам
+	conversations := r.Group("/conversations", middleware.AuthMiddleware())
+	{
+		conversations.POST("/", conversationHandler.Create)                                       // Создание беседы
+		conversations.GET("/", conversationHandler.List)                                          // Беседы пользователя или сущности
+		conversations.GET("/mentions", conversationHandler.GetMentions)                           // Упоминания пользователя
+		conversations.GET("/:id", conversationHandler.GetByID)                                    // Беседа с участниками
+		conversations.POST("/:id/participants", conversationHandler.AddParticipants)              // Добавление участников
+		conversations.DELETE("/:id/participants/:user_id", conversationHandler.RemoveParticipant) // Удаление участника
+		conversations.POST("/:id/messages", conversationHandler.Send)                             // Отправка сообщения
+		conversations.GET("/:id/messages", conversationHandler.GetHistory)                        // История беседы
+		conversations.POST("/:id/read", conversationHandler.MarkRead)                             // Отметка о прочтении
 	}
 
+	// Вложения сообщений: загрузка до отправки и скачивание участниками беседы
+	attachments := r.Group("/attachments", middleware.AuthMiddleware())
+	{
+		attachments.POST("/", attachmentHandler.Upload)                // Загрузка вложения
+		attachments.GET("/:id", attachmentHandler.Download)            // Скачивание вложения
+		attachments.GET("/:id/thumbnail", attachmentHandler.Thumbnail) // Превью изображения
+	}
+
+	// WebSocket чата
+	r.GET("/ws/chat", middleware.WebSocketAuthMiddleware(), chatHandler.Connect)
+
 	// Маршруты для SMS
 	sms := r.Group("/sms")
 	{
 		sms.POST("/send", smsHandler.SendSMSHandler)                    // Отправка SMS
 		sms.POST("/resend", smsHandler.ResendSMSHandler)                // Повторная отправка SMS
-		sms.POST("/confirm", smsHandler.ConfirmSMSHandler)              // Подтверждение SMS
 		sms.GET("/latest/:document_id", smsHandler.GetLatestSMSHandler) // Последняя SMS для документа
-		sms.DELETE("/:document_id", smsHandler.DeleteSMSHandler)        // Удаление SMS
 	}
 
-	// Маршруты для отчетов
-	reports := r.Group("/reports")
-	reports.GET("/summary", reportHandler.GetSummary)
-	reports.GET("/leads/filter", reportHandler.FilterLeads)
-	reports.GET("/deals/filter", reportHandler.FilterDeals)
+	// Привязка Telegram для уведомлений сотрудникам
+	telegram := r.Group("/telegram", middleware.AuthMiddleware())
+	{
+		telegram.POST("/link-code", telegramLinkHandler.GenerateCode) // Одноразовый код для /link
+		telegram.GET("/link", telegramLinkHandler.GetLink)            // Привязанный чат
+		telegram.DELETE("/link", telegramLinkHandler.Unlink)          // Отвязка чата
+	}
+
+	// Единый инбокс сообщений клиентов из Telegram и почты
+	inbox := r.Group("/inbox", middleware.AuthMiddleware())
+	{
+		inbox.GET("/threads", inboxHandler.ListThreads)              // Диалоги с клиентами
+		inbox.GET("/threads/:id", inboxHandler.GetThread)            // Диалог по ID
+		inbox.GET("/threads/:id/messages", inboxHandler.GetMessages) // История диалога
+		inbox.POST("/threads/:id/reply", inboxHandler.Reply)         // Ответ клиенту в канал обращения
+		inbox.POST("/threads/:id/read", inboxHandler.MarkRead)       // Отметка о прочтении
+		inbox.GET("/leads/:lead_id", inboxHandler.GetThreadByLead)   // Диалог лида
+	}
+
+	// Уведомления CRM: напоминания и эскалации задач
+	notifications := r.Group("/notifications", middleware.AuthMiddleware())
+	{
+		notifications.GET("/", notificationHandler.List)                       // Уведомления пользователя
+		notifications.GET("/unread-count", notificationHandler.GetUnreadCount) // Количество непрочитанных
+		notifications.POST("/read-all", notificationHandler.MarkAllRead)       // Прочитать все
+		notifications.POST("/:id/read", notificationHandler.MarkRead)          // Отметка о прочтении
+	}
+
+	// Административные маршруты (требуют авторизации)
+	admin := r.Group("/admin", middleware.AuthMiddleware(), middleware.RequireRole(middleware.AdminRoleID))
+	{
+		admin.GET("/sms/messages", smsHandler.ListMessagesHandler) // Журнал SMS со статусами доставки
+	}
+
+	// Маршруты для отчетов (требуют авторизации)
+	reports := r.Group("/reports", middleware.AuthMiddleware())
+	{
+		reports.GET("/summary", reportHandler.GetSummary)
+		reports.GET("/leads/filter", reportHandler.FilterLeads)
+		reports.GET("/deals/filter", reportHandler.FilterDeals)
+		reports.GET("/funnel", reportHandler.Funnel)
+		reports.GET("/conversion", reportHandler.Conversion)
+		reports.GET("/stage-durations", reportHandler.StageDurations)
+		reports.GET("/win-loss", reportHandler.WinLoss)
+		reports.GET("/revenue", reportHandler.Revenue)
+		reports.GET("/forecast", reportHandler.Forecast)
+		reports.GET("/dashboard", reportHandler.Dashboard)
+	}
+
+	// Конструктор отчётов: произвольные фильтры и группировки по разрешённым полям
+	builder := r.Group("/reports/builder", middleware.AuthMiddleware())
+	{
+		builder.GET("", reportBuilderHandler.Schemas)            // Доступные поля
+		builder.GET("/:entity", reportBuilderHandler.Query)      // Запрос в строке запроса
+		builder.POST("/:entity", reportBuilderHandler.QueryJSON) // Запрос в JSON
+	}
+
+	// Отчёты по расписанию: рассылка вложением на email
+	scheduledReports := r.Group("/scheduled-reports", middleware.AuthMiddleware())
+	{
+		scheduledReports.POST("/", scheduledReportHandler.Create)                  // Сохранение отчёта с расписанием
+		scheduledReports.GET("/", scheduledReportHandler.List)                     // Список отчётов
+		scheduledReports.GET("/:id", scheduledReportHandler.GetByID)               // Отчёт по ID
+		scheduledReports.PUT("/:id", scheduledReportHandler.Update)                // Обновление отчёта
+		scheduledReports.DELETE("/:id", scheduledReportHandler.Delete)             // Удаление отчёта
+		scheduledReports.POST("/:id/send", scheduledReportHandler.SendNow)         // Отправка сейчас
+		scheduledReports.GET("/:id/deliveries", scheduledReportHandler.Deliveries) // История отправок
+	}
 
 	return r
 }
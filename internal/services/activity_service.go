@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+var (
+	ErrTimelineNotFound      = errors.New("lead or deal not found")
+	ErrInvalidActivityFilter = errors.New("invalid activity filter")
+)
+
+// ActivityTypes are the event types a timeline can be filtered by.
+var ActivityTypes = []string{
+	models.ActivityLeadCreated,
+	models.ActivityLeadStatusChanged,
+	models.ActivityDealCreated,
+	models.ActivityDealConverted,
+	models.ActivityDealStatusChanged,
+	models.ActivityDocumentGenerated,
+	models.ActivitySMSSent,
+	models.ActivitySMSConfirmed,
+	models.ActivityTaskCompleted,
+	models.ActivityMessageSent,
+	models.ActivityMessageReceived,
+}
+
+const (
+	defaultTimelinePageSize = 50
+	maxTimelinePageSize     = 200
+)
+
+// ActivityRecorder records timeline events for the services that cause them. Record does not
+// fail the caller: errors are only logged.
+type ActivityRecorder interface {
+	Record(ctx context.Context, a *models.Activity)
+}
+
+// ActivityService records events and returns the lead and deal timelines.
+type ActivityService interface {
+	ActivityRecorder
+	LeadTimeline(ctx context.Context, leadID int64, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error)
+	DealTimeline(ctx context.Context, dealID int64, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error)
+}
+
+type activityService struct {
+	repo      repositories.ActivityRepository
+	leads     *repositories.LeadRepository
+	deals     *repositories.DealRepository
+	documents *repositories.DocumentRepository
+	now       func() time.Time
+}
+
+// NewActivityService creates a new instance of ActivityService.
+func NewActivityService(
+	repo repositories.ActivityRepository,
+	leads *repositories.LeadRepository,
+	deals *repositories.DealRepository,
+	documents *repositories.DocumentRepository,
+) ActivityService {
+	return &activityService{repo: repo, leads: leads, deals: deals, documents: documents, now: time.Now}
+}
+
+// Record fills in the lead and deal of the event's entity and stores it.
+func (s *activityService) Record(ctx context.Context, a *models.Activity) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = s.now()
+	}
+	if err := s.resolve(a); err != nil {
+		log.Printf("Activity %s for %s %d: %v", a.Type, a.EntityType, a.EntityID, err)
+	}
+	if a.LeadID == nil && a.DealID == nil {
+		return // nothing would show it
+	}
+	if err := s.repo.Create(ctx, a); err != nil {
+		log.Printf("Activity %s for %s %d: %v", a.Type, a.EntityType, a.EntityID, err)
+	}
+}
+
+// resolve sets LeadID and DealID from the entity: a document belongs to a deal, a deal to a lead.
+func (s *activityService) resolve(a *models.Activity) error {
+	id := a.EntityID
+	switch a.EntityType {
+	case models.EntityTypeLead:
+		if a.LeadID == nil {
+			a.LeadID = &id
+		}
+		return nil
+	case models.EntityTypeDocument:
+		if a.DealID == nil {
+			doc, err := s.documents.GetByID(id)
+			if err != nil || doc == nil {
+				return err
+			}
+			dealID := doc.DealID
+			a.DealID = &dealID
+		}
+	case models.EntityTypeDeal:
+		a.DealID = &id
+	default:
+		return fmt.Errorf("unknown entity type %q", a.EntityType)
+	}
+
+	if a.LeadID != nil {
+		return nil
+	}
+	deal, err := s.deals.GetByID(int(*a.DealID))
+	if err != nil || deal == nil {
+		return err
+	}
+	leadID := int64(deal.LeadID)
+	a.LeadID = &leadID
+	return nil
+}
+
+func (s *activityService) LeadTimeline(ctx context.Context, leadID int64, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error) {
+	lead, err := s.leads.GetByID(int(leadID))
+	if err != nil {
+		return nil, err
+	}
+	if lead == nil {
+		return nil, ErrTimelineNotFound
+	}
+	filter.LeadID = &leadID
+	filter.DealID = nil
+	return s.timeline(ctx, filter, page, size)
+}
+
+func (s *activityService) DealTimeline(ctx context.Context, dealID int64, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error) {
+	deal, err := s.deals.GetByID(int(dealID))
+	if err != nil {
+		return nil, err
+	}
+	if deal == nil {
+		return nil, ErrTimelineNotFound
+	}
+	filter.DealID = &dealID
+	filter.LeadID = nil
+	return s.timeline(ctx, filter, page, size)
+}
+
+func (s *activityService) timeline(ctx context.Context, filter models.ActivityFilter, page, size int) (*models.ActivityPage, error) {
+	for _, t := range filter.Types {
+		if !slices.Contains(ActivityTypes, t) {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidActivityFilter, t)
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidActivityFilter)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultTimelinePageSize
+	}
+	if size > maxTimelinePageSize {
+		size = maxTimelinePageSize
+	}
+	filter.Limit = size
+	filter.Offset = (page - 1) * size
+
+	activities, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ActivityPage{Activities: activities, Total: total, Page: page, Size: size}, nil
+}
+
+// recordActivity records the event if the service was given a recorder.
+func recordActivity(ctx context.Context, recorder ActivityRecorder, a *models.Activity) {
+	if recorder != nil {
+		recorder.Record(ctx, a)
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // decoders for thumbnails
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+	"turcompany/internal/storage"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// AttachmentService defines the interface for uploading and downloading message attachments.
+type AttachmentService interface {
+	Upload(ctx context.Context, uploaderID int64, fileName string, r io.Reader) (*models.MessageAttachment, error)
+	Open(ctx context.Context, userID, attachmentID int64, thumbnail bool) (*models.MessageAttachment, *os.File, error)
+	CleanupPending(ctx context.Context) (int, error)
+}
+
+var (
+	ErrAttachmentNotFound      = errors.New("attachment not found")
+	ErrAttachmentTooLarge      = errors.New("attachment exceeds the size limit")
+	ErrAttachmentType          = errors.New("attachment type is not allowed")
+	ErrAttachmentUnavailable   = errors.New("attachment does not exist, belongs to another user or was already sent")
+	ErrTooManyAttachments      = errors.New("too many attachments in one message")
+	ErrEmptyMessage            = errors.New("message must have content or attachments")
+	ErrAttachmentNoThumbnail   = errors.New("attachment has no thumbnail")
+	errThumbnailSourceTooLarge = errors.New("image is too large for a thumbnail")
+)
+
+const (
+	// maxAttachmentsPerMessage keeps a message with attachments within the NOTIFY payload limit.
+	maxAttachmentsPerMessage = 5
+	// maxThumbnailPixels protects thumbnail generation from decompression bombs.
+	maxThumbnailPixels = 50_000_000
+	attachmentsDir     = "chat_attachments"
+)
+
+// AttachmentPolicy holds the limits for message attachments.
+type AttachmentPolicy struct {
+	MaxSize       int64
+	AllowedTypes  []string
+	ThumbnailSize int
+	PendingTTL    time.Duration
+}
+
+// NewAttachmentPolicy returns a policy with defaults for unset values:
+// 10 MB, JPEG/PNG/WebP/PDF, 320px thumbnails and 24h for unsent uploads.
+func NewAttachmentPolicy(maxSize int64, allowedTypes []string, thumbnailSize int, pendingTTL time.Duration) AttachmentPolicy {
+	p := AttachmentPolicy{
+		MaxSize:       maxSize,
+		AllowedTypes:  allowedTypes,
+		ThumbnailSize: thumbnailSize,
+		PendingTTL:    pendingTTL,
+	}
+	if p.MaxSize <= 0 {
+		p.MaxSize = 10 << 20
+	}
+	if len(p.AllowedTypes) == 0 {
+		p.AllowedTypes = []string{"image/jpeg", "image/png", "image/webp", "application/pdf"}
+	}
+	if p.ThumbnailSize <= 0 {
+		p.ThumbnailSize = 320
+	}
+	if p.PendingTTL <= 0 {
+		p.PendingTTL = 24 * time.Hour
+	}
+	return p
+}
+
+func (p AttachmentPolicy) allows(contentType string) bool {
+	for _, t := range p.AllowedTypes {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+type attachmentService struct {
+	repo          repositories.MessageAttachmentRepository
+	conversations repositories.ConversationRepository
+	files         *storage.Local
+	policy        AttachmentPolicy
+	now           func() time.Time
+}
+
+// NewAttachmentService creates a new instance of AttachmentService.
+func NewAttachmentService(
+	repo repositories.MessageAttachmentRepository,
+	conversations repositories.ConversationRepository,
+	files *storage.Local,
+	policy AttachmentPolicy,
+) AttachmentService {
+	return &attachmentService{
+		repo:          repo,
+		conversations: conversations,
+		files:         files,
+		policy:        policy,
+		now:           time.Now,
+	}
+}
+
+// Upload stores a file that is not yet linked to a message. The type is detected from
+// the content, not from the file name or the client's Content-Type.
+func (s *attachmentService) Upload(ctx context.Context, uploaderID int64, fileName string, r io.Reader) (*models.MessageAttachment, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
+	head = head[:n]
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !s.policy.allows(contentType) {
+		return nil, ErrAttachmentType
+	}
+
+	now := s.now()
+	name := randomName()
+	rel := filepath.Join(attachmentsDir, fmt.Sprintf("user_%d", uploaderID), now.Format("200601"), name+extensionFor(contentType, fileName))
+
+	storedPath, size, err := s.files.Save(rel, io.MultiReader(bytes.NewReader(head), r), s.policy.MaxSize)
+	if err != nil {
+		if errors.Is(err, storage.ErrTooLarge) {
+			return nil, ErrAttachmentTooLarge
+		}
+		return nil, err
+	}
+
+	attachment := &models.MessageAttachment{
+		UploaderID:  uploaderID,
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		FilePath:    storedPath,
+		CreatedAt:   now,
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumbRel := filepath.Join(filepath.Dir(rel), name+"_thumb.jpg")
+		thumbPath, err := s.makeThumbnail(storedPath, thumbRel)
+		if err != nil {
+			// A thumbnail is optional: the file can still be downloaded
+			log.Printf("Attachment thumbnail %s: %v", storedPath, err)
+		}
+		attachment.ThumbnailPath = thumbPath
+		attachment.HasThumbnail = thumbPath != ""
+	}
+
+	if err := s.repo.Create(ctx, attachment); err != nil {
+		_ = s.files.Remove(storedPath)
+		if attachment.ThumbnailPath != "" {
+			_ = s.files.Remove(attachment.ThumbnailPath)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Open returns the attachment file (or its thumbnail) if the user may see it: the uploader,
+// the sender or receiver of a direct message, or a participant of the group conversation.
+func (s *attachmentService) Open(ctx context.Context, userID, attachmentID int64, thumbnail bool) (*models.MessageAttachment, *os.File, error) {
+	access, err := s.repo.FindAccess(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if access == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err := s.checkAccess(ctx, userID, access); err != nil {
+		return nil, nil, err
+	}
+
+	attachment := &access.Attachment
+	path := attachment.FilePath
+	if thumbnail {
+		if !attachment.HasThumbnail {
+			return nil, nil, ErrAttachmentNoThumbnail
+		}
+		path = attachment.ThumbnailPath
+	}
+
+	f, err := s.files.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+	return attachment, f, nil
+}
+
+// CleanupPending deletes attachments that were uploaded but never sent within PendingTTL.
+func (s *attachmentService) CleanupPending(ctx context.Context) (int, error) {
+	deleted, err := s.repo.DeletePendingBefore(ctx, s.now().Add(-s.policy.PendingTTL))
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range deleted {
+		if err := s.files.Remove(a.FilePath); err != nil {
+			log.Printf("Remove attachment %d: %v", a.ID, err)
+		}
+		if a.ThumbnailPath != "" {
+			_ = s.files.Remove(a.ThumbnailPath)
+		}
+	}
+	return len(deleted), nil
+}
+
+// RunAttachmentCleanup periodically deletes unsent attachments until ctx is cancelled.
+func RunAttachmentCleanup(ctx context.Context, service AttachmentService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := service.CleanupPending(ctx); err != nil {
+			log.Printf("Attachment cleanup: %v", err)
+		} else if n > 0 {
+			log.Printf("Attachment cleanup: removed %d unsent attachments", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *attachmentService) checkAccess(ctx context.Context, userID int64, access *models.AttachmentAccess) error {
+	if access.Attachment.MessageID == nil {
+		if access.Attachment.UploaderID != userID {
+			return ErrAttachmentNotFound
+		}
+		return nil
+	}
+	if access.ConversationID != nil {
+		member, err := s.conversations.FindParticipant(ctx, *access.ConversationID, userID)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return ErrNotConversationMember
+		}
+		return nil
+	}
+	if userID != access.SenderID && userID != access.ReceiverID {
+		return ErrNotConversationMember
+	}
+	return nil
+}
+
+// makeThumbnail scales the image down to fit ThumbnailSize and stores it as JPEG.
+func (s *attachmentService) makeThumbnail(storedPath, thumbRel string) (string, error) {
+	f, err := s.files.Open(storedPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return "", errThumbnailSourceTooLarge
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if limit := s.policy.ThumbnailSize; w > limit || h > limit {
+		if w >= h {
+			w, h = limit, max(1, h*limit/w)
+		} else {
+			w, h = max(1, w*limit/h), limit
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return "", err
+	}
+	thumbPath, _, err := s.files.Save(thumbRel, &buf, 0)
+	return thumbPath, err
+}
+
+// attachMessageFiles replaces attachment IDs in msg.Attachments with the sender's pending
+// attachments and checks that the message is not empty.
+func attachMessageFiles(ctx context.Context, repo repositories.MessageAttachmentRepository, msg *models.Message, attachmentIDs []int64) error {
+	ids := uniqueIDs(attachmentIDs, 0)
+	if strings.TrimSpace(msg.Content) == "" && len(ids) == 0 {
+		return ErrEmptyMessage
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(ids) > maxAttachmentsPerMessage {
+		return ErrTooManyAttachments
+	}
+
+	pending, err := repo.FindPending(ctx, msg.SenderID, ids)
+	if err != nil {
+		return err
+	}
+	if len(pending) != len(ids) {
+		return ErrAttachmentUnavailable
+	}
+	msg.Attachments = pending
+	return nil
+}
+
+func randomName() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// extensionFor keeps the client's extension if it matches the detected type.
+func extensionFor(contentType, fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext != "" && mime.TypeByExtension(ext) != "" {
+		if t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext)); t == contentType {
+			return ext
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// cleanFileName strips directories from the client's file name.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[len(name)-255:], "")
+	}
+	return name
+}
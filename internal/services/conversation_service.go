@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// ConversationService defines the interface for group conversation business logic.
+type ConversationService interface {
+	Create(ctx context.Context, conv *models.GroupConversation, participantIDs []int64) (*models.GroupConversation, error)
+	Get(ctx context.Context, userID, conversationID int64) (*models.GroupConversation, error)
+	List(ctx context.Context, userID int64) ([]models.GroupConversationSummary, error)
+	ListByEntity(ctx context.Context, userID int64, entityType string, entityID int64) ([]models.GroupConversation, error)
+	AddParticipants(ctx context.Context, userID, conversationID int64, participantIDs []int64) ([]models.ConversationParticipant, error)
+	RemoveParticipant(ctx context.Context, userID, conversationID, participantID int64) error
+	Send(ctx context.Context, userID, conversationID int64, content string, mentionIDs, attachmentIDs []int64) (*models.Message, error)
+	GetHistory(ctx context.Context, userID, conversationID int64, cursor models.MessageCursor) (*models.MessagePage, error)
+	MarkRead(ctx context.Context, userID, conversationID, upToMessageID int64) error
+	GetMentions(ctx context.Context, userID int64, limit, offset int) ([]models.Mention, error)
+}
+
+var (
+	ErrConversationNotFound   = errors.New("conversation not found")
+	ErrInvalidEntity          = errors.New("entity_type must be lead, deal or document and the entity must exist")
+	ErrNotConversationOwner   = errors.New("only the conversation owner can remove other participants")
+	ErrMentionNotParticipant  = errors.New("mentioned user is not a participant of this conversation")
+	ErrTooManyParticipants    = errors.New("too many participants")
+	ErrEmptyConversationTitle = errors.New("conversation title is empty")
+)
+
+// maxParticipants keeps the recipient list of a real-time event within the NOTIFY payload limit.
+const maxParticipants = 100
+
+// mentionPattern matches @email mentions in message content, e.g. "@manager@company.kz".
+var mentionPattern = regexp.MustCompile(`@([\w.+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+// ConversationNotifier delivers group conversation events to connected participants in real time.
+type ConversationNotifier interface {
+	NotifyConversationMessage(msg *models.Message, participantIDs []int64)
+	NotifyConversationRead(receipt models.ConversationReadReceipt, participantIDs []int64)
+	NotifyMention(mention models.Mention)
+}
+
+type conversationService struct {
+	repo        repositories.ConversationRepository
+	attachments repositories.MessageAttachmentRepository
+	notifier    ConversationNotifier
+	activities  ActivityRecorder
+}
+
+// NewConversationService creates a new instance of ConversationService.
+// notifier may be nil when real-time delivery is not needed.
+func NewConversationService(
+	repo repositories.ConversationRepository,
+	attachments repositories.MessageAttachmentRepository,
+	notifier ConversationNotifier,
+	activities ActivityRecorder,
+) ConversationService {
+	return &conversationService{repo: repo, attachments: attachments, notifier: notifier, activities: activities}
+}
+
+// Create stores a conversation owned by conv.CreatedBy. If EntityType is set the thread
+// is linked to that lead, deal or document, which must exist.
+func (s *conversationService) Create(ctx context.Context, conv *models.GroupConversation, participantIDs []int64) (*models.GroupConversation, error) {
+	conv.Title = strings.TrimSpace(conv.Title)
+	if conv.Title == "" {
+		return nil, ErrEmptyConversationTitle
+	}
+	if conv.EntityType != "" || conv.EntityID != 0 {
+		exists, err := s.repo.EntityExists(ctx, conv.EntityType, conv.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrInvalidEntity
+		}
+	}
+
+	members := uniqueIDs(participantIDs, conv.CreatedBy)
+	if len(members)+1 > maxParticipants {
+		return nil, ErrTooManyParticipants
+	}
+
+	conv.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, conv, members); err != nil {
+		return nil, err
+	}
+	return s.withParticipants(ctx, conv)
+}
+
+// Get returns the conversation with its participants and their read positions.
+func (s *conversationService) Get(ctx context.Context, userID, conversationID int64) (*models.GroupConversation, error) {
+	conv, err := s.conversationFor(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withParticipants(ctx, conv)
+}
+
+func (s *conversationService) List(ctx context.Context, userID int64) ([]models.GroupConversationSummary, error) {
+	return s.repo.FindForUser(ctx, userID)
+}
+
+func (s *conversationService) ListByEntity(ctx context.Context, userID int64, entityType string, entityID int64) ([]models.GroupConversation, error) {
+	return s.repo.FindByEntity(ctx, userID, entityType, entityID)
+}
+
+// AddParticipants lets any participant invite other users.
+func (s *conversationService) AddParticipants(ctx context.Context, userID, conversationID int64, participantIDs []int64) ([]models.ConversationParticipant, error) {
+	if _, err := s.conversationFor(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.FindParticipants(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	newIDs := uniqueIDs(participantIDs, 0)
+	if len(current)+len(newIDs) > maxParticipants {
+		return nil, ErrTooManyParticipants
+	}
+
+	if _, err := s.repo.AddParticipants(ctx, conversationID, newIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.FindParticipants(ctx, conversationID)
+}
+
+// RemoveParticipant lets a participant leave; only the owner can remove someone else.
+// If the owner leaves, the participant who joined first becomes the owner.
+func (s *conversationService) RemoveParticipant(ctx context.Context, userID, conversationID, participantID int64) error {
+	member, err := s.participant(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if participantID != userID && member.Role != models.ParticipantRoleOwner {
+		return ErrNotConversationOwner
+	}
+
+	removed, err := s.repo.RemoveParticipant(ctx, conversationID, participantID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotConversationMember
+	}
+	return nil
+}
+
+// Send stores a message from a participant with their previously uploaded attachments.
+// Mentions come from mentionIDs, which must all be participants, and from @email
+// references in the content that match a participant.
+func (s *conversationService) Send(ctx context.Context, userID, conversationID int64, content string, mentionIDs, attachmentIDs []int64) (*models.Message, error) {
+	conv, err := s.conversationFor(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.repo.FindParticipants(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, err := resolveMentions(content, mentionIDs, participants, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &models.Message{
+		SenderID:       userID,
+		ConversationID: &conversationID,
+		Content:        content,
+		SentAt:         time.Now(),
+		Mentions:       mentions,
+	}
+	if err := attachMessageFiles(ctx, s.attachments, msg, attachmentIDs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.StoreMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	if s.notifier != nil {
+		s.notifier.NotifyConversationMessage(msg, participantIDs(participants))
+		for _, mentioned := range mentions {
+			s.notifier.NotifyMention(models.Mention{UserID: mentioned, Message: *msg})
+		}
+	}
+	if conv.EntityType != "" {
+		recordActivity(ctx, s.activities, &models.Activity{
+			Type:       models.ActivityMessageSent,
+			EntityType: conv.EntityType,
+			EntityID:   conv.EntityID,
+			ActorID:    userID,
+			Summary:    fmt.Sprintf("Message in \"%s\": %s", conv.Title, preview(strings.TrimSpace(content))),
+			Details: map[string]string{
+				"conversation_id": strconv.FormatInt(conversationID, 10),
+				"message_id":      strconv.FormatInt(msg.ID, 10),
+			},
+		})
+	}
+	return msg, nil
+}
+
+// GetHistory returns one page of the conversation and moves the caller's read position
+// to the newest message in it.
+func (s *conversationService) GetHistory(ctx context.Context, userID, conversationID int64, cursor models.MessageCursor) (*models.MessagePage, error) {
+	member, err := s.participant(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Before > 0 && cursor.After > 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Limit <= 0 {
+		cursor.Limit = defaultHistoryLimit
+	}
+	if cursor.Limit > maxHistoryLimit {
+		cursor.Limit = maxHistoryLimit
+	}
+
+	page, err := s.repo.FindHistory(ctx, conversationID, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(page.Messages); n > 0 && page.Messages[n-1].ID > member.LastReadMessageID {
+		if err := s.markRead(ctx, userID, conversationID, page.Messages[n-1].ID); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// MarkRead moves the caller's read position to upToMessageID, which must belong to the conversation.
+func (s *conversationService) MarkRead(ctx context.Context, userID, conversationID, upToMessageID int64) error {
+	if _, err := s.participant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	msg, err := s.repo.FindMessage(ctx, conversationID, upToMessageID)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		return ErrMessageNotFound
+	}
+	return s.markRead(ctx, userID, conversationID, upToMessageID)
+}
+
+func (s *conversationService) GetMentions(ctx context.Context, userID int64, limit, offset int) ([]models.Mention, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	return s.repo.FindMentions(ctx, userID, limit, offset)
+}
+
+func (s *conversationService) markRead(ctx context.Context, userID, conversationID, upToMessageID int64) error {
+	readAt := time.Now()
+	moved, err := s.repo.MarkRead(ctx, conversationID, userID, upToMessageID, readAt)
+	if err != nil || !moved || s.notifier == nil {
+		return err
+	}
+
+	participants, err := s.repo.FindParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	s.notifier.NotifyConversationRead(models.ConversationReadReceipt{
+		ConversationID: conversationID,
+		ReaderID:       userID,
+		UpToMessageID:  upToMessageID,
+		ReadAt:         readAt,
+	}, participantIDs(participants))
+	return nil
+}
+
+// conversationFor returns the conversation if userID takes part in it.
+func (s *conversationService) conversationFor(ctx context.Context, userID, conversationID int64) (*models.GroupConversation, error) {
+	if _, err := s.participant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	conv, err := s.repo.FindByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+// participant returns the caller's membership. A missing conversation and a conversation
+// the caller is not in both report ErrNotConversationMember, so IDs cannot be probed.
+func (s *conversationService) participant(ctx context.Context, userID, conversationID int64) (*models.ConversationParticipant, error) {
+	member, err := s.repo.FindParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotConversationMember
+	}
+	return member, nil
+}
+
+func (s *conversationService) withParticipants(ctx context.Context, conv *models.GroupConversation) (*models.GroupConversation, error) {
+	participants, err := s.repo.FindParticipants(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	conv.Participants = participants
+	return conv, nil
+}
+
+// resolveMentions merges explicit mention IDs with @email mentions in content.
+// The sender is never mentioned. An explicit ID of a non-participant is an error, while
+// an @email that matches no participant is ordinary text, e.g. a client's address.
+func resolveMentions(content string, mentionIDs []int64, participants []models.ConversationParticipant, senderID int64) ([]int64, error) {
+	byEmail := make(map[string]int64, len(participants))
+	members := make(map[int64]struct{}, len(participants))
+	for _, p := range participants {
+		byEmail[strings.ToLower(p.Email)] = p.UserID
+		members[p.UserID] = struct{}{}
+	}
+
+	ids := uniqueIDs(mentionIDs, senderID)
+	for _, id := range ids {
+		if _, ok := members[id]; !ok {
+			return nil, ErrMentionNotParticipant
+		}
+	}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if id, ok := byEmail[strings.ToLower(match[1])]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return uniqueIDs(ids, senderID), nil
+}
+
+// uniqueIDs drops duplicates, non-positive IDs and exclude, keeping the original order.
+func uniqueIDs(ids []int64, exclude int64) []int64 {
+	seen := map[int64]struct{}{}
+	result := []int64{}
+	for _, id := range ids {
+		if _, ok := seen[id]; ok || id <= 0 || id == exclude {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+func participantIDs(participants []models.ConversationParticipant) []int64 {
+	ids := make([]int64, len(participants))
+	for i, p := range participants {
+		ids[i] = p.UserID
+	}
+	return ids
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"turcompany/internal/models"
+)
+
+func TestResolveMentions(t *testing.T) {
+	participants := []models.ConversationParticipant{
+		{UserID: 1, Email: "owner@turcompany.kz"},
+		{UserID: 2, Email: "Manager@TurCompany.kz"},
+		{UserID: 3, Email: "agent@turcompany.kz"},
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		mentionIDs []int64
+		want       []int64
+		err        error
+	}{
+		{name: "email of participant", content: "@manager@turcompany.kz посмотри", want: []int64{2}},
+		{name: "explicit and email", content: "@agent@turcompany.kz", mentionIDs: []int64{2}, want: []int64{2, 3}},
+		{name: "client email is text", content: "пишите на @client@example.com", want: []int64{}},
+		{name: "sender is skipped", content: "@owner@turcompany.kz", mentionIDs: []int64{1}, want: []int64{}},
+		{name: "explicit non-participant", content: "привет", mentionIDs: []int64{4}, err: ErrMentionNotParticipant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveMentions(tt.content, tt.mentionIDs, participants, 1)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("mentions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"time"
+	"turcompany/internal/models"
+)
+
+// Dashboard returns the key figures of the leads and deals created between from and to
+// (the last twelve buckets by default) with a series per bucket, and the open pipeline,
+// converted into currency. It reads the daily rollups only, so it does not depend on the
+// size of the lead and deal tables.
+func (s *ReportService) Dashboard(ctx context.Context, filter models.ReportFilter, currency string) (*models.DashboardReport, error) {
+	if err := validateReportFilter(&filter); err != nil {
+		return nil, err
+	}
+	currency, err := s.reportCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	to := addReportPeriods(truncateReportPeriod(s.now(), filter.Interval), filter.Interval, 1)
+	periods, err := reportPeriods(filter, addReportPeriods(to, filter.Interval, -defaultReportBuckets), to)
+	if err != nil {
+		return nil, err
+	}
+	from, _ := time.Parse(reportDateLayout, periods[0])
+	filter.From = &from
+	filter.To = timePtr(addReportPeriods(from, filter.Interval, len(periods)))
+
+	leads, err := s.Reports.RollupLeads(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	deals, err := s.Reports.RollupDeals(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	all := models.ReportFilter{OwnerID: filter.OwnerID, Source: filter.Source}
+	pipeline, err := s.Reports.RollupDeals(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.Reports.RollupState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.DashboardReport{
+		Currency:      currency,
+		Interval:      filter.Interval,
+		From:          periods[0],
+		To:            filter.To.Format(reportDateLayout),
+		LeadsByStatus: map[string]int{},
+		DealsByStatus: map[string]int{},
+		Series:        make([]models.DashboardPoint, len(periods)),
+		Rollup:        *state,
+	}
+	points := make(map[string]*models.DashboardPoint, len(periods))
+	for i, period := range periods {
+		report.Series[i].Period = period
+		points[period] = &report.Series[i]
+	}
+
+	for _, c := range leads {
+		report.Leads += c.Leads
+		report.ConvertedLeads += c.Converted
+		report.LeadsByStatus[c.Status] += c.Leads
+		if p := points[c.Period]; p != nil {
+			p.Leads += c.Leads
+			p.Converted += c.Converted
+		}
+	}
+	report.ConversionRate = ratio(report.ConvertedLeads, report.Leads)
+
+	skipped := map[string]bool{}
+	for _, a := range deals {
+		report.Deals += a.Deals
+		report.DealsByStatus[a.Status] += a.Deals
+		p := points[a.Period]
+		if p != nil {
+			p.Deals += a.Deals
+		}
+		switch a.Status {
+		case models.DealStatusLost:
+			report.LostDeals += a.Deals
+		case models.DealStatusWon:
+			report.WonDeals += a.Deals
+			if p != nil {
+				p.WonDeals += a.Deals
+			}
+			amount, ok := s.Policy.convert(a.Amount, a.Currency, currency)
+			if !ok {
+				skipped[a.Currency] = true
+				continue
+			}
+			report.WonAmount += amount
+			if p != nil {
+				p.WonAmount += amount
+			}
+		}
+	}
+	report.WinRate = ratio(report.WonDeals, report.WonDeals+report.LostDeals)
+	if report.WonDeals > 0 {
+		report.AvgWonAmount = roundAmount(report.WonAmount / float64(report.WonDeals))
+	}
+	report.WonAmount = roundAmount(report.WonAmount)
+	for i := range report.Series {
+		report.Series[i].WonAmount = roundAmount(report.Series[i].WonAmount)
+	}
+
+	for _, a := range pipeline {
+		if a.Status == models.DealStatusWon || a.Status == models.DealStatusLost {
+			continue
+		}
+		amount, ok := s.Policy.convert(a.Amount, a.Currency, currency)
+		if !ok {
+			skipped[a.Currency] = true
+			continue
+		}
+		addForecastTotal(&report.Pipeline, models.ForecastTotal{
+			Deals:    a.Deals,
+			Amount:   amount,
+			Weighted: amount * s.Policy.probability(a.Status),
+		})
+	}
+	roundForecastTotal(&report.Pipeline)
+	report.SkippedCurrencies = sortedKeys(skipped)
+	return report, nil
+}
@@ -1,27 +1,85 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"turcompany/internal/models"
 	"turcompany/internal/repositories"
 )
 
 type DealService struct {
-	Repo *repositories.DealRepository
+	Repo          *repositories.DealRepository
+	taskTemplates TaskTemplateService
+	activities    ActivityRecorder
 }
 
-func NewDealService(repo *repositories.DealRepository) *DealService {
-	return &DealService{Repo: repo}
+// NewDealService создаёт сервис сделок; taskTemplates может быть nil — тогда задачи
+// по шаблонам при смене этапа не создаются.
+func NewDealService(repo *repositories.DealRepository, taskTemplates TaskTemplateService, activities ActivityRecorder) *DealService {
+	return &DealService{Repo: repo, taskTemplates: taskTemplates, activities: activities}
 }
 
 func (s *DealService) Create(deal *models.Deals) (int64, error) {
 	if deal.Status == "" {
 		deal.Status = "new"
 	}
-	return s.Repo.Create(deal)
+	id, err := s.Repo.Create(deal)
+	if err != nil {
+		return 0, err
+	}
+	deal.ID = int(id)
+	s.record(deal, models.ActivityDealCreated, fmt.Sprintf("Deal created: %s %s", deal.Amount, deal.Currency),
+		map[string]string{"status": deal.Status})
+	s.onStage(deal)
+	return id, nil
 }
 
 func (s *DealService) Update(deal *models.Deals) error {
-	return s.Repo.Update(deal)
+	if deal.Status != models.DealStatusLost {
+		deal.LossReason = ""
+	}
+	// Шаблоны задач применяются при переходе на новый этап и при первом указании даты вылета
+	previous, err := s.Repo.GetByID(deal.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Update(deal); err != nil {
+		return err
+	}
+	if previous != nil && previous.Status == deal.Status && previous.DepartureDate == nil && deal.DepartureDate != nil {
+		s.onStage(deal)
+	}
+	if previous == nil || previous.Status != deal.Status {
+		if previous != nil {
+			summary := fmt.Sprintf("Deal status changed from %s to %s", previous.Status, deal.Status)
+			details := map[string]string{"from": previous.Status, "to": deal.Status}
+			if deal.LossReason != "" {
+				summary += ": " + deal.LossReason
+				details["loss_reason"] = deal.LossReason
+			}
+			s.record(deal, models.ActivityDealStatusChanged, summary, details)
+		}
+		s.onStage(deal)
+	}
+	return nil
+}
+
+// record добавляет событие в ленту сделки и её лида.
+func (s *DealService) record(deal *models.Deals, activityType, summary string, details map[string]string) {
+	recordActivity(context.Background(), s.activities, &models.Activity{
+		Type:       activityType,
+		EntityType: models.EntityTypeDeal,
+		EntityID:   int64(deal.ID),
+		Summary:    summary,
+		Details:    details,
+	})
+}
+
+// onStage создаёт задачи по шаблонам текущего этапа сделки.
+func (s *DealService) onStage(deal *models.Deals) {
+	if s.taskTemplates != nil {
+		s.taskTemplates.OnDealStage(context.Background(), deal)
+	}
 }
 func (s *DealService) GetByID(id int) (*models.Deals, error) {
 	return s.Repo.GetByID(id)
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"turcompany/internal/config"
+	"turcompany/internal/repositories"
+)
+
+const defaultNumberWidth = 6
+
+// DocumentNumberingService выдаёт последовательные номера документов
+// с отдельным счётчиком для каждого типа документа и ежегодным сбросом.
+type DocumentNumberingService struct {
+	repo    *repositories.DocumentNumberRepository
+	formats map[string]config.DocumentNumberFormat
+	now     func() time.Time
+}
+
+func NewDocumentNumberingService(repo *repositories.DocumentNumberRepository, formats map[string]config.DocumentNumberFormat) *DocumentNumberingService {
+	return &DocumentNumberingService{
+		repo:    repo,
+		formats: formats,
+		now:     time.Now,
+	}
+}
+
+// Allocate выделяет следующий номер для docType в рамках транзакции tx.
+// Номер становится окончательным только после фиксации транзакции.
+func (s *DocumentNumberingService) Allocate(tx *sql.Tx, docType string) (string, error) {
+	year := s.now().Year()
+	seq, err := s.repo.NextValue(tx, docType, year)
+	if err != nil {
+		return "", err
+	}
+	return s.Format(docType, year, seq), nil
+}
+
+// Format собирает номер по шаблону типа документа. Для типов без настроенного
+// шаблона используется "<DOC_TYPE>-{year}-{seq}".
+func (s *DocumentNumberingService) Format(docType string, year int, seq int64) string {
+	format, ok := s.formats[docType]
+	if !ok || format.Pattern == "" {
+		format.Pattern = strings.ToUpper(docType) + "-{year}-{seq}"
+	}
+	width := format.Width
+	if width <= 0 {
+		width = defaultNumberWidth
+	}
+
+	return strings.NewReplacer(
+		"{year}", fmt.Sprintf("%04d", year),
+		"{seq}", fmt.Sprintf("%0*d", width, seq),
+	).Replace(format.Pattern)
+}
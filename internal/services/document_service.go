@@ -1,22 +1,27 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/pdf"
 	"turcompany/internal/repositories"
+	"turcompany/internal/storage"
 )
 
 type DocumentService struct {
-	Repo     *repositories.DocumentRepository
-	LeadRepo *repositories.LeadRepository
-	DealRepo *repositories.DealRepository
-	smsRepo  *repositories.SMSConfirmationRepository
-	pdfGen   pdf.Generator
-	basePath string
+	Repo       *repositories.DocumentRepository
+	LeadRepo   *repositories.LeadRepository
+	DealRepo   *repositories.DealRepository
+	smsRepo    *repositories.SMSConfirmationRepository
+	numbering  *DocumentNumberingService
+	pdfGen     pdf.Generator
+	files      *storage.Local
+	activities ActivityRecorder
 }
 
 func NewDocumentService(
@@ -24,15 +29,19 @@ func NewDocumentService(
 	leadRepo *repositories.LeadRepository,
 	dealRepo *repositories.DealRepository,
 	smsRepo *repositories.SMSConfirmationRepository,
-	basePath string,
+	numbering *DocumentNumberingService,
+	files *storage.Local,
+	activities ActivityRecorder,
 ) *DocumentService {
 	return &DocumentService{
-		Repo:     repo,
-		LeadRepo: leadRepo,
-		DealRepo: dealRepo,
-		smsRepo:  smsRepo,
-		pdfGen:   pdf.NewDocumentGenerator(),
-		basePath: basePath,
+		Repo:       repo,
+		LeadRepo:   leadRepo,
+		DealRepo:   dealRepo,
+		smsRepo:    smsRepo,
+		numbering:  numbering,
+		pdfGen:     pdf.NewDocumentGenerator(),
+		files:      files,
+		activities: activities,
 	}
 }
 
@@ -46,6 +55,9 @@ func (s *DocumentService) CreateDocumentFromLead(leadID int, docType string) (*m
 	// Получаем или создаем сделку для этого лида
 	deal, err := s.DealRepo.GetByLeadID(leadID)
 	if err != nil {
+		return nil, err
+	}
+	if deal == nil {
 		// Если сделки нет, создаем новую
 		newDeal := &models.Deals{
 			LeadID:    leadID,
@@ -60,69 +72,136 @@ func (s *DocumentService) CreateDocumentFromLead(leadID int, docType string) (*m
 		deal = newDeal
 	}
 
-	// Создаем структуру директорий (путь уже абсолютный из конструктора)
-	docDir := filepath.Join(s.basePath, fmt.Sprintf("deal_%d", deal.ID))
-	if err := os.MkdirAll(docDir, 0755); err != nil {
-		return nil, fmt.Errorf("создание директории: %w", err)
-	}
-
-	// Формируем имя файла и путь
+	// Формируем имя файла; в БД сохраняется путь внутри хранилища для переносимости
 	fileName := fmt.Sprintf("%s_%s_%s.pdf",
 		lead.Title,
 		docType,
 		time.Now().Format("20060102_150405"),
 	)
-	filePath := filepath.Join(docDir, fileName)
-
-	// Сохраняем относительный путь в БД для переносимости
-	relPath, err := filepath.Rel(s.basePath, filePath)
-	if err != nil {
-		return nil, fmt.Errorf("создание относительного пути: %w", err)
+	storedPath, filePath := s.files.Path(filepath.Join(fmt.Sprintf("deal_%d", deal.ID), fileName))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("создание директории: %w", err)
 	}
 
-	// Создаем документ с относительным путем
 	doc := &models.Document{
 		DealID:   int64(deal.ID),
 		DocType:  docType,
-		FilePath: filepath.Join("document_storage", relPath), // Сохраняем относительный путь
+		FilePath: storedPath,
 		Status:   "new",
 	}
 
+	if docType != "contract" && docType != "invoice" {
+		return nil, fmt.Errorf("неизвестный тип документа: %s", docType)
+	}
+
+	// Номер выделяется в той же транзакции, что и вставка документа:
+	// если генерация PDF или сохранение не удались, номер не расходуется.
+	tx, err := s.Repo.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	doc.Number, err = s.numbering.Allocate(tx, docType)
+	if err != nil {
+		return nil, err
+	}
+
 	// При генерации PDF используем абсолютный путь
-	switch docType {
+	if err := s.generatePDF(doc, lead, deal, filePath, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := s.Repo.Create(tx, doc)
+	if err != nil {
+		_ = os.Remove(filePath)
+		return nil, fmt.Errorf("сохранение документа: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = os.Remove(filePath)
+		return nil, fmt.Errorf("фиксация документа: %w", err)
+	}
+
+	doc.ID = id
+	s.recordGenerated(doc)
+	return doc, nil
+}
+
+// recordGenerated добавляет в ленту сделки событие о созданном документе.
+func (s *DocumentService) recordGenerated(doc *models.Document) {
+	dealID := doc.DealID
+	recordActivity(context.Background(), s.activities, &models.Activity{
+		Type:       models.ActivityDocumentGenerated,
+		EntityType: models.EntityTypeDocument,
+		EntityID:   doc.ID,
+		DealID:     &dealID,
+		Summary:    fmt.Sprintf("Document %s %s generated", doc.DocType, doc.Number),
+		Details:    map[string]string{"doc_type": doc.DocType, "number": doc.Number},
+	})
+}
+
+// GenerateSignedCopy перегенерирует PDF документа с листом подписи рядом с
+// исходным файлом. Возвращает путь для хранения в БД и абсолютный путь к файлу.
+func (s *DocumentService) GenerateSignedCopy(doc *models.Document, sig pdf.SignatureData) (string, string, error) {
+	deal, err := s.DealRepo.GetByID(int(doc.DealID))
+	if err != nil {
+		return "", "", fmt.Errorf("получение сделки документа: %w", err)
+	}
+	if deal == nil {
+		return "", "", fmt.Errorf("сделка документа %d не найдена", doc.ID)
+	}
+	lead, err := s.LeadRepo.GetByID(deal.LeadID)
+	if err != nil {
+		return "", "", fmt.Errorf("получение lead: %w", err)
+	}
+
+	storedPath := strings.TrimSuffix(doc.FilePath, filepath.Ext(doc.FilePath)) + "_signed.pdf"
+	absPath := s.ResolvePath(storedPath)
+	if err := s.generatePDF(doc, lead, deal, absPath, &sig); err != nil {
+		return "", "", err
+	}
+	return storedPath, absPath, nil
+}
+
+// ResolvePath переводит путь из БД ("document_storage/...") в путь на диске.
+func (s *DocumentService) ResolvePath(storedPath string) string {
+	return s.files.Resolve(storedPath)
+}
+
+func (s *DocumentService) generatePDF(doc *models.Document, lead *models.Leads, deal *models.Deals, filePath string, sig *pdf.SignatureData) error {
+	var err error
+	switch doc.DocType {
 	case "contract":
 		err = s.pdfGen.GenerateContract(pdf.ContractData{
+			Number:       doc.Number,
 			LeadTitle:    lead.Title,
 			DealID:       deal.ID,
 			Amount:       deal.Amount,
 			Currency:     deal.Currency,
 			CreatedAt:    time.Now(),
-			DocumentPath: filePath, // Используем абсолютный путь
+			DocumentPath: filePath,
+			Signature:    sig,
 		})
 	case "invoice":
 		err = s.pdfGen.GenerateInvoice(pdf.InvoiceData{
+			Number:       doc.Number,
 			LeadTitle:    lead.Title,
 			DealID:       deal.ID,
 			Amount:       deal.Amount,
 			Currency:     deal.Currency,
 			CreatedAt:    time.Now(),
-			DocumentPath: filePath, // Используем абсолютный путь
+			DocumentPath: filePath,
+			Signature:    sig,
 		})
 	default:
-		return nil, fmt.Errorf("неизвестный тип документа: %s", docType)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("генерация PDF: %w", err)
+		return fmt.Errorf("неизвестный тип документа: %s", doc.DocType)
 	}
 
-	id, err := s.Repo.Create(doc)
 	if err != nil {
-		return nil, fmt.Errorf("сохранение документа: %w", err)
+		return fmt.Errorf("генерация PDF: %w", err)
 	}
-
-	doc.ID = id
-	return doc, nil
+	return nil
 }
 
 // Существующие методы остаются без изменений
@@ -137,7 +216,28 @@ func (s *DocumentService) CreateDocument(doc *models.Document) (int64, error) {
 		return 0, fmt.Errorf("создание директории для документа: %w", err)
 	}
 
-	return s.Repo.Create(doc)
+	tx, err := s.Repo.BeginTx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	doc.Number, err = s.numbering.Allocate(tx, doc.DocType)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.Repo.Create(tx, doc)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("фиксация документа: %w", err)
+	}
+	doc.ID = id
+	s.recordGenerated(doc)
+	return id, nil
 }
 
 func (s *DocumentService) GetDocument(id int64) (*models.Document, error) {
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/pdf"
+	"turcompany/internal/repositories"
+)
+
+var (
+	ErrDocumentNotFound      = errors.New("документ не найден")
+	ErrDocumentAlreadySigned = errors.New("документ уже подписан")
+	ErrInvalidSignCode       = errors.New("неверный или просроченный код подтверждения")
+)
+
+// SignerInfo сведения о клиенте, с которого был подтверждён код подписи.
+type SignerInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SignatureCheck результат проверки целостности подписанного файла.
+type SignatureCheck struct {
+	Signature   *models.DocumentSignature `json:"signature"`
+	CurrentHash string                    `json:"current_hash"`
+	Valid       bool                      `json:"valid"`
+}
+
+// DocumentSigningService реализует подписание документов кодом из SMS:
+// отправка кода, подтверждение, штамп подписи в PDF и фиксация SHA-256 файла.
+type DocumentSigningService struct {
+	docRepo   *repositories.DocumentRepository
+	sigRepo   *repositories.DocumentSignatureRepository
+	documents *DocumentService
+	sms       *SMS_Service
+	notifier  StaffNotifier
+}
+
+func NewDocumentSigningService(
+	docRepo *repositories.DocumentRepository,
+	sigRepo *repositories.DocumentSignatureRepository,
+	documents *DocumentService,
+	sms *SMS_Service,
+	notifier StaffNotifier,
+) *DocumentSigningService {
+	return &DocumentSigningService{
+		docRepo:   docRepo,
+		sigRepo:   sigRepo,
+		documents: documents,
+		sms:       sms,
+		notifier:  notifier,
+	}
+}
+
+// RequestSignature отправляет код подписи на телефон подписанта.
+func (s *DocumentSigningService) RequestSignature(documentID int64, phone string) error {
+	if _, err := s.getUnsigned(documentID); err != nil {
+		return err
+	}
+	return s.sms.SendSMS(documentID, phone)
+}
+
+// Sign проверяет код и подписывает документ: генерирует PDF с листом подписи,
+// переводит документ в статус "signed" и сохраняет хэш подписанного файла.
+// Код подтверждается только после того, как подписанная копия создана, поэтому
+// ошибка генерации PDF или хранилища не сжигает код клиента.
+func (s *DocumentSigningService) Sign(documentID int64, code string, signer SignerInfo) (*models.DocumentSignature, error) {
+	doc, err := s.getUnsigned(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation, err := s.sms.CheckCode(documentID, code)
+	if err != nil {
+		return nil, err
+	}
+	if confirmation == nil {
+		return nil, ErrInvalidSignCode
+	}
+
+	signedAt := time.Now()
+	storedPath, absPath, err := s.documents.GenerateSignedCopy(doc, pdf.SignatureData{
+		DocumentNumber: doc.Number,
+		Phone:          confirmation.Phone,
+		IP:             signer.IP,
+		UserAgent:      signer.UserAgent,
+		SignedAt:       signedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := fileSHA256(absPath)
+	if err != nil {
+		_ = os.Remove(absPath)
+		return nil, err
+	}
+
+	confirmed, err := s.sms.ConfirmCode(confirmation)
+	if err != nil || !confirmed {
+		_ = os.Remove(absPath)
+		if err == nil {
+			err = ErrInvalidSignCode
+		}
+		return nil, err
+	}
+
+	sig := &models.DocumentSignature{
+		DocumentID:        doc.ID,
+		SMSConfirmationID: confirmation.ID,
+		Phone:             confirmation.Phone,
+		IP:                signer.IP,
+		UserAgent:         signer.UserAgent,
+		FilePath:          storedPath,
+		FileHash:          hash,
+		SignedAt:          signedAt,
+	}
+
+	if err := s.saveSignature(sig); err != nil {
+		_ = os.Remove(absPath)
+		return nil, err
+	}
+	s.notifySigned(doc)
+	return sig, nil
+}
+
+// Verify пересчитывает SHA-256 подписанного файла и сверяет его с сохранённым.
+func (s *DocumentSigningService) Verify(documentID int64) (*SignatureCheck, error) {
+	sig, err := s.sigRepo.GetByDocumentID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if sig == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	check := &SignatureCheck{Signature: sig}
+	hash, err := fileSHA256(s.documents.ResolvePath(sig.FilePath))
+	if err != nil {
+		// Отсутствующий или нечитаемый файл считаем нарушением целостности
+		return check, nil
+	}
+	check.CurrentHash = hash
+	check.Valid = hash == sig.FileHash
+	return check, nil
+}
+
+func (s *DocumentSigningService) getUnsigned(documentID int64) (*models.Document, error) {
+	doc, err := s.docRepo.GetByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+	if doc.Status == "signed" {
+		return nil, ErrDocumentAlreadySigned
+	}
+	return doc, nil
+}
+
+// notifySigned сообщает владельцу лида сделки, что клиент подписал документ.
+func (s *DocumentSigningService) notifySigned(doc *models.Document) {
+	if s.notifier == nil {
+		return
+	}
+	deal, err := s.documents.DealRepo.GetByID(int(doc.DealID))
+	if err != nil || deal == nil {
+		return
+	}
+	lead, err := s.documents.LeadRepo.GetByID(deal.LeadID)
+	if err != nil {
+		return
+	}
+	s.notifier.Notify(int64(lead.OwnerID), fmt.Sprintf("Document %s (#%d) for deal #%d has been signed.", doc.Number, doc.ID, deal.ID))
+}
+
+func (s *DocumentSigningService) saveSignature(sig *models.DocumentSignature) error {
+	tx, err := s.docRepo.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updated, err := s.docRepo.MarkSigned(tx, sig.DocumentID, sig.FilePath, sig.SignedAt)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrDocumentAlreadySigned
+	}
+
+	if _, err := s.sigRepo.Create(tx, sig); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("фиксация подписи документа: %w", err)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("открытие файла для хэширования: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("хэширование файла: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -3,10 +3,22 @@ package services
 import (
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"io"
+	"strings"
 )
 
 type EmailService interface {
 	SendWelcomeEmail(email, companyName string) error
+	SendReply(to, subject, text, inReplyTo string) (string, error)
+	SendNotification(to, subject, text string) error
+	SendAttachment(to []string, subject, text string, file EmailAttachment) error
+}
+
+// EmailAttachment файл, прикладываемый к письму.
+type EmailAttachment struct {
+	Name        string
+	ContentType string
+	Content     []byte
 }
 
 type emailService struct {
@@ -43,3 +55,64 @@ func (s *emailService) SendWelcomeEmail(email, companyName string) error {
 
 	return nil
 }
+
+// SendReply отправляет ответ клиенту из инбокса и возвращает Message-ID письма.
+// С inReplyTo письмо попадает в ту же цепочку у клиента.
+func (s *emailService) SendReply(to, subject, text, inReplyTo string) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(s.from, "@"); at >= 0 {
+		domain = strings.Trim(s.from[at+1:], "> ")
+	}
+	messageID := fmt.Sprintf("<%s@%s>", randomName(), domain)
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetHeader("Message-ID", messageID)
+	if inReplyTo != "" {
+		m.SetHeader("In-Reply-To", inReplyTo)
+		m.SetHeader("References", inReplyTo)
+	}
+	m.SetBody("text/plain", text)
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		return "", fmt.Errorf("failed to send reply: %w", err)
+	}
+	return messageID, nil
+}
+
+// SendNotification отправляет сотруднику текстовое уведомление CRM.
+func (s *emailService) SendNotification(to, subject, text string) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", text)
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
+
+// SendAttachment отправляет письмо с файлом одним сообщением всем получателям.
+func (s *emailService) SendAttachment(to []string, subject, text string, file EmailAttachment) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to...)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", text)
+	m.Attach(file.Name,
+		gomail.SetHeader(map[string][]string{"Content-Type": {file.ContentType}}),
+		gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(file.Content)
+			return err
+		}),
+	)
+
+	if err := s.dialer.DialAndSend(m); err != nil {
+		return fmt.Errorf("failed to send attachment: %w", err)
+	}
+	return nil
+}
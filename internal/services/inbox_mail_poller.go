@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"turcompany/internal/mailbox"
+	"turcompany/internal/models"
+)
+
+// RunInboxMailPoll periodically moves client emails from the mailbox into the inbox until ctx
+// is cancelled. An email stays in the mailbox until it is stored, so a failed one is retried.
+func RunInboxMailPoll(ctx context.Context, source mailbox.Source, inbox InboxService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pollMailbox(ctx, source, inbox)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pollMailbox(ctx context.Context, source mailbox.Source, inbox InboxService) {
+	emails, err := source.Fetch(ctx)
+	if err != nil {
+		log.Printf("Fetch inbox mail: %v", err)
+		return
+	}
+	for _, e := range emails {
+		_, err := inbox.Receive(ctx, &models.InboundMessage{
+			Channel:    models.ChannelEmail,
+			ExternalID: e.MessageID,
+			Name:       e.FromName,
+			Email:      e.From,
+			Subject:    e.Subject,
+			Body:       e.Text,
+			ReceivedAt: e.Date,
+		})
+		// There is nothing to show for an empty email, keeping it would only retry it forever
+		if err != nil && !errors.Is(err, ErrEmptyInboxMessage) {
+			log.Printf("Store email %s from %s: %v", e.ID, e.From, err)
+			continue
+		}
+		if err := source.Ack(ctx, e.ID); err != nil {
+			log.Printf("Ack email %s: %v", e.ID, err)
+		}
+	}
+}
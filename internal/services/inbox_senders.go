@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"turcompany/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramExternalID is the inbox ID of a Telegram message; message IDs are unique only within a chat.
+func TelegramExternalID(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+type telegramInboxSender struct {
+	bot *tgbotapi.BotAPI
+}
+
+// NewTelegramInboxSender creates an InboxSender that writes to the lead's Telegram chat.
+func NewTelegramInboxSender(bot *tgbotapi.BotAPI) InboxSender {
+	return &telegramInboxSender{bot: bot}
+}
+
+func (s *telegramInboxSender) Recipient(lead *models.Leads, _ *models.InboxMessage) (string, error) {
+	if lead.TelegramChatID == nil {
+		return "", ErrNoChannelAddress
+	}
+	return strconv.FormatInt(*lead.TelegramChatID, 10), nil
+}
+
+func (s *telegramInboxSender) Send(_ context.Context, recipient string, msg *models.InboxMessage, _ *models.InboxMessage) (string, error) {
+	chatID, err := strconv.ParseInt(recipient, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid chat id %q", recipient)
+	}
+	sent, err := s.bot.Send(tgbotapi.NewMessage(chatID, msg.Body))
+	if err != nil {
+		return "", err
+	}
+	return TelegramExternalID(chatID, sent.MessageID), nil
+}
+
+type emailInboxSender struct {
+	email EmailService
+}
+
+// NewEmailInboxSender creates an InboxSender that answers by email in the client's thread.
+func NewEmailInboxSender(email EmailService) InboxSender {
+	return &emailInboxSender{email: email}
+}
+
+func (s *emailInboxSender) Recipient(lead *models.Leads, lastInbound *models.InboxMessage) (string, error) {
+	// The client may write from an address other than the one in the lead
+	if lastInbound != nil && lastInbound.Channel == models.ChannelEmail && lastInbound.Address != "" {
+		return lastInbound.Address, nil
+	}
+	if lead.Email == "" {
+		return "", ErrNoChannelAddress
+	}
+	return lead.Email, nil
+}
+
+func (s *emailInboxSender) Send(_ context.Context, recipient string, msg *models.InboxMessage, lastInbound *models.InboxMessage) (string, error) {
+	inReplyTo := ""
+	if lastInbound != nil && lastInbound.Channel == models.ChannelEmail {
+		inReplyTo = lastInbound.ExternalID
+	}
+	return s.email.SendReply(recipient, msg.Subject, msg.Body, inReplyTo)
+}
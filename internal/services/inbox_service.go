@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// InboxService defines the interface for the unified client inbox: inbound messages from all
+// channels are attached to leads, and agents reply through the channel the client used.
+type InboxService interface {
+	Receive(ctx context.Context, in *models.InboundMessage) (*models.InboxMessage, error)
+	ListThreads(ctx context.Context, filter models.InboxThreadFilter) ([]models.InboxThread, error)
+	GetThread(ctx context.Context, id int64) (*models.InboxThread, error)
+	GetThreadByLead(ctx context.Context, leadID int64) (*models.InboxThread, error)
+	GetMessages(ctx context.Context, threadID int64, cursor models.MessageCursor) (*models.InboxMessagePage, error)
+	Reply(ctx context.Context, agentID, threadID int64, channel, body string) (*models.InboxMessage, error)
+	MarkRead(ctx context.Context, threadID int64) error
+}
+
+// InboxSender delivers agent replies through one external channel.
+type InboxSender interface {
+	// Recipient returns the client's address in the channel.
+	Recipient(lead *models.Leads, lastInbound *models.InboxMessage) (string, error)
+	// Send delivers the reply and returns its ID in the channel.
+	Send(ctx context.Context, recipient string, msg *models.InboxMessage, lastInbound *models.InboxMessage) (string, error)
+}
+
+var (
+	ErrInboxThreadNotFound = errors.New("inbox thread not found")
+	ErrUnknownChannel      = errors.New("unknown channel")
+	ErrChannelUnavailable  = errors.New("channel is not configured")
+	ErrNoChannelAddress    = errors.New("lead has no address in this channel")
+	ErrNoReplyChannel      = errors.New("client has not written yet, choose a channel")
+	ErrEmptyInboxMessage   = errors.New("message is empty")
+)
+
+const (
+	maxInboxBody      = 10000
+	inboxPreviewRunes = 200
+)
+
+type inboxService struct {
+	repo           repositories.InboxRepository
+	leads          *LeadService
+	senders        map[string]InboxSender
+	notifier       StaffNotifier
+	activities     ActivityRecorder
+	defaultOwnerID int
+	now            func() time.Time
+}
+
+// NewInboxService creates a new instance of InboxService. Leads for unknown clients are
+// assigned to defaultOwnerID. Channels without a sender can receive but not reply.
+func NewInboxService(
+	repo repositories.InboxRepository,
+	leads *LeadService,
+	senders map[string]InboxSender,
+	notifier StaffNotifier,
+	activities ActivityRecorder,
+	defaultOwnerID int,
+) InboxService {
+	return &inboxService{
+		repo:           repo,
+		leads:          leads,
+		senders:        senders,
+		notifier:       notifier,
+		activities:     activities,
+		defaultOwnerID: defaultOwnerID,
+		now:            time.Now,
+	}
+}
+
+// Receive stores an inbound client message in the thread of the matching lead, creating
+// the lead if the client is unknown. It returns nil if the message was already received.
+func (s *inboxService) Receive(ctx context.Context, in *models.InboundMessage) (*models.InboxMessage, error) {
+	if in.Channel != models.ChannelTelegram && in.Channel != models.ChannelEmail {
+		return nil, ErrUnknownChannel
+	}
+	body := strings.TrimSpace(in.Body)
+	if body == "" {
+		return nil, ErrEmptyInboxMessage
+	}
+	if len(body) > maxInboxBody {
+		body = strings.ToValidUTF8(body[:maxInboxBody], "")
+	}
+
+	lead, created, err := s.matchLead(in)
+	if err != nil {
+		return nil, err
+	}
+
+	receivedAt := in.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = s.now()
+	}
+	msg := &models.InboxMessage{
+		Channel:    in.Channel,
+		Direction:  models.DirectionIn,
+		Address:    inboundAddress(in),
+		Subject:    in.Subject,
+		Body:       body,
+		ExternalID: in.ExternalID,
+		Status:     models.InboxStatusReceived,
+		CreatedAt:  receivedAt,
+	}
+	stored, err := s.repo.StoreMessage(ctx, int64(lead.ID), msg)
+	if err != nil || !stored {
+		return nil, err
+	}
+
+	// A new lead already notified its owner about the assignment
+	if !created && s.notifier != nil {
+		s.notifier.Notify(int64(lead.OwnerID), fmt.Sprintf("New %s message from %s (lead #%d):\n%s",
+			in.Channel, msg.Address, lead.ID, preview(body)))
+	}
+	s.record(ctx, int64(lead.ID), models.ActivityMessageReceived, 0,
+		fmt.Sprintf("%s message from %s: %s", in.Channel, msg.Address, preview(body)), msg)
+	return msg, nil
+}
+
+// record adds an inbox message to the lead's timeline.
+func (s *inboxService) record(ctx context.Context, leadID int64, activityType string, actorID int64, summary string, msg *models.InboxMessage) {
+	recordActivity(ctx, s.activities, &models.Activity{
+		Type:       activityType,
+		EntityType: models.EntityTypeLead,
+		EntityID:   leadID,
+		ActorID:    actorID,
+		Summary:    summary,
+		Details: map[string]string{
+			"channel":          msg.Channel,
+			"inbox_message_id": strconv.FormatInt(msg.ID, 10),
+		},
+	})
+}
+
+// matchLead finds the lead by Telegram chat, phone or email, or creates one for a new client.
+func (s *inboxService) matchLead(in *models.InboundMessage) (*models.Leads, bool, error) {
+	email := strings.ToLower(strings.TrimSpace(in.Email))
+	lead, err := s.leads.Repo.FindByContact(in.TelegramChatID, in.Phone, email)
+	if err != nil {
+		return nil, false, err
+	}
+	if lead != nil {
+		// A lead found by phone learns its chat, so the next messages match directly
+		if in.TelegramChatID != 0 && lead.TelegramChatID == nil {
+			if err := s.leads.Repo.SetTelegramChat(lead.ID, in.TelegramChatID); err != nil {
+				log.Printf("Link lead %d to Telegram chat: %v", lead.ID, err)
+			}
+		}
+		return lead, false, nil
+	}
+
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		name = inboundAddress(in)
+	}
+	lead = &models.Leads{
+		Title:       fmt.Sprintf("%s (%s)", name, in.Channel),
+		Description: fmt.Sprintf("Created from an inbound %s message.", in.Channel),
+		CreatedAt:   s.now(),
+		OwnerID:     s.defaultOwnerID,
+		Source:      in.Channel,
+		Phone:       in.Phone,
+		Email:       email,
+	}
+	if in.Subject != "" {
+		lead.Description += "\nSubject: " + in.Subject
+	}
+	if in.TelegramChatID != 0 {
+		chatID := in.TelegramChatID
+		lead.TelegramChatID = &chatID
+	}
+	if err := s.leads.Create(lead); err != nil {
+		return nil, false, fmt.Errorf("create inbox lead: %w", err)
+	}
+	return lead, true, nil
+}
+
+func (s *inboxService) ListThreads(ctx context.Context, filter models.InboxThreadFilter) ([]models.InboxThread, error) {
+	return s.repo.ListThreads(ctx, filter)
+}
+
+func (s *inboxService) GetThread(ctx context.Context, id int64) (*models.InboxThread, error) {
+	thread, err := s.repo.FindThread(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if thread == nil {
+		return nil, ErrInboxThreadNotFound
+	}
+	return thread, nil
+}
+
+func (s *inboxService) GetThreadByLead(ctx context.Context, leadID int64) (*models.InboxThread, error) {
+	thread, err := s.repo.FindThreadByLead(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread == nil {
+		return nil, ErrInboxThreadNotFound
+	}
+	return thread, nil
+}
+
+func (s *inboxService) GetMessages(ctx context.Context, threadID int64, cursor models.MessageCursor) (*models.InboxMessagePage, error) {
+	if _, err := s.GetThread(ctx, threadID); err != nil {
+		return nil, err
+	}
+	if cursor.Before > 0 && cursor.After > 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Limit <= 0 {
+		cursor.Limit = defaultHistoryLimit
+	}
+	if cursor.Limit > maxHistoryLimit {
+		cursor.Limit = maxHistoryLimit
+	}
+	return s.repo.FindMessages(ctx, threadID, cursor)
+}
+
+// Reply sends the agent's message to the client. Without a channel it goes where the client
+// wrote last; the internal channel stores a note that the client never sees. A failed delivery
+// is stored with status failed and returned without an error.
+func (s *inboxService) Reply(ctx context.Context, agentID, threadID int64, channel, body string) (*models.InboxMessage, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyInboxMessage
+	}
+	thread, err := s.GetThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	lastInbound, err := s.repo.FindLastInbound(ctx, threadID, channel)
+	if err != nil {
+		return nil, err
+	}
+	if channel == "" {
+		if lastInbound == nil {
+			return nil, ErrNoReplyChannel
+		}
+		channel = lastInbound.Channel
+	}
+
+	msg := &models.InboxMessage{
+		Channel:   channel,
+		Direction: models.DirectionOut,
+		AuthorID:  &agentID,
+		Body:      body,
+		Status:    models.InboxStatusSent,
+		CreatedAt: s.now(),
+	}
+
+	var sender InboxSender
+	if channel != models.ChannelInternal {
+		if channel != models.ChannelTelegram && channel != models.ChannelEmail {
+			return nil, ErrUnknownChannel
+		}
+		if sender = s.senders[channel]; sender == nil {
+			return nil, ErrChannelUnavailable
+		}
+		lead, err := s.leads.GetByID(int(thread.LeadID))
+		if err != nil {
+			return nil, err
+		}
+		if lead == nil {
+			return nil, ErrInboxThreadNotFound
+		}
+		if msg.Address, err = sender.Recipient(lead, lastInbound); err != nil {
+			return nil, err
+		}
+		msg.Status = models.InboxStatusPending
+		if channel == models.ChannelEmail {
+			msg.Subject = replySubject(lastInbound, thread)
+		}
+	}
+
+	if _, err := s.repo.StoreMessage(ctx, thread.LeadID, msg); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkRead(ctx, threadID); err != nil {
+		log.Printf("Mark inbox thread %d read: %v", threadID, err)
+	}
+	if sender == nil {
+		return msg, nil
+	}
+
+	externalID, sendErr := sender.Send(ctx, msg.Address, msg, lastInbound)
+	msg.Status, msg.ExternalID = models.InboxStatusSent, externalID
+	if sendErr != nil {
+		msg.Status, msg.Error = models.InboxStatusFailed, sendErr.Error()
+	}
+	// The delivery may already have happened, so the result is saved even if the request was cancelled
+	if err := s.repo.UpdateStatus(context.WithoutCancel(ctx), msg.ID, msg.Status, msg.ExternalID, msg.Error); err != nil {
+		return nil, err
+	}
+	if msg.Status == models.InboxStatusSent {
+		s.record(context.WithoutCancel(ctx), thread.LeadID, models.ActivityMessageSent, agentID,
+			fmt.Sprintf("Reply by %s to %s: %s", channel, msg.Address, preview(body)), msg)
+	}
+	return msg, nil
+}
+
+func (s *inboxService) MarkRead(ctx context.Context, threadID int64) error {
+	if _, err := s.GetThread(ctx, threadID); err != nil {
+		return err
+	}
+	return s.repo.MarkRead(ctx, threadID)
+}
+
+func inboundAddress(in *models.InboundMessage) string {
+	switch {
+	case in.Channel == models.ChannelEmail && in.Email != "":
+		return strings.ToLower(strings.TrimSpace(in.Email))
+	case strings.TrimSpace(in.Name) != "":
+		return strings.TrimSpace(in.Name)
+	case in.Phone != "":
+		return in.Phone
+	}
+	return fmt.Sprintf("chat %d", in.TelegramChatID)
+}
+
+func replySubject(lastInbound *models.InboxMessage, thread *models.InboxThread) string {
+	if lastInbound == nil || lastInbound.Subject == "" {
+		return thread.LeadTitle
+	}
+	if strings.HasPrefix(strings.ToLower(lastInbound.Subject), "re:") {
+		return lastInbound.Subject
+	}
+	return "Re: " + lastInbound.Subject
+}
+
+func preview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= inboxPreviewRunes {
+		return text
+	}
+	return string(runes[:inboxPreviewRunes]) + "…"
+}
@@ -1,21 +1,35 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/repositories"
 )
 
 type LeadService struct {
-	Repo     *repositories.LeadRepository
-	DealRepo *repositories.DealRepository
+	Repo          *repositories.LeadRepository
+	DealRepo      *repositories.DealRepository
+	notifier      StaffNotifier
+	taskTemplates TaskTemplateService
+	activities    ActivityRecorder
 }
 
-func NewLeadService(leadRepo *repositories.LeadRepository, dealRepo *repositories.DealRepository) *LeadService {
+func NewLeadService(
+	leadRepo *repositories.LeadRepository,
+	dealRepo *repositories.DealRepository,
+	notifier StaffNotifier,
+	taskTemplates TaskTemplateService,
+	activities ActivityRecorder,
+) *LeadService {
 	return &LeadService{
-		Repo:     leadRepo,
-		DealRepo: dealRepo,
+		Repo:          leadRepo,
+		DealRepo:      dealRepo,
+		notifier:      notifier,
+		taskTemplates: taskTemplates,
+		activities:    activities,
 	}
 }
 
@@ -26,10 +40,55 @@ func (s *LeadService) Create(lead *models.Leads) error {
 	if lead.Status == "" {
 		lead.Status = "new"
 	}
-	return s.Repo.Create(lead)
+	if err := s.Repo.Create(lead); err != nil {
+		return err
+	}
+	s.notifyAssigned(lead)
+	summary := "Lead created: " + lead.Title
+	if lead.Source != "" && lead.Source != models.LeadSourceCRM {
+		summary += " (" + lead.Source + ")"
+	}
+	s.record(lead.ID, models.ActivityLeadCreated, summary, map[string]string{"source": lead.Source})
+	return nil
 }
 func (s *LeadService) Update(lead *models.Leads) error {
-	return s.Repo.Update(lead)
+	// Предыдущий владелец нужен, чтобы уведомить только при переназначении
+	previous, _ := s.Repo.GetByID(lead.ID)
+	if err := s.Repo.Update(lead); err != nil {
+		return err
+	}
+	if previous != nil && previous.OwnerID != lead.OwnerID {
+		s.notifyAssigned(lead)
+	}
+	if previous != nil && previous.Status != lead.Status {
+		s.record(lead.ID, models.ActivityLeadStatusChanged,
+			fmt.Sprintf("Lead status changed from %s to %s", previous.Status, lead.Status),
+			map[string]string{"from": previous.Status, "to": lead.Status})
+	}
+	return nil
+}
+
+// record добавляет событие в ленту лида.
+func (s *LeadService) record(leadID int, activityType, summary string, details map[string]string) {
+	recordActivity(context.Background(), s.activities, &models.Activity{
+		Type:       activityType,
+		EntityType: models.EntityTypeLead,
+		EntityID:   int64(leadID),
+		Summary:    summary,
+		Details:    details,
+	})
+}
+
+// notifyAssigned сообщает владельцу лида, что лид назначен на него.
+func (s *LeadService) notifyAssigned(lead *models.Leads) {
+	if s.notifier == nil {
+		return
+	}
+	text := fmt.Sprintf("New lead #%d assigned to you: %s", lead.ID, lead.Title)
+	if lead.Source != "" && lead.Source != models.LeadSourceCRM {
+		text += " (" + lead.Source + ")"
+	}
+	s.notifier.Notify(int64(lead.OwnerID), text)
 }
 
 func (s *LeadService) ListPaginated(limit, offset int) ([]*models.Leads, error) {
@@ -88,5 +147,15 @@ func (s *LeadService) ConvertLeadToDeal(leadID int, amount, currency string) (*m
 		return nil, err
 	}
 
+	recordActivity(context.Background(), s.activities, &models.Activity{
+		Type:       models.ActivityDealConverted,
+		EntityType: models.EntityTypeDeal,
+		EntityID:   int64(deal.ID),
+		Summary:    fmt.Sprintf("Lead converted to deal #%d: %s %s", deal.ID, deal.Amount, deal.Currency),
+		Details:    map[string]string{"amount": deal.Amount, "currency": deal.Currency},
+	})
+	if s.taskTemplates != nil {
+		s.taskTemplates.OnDealStage(context.Background(), deal)
+	}
 	return deal, nil
 }
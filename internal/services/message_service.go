@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/repositories"
@@ -9,33 +11,168 @@ import (
 
 // MessageService defines the interface for message-related business logic.
 type MessageService interface {
-	Send(ctx context.Context, msg *models.Message) (*models.Message, error)
-	GetConversationHistory(ctx context.Context, userID, partnerID int64) ([]models.Message, error)
+	Send(ctx context.Context, msg *models.Message, attachmentIDs []int64) (*models.Message, error)
+	GetConversationHistory(ctx context.Context, userID, partnerID int64, cursor models.MessageCursor) (*models.MessagePage, error)
 	GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error)
+	Search(ctx context.Context, userID, partnerID int64, text string, limit, offset int) ([]models.MessageSearchResult, error)
+	MarkRead(ctx context.Context, userID, upToMessageID int64) (int64, error)
+	GetUnreadCount(ctx context.Context, userID int64) (int, error)
+}
+
+var (
+	ErrMessageNotFound       = errors.New("message not found")
+	ErrNotConversationMember = errors.New("user is not a member of this conversation")
+	ErrInvalidCursor         = errors.New("before and after cannot be used together")
+	ErrEmptySearchQuery      = errors.New("search query is empty")
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// MessageNotifier delivers newly stored messages and read receipts to connected clients in real time.
+type MessageNotifier interface {
+	NotifyMessage(msg *models.Message)
+	NotifyRead(receipt models.ReadReceipt)
 }
 
 type messageService struct {
-	repo repositories.MessageRepository
+	repo        repositories.MessageRepository
+	attachments repositories.MessageAttachmentRepository
+	notifier    MessageNotifier
 }
 
 // NewMessageService creates a new instance of MessageService.
-func NewMessageService(repo repositories.MessageRepository) MessageService {
-	return &messageService{repo: repo}
+// notifier may be nil when real-time delivery is not needed.
+func NewMessageService(repo repositories.MessageRepository, attachments repositories.MessageAttachmentRepository, notifier MessageNotifier) MessageService {
+	return &messageService{repo: repo, attachments: attachments, notifier: notifier}
 }
 
-func (s *messageService) Send(ctx context.Context, msg *models.Message) (*models.Message, error) {
+// Send stores a direct message with the sender's previously uploaded attachments.
+func (s *messageService) Send(ctx context.Context, msg *models.Message, attachmentIDs []int64) (*models.Message, error) {
+	if err := attachMessageFiles(ctx, s.attachments, msg, attachmentIDs); err != nil {
+		return nil, err
+	}
 	msg.SentAt = time.Now()
 	if err := s.repo.Store(ctx, msg); err != nil {
 		return nil, err
 	}
+	if s.notifier != nil {
+		s.notifier.NotifyMessage(msg)
+	}
 	return msg, nil
 }
 
-func (s *messageService) GetConversationHistory(ctx context.Context, userID, partnerID int64) ([]models.Message, error) {
-	// Future logic: mark messages as read
-	return s.repo.FindConversationHistory(ctx, userID, partnerID)
+// GetConversationHistory returns one page of the conversation; without a cursor it is the latest page.
+func (s *messageService) GetConversationHistory(ctx context.Context, userID, partnerID int64, cursor models.MessageCursor) (*models.MessagePage, error) {
+	if cursor.Before > 0 && cursor.After > 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Limit <= 0 {
+		cursor.Limit = defaultHistoryLimit
+	}
+	if cursor.Limit > maxHistoryLimit {
+		cursor.Limit = maxHistoryLimit
+	}
+
+	page, err := s.repo.FindConversationHistory(ctx, userID, partnerID, cursor)
+	if err != nil {
+		return nil, err
+	}
+	history := page.Messages
+
+	// Fetching the history means the user has seen the partner's messages in it
+	var lastIncomingID int64
+	for i := range history {
+		if history[i].SenderID == partnerID && history[i].ReadAt == nil && history[i].ID > lastIncomingID {
+			lastIncomingID = history[i].ID
+		}
+	}
+	if lastIncomingID > 0 {
+		_, readAt, err := s.markRead(ctx, userID, partnerID, lastIncomingID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range history {
+			if history[i].SenderID == partnerID && history[i].ReadAt == nil && history[i].ID <= lastIncomingID {
+				history[i].ReadAt = &readAt
+			}
+		}
+	}
+	return page, nil
+}
+
+// MarkRead marks the partner's messages up to and including upToMessageID as read.
+// The partner is derived from the conversation the message belongs to.
+func (s *messageService) MarkRead(ctx context.Context, userID, upToMessageID int64) (int64, error) {
+	msg, err := s.repo.FindByID(ctx, upToMessageID)
+	if err != nil {
+		return 0, err
+	}
+	// Group conversation messages are read through the conversation service
+	if msg == nil || msg.ConversationID != nil {
+		return 0, ErrMessageNotFound
+	}
+
+	var partnerID int64
+	switch userID {
+	case msg.ReceiverID:
+		partnerID = msg.SenderID
+	case msg.SenderID:
+		partnerID = msg.ReceiverID
+	default:
+		return 0, ErrNotConversationMember
+	}
+
+	marked, _, err := s.markRead(ctx, userID, partnerID, upToMessageID)
+	return marked, err
+}
+
+func (s *messageService) GetUnreadCount(ctx context.Context, userID int64) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+// markRead stores the read state and sends a read receipt to the partner.
+func (s *messageService) markRead(ctx context.Context, userID, partnerID, upToMessageID int64) (int64, time.Time, error) {
+	readAt := time.Now()
+	marked, err := s.repo.MarkRead(ctx, userID, partnerID, upToMessageID, readAt)
+	if err != nil {
+		return 0, readAt, err
+	}
+	if marked > 0 {
+		s.notifyRead(userID, partnerID, upToMessageID, readAt)
+	}
+	return marked, readAt, nil
+}
+
+func (s *messageService) notifyRead(readerID, partnerID, upToMessageID int64, readAt time.Time) {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.NotifyRead(models.ReadReceipt{
+		ReaderID:      readerID,
+		PartnerID:     partnerID,
+		UpToMessageID: upToMessageID,
+		ReadAt:        readAt,
+	})
 }
 
 func (s *messageService) GetConversations(ctx context.Context, userID int64) ([]models.Conversation, error) {
 	return s.repo.FindConversations(ctx, userID)
 }
+
+// Search finds the user's messages matching text; partnerID > 0 limits it to one conversation.
+func (s *messageService) Search(ctx context.Context, userID, partnerID int64, text string, limit, offset int) ([]models.MessageSearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	return s.repo.SearchMessages(ctx, userID, partnerID, text, limit, offset)
+}
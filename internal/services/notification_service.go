@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// NotificationService defines the interface for the in-app notifications of the current user.
+type NotificationService interface {
+	List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error)
+	UnreadCount(ctx context.Context, userID int64) (int, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+}
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type notificationService struct {
+	repo repositories.NotificationRepository
+}
+
+// NewNotificationService creates a new instance of NotificationService.
+func NewNotificationService(repo repositories.NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+func (s *notificationService) List(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	return s.repo.List(ctx, userID, unreadOnly, limit, offset)
+}
+
+func (s *notificationService) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, id int64) error {
+	found, err := s.repo.MarkRead(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID int64) error {
+	return s.repo.MarkAllRead(ctx, userID)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"turcompany/internal/models"
+	"turcompany/internal/querydsl"
+	"turcompany/internal/repositories"
+)
+
+var ErrUnknownBuilderEntity = errors.New("unknown report builder entity")
+
+const (
+	defaultBuilderPageSize = 100
+	maxBuilderPageSize     = 1000
+)
+
+// ReportBuilderService runs ad hoc filter, sort and group-by queries over leads, deals,
+// tasks and documents.
+type ReportBuilderService interface {
+	// Schemas returns the fields each entity can be queried by.
+	Schemas() map[string][]querydsl.FieldInfo
+	// Run compiles the query against the entity's fields and returns one page of the result.
+	// Invalid queries return an error wrapping querydsl.ErrInvalidQuery.
+	Run(ctx context.Context, entity string, q querydsl.Query, page, size int) (*models.BuilderResult, error)
+}
+
+type reportBuilderService struct {
+	repo repositories.ReportBuilderRepository
+}
+
+// NewReportBuilderService creates a new instance of ReportBuilderService.
+func NewReportBuilderService(repo repositories.ReportBuilderRepository) ReportBuilderService {
+	return &reportBuilderService{repo: repo}
+}
+
+func (s *reportBuilderService) Schemas() map[string][]querydsl.FieldInfo {
+	schemas := map[string][]querydsl.FieldInfo{}
+	for _, entity := range s.repo.Entities() {
+		schemas[entity] = s.repo.Schema(entity).Fields()
+	}
+	return schemas
+}
+
+func (s *reportBuilderService) Run(ctx context.Context, entity string, q querydsl.Query, page, size int) (*models.BuilderResult, error) {
+	schema := s.repo.Schema(entity)
+	if schema == nil {
+		return nil, ErrUnknownBuilderEntity
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultBuilderPageSize
+	}
+	if size > maxBuilderPageSize {
+		size = maxBuilderPageSize
+	}
+	q.Limit, q.Offset = size, (page-1)*size
+
+	st, err := schema.Compile(q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.Run(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BuilderResult{
+		Entity:  entity,
+		Columns: st.Columns,
+		Rows:    rows,
+		Total:   total,
+		Page:    page,
+		Size:    size,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"turcompany/internal/repositories"
+)
+
+// reportRollupLease is the lease name; only its holder refreshes the daily rollups.
+const reportRollupLease = "report_rollups"
+
+const defaultRollupBatch = 50
+
+// ReportRollupRefresher rebuilds the days of the dashboard rollups changed since the last
+// pass. Several instances may run it: each pass is done by the instance holding the lease.
+type ReportRollupRefresher struct {
+	reports  repositories.ReportRepository
+	leases   repositories.LeaseRepository
+	batch    int
+	leaseTTL time.Duration
+	holder   string
+	now      func() time.Time
+}
+
+// NewReportRollupRefresher creates a ReportRollupRefresher; batch is the number of days
+// rebuilt in one transaction.
+func NewReportRollupRefresher(reports repositories.ReportRepository, leases repositories.LeaseRepository, batch int, leaseTTL time.Duration) *ReportRollupRefresher {
+	if batch <= 0 {
+		batch = defaultRollupBatch
+	}
+	if leaseTTL <= 0 {
+		leaseTTL = 5 * time.Minute
+	}
+	return &ReportRollupRefresher{
+		reports:  reports,
+		leases:   leases,
+		batch:    batch,
+		leaseTTL: leaseTTL,
+		holder:   schedulerHolder(),
+		now:      time.Now,
+	}
+}
+
+// Run runs a pass every interval until ctx is cancelled, then releases the lease.
+func (r *ReportRollupRefresher) Run(ctx context.Context, interval time.Duration) {
+	runLeased(ctx, r.leases, reportRollupLease, r.holder, interval, r.Tick)
+}
+
+// Tick rebuilds all queued days, batch by batch, if this instance holds (or takes) the lease.
+// The lease is extended before every batch, so a long backlog does not outlive it and let
+// another instance rebuild the same days; the pass stops as soon as the lease is lost.
+func (r *ReportRollupRefresher) Tick(ctx context.Context) error {
+	for ctx.Err() == nil {
+		held, err := r.leases.Acquire(ctx, reportRollupLease, r.holder, r.now(), r.leaseTTL)
+		if err != nil {
+			return fmt.Errorf("acquire lease: %w", err)
+		}
+		if !held {
+			return nil
+		}
+		n, err := r.reports.RefreshRollups(ctx, r.batch, r.now())
+		if err != nil {
+			return err
+		}
+		if n < r.batch {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"turcompany/internal/repositories"
+)
+
+// fakeRollupQueue отдаёт очередь дней пачками; каждая пачка занимает step времени.
+type fakeRollupQueue struct {
+	repositories.ReportRepository
+	queued  int
+	batches int
+	clock   *time.Time
+	step    time.Duration
+}
+
+func (f *fakeRollupQueue) RefreshRollups(ctx context.Context, limit int, now time.Time) (int, error) {
+	n := min(limit, f.queued)
+	f.queued -= n
+	f.batches++
+	*f.clock = f.clock.Add(f.step)
+	return n, nil
+}
+
+func TestReportRollupRefresherKeepsLeaseThroughBacklog(t *testing.T) {
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	leases := &fakeLeases{}
+	queue := &fakeRollupQueue{queued: 10 * defaultRollupBatch, clock: &clock, step: 2 * time.Minute}
+	now := func() time.Time { return clock }
+
+	first := NewReportRollupRefresher(queue, leases, 0, 5*time.Minute)
+	first.now = now
+	if err := first.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	// Очередь разбирается дольше аренды, но аренда продлевается перед каждой пачкой.
+	if queue.queued != 0 || queue.batches != 11 {
+		t.Fatalf("queued = %d after %d batches, want the whole backlog in 11", queue.queued, queue.batches)
+	}
+	if leases.holder != first.holder || !leases.expires.After(clock) {
+		t.Fatalf("lease = %s until %s at %s, want it held by the refresher", leases.holder, leases.expires, clock)
+	}
+
+	// Пока аренда у первого экземпляра, второй ничего не перестраивает.
+	second := NewReportRollupRefresher(queue, leases, 0, 5*time.Minute)
+	second.now = now
+	queue.queued, queue.batches = defaultRollupBatch, 0
+	if err := second.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	if queue.batches != 0 {
+		t.Fatalf("second instance ran %d batches without the lease", queue.batches)
+	}
+}
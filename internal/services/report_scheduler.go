@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"turcompany/internal/repositories"
+)
+
+// reportSchedulerLease is the lease name; only its holder sends scheduled reports.
+const reportSchedulerLease = "report_scheduler"
+
+// ReportScheduler sends the scheduled reports when they are due. Several instances may run
+// it: each pass is done by the instance holding the lease.
+type ReportScheduler struct {
+	reports  ScheduledReportService
+	leases   repositories.LeaseRepository
+	leaseTTL time.Duration
+	holder   string
+	now      func() time.Time
+}
+
+// NewReportScheduler creates a ReportScheduler; leaseTTL is how long an instance keeps the
+// scheduler after its last pass.
+func NewReportScheduler(reports ScheduledReportService, leases repositories.LeaseRepository, leaseTTL time.Duration) *ReportScheduler {
+	if leaseTTL <= 0 {
+		leaseTTL = 5 * time.Minute
+	}
+	return &ReportScheduler{
+		reports:  reports,
+		leases:   leases,
+		leaseTTL: leaseTTL,
+		holder:   schedulerHolder(),
+		now:      time.Now,
+	}
+}
+
+// Run runs a pass every interval until ctx is cancelled, then releases the lease.
+func (s *ReportScheduler) Run(ctx context.Context, interval time.Duration) {
+	runLeased(ctx, s.leases, reportSchedulerLease, s.holder, interval, s.Tick)
+}
+
+// Tick sends the due reports if this instance holds (or takes) the lease.
+func (s *ReportScheduler) Tick(ctx context.Context) error {
+	now := s.now()
+	held, err := s.leases.Acquire(ctx, reportSchedulerLease, s.holder, now, s.leaseTTL)
+	if err != nil {
+		return fmt.Errorf("acquire lease: %w", err)
+	}
+	if !held {
+		return nil
+	}
+	return s.reports.RunDue(ctx, now)
+}
@@ -1,19 +1,38 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/repositories"
 )
 
+var ErrInvalidReportFilter = errors.New("invalid report filter")
+
 type ReportService struct {
 	LeadRepo *repositories.LeadRepository
 	DealRepo *repositories.DealRepository
+	Reports  repositories.ReportRepository
+	Policy   RevenuePolicy
+	now      func() time.Time
 }
 
-func NewReportService(leadRepo *repositories.LeadRepository, dealRepo *repositories.DealRepository) *ReportService {
+func NewReportService(
+	leadRepo *repositories.LeadRepository,
+	dealRepo *repositories.DealRepository,
+	reports repositories.ReportRepository,
+	policy RevenuePolicy,
+) *ReportService {
 	return &ReportService{
 		LeadRepo: leadRepo,
 		DealRepo: dealRepo,
+		Reports:  reports,
+		Policy:   policy.normalized(),
+		now:      time.Now,
 	}
 }
 
@@ -51,3 +70,125 @@ func (s *ReportService) FilterDeals(
 ) ([]models.Deals, error) {
 	return s.DealRepo.FilterDeals(status, from, to, currency, sortBy, order, amountMin, amountMax, limit, offset)
 }
+
+// EachFilteredLead calls fn for every lead matching the filter, for exports.
+func (s *ReportService) EachFilteredLead(status string, ownerID int, sortBy, order string, fn func(*models.Leads) error) error {
+	return s.LeadRepo.EachFilteredLead(status, ownerID, sortBy, order, fn)
+}
+
+// EachFilteredDeal calls fn for every deal matching the filter, for exports.
+func (s *ReportService) EachFilteredDeal(
+	status, from, to, currency string,
+	amountMin, amountMax float64,
+	sortBy, order string,
+	fn func(*models.Deals) error,
+) error {
+	return s.DealRepo.EachFilteredDeal(status, from, to, currency, sortBy, order, amountMin, amountMax, fn)
+}
+
+// Funnel returns the number of leads in each status per group.
+func (s *ReportService) Funnel(ctx context.Context, filter models.ReportFilter) (*models.FunnelReport, error) {
+	if err := validateReportFilter(&filter, funnelGroups...); err != nil {
+		return nil, err
+	}
+	counts, err := s.Reports.LeadStatusCounts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.FunnelReport{GroupBy: filter.GroupBy, Groups: []models.FunnelGroup{}}
+	for _, c := range counts {
+		// counts are ordered by key
+		n := len(report.Groups)
+		if n == 0 || report.Groups[n-1].Key != c.Key {
+			report.Groups = append(report.Groups, models.FunnelGroup{Key: c.Key, Statuses: map[string]int{}})
+			n++
+		}
+		report.Groups[n-1].Statuses[c.Status] = c.Count
+		report.Groups[n-1].Total += c.Count
+	}
+	return report, nil
+}
+
+// Conversion returns the share of leads that became deals and how long it took.
+func (s *ReportService) Conversion(ctx context.Context, filter models.ReportFilter) (*models.ConversionReport, error) {
+	if err := validateReportFilter(&filter, funnelGroups...); err != nil {
+		return nil, err
+	}
+	groups, err := s.Reports.Conversion(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Rate = ratio(groups[i].Converted, groups[i].Leads)
+	}
+	return &models.ConversionReport{GroupBy: filter.GroupBy, Groups: groups}, nil
+}
+
+// StageDurations returns the average time leads or deals stay in each status. The period
+// filter applies to the time a stay began.
+func (s *ReportService) StageDurations(ctx context.Context, entity string, filter models.ReportFilter) (*models.StageDurationReport, error) {
+	if entity != models.EntityTypeLead && entity != models.EntityTypeDeal {
+		return nil, fmt.Errorf("%w: entity must be lead or deal", ErrInvalidReportFilter)
+	}
+	if err := validateReportFilter(&filter, funnelGroups...); err != nil {
+		return nil, err
+	}
+	stages, err := s.Reports.StageDurations(ctx, entity, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StageDurationReport{Entity: entity, GroupBy: filter.GroupBy, Stages: stages}, nil
+}
+
+// WinLoss returns the won and lost deals, the win rate and the reasons of the losses.
+func (s *ReportService) WinLoss(ctx context.Context, filter models.ReportFilter) (*models.WinLossReport, error) {
+	if err := validateReportFilter(&filter, funnelGroups...); err != nil {
+		return nil, err
+	}
+	groups, err := s.Reports.WinLoss(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	reasons, err := s.Reports.LossReasons(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].WinRate = ratio(groups[i].Won, groups[i].Won+groups[i].Lost)
+		groups[i].LossReasons = reasons[groups[i].Key]
+		if groups[i].LossReasons == nil {
+			groups[i].LossReasons = []models.LossReasonCount{}
+		}
+	}
+	return &models.WinLossReport{GroupBy: filter.GroupBy, Groups: groups}, nil
+}
+
+// funnelGroups are the breakdowns of the lead and deal funnel reports.
+var funnelGroups = []string{models.ReportGroupOwner, models.ReportGroupSource, models.ReportGroupPeriod}
+
+// validateReportFilter checks the breakdown against the ones the report supports and
+// defaults the interval to a month.
+func validateReportFilter(filter *models.ReportFilter, groups ...string) error {
+	if filter.GroupBy != "" && !slices.Contains(groups, filter.GroupBy) {
+		return fmt.Errorf("%w: group_by must be one of %s", ErrInvalidReportFilter, strings.Join(groups, ", "))
+	}
+	switch filter.Interval {
+	case "":
+		filter.Interval = models.ReportIntervalMonth
+	case models.ReportIntervalDay, models.ReportIntervalWeek, models.ReportIntervalMonth:
+	default:
+		return fmt.Errorf("%w: interval must be day, week or month", ErrInvalidReportFilter)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidReportFilter)
+	}
+	return nil
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+)
+
+const (
+	// defaultReportBuckets is the length of a time series without from and to.
+	defaultReportBuckets = 12
+	maxReportBuckets     = 400
+	reportDateLayout     = "2006-01-02"
+)
+
+// revenueGroups and forecastGroups are the breakdowns of the revenue and forecast series.
+var (
+	revenueGroups  = []string{models.ReportGroupOwner, models.ReportGroupSource, models.ReportGroupDestination}
+	forecastGroups = []string{models.ReportGroupOwner, models.ReportGroupSource, models.ReportGroupDestination, models.ReportGroupStatus}
+)
+
+// RevenuePolicy converts deal amounts into one currency and weights open deals for the forecast.
+type RevenuePolicy struct {
+	BaseCurrency string
+	// ExchangeRates is the number of base currency units in one unit of each currency.
+	ExchangeRates map[string]float64
+	// StageProbabilities is the chance of an open deal in each status to be won, 0..1.
+	StageProbabilities map[string]float64
+	DefaultProbability float64
+}
+
+// normalized upper-cases the currency codes; the base currency is worth one of itself.
+func (p RevenuePolicy) normalized() RevenuePolicy {
+	p.BaseCurrency = strings.ToUpper(strings.TrimSpace(p.BaseCurrency))
+	if p.BaseCurrency == "" {
+		p.BaseCurrency = "KZT"
+	}
+	rates := make(map[string]float64, len(p.ExchangeRates)+1)
+	for currency, rate := range p.ExchangeRates {
+		if rate > 0 {
+			rates[strings.ToUpper(strings.TrimSpace(currency))] = rate
+		}
+	}
+	if _, ok := rates[p.BaseCurrency]; !ok {
+		rates[p.BaseCurrency] = 1
+	}
+	p.ExchangeRates = rates
+	if p.StageProbabilities == nil {
+		p.StageProbabilities = map[string]float64{}
+	}
+	return p
+}
+
+// convert changes amount from one currency into another; ok is false without a rate.
+func (p RevenuePolicy) convert(amount float64, from, to string) (float64, bool) {
+	fromRate, ok := p.ExchangeRates[from]
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := p.ExchangeRates[to]
+	if !ok {
+		return 0, false
+	}
+	return amount * fromRate / toRate, true
+}
+
+func (p RevenuePolicy) probability(status string) float64 {
+	if probability, ok := p.StageProbabilities[status]; ok {
+		return probability
+	}
+	return p.DefaultProbability
+}
+
+// Revenue returns the won deals by the bucket they were won in, converted into currency
+// (the base currency if empty). Each bucket and the whole period are compared with the
+// ones before them.
+func (s *ReportService) Revenue(ctx context.Context, filter models.ReportFilter, currency string) (*models.RevenueReport, error) {
+	if err := validateReportFilter(&filter, revenueGroups...); err != nil {
+		return nil, err
+	}
+	currency, err := s.reportCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	to := truncateReportPeriod(s.now(), filter.Interval)
+	to = addReportPeriods(to, filter.Interval, 1)
+	periods, err := reportPeriods(filter, addReportPeriods(to, filter.Interval, -defaultReportBuckets), to)
+	if err != nil {
+		return nil, err
+	}
+
+	// The query also covers as many buckets before from, for the comparison
+	from, _ := time.Parse(reportDateLayout, periods[0])
+	previous := reportPeriodRange(from, filter.Interval, -len(periods))
+	filter.From = &previous[0]
+	filter.To = timePtr(addReportPeriods(from, filter.Interval, len(periods)))
+	amounts, err := s.Reports.Revenue(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	all := append(formatReportPeriods(previous), periods...)
+	type bucket struct {
+		deals  int
+		amount float64
+	}
+	groups := map[string]map[string]*bucket{}
+	skipped := map[string]bool{}
+	for _, a := range amounts {
+		amount, ok := s.Policy.convert(a.Amount, a.Currency, currency)
+		if !ok {
+			skipped[a.Currency] = true
+			continue
+		}
+		if groups[a.Key] == nil {
+			groups[a.Key] = map[string]*bucket{}
+		}
+		b := groups[a.Key][a.Period]
+		if b == nil {
+			b = &bucket{}
+			groups[a.Key][a.Period] = b
+		}
+		b.deals += a.Deals
+		b.amount += amount
+	}
+	if filter.GroupBy == "" && len(groups) == 0 {
+		groups["all"] = map[string]*bucket{}
+	}
+
+	report := &models.RevenueReport{
+		Currency:          currency,
+		Interval:          filter.Interval,
+		GroupBy:           filter.GroupBy,
+		From:              periods[0],
+		To:                filter.To.Format(reportDateLayout),
+		Series:            []models.RevenueSeries{},
+		SkippedCurrencies: sortedKeys(skipped),
+	}
+	for _, key := range sortedKeys(groups) {
+		series := models.RevenueSeries{Key: key, Points: make([]models.RevenuePoint, 0, len(periods))}
+		offset := len(all) - len(periods)
+		for i, period := range all {
+			var current bucket
+			if b := groups[key][period]; b != nil {
+				current = *b
+			}
+			if i < offset {
+				series.PreviousTotal += current.amount
+				continue
+			}
+			point := models.RevenuePoint{Period: period, Deals: current.deals, Amount: roundAmount(current.amount)}
+			if b := groups[key][all[i-1]]; b != nil {
+				point.Previous = roundAmount(b.amount)
+			}
+			point.Change = relativeChange(point.Amount, point.Previous)
+			series.Points = append(series.Points, point)
+			series.Total += current.amount
+		}
+		series.Total = roundAmount(series.Total)
+		series.PreviousTotal = roundAmount(series.PreviousTotal)
+		series.Change = relativeChange(series.Total, series.PreviousTotal)
+		report.Total += series.Total
+		report.Previous += series.PreviousTotal
+		report.Series = append(report.Series, series)
+	}
+	report.Total = roundAmount(report.Total)
+	report.Previous = roundAmount(report.Previous)
+	report.Change = relativeChange(report.Total, report.Previous)
+	return report, nil
+}
+
+// Forecast returns the open deals by the bucket of their departure date, converted into
+// currency and weighted by the probability of their status. Without from and to the series
+// starts with the current bucket.
+func (s *ReportService) Forecast(ctx context.Context, filter models.ReportFilter, currency string) (*models.ForecastReport, error) {
+	if err := validateReportFilter(&filter, forecastGroups...); err != nil {
+		return nil, err
+	}
+	currency, err := s.reportCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	from := truncateReportPeriod(s.now(), filter.Interval)
+	periods, err := reportPeriods(filter, from, addReportPeriods(from, filter.Interval, defaultReportBuckets))
+	if err != nil {
+		return nil, err
+	}
+
+	from, _ = time.Parse(reportDateLayout, periods[0])
+	filter.From = &from
+	filter.To = timePtr(addReportPeriods(from, filter.Interval, len(periods)))
+	amounts, err := s.Reports.Pipeline(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]map[string]*models.ForecastTotal{}
+	skipped := map[string]bool{}
+	for _, a := range amounts {
+		amount, ok := s.Policy.convert(a.Amount, a.Currency, currency)
+		if !ok {
+			skipped[a.Currency] = true
+			continue
+		}
+		if groups[a.Key] == nil {
+			groups[a.Key] = map[string]*models.ForecastTotal{}
+		}
+		t := groups[a.Key][a.Period]
+		if t == nil {
+			t = &models.ForecastTotal{}
+			groups[a.Key][a.Period] = t
+		}
+		t.Deals += a.Deals
+		t.Amount += amount
+		t.Weighted += amount * s.Policy.probability(a.Status)
+	}
+	if filter.GroupBy == "" && len(groups) == 0 {
+		groups["all"] = map[string]*models.ForecastTotal{}
+	}
+
+	report := &models.ForecastReport{
+		Currency:          currency,
+		Interval:          filter.Interval,
+		GroupBy:           filter.GroupBy,
+		From:              periods[0],
+		To:                filter.To.Format(reportDateLayout),
+		Probabilities:     s.Policy.StageProbabilities,
+		Series:            []models.ForecastSeries{},
+		SkippedCurrencies: sortedKeys(skipped),
+	}
+	for _, key := range sortedKeys(groups) {
+		series := models.ForecastSeries{Key: key, Points: make([]models.ForecastPoint, 0, len(periods))}
+		for i, period := range periods {
+			var t models.ForecastTotal
+			if b := groups[key][period]; b != nil {
+				t = *b
+			}
+			point := models.ForecastPoint{
+				Period:   period,
+				Deals:    t.Deals,
+				Amount:   roundAmount(t.Amount),
+				Weighted: roundAmount(t.Weighted),
+			}
+			if i > 0 {
+				point.Change = relativeChange(point.Weighted, series.Points[i-1].Weighted)
+			}
+			series.Points = append(series.Points, point)
+			addForecastTotal(&series.Total, t)
+		}
+		if undated := groups[key][""]; undated != nil {
+			addForecastTotal(&series.Undated, *undated)
+		}
+		roundForecastTotal(&series.Total)
+		roundForecastTotal(&series.Undated)
+		addForecastTotal(&report.Total, series.Total)
+		addForecastTotal(&report.Undated, series.Undated)
+		report.Series = append(report.Series, series)
+	}
+	roundForecastTotal(&report.Total)
+	roundForecastTotal(&report.Undated)
+	return report, nil
+}
+
+// reportCurrency returns the requested currency if amounts can be converted into it.
+func (s *ReportService) reportCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return s.Policy.BaseCurrency, nil
+	}
+	if _, ok := s.Policy.ExchangeRates[currency]; !ok {
+		return "", fmt.Errorf("%w: no exchange rate for %s", ErrInvalidReportFilter, currency)
+	}
+	return currency, nil
+}
+
+// reportPeriods returns the buckets between the filter's from and to, widened to whole
+// buckets, or between the given defaults.
+func reportPeriods(filter models.ReportFilter, defaultFrom, defaultTo time.Time) ([]string, error) {
+	from, to := defaultFrom, defaultTo
+	if filter.From != nil {
+		from = truncateReportPeriod(*filter.From, filter.Interval)
+		if filter.To == nil {
+			to = addReportPeriods(from, filter.Interval, defaultReportBuckets)
+		}
+	}
+	if filter.To != nil {
+		to = truncateReportPeriod(filter.To.Add(-time.Nanosecond), filter.Interval)
+		to = addReportPeriods(to, filter.Interval, 1)
+		if filter.From == nil {
+			from = addReportPeriods(to, filter.Interval, -defaultReportBuckets)
+		}
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReportFilter)
+	}
+	var periods []string
+	for t := from; t.Before(to); t = addReportPeriods(t, filter.Interval, 1) {
+		if len(periods) == maxReportBuckets {
+			return nil, fmt.Errorf("%w: more than %d %ss", ErrInvalidReportFilter, maxReportBuckets, filter.Interval)
+		}
+		periods = append(periods, t.Format(reportDateLayout))
+	}
+	return periods, nil
+}
+
+// reportPeriodRange returns n buckets starting at from, or the -n buckets before it.
+func reportPeriodRange(from time.Time, interval string, n int) []time.Time {
+	if n < 0 {
+		from, n = addReportPeriods(from, interval, n), -n
+	}
+	periods := make([]time.Time, n)
+	for i := range periods {
+		periods[i] = addReportPeriods(from, interval, i)
+	}
+	return periods
+}
+
+func formatReportPeriods(periods []time.Time) []string {
+	formatted := make([]string, len(periods))
+	for i, t := range periods {
+		formatted[i] = t.Format(reportDateLayout)
+	}
+	return formatted
+}
+
+// truncateReportPeriod returns the start of the UTC bucket of t, as date_trunc does; weeks
+// start on Monday.
+func truncateReportPeriod(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case models.ReportIntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case models.ReportIntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func addReportPeriods(t time.Time, interval string, n int) time.Time {
+	switch interval {
+	case models.ReportIntervalDay:
+		return t.AddDate(0, 0, n)
+	case models.ReportIntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+func addForecastTotal(total *models.ForecastTotal, t models.ForecastTotal) {
+	total.Deals += t.Deals
+	total.Amount += t.Amount
+	total.Weighted += t.Weighted
+}
+
+func roundForecastTotal(t *models.ForecastTotal) {
+	t.Amount = roundAmount(t.Amount)
+	t.Weighted = roundAmount(t.Weighted)
+}
+
+// relativeChange returns (current - previous) / previous, nil if there was nothing before.
+func relativeChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := (current - previous) / previous
+	return &change
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
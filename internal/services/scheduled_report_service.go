@@ -0,0 +1,432 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"turcompany/internal/cron"
+	"turcompany/internal/export"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+var (
+	ErrScheduledReportNotFound = errors.New("scheduled report not found")
+	ErrInvalidScheduledReport  = errors.New("invalid scheduled report")
+)
+
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+)
+
+// ScheduledReportTypes are the reports that can be saved and emailed.
+var ScheduledReportTypes = []string{
+	models.ReportTypeSummary,
+	models.ReportTypeLeads,
+	models.ReportTypeDeals,
+	models.ReportTypeFunnel,
+	models.ReportTypeConversion,
+	models.ReportTypeStageDurations,
+	models.ReportTypeWinLoss,
+	models.ReportTypeRevenue,
+	models.ReportTypeForecast,
+}
+
+var reportEmailText = export.Text{
+	RU: "Отчёт «%s» во вложении.",
+	KK: "«%s» есебі тіркемеде.",
+}
+
+// ScheduledReportService manages saved reports and emails them as attachments.
+type ScheduledReportService interface {
+	Create(ctx context.Context, r *models.ScheduledReport) error
+	Update(ctx context.Context, r *models.ScheduledReport) error
+	GetByID(ctx context.Context, id int64) (*models.ScheduledReport, error)
+	List(ctx context.Context) ([]models.ScheduledReport, error)
+	Delete(ctx context.Context, id int64) error
+	// SendNow renders and emails the report at once. A failed sending is recorded and
+	// returned as a delivery with the failed status, not as an error.
+	SendNow(ctx context.Context, actorID, id int64) (*models.ReportDelivery, error)
+	Deliveries(ctx context.Context, id int64, page, size int) (*models.ReportDeliveryPage, error)
+	// RunDue sends the active reports whose next run is not after now and moves their next
+	// run on. A run missed while no instance was running is sent once. A report whose
+	// schedule has no next run is deactivated with the reason in LastError.
+	RunDue(ctx context.Context, now time.Time) error
+}
+
+type scheduledReportService struct {
+	repo    repositories.ScheduledReportRepository
+	reports *ReportService
+	email   EmailService
+	pdfFont string
+	now     func() time.Time
+}
+
+// NewScheduledReportService creates a new instance of ScheduledReportService.
+func NewScheduledReportService(
+	repo repositories.ScheduledReportRepository,
+	reports *ReportService,
+	email EmailService,
+	pdfFont string,
+) ScheduledReportService {
+	return &scheduledReportService{repo: repo, reports: reports, email: email, pdfFont: pdfFont, now: time.Now}
+}
+
+func (s *scheduledReportService) Create(ctx context.Context, r *models.ScheduledReport) error {
+	now := s.now()
+	if err := s.prepare(r, now); err != nil {
+		return err
+	}
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	return s.repo.Create(ctx, r)
+}
+
+func (s *scheduledReportService) Update(ctx context.Context, r *models.ScheduledReport) error {
+	existing, err := s.GetByID(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if err := s.prepare(r, now); err != nil {
+		return err
+	}
+	r.CreatedBy = existing.CreatedBy
+	r.CreatedAt = existing.CreatedAt
+	r.LastRunAt = existing.LastRunAt
+	r.LastError = ""
+	r.UpdatedAt = now
+	return s.repo.Update(ctx, r)
+}
+
+// prepare validates the report, normalizes it and sets its next run after now.
+func (s *scheduledReportService) prepare(r *models.ScheduledReport, now time.Time) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidScheduledReport, fmt.Sprintf(format, args...))
+	}
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return invalid("name is required")
+	}
+	if !slices.Contains(ScheduledReportTypes, r.ReportType) {
+		return invalid("report_type must be one of %s", strings.Join(ScheduledReportTypes, ", "))
+	}
+	if !export.ValidFormat(r.Format) {
+		return invalid("format must be csv, xlsx or pdf")
+	}
+	if r.Lang == "" {
+		r.Lang = export.LangRU
+	}
+	if r.Lang != export.LangRU && r.Lang != export.LangKK {
+		return invalid("lang must be ru or kk")
+	}
+
+	if len(r.Recipients) == 0 {
+		return invalid("at least one recipient is required")
+	}
+	recipients := make([]string, 0, len(r.Recipients))
+	for _, recipient := range r.Recipients {
+		address, err := mail.ParseAddress(strings.TrimSpace(recipient))
+		if err != nil {
+			return invalid("recipient %q is not an email address", recipient)
+		}
+		if !slices.Contains(recipients, address.Address) {
+			recipients = append(recipients, address.Address)
+		}
+	}
+	r.Recipients = recipients
+
+	schedule, err := reportSchedule(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScheduledReport, err)
+	}
+	if err := s.validateParams(r); err != nil {
+		return err
+	}
+
+	r.NextRunAt = nil
+	if r.Active {
+		next, ok := schedule.Next(now)
+		if !ok {
+			return invalid("schedule %q has no runs in the coming years", r.Schedule)
+		}
+		r.NextRunAt = &next
+	}
+	return nil
+}
+
+func (s *scheduledReportService) validateParams(r *models.ScheduledReport) error {
+	p := &r.Params
+	switch p.Period {
+	case "", models.ReportIntervalDay, models.ReportIntervalWeek, models.ReportIntervalMonth:
+	default:
+		return fmt.Errorf("%w: period must be day, week or month", ErrInvalidScheduledReport)
+	}
+	if r.ReportType == models.ReportTypeStageDurations && p.Entity == "" {
+		p.Entity = models.EntityTypeLead
+	}
+	if p.Entity != "" && p.Entity != models.EntityTypeLead && p.Entity != models.EntityTypeDeal {
+		return fmt.Errorf("%w: entity must be lead or deal", ErrInvalidScheduledReport)
+	}
+	// The lists are exported with the same filters as /reports/leads and /reports/deals
+	switch {
+	case r.ReportType == models.ReportTypeLeads && (p.Period != "" || p.Source != ""):
+		return fmt.Errorf("%w: the lead list is filtered by status and owner only", ErrInvalidScheduledReport)
+	case r.ReportType == models.ReportTypeDeals && (p.Source != "" || p.OwnerID != nil):
+		return fmt.Errorf("%w: the deal list is filtered by status and period only", ErrInvalidScheduledReport)
+	}
+
+	filter := models.ReportFilter{GroupBy: p.GroupBy, Interval: p.Interval}
+	var err error
+	switch r.ReportType {
+	case models.ReportTypeFunnel, models.ReportTypeConversion, models.ReportTypeStageDurations, models.ReportTypeWinLoss:
+		err = validateReportFilter(&filter, funnelGroups...)
+	case models.ReportTypeRevenue:
+		err = validateReportFilter(&filter, revenueGroups...)
+	case models.ReportTypeForecast:
+		err = validateReportFilter(&filter, forecastGroups...)
+	default:
+		err = validateReportFilter(&filter)
+	}
+	if err == nil {
+		_, err = s.reports.reportCurrency(p.Currency)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScheduledReport, err)
+	}
+	return nil
+}
+
+// reportSchedule parses the cron schedule in the report's time zone.
+func reportSchedule(r *models.ScheduledReport) (*cron.Schedule, error) {
+	loc := time.Local
+	if r.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(r.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", r.Timezone)
+		}
+	}
+	return cron.Parse(r.Schedule, loc)
+}
+
+func (s *scheduledReportService) GetByID(ctx context.Context, id int64) (*models.ScheduledReport, error) {
+	r, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrScheduledReportNotFound
+	}
+	return r, nil
+}
+
+func (s *scheduledReportService) List(ctx context.Context) ([]models.ScheduledReport, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *scheduledReportService) Delete(ctx context.Context, id int64) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *scheduledReportService) SendNow(ctx context.Context, actorID, id int64) (*models.ReportDelivery, error) {
+	r, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliver(ctx, r, models.DeliveryTriggerManual, actorID, s.now())
+}
+
+func (s *scheduledReportService) Deliveries(ctx context.Context, id int64, page, size int) (*models.ReportDeliveryPage, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultDeliveryPageSize
+	}
+	if size > maxDeliveryPageSize {
+		size = maxDeliveryPageSize
+	}
+	deliveries, err := s.repo.ListDeliveries(ctx, id, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountDeliveries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReportDeliveryPage{Deliveries: deliveries, Total: total, Page: page, Size: size}, nil
+}
+
+func (s *scheduledReportService) RunDue(ctx context.Context, now time.Time) error {
+	due, err := s.repo.ListDue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("due reports: %w", err)
+	}
+	var errs []error
+	for i := range due {
+		r := &due[i]
+		next, lastError := nextReportRun(r, now)
+		if lastError != "" {
+			log.Printf("Scheduled report %d deactivated: %s", r.ID, lastError)
+		}
+		// The run is claimed before sending, so a report is not sent twice
+		claimed, err := s.repo.Advance(ctx, r.ID, *r.NextRunAt, next, now, lastError)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("advance report %d: %w", r.ID, err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := s.deliver(ctx, r, models.DeliveryTriggerSchedule, 0, now); err != nil {
+			errs = append(errs, fmt.Errorf("deliver report %d: %w", r.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nextReportRun returns the run after now, or the reason the report cannot run again.
+func nextReportRun(r *models.ScheduledReport, now time.Time) (*time.Time, string) {
+	schedule, err := reportSchedule(r)
+	if err != nil {
+		return nil, err.Error()
+	}
+	next, ok := schedule.Next(now)
+	if !ok {
+		return nil, fmt.Sprintf("schedule %q has no runs after %s", r.Schedule, now.Format(time.RFC3339))
+	}
+	return &next, ""
+}
+
+// deliver renders the report, emails it and records the delivery.
+func (s *scheduledReportService) deliver(ctx context.Context, r *models.ScheduledReport, trigger string, actorID int64, now time.Time) (*models.ReportDelivery, error) {
+	d := &models.ReportDelivery{
+		ReportID:    r.ID,
+		Trigger:     trigger,
+		Status:      models.DeliveryStatusSent,
+		Recipients:  r.Recipients,
+		TriggeredBy: actorID,
+		CreatedAt:   now,
+	}
+	err := s.send(ctx, r, d, now)
+	if err != nil {
+		d.Status = models.DeliveryStatusFailed
+		d.Error = err.Error()
+		log.Printf("Scheduled report %d (%s): %v", r.ID, trigger, err)
+	}
+	if err := s.repo.CreateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *scheduledReportService) send(ctx context.Context, r *models.ScheduledReport, d *models.ReportDelivery, now time.Time) error {
+	report, rows, err := s.reportRows(ctx, r, now)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := export.Write(r.Format, &buf, report, r.Lang, export.Options{PDFFont: s.pdfFont}, rows); err != nil {
+		return err
+	}
+	d.FileName = export.FileName(report.Name, r.Format, now)
+	d.FileSize = buf.Len()
+
+	subject := fmt.Sprintf("%s — %s", r.Name, now.Format("02.01.2006"))
+	return s.email.SendAttachment(r.Recipients, subject, fmt.Sprintf(reportEmailText.In(r.Lang), r.Name), EmailAttachment{
+		Name:        d.FileName,
+		ContentType: export.ContentType(r.Format),
+		Content:     buf.Bytes(),
+	})
+}
+
+// reportRows builds the report with the saved filters.
+func (s *scheduledReportService) reportRows(ctx context.Context, r *models.ScheduledReport, now time.Time) (export.Report, export.Rows, error) {
+	p := r.Params
+	filter := models.ReportFilter{OwnerID: p.OwnerID, Source: p.Source, GroupBy: p.GroupBy, Interval: p.Interval}
+	if p.Period != "" {
+		loc := time.Local
+		if r.Timezone != "" {
+			loc, _ = time.LoadLocation(r.Timezone)
+		}
+		from, to := previousPeriod(now.In(loc), p.Period)
+		filter.From, filter.To = &from, &to
+	}
+
+	switch r.ReportType {
+	case models.ReportTypeSummary:
+		data, err := s.reports.GetSummary()
+		return export.SummaryReport, export.SummaryRows(data), err
+	case models.ReportTypeLeads:
+		ownerID := 0
+		if p.OwnerID != nil {
+			ownerID = *p.OwnerID
+		}
+		return export.LeadsReport, func(_ string, write func(...interface{}) error) error {
+			return s.reports.EachFilteredLead(p.Status, ownerID, "created_at", "desc", func(lead *models.Leads) error {
+				return export.WriteLead(write, lead)
+			})
+		}, nil
+	case models.ReportTypeDeals:
+		var from, to string
+		if filter.From != nil {
+			from = filter.From.Format(time.RFC3339Nano)
+			to = filter.To.Add(-time.Nanosecond).Format(time.RFC3339Nano)
+		}
+		return export.DealsReport, func(_ string, write func(...interface{}) error) error {
+			return s.reports.EachFilteredDeal(p.Status, from, to, "", 0, 0, "created_at", "desc", func(deal *models.Deals) error {
+				return export.WriteDeal(write, deal)
+			})
+		}, nil
+	case models.ReportTypeFunnel:
+		report, err := s.reports.Funnel(ctx, filter)
+		return export.FunnelReport, export.FunnelRows(report), err
+	case models.ReportTypeConversion:
+		report, err := s.reports.Conversion(ctx, filter)
+		return export.ConversionReport, export.ConversionRows(report), err
+	case models.ReportTypeStageDurations:
+		report, err := s.reports.StageDurations(ctx, p.Entity, filter)
+		return export.StageDurationsReport, export.StageDurationRows(report), err
+	case models.ReportTypeWinLoss:
+		report, err := s.reports.WinLoss(ctx, filter)
+		return export.WinLossReport, export.WinLossRows(report), err
+	case models.ReportTypeRevenue:
+		report, err := s.reports.Revenue(ctx, filter, p.Currency)
+		return export.RevenueReport, export.RevenueRows(report), err
+	case models.ReportTypeForecast:
+		// The forecast looks ahead from the run, the period does not apply
+		filter.From, filter.To = nil, nil
+		report, err := s.reports.Forecast(ctx, filter, p.Currency)
+		return export.ForecastReport, export.ForecastRows(report), err
+	}
+	return export.Report{}, nil, fmt.Errorf("unknown report type %q", r.ReportType)
+}
+
+// previousPeriod returns the last full day, week (from Monday) or month before now, in
+// now's time zone.
+func previousPeriod(now time.Time, period string) (from, to time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case models.ReportIntervalDay:
+		return today.AddDate(0, 0, -1), today
+	case models.ReportIntervalWeek:
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday.AddDate(0, 0, -7), monday
+	}
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return first.AddDate(0, -1, 0), first
+}
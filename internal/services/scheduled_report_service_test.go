@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// fakeScheduledReports повторяет в памяти выборку и сдвиг запусков scheduled_reports.
+type fakeScheduledReports struct {
+	repositories.ScheduledReportRepository
+	reports    []*models.ScheduledReport
+	deliveries []models.ReportDelivery
+}
+
+func (f *fakeScheduledReports) ListDue(ctx context.Context, now time.Time) ([]models.ScheduledReport, error) {
+	var due []models.ScheduledReport
+	for _, r := range f.reports {
+		if r.Active && r.NextRunAt != nil && !r.NextRunAt.After(now) {
+			due = append(due, *r)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeScheduledReports) Advance(ctx context.Context, id int64, seen time.Time, next *time.Time, ranAt time.Time, lastError string) (bool, error) {
+	r := f.reports[id-1]
+	if r.NextRunAt == nil || !r.NextRunAt.Equal(seen) {
+		return false, nil
+	}
+	r.NextRunAt, r.LastRunAt, r.LastError = next, &ranAt, lastError
+	r.Active = r.Active && lastError == ""
+	return true, nil
+}
+
+func (f *fakeScheduledReports) CreateDelivery(ctx context.Context, d *models.ReportDelivery) error {
+	f.deliveries = append(f.deliveries, *d)
+	return nil
+}
+
+func TestRunDueDeactivatesReportWithoutNextRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Minute)
+	repo := &fakeScheduledReports{reports: []*models.ScheduledReport{
+		{ID: 1, Name: "weekly", ReportType: "unknown", Schedule: "0 8 * * MON", Timezone: "UTC", Active: true, NextRunAt: &due},
+		{ID: 2, Name: "feb 30", ReportType: "unknown", Schedule: "0 8 30 2 *", Timezone: "UTC", Active: true, NextRunAt: &due},
+	}}
+	s := &scheduledReportService{repo: repo, now: func() time.Time { return now }}
+
+	if err := s.RunDue(context.Background(), now); err != nil {
+		t.Fatalf("RunDue: %v", err)
+	}
+
+	weekly, feb := repo.reports[0], repo.reports[1]
+	if !weekly.Active || weekly.NextRunAt == nil || weekly.LastError != "" {
+		t.Fatalf("weekly report = %+v, want it active with the next run", weekly)
+	}
+	if feb.Active || feb.NextRunAt != nil || feb.LastError == "" {
+		t.Fatalf("report without runs = %+v, want it inactive with an error", feb)
+	}
+	// Запуск, который уже наступил, всё равно записывается в историю.
+	if len(repo.deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(repo.deliveries))
+	}
+}
+
+func TestScheduledReportWithoutRunsIsRejected(t *testing.T) {
+	s := &scheduledReportService{reports: &ReportService{}}
+	r := &models.ScheduledReport{
+		Name: "feb 30", ReportType: models.ReportTypeSummary, Format: "csv",
+		Recipients: []string{"boss@turcompany.kz"}, Schedule: "0 8 30 2 *", Timezone: "UTC", Active: true,
+	}
+	if err := s.prepare(r, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)); !errors.Is(err, ErrInvalidScheduledReport) || !strings.Contains(err.Error(), "no runs") {
+		t.Fatalf("prepare: got %v, want ErrInvalidScheduledReport about the schedule", err)
+	}
+}
+
+func TestScheduledListRejectsUnsupportedFilters(t *testing.T) {
+	s := &scheduledReportService{reports: &ReportService{}}
+	owner := 3
+	tests := []struct {
+		reportType string
+		params     models.ScheduledReportParams
+		wantErr    bool
+	}{
+		{models.ReportTypeLeads, models.ScheduledReportParams{Status: "new", OwnerID: &owner}, false},
+		{models.ReportTypeLeads, models.ScheduledReportParams{Period: models.ReportIntervalWeek}, true},
+		{models.ReportTypeLeads, models.ScheduledReportParams{Source: "telegram"}, true},
+		{models.ReportTypeDeals, models.ScheduledReportParams{Status: "won", Period: models.ReportIntervalMonth}, false},
+		{models.ReportTypeDeals, models.ScheduledReportParams{Source: "telegram"}, true},
+		{models.ReportTypeDeals, models.ScheduledReportParams{OwnerID: &owner}, true},
+	}
+	for _, tc := range tests {
+		err := s.validateParams(&models.ScheduledReport{ReportType: tc.reportType, Params: tc.params})
+		if tc.wantErr != errors.Is(err, ErrInvalidScheduledReport) || !tc.wantErr && err != nil {
+			t.Errorf("%s %+v: got %v, want error %v", tc.reportType, tc.params, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+	"turcompany/internal/repositories"
+	"turcompany/internal/smsprovider"
+)
+
+const smsDeliveryBatchSize = 100
+
+// SMSDeliveryTracker периодически опрашивает провайдеров о статусе
+// доставки сообщений из журнала sms_messages.
+type SMSDeliveryTracker struct {
+	messages *repositories.SMSMessageRepository
+	provider smsprovider.Provider
+	window   time.Duration
+	now      func() time.Time
+}
+
+// NewSMSDeliveryTracker создаёт трекер; сообщения старше window больше не опрашиваются.
+func NewSMSDeliveryTracker(messages *repositories.SMSMessageRepository, provider smsprovider.Provider, window time.Duration) *SMSDeliveryTracker {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &SMSDeliveryTracker{
+		messages: messages,
+		provider: provider,
+		window:   window,
+		now:      time.Now,
+	}
+}
+
+// Run опрашивает статусы каждые interval до отмены ctx.
+func (t *SMSDeliveryTracker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Poll(ctx); err != nil {
+				log.Printf("Опрос статусов SMS: %v", err)
+			}
+		}
+	}
+}
+
+// Poll обновляет статусы и стоимость недоставленных сообщений за последние window.
+// Опрошенные сообщения уходят в конец очереди, даже если провайдер ответил ошибкой,
+// поэтому сбойные сообщения не занимают весь пакет.
+func (t *SMSDeliveryTracker) Poll(ctx context.Context) error {
+	now := t.now()
+	pending, err := t.messages.ListPending(now.Add(-t.window), smsDeliveryBatchSize)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(pending))
+	for i, msg := range pending {
+		ids[i] = msg.ID
+	}
+	if err := t.messages.MarkPolled(ids, now); err != nil {
+		return err
+	}
+
+	for _, msg := range pending {
+		provider := t.providerFor(msg.Provider)
+		if provider == nil {
+			continue
+		}
+
+		result, err := provider.Status(ctx, msg.MessageID)
+		if err != nil {
+			log.Printf("Статус SMS %s у %s: %v", msg.MessageID, msg.Provider, err)
+			continue
+		}
+
+		next := messageStatus(result.Status, msg.Status)
+		if next == msg.Status && (result.Cost == nil || msg.Cost != nil) {
+			continue
+		}
+		msg.Status = next
+		if result.Cost != nil {
+			msg.Cost = result.Cost
+		}
+		msg.UpdatedAt = t.now()
+		if err := t.messages.UpdateDelivery(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// providerFor находит провайдера, отправившего сообщение.
+func (t *SMSDeliveryTracker) providerFor(name string) smsprovider.Provider {
+	if t.provider.Name() == name {
+		return t.provider
+	}
+	if failover, ok := t.provider.(*smsprovider.Failover); ok {
+		return failover.Lookup(name)
+	}
+	return nil
+}
@@ -1,106 +1,340 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
 	"time"
 	"turcompany/internal/models"
 	"turcompany/internal/repositories"
-	"turcompany/internal/utils"
+	"turcompany/internal/smsprovider"
 )
 
-type SMS_Service struct {
-	Repo   *repositories.SMSConfirmationRepository
-	Client *utils.Client
+var (
+	ErrSMSResendCooldown  = errors.New("повторная отправка кода пока недоступна")
+	ErrSMSDailyLimit      = errors.New("превышен дневной лимит SMS для этого номера")
+	ErrSMSTooManyAttempts = errors.New("превышено число попыток ввода кода, запросите новый код")
+)
+
+// SMSPolicy ограничения на выдачу и проверку одноразовых кодов.
+// Нулевые значения отключают соответствующее ограничение.
+type SMSPolicy struct {
+	CodeTTL            time.Duration
+	MaxAttempts        int
+	ResendCooldown     time.Duration
+	DailyLimitPerPhone int
 }
 
-func NewSMSService(repo *repositories.SMSConfirmationRepository, client *utils.Client) *SMS_Service {
-	return &SMS_Service{Repo: repo, Client: client}
+// NewSMSPolicy собирает политику из настроек; незаданные (нулевые)
+// значения заменяются значениями по умолчанию.
+func NewSMSPolicy(codeTTL time.Duration, maxAttempts int, resendCooldown time.Duration, dailyLimitPerPhone int) SMSPolicy {
+	policy := SMSPolicy{
+		CodeTTL:            5 * time.Minute,
+		MaxAttempts:        5,
+		ResendCooldown:     time.Minute,
+		DailyLimitPerPhone: 10,
+	}
+	if codeTTL > 0 {
+		policy.CodeTTL = codeTTL
+	}
+	if maxAttempts > 0 {
+		policy.MaxAttempts = maxAttempts
+	}
+	if resendCooldown > 0 {
+		policy.ResendCooldown = resendCooldown
+	}
+	if dailyLimitPerPhone > 0 {
+		policy.DailyLimitPerPhone = dailyLimitPerPhone
+	}
+	return policy
 }
 
-func (s *SMS_Service) generateCode() string {
-	src := rand.NewSource(time.Now().UnixNano())
-	rnd := rand.New(src)
-	return fmt.Sprintf("%06d", rnd.Intn(1000000))
+// confirmationTemplate текст SMS с кодом; в журнал SMS попадает без кода.
+const confirmationTemplate = "Код подтверждения: {code}"
+
+// SMSConfirmationStore хранит выданные по документам коды подтверждения.
+type SMSConfirmationStore interface {
+	Create(sms *models.SMSConfirmation) (int64, error)
+	GetLatestByDocumentID(documentID int64) (*models.SMSConfirmation, error)
+	MarkConfirmed(id int64, confirmedAt time.Time, maxAttempts int) (bool, error)
+	ClaimAttempt(id int64, maxAttempts int) (int, bool, error)
 }
 
+// SMSMessageStore журнал исходящих SMS; по нему же считаются лимиты отправки.
+type SMSMessageStore interface {
+	CreateWithinLimits(msg *models.SMSMessage, limits repositories.SMSSendLimits) (int64, error)
+	UpdateDelivery(msg *models.SMSMessage) error
+	List(filter models.SMSMessageFilter, limit, offset int) ([]*models.SMSMessage, error)
+}
+
+type SMS_Service struct {
+	Repo       SMSConfirmationStore
+	Messages   SMSMessageStore
+	Provider   smsprovider.Provider
+	policy     SMSPolicy
+	activities ActivityRecorder
+	now        func() time.Time
+}
+
+func NewSMSService(
+	repo SMSConfirmationStore,
+	messages SMSMessageStore,
+	provider smsprovider.Provider,
+	policy SMSPolicy,
+	activities ActivityRecorder,
+) *SMS_Service {
+	return &SMS_Service{Repo: repo, Messages: messages, Provider: provider, policy: policy, activities: activities, now: time.Now}
+}
+
+// SendSMS выдаёт новый код по документу. Пауза между кодами и суточный лимит на номер
+// проверяются атомарно при записи SMS в журнал, до обращения к провайдеру.
 func (s *SMS_Service) SendSMS(documentID int64, phone string) error {
-	code := s.generateCode()
-	text := fmt.Sprintf("Код подтверждения: %s", code)
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+	salt, err := generateSalt()
+	if err != nil {
+		return err
+	}
+	text := strings.Replace(confirmationTemplate, "{code}", code, 1)
 
-	resp, err := s.Client.SendSMS(phone, text)
+	result, err := s.deliver(documentID, phone, confirmationTemplate, text)
 	if err != nil {
-		return fmt.Errorf("mobizon error: %w", err)
+		return err
 	}
 
 	sms := &models.SMSConfirmation{
 		DocumentID:  documentID,
 		Phone:       phone,
-		SMSCode:     code,
-		SentAt:      time.Now(),
+		SMSCode:     hashCode(salt, code),
+		CodeSalt:    salt,
+		SentAt:      s.now(),
 		Confirmed:   false,
 		ConfirmedAt: time.Time{},
 	}
 
-	fmt.Printf("📦 Inserting SMS: doc_id=%d phone=%s code=%s\n", documentID, phone, code)
 	_, err = s.Repo.Create(sms)
 	if err != nil {
 		return fmt.Errorf("db error after SMS: %w", err)
 	}
 
-	fmt.Printf("✅ SMS sent to %s with code %s [MobizonMessageID: %s]\n", phone, code, resp.Data.MessageID)
+	log.Printf("SMS с кодом отправлено на %s по документу %d [%s, ID сообщения %s]", maskPhone(phone), documentID, result.Provider, result.MessageID)
+	s.record(documentID, models.ActivitySMSSent, "Signing code sent by SMS to "+maskPhone(phone), phone)
 	return nil
 }
 
+// record добавляет событие подписания в ленту сделки документа.
+func (s *SMS_Service) record(documentID int64, activityType, summary, phone string) {
+	recordActivity(context.Background(), s.activities, &models.Activity{
+		Type:       activityType,
+		EntityType: models.EntityTypeDocument,
+		EntityID:   documentID,
+		Summary:    summary,
+		Details:    map[string]string{"document_id": strconv.FormatInt(documentID, 10), "phone": maskPhone(phone)},
+	})
+}
+
+// maskPhone оставляет видимыми только последние четыре цифры номера.
+func maskPhone(phone string) string {
+	runes := []rune(phone)
+	if len(runes) <= 4 {
+		return phone
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// ListMessages возвращает журнал исходящих SMS.
+func (s *SMS_Service) ListMessages(filter models.SMSMessageFilter, limit, offset int) ([]*models.SMSMessage, error) {
+	return s.Messages.List(filter, limit, offset)
+}
+
+// deliver отправляет SMS через провайдера и ведёт запись в журнале sms_messages.
+func (s *SMS_Service) deliver(documentID int64, phone, template, text string) (*smsprovider.SendResult, error) {
+	now := s.now()
+	entry := &models.SMSMessage{
+		DocumentID: &documentID,
+		Recipient:  phone,
+		Template:   template,
+		Status:     models.SMSStatusQueued,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := s.Messages.CreateWithinLimits(entry, s.sendLimits(now)); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrSMSCooldown):
+			return nil, ErrSMSResendCooldown
+		case errors.Is(err, repositories.ErrSMSDailyLimit):
+			return nil, ErrSMSDailyLimit
+		}
+		return nil, err
+	}
+
+	result, sendErr := s.Provider.Send(context.Background(), smsprovider.Message{To: phone, Text: text})
+
+	entry.UpdatedAt = s.now()
+	if sendErr != nil {
+		entry.Status = models.SMSStatusFailed
+		entry.Error = sendErr.Error()
+	} else {
+		entry.Provider = result.Provider
+		entry.MessageID = result.MessageID
+		entry.Cost = result.Cost
+		entry.Status = messageStatus(result.Status, models.SMSStatusSent)
+	}
+	if err := s.Messages.UpdateDelivery(entry); err != nil {
+		log.Printf("Не удалось обновить журнал SMS %d: %v", entry.ID, err)
+	}
+	if sendErr != nil {
+		return nil, fmt.Errorf("sms provider error: %w", sendErr)
+	}
+	return result, nil
+}
+
+// sendLimits переводит политику в ограничения на запись в журнал SMS на момент now.
+func (s *SMS_Service) sendLimits(now time.Time) repositories.SMSSendLimits {
+	var limits repositories.SMSSendLimits
+	if s.policy.ResendCooldown > 0 {
+		limits.CooldownSince = now.Add(-s.policy.ResendCooldown)
+	}
+	if s.policy.DailyLimitPerPhone > 0 {
+		limits.DailyLimit = s.policy.DailyLimitPerPhone
+		limits.DailySince = now.Add(-24 * time.Hour)
+	}
+	return limits
+}
+
+// messageStatus переводит статус провайдера в статус журнала;
+// для неизвестного статуса возвращается fallback.
+func messageStatus(status smsprovider.DeliveryStatus, fallback models.SMSMessageStatus) models.SMSMessageStatus {
+	switch status {
+	case smsprovider.StatusQueued:
+		return models.SMSStatusQueued
+	case smsprovider.StatusSent:
+		return models.SMSStatusSent
+	case smsprovider.StatusDelivered:
+		return models.SMSStatusDelivered
+	case smsprovider.StatusFailed:
+		return models.SMSStatusFailed
+	default:
+		return fallback
+	}
+}
+
+// ResendSMS отправляет новый код. Коды хранятся только в виде хэша,
+// поэтому повторно отправить прежний код невозможно — он заменяется новым.
 func (s *SMS_Service) ResendSMS(documentID int64, phone string) error {
 	existing, err := s.Repo.GetLatestByDocumentID(documentID)
 	if err != nil {
 		return err
 	}
 
-	if existing == nil {
-		if phone == "" {
+	if phone == "" {
+		if existing == nil {
 			return fmt.Errorf("номер телефона обязателен при первом отправлении")
 		}
-		return s.SendSMS(documentID, phone)
+		phone = existing.Phone
 	}
+	return s.SendSMS(documentID, phone)
+}
 
-	if existing.Confirmed || s.IsCodeExpired(existing.SentAt) {
-		return s.SendSMS(documentID, existing.Phone)
+// VerifyCode проверяет последний выданный для документа код и подтверждает его.
+// Если код неверен, уже использован или истёк, возвращается nil без ошибки.
+// После MaxAttempts неверных попыток код блокируется и возвращается ErrSMSTooManyAttempts.
+func (s *SMS_Service) VerifyCode(documentID int64, code string) (*models.SMSConfirmation, error) {
+	sms, err := s.CheckCode(documentID, code)
+	if err != nil || sms == nil {
+		return nil, err
+	}
+	ok, err := s.ConfirmCode(sms)
+	if err != nil || !ok {
+		return nil, err
 	}
+	return sms, nil
+}
 
-	text := fmt.Sprintf("Код подтверждения: %s", existing.SMSCode)
-	_, err = s.Client.SendSMS(existing.Phone, text)
+// CheckCode проверяет код так же, как VerifyCode, но не подтверждает его: код можно
+// подтвердить через ConfirmCode после того, как действие по нему выполнено.
+func (s *SMS_Service) CheckCode(documentID int64, code string) (*models.SMSConfirmation, error) {
+	sms, err := s.Repo.GetLatestByDocumentID(documentID)
 	if err != nil {
-		return fmt.Errorf("resend error: %w", err)
+		return nil, err
+	}
+	if sms == nil || sms.Confirmed || sms.CodeSalt == "" || s.IsCodeExpired(sms.SentAt) {
+		return nil, nil
 	}
 
-	fmt.Printf("🔁 Resent SMS to %s with existing code %s\n", existing.Phone, existing.SMSCode)
-	return nil
-}
-
-func (s *SMS_Service) ConfirmCode(documentID int64, code string) (bool, error) {
-	sms, err := s.Repo.GetByDocumentIDAndCode(documentID, code)
+	// Попытка засчитывается до сравнения: параллельные запросы не получают лишних сравнений
+	attempts, ok, err := s.Repo.ClaimAttempt(sms.ID, s.policy.MaxAttempts)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if sms == nil || sms.Confirmed || s.IsCodeExpired(sms.SentAt) {
-		return false, nil
+	if !ok {
+		return nil, ErrSMSTooManyAttempts
 	}
+	sms.Attempts = attempts
 
-	sms.Confirmed = true
-	sms.ConfirmedAt = time.Now()
-	return true, s.Repo.Update(sms)
+	if !codeMatches(sms, code) {
+		if s.policy.MaxAttempts > 0 && attempts >= s.policy.MaxAttempts {
+			return nil, ErrSMSTooManyAttempts
+		}
+		return nil, nil
+	}
+	return sms, nil
 }
 
-func (s *SMS_Service) IsCodeExpired(sentAt time.Time) bool {
-	return time.Now().After(sentAt.Add(5 * time.Minute))
+// ConfirmCode отмечает проверенный код использованным. false — код успели
+// использовать параллельно.
+func (s *SMS_Service) ConfirmCode(sms *models.SMSConfirmation) (bool, error) {
+	confirmedAt := s.now()
+	ok, err := s.Repo.MarkConfirmed(sms.ID, confirmedAt, s.policy.MaxAttempts)
+	if err != nil || !ok {
+		return false, err
+	}
+	sms.Confirmed = true
+	sms.ConfirmedAt = confirmedAt
+	s.record(sms.DocumentID, models.ActivitySMSConfirmed, "SMS code confirmed from "+maskPhone(sms.Phone), sms.Phone)
+	return true, nil
 }
 
-func (s *SMS_Service) DeleteConfirmation(documentID int64) error {
-	return s.Repo.DeleteByDocumentID(documentID)
+func (s *SMS_Service) IsCodeExpired(sentAt time.Time) bool {
+	return s.policy.CodeTTL > 0 && s.now().After(sentAt.Add(s.policy.CodeTTL))
 }
 
 func (s *SMS_Service) GetLatestByDocumentID(documentID int64) (*models.SMSConfirmation, error) {
 	return s.Repo.GetLatestByDocumentID(documentID)
 }
+
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("генерация кода: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func generateSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("генерация соли: %w", err)
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+func hashCode(salt, code string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func codeMatches(sms *models.SMSConfirmation, code string) bool {
+	expected := hashCode(sms.CodeSalt, code)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(sms.SMSCode)) == 1
+}
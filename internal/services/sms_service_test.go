@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+	"turcompany/internal/smsprovider"
+)
+
+// fakeConfirmations хранит коды в памяти так же, как sms_confirmations.
+type fakeConfirmations struct {
+	items []*models.SMSConfirmation
+	// beforeClaim имитирует попытки, которые параллельные запросы делают между чтением кода
+	// и засчитыванием попытки.
+	beforeClaim func(*models.SMSConfirmation)
+}
+
+func (f *fakeConfirmations) Create(sms *models.SMSConfirmation) (int64, error) {
+	sms.ID = int64(len(f.items) + 1)
+	copied := *sms
+	f.items = append(f.items, &copied)
+	return sms.ID, nil
+}
+
+func (f *fakeConfirmations) GetLatestByDocumentID(documentID int64) (*models.SMSConfirmation, error) {
+	var latest *models.SMSConfirmation
+	for _, sms := range f.items {
+		if sms.DocumentID == documentID && (latest == nil || !sms.SentAt.Before(latest.SentAt)) {
+			latest = sms
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (f *fakeConfirmations) MarkConfirmed(id int64, confirmedAt time.Time, maxAttempts int) (bool, error) {
+	sms := f.items[id-1]
+	if sms.Confirmed || maxAttempts > 0 && sms.Attempts > maxAttempts {
+		return false, nil
+	}
+	sms.Confirmed, sms.ConfirmedAt = true, confirmedAt
+	return true, nil
+}
+
+func (f *fakeConfirmations) ClaimAttempt(id int64, maxAttempts int) (int, bool, error) {
+	if f.beforeClaim != nil {
+		f.beforeClaim(f.items[id-1])
+	}
+	sms := f.items[id-1]
+	if sms.Confirmed || maxAttempts > 0 && sms.Attempts >= maxAttempts {
+		return 0, false, nil
+	}
+	sms.Attempts++
+	return sms.Attempts, true, nil
+}
+
+// fakeMessages повторяет проверки CreateWithinLimits по журналу в памяти.
+type fakeMessages struct {
+	items []*models.SMSMessage
+}
+
+func (f *fakeMessages) CreateWithinLimits(msg *models.SMSMessage, limits repositories.SMSSendLimits) (int64, error) {
+	sent := 0
+	for _, m := range f.items {
+		if msg.DocumentID != nil && !limits.CooldownSince.IsZero() &&
+			m.DocumentID != nil && *m.DocumentID == *msg.DocumentID && m.CreatedAt.After(limits.CooldownSince) {
+			return 0, repositories.ErrSMSCooldown
+		}
+		if m.Recipient == msg.Recipient && !m.CreatedAt.Before(limits.DailySince) {
+			sent++
+		}
+	}
+	if limits.DailyLimit > 0 && sent >= limits.DailyLimit {
+		return 0, repositories.ErrSMSDailyLimit
+	}
+	msg.ID = int64(len(f.items) + 1)
+	f.items = append(f.items, msg)
+	return msg.ID, nil
+}
+
+func (f *fakeMessages) UpdateDelivery(msg *models.SMSMessage) error { return nil }
+
+func (f *fakeMessages) List(filter models.SMSMessageFilter, limit, offset int) ([]*models.SMSMessage, error) {
+	return f.items, nil
+}
+
+// fakeProvider запоминает последний отправленный текст, чтобы тест мог достать код.
+type fakeProvider struct {
+	last string
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Send(ctx context.Context, msg smsprovider.Message) (*smsprovider.SendResult, error) {
+	p.last = msg.Text
+	return &smsprovider.SendResult{Provider: p.Name(), MessageID: "1", Status: smsprovider.StatusSent}, nil
+}
+
+func (p *fakeProvider) Status(ctx context.Context, messageID string) (*smsprovider.StatusResult, error) {
+	return &smsprovider.StatusResult{Status: smsprovider.StatusSent}, nil
+}
+
+func (p *fakeProvider) code() string {
+	return strings.TrimPrefix(p.last, strings.Replace(confirmationTemplate, "{code}", "", 1))
+}
+
+type smsTestEnv struct {
+	service       *SMS_Service
+	confirmations *fakeConfirmations
+	provider      *fakeProvider
+	clock         time.Time
+}
+
+func newSMSTestEnv(policy SMSPolicy) *smsTestEnv {
+	env := &smsTestEnv{confirmations: &fakeConfirmations{}, provider: &fakeProvider{}, clock: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	env.service = NewSMSService(env.confirmations, &fakeMessages{}, env.provider, policy, nil)
+	env.service.now = func() time.Time { return env.clock }
+	return env
+}
+
+func (e *smsTestEnv) advance(d time.Duration) {
+	e.clock = e.clock.Add(d)
+}
+
+func TestSMSCodeExpiresAfterTTL(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 5, time.Minute, 10))
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	code := env.provider.code()
+
+	env.advance(5*time.Minute + time.Second)
+	sms, err := env.service.VerifyCode(1, code)
+	if err != nil || sms != nil {
+		t.Fatalf("expired code: got %v, %v; want nil, nil", sms, err)
+	}
+
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	code = env.provider.code()
+	env.advance(5 * time.Minute)
+	sms, err = env.service.VerifyCode(1, code)
+	if err != nil || sms == nil {
+		t.Fatalf("code at TTL: got %v, %v; want confirmation", sms, err)
+	}
+	if sms, _ := env.service.VerifyCode(1, code); sms != nil {
+		t.Fatal("code confirmed twice")
+	}
+}
+
+func TestSMSCodeLockedAfterMaxAttempts(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 3, time.Minute, 10))
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	code := env.provider.code()
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 1; i < 3; i++ {
+		if sms, err := env.service.VerifyCode(1, wrong); sms != nil || err != nil {
+			t.Fatalf("attempt %d: got %v, %v; want nil, nil", i, sms, err)
+		}
+	}
+	if _, err := env.service.VerifyCode(1, wrong); !errors.Is(err, ErrSMSTooManyAttempts) {
+		t.Fatalf("attempt 3: got %v, want ErrSMSTooManyAttempts", err)
+	}
+	if _, err := env.service.VerifyCode(1, code); !errors.Is(err, ErrSMSTooManyAttempts) {
+		t.Fatalf("correct code after lock: got %v, want ErrSMSTooManyAttempts", err)
+	}
+
+	// Новый код сбрасывает счётчик попыток.
+	env.advance(time.Minute + time.Second)
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if sms, err := env.service.VerifyCode(1, env.provider.code()); err != nil || sms == nil {
+		t.Fatalf("new code: got %v, %v; want confirmation", sms, err)
+	}
+}
+
+func TestSMSResendCooldown(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 5, time.Minute, 10))
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+
+	env.advance(59 * time.Second)
+	if err := env.service.ResendSMS(1, ""); !errors.Is(err, ErrSMSResendCooldown) {
+		t.Fatalf("resend within cooldown: got %v, want ErrSMSResendCooldown", err)
+	}
+	if err := env.service.SendSMS(2, "+77010000001"); err != nil {
+		t.Fatalf("other document within cooldown: %v", err)
+	}
+
+	env.advance(time.Second)
+	if err := env.service.ResendSMS(1, ""); err != nil {
+		t.Fatalf("resend after cooldown: %v", err)
+	}
+}
+
+func TestSMSDailyLimitPerPhone(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 5, time.Minute, 2))
+	for doc := int64(1); doc <= 2; doc++ {
+		if err := env.service.SendSMS(doc, "+77010000001"); err != nil {
+			t.Fatalf("SendSMS %d: %v", doc, err)
+		}
+		env.advance(time.Hour)
+	}
+	if err := env.service.SendSMS(3, "+77010000001"); !errors.Is(err, ErrSMSDailyLimit) {
+		t.Fatalf("third SMS: got %v, want ErrSMSDailyLimit", err)
+	}
+	if err := env.service.SendSMS(3, "+77010000002"); err != nil {
+		t.Fatalf("other phone: %v", err)
+	}
+
+	env.advance(22 * time.Hour)
+	if err := env.service.SendSMS(4, "+77010000001"); !errors.Is(err, ErrSMSDailyLimit) {
+		t.Fatalf("SMS at window edge: got %v, want ErrSMSDailyLimit", err)
+	}
+	// Первое SMS выходит из суточного окна через 24 часа после отправки.
+	env.advance(time.Second)
+	if err := env.service.SendSMS(4, "+77010000001"); err != nil {
+		t.Fatalf("SMS after window: %v", err)
+	}
+}
+
+func TestSMSCheckCodeDoesNotConsumeCode(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 5, time.Minute, 10))
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	code := env.provider.code()
+
+	// Подписание не удалось: код проверен, но не подтверждён, и его можно ввести снова.
+	if sms, err := env.service.CheckCode(1, code); err != nil || sms == nil {
+		t.Fatalf("CheckCode: got %v, %v; want confirmation", sms, err)
+	}
+	sms, err := env.service.CheckCode(1, code)
+	if err != nil || sms == nil {
+		t.Fatalf("CheckCode again: got %v, %v; want confirmation", sms, err)
+	}
+
+	if ok, err := env.service.ConfirmCode(sms); err != nil || !ok {
+		t.Fatalf("ConfirmCode: got %v, %v; want true", ok, err)
+	}
+	if ok, _ := env.service.ConfirmCode(sms); ok {
+		t.Fatal("code confirmed twice")
+	}
+	if sms, _ := env.service.CheckCode(1, code); sms != nil {
+		t.Fatal("confirmed code passed the check")
+	}
+}
+
+func TestSMSAttemptLimitHoldsUnderConcurrentGuesses(t *testing.T) {
+	env := newSMSTestEnv(NewSMSPolicy(5*time.Minute, 3, time.Minute, 10))
+	if err := env.service.SendSMS(1, "+77010000001"); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	code := env.provider.code()
+
+	// Пока запрос читал код, параллельные запросы исчерпали попытки: верный код уже не принимается.
+	env.confirmations.beforeClaim = func(sms *models.SMSConfirmation) { sms.Attempts = 3 }
+	if sms, err := env.service.VerifyCode(1, code); !errors.Is(err, ErrSMSTooManyAttempts) || sms != nil {
+		t.Fatalf("correct code after the cap: got %v, %v; want ErrSMSTooManyAttempts", sms, err)
+	}
+	env.confirmations.beforeClaim = nil
+
+	if attempts := env.confirmations.items[0].Attempts; attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := env.service.VerifyCode(1, code); !errors.Is(err, ErrSMSTooManyAttempts) {
+			t.Fatalf("guess %d: got %v, want ErrSMSTooManyAttempts", i, err)
+		}
+	}
+	if attempts := env.confirmations.items[0].Attempts; attempts != 3 {
+		t.Fatalf("attempts grew past the cap: %d", attempts)
+	}
+}
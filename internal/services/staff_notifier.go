@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// StaffNotifier delivers short notifications to CRM users outside the web app.
+// Notify does not block: delivery errors are only logged.
+type StaffNotifier interface {
+	Notify(userID int64, text string)
+}
+
+const staffNotifyTimeout = 15 * time.Second
+
+type telegramNotifier struct {
+	links repositories.TelegramLinkRepository
+	bot   *tgbotapi.BotAPI
+}
+
+// NewTelegramNotifier creates a StaffNotifier that writes to the user's linked Telegram chat.
+// With a nil bot (no token configured) notifications are dropped.
+func NewTelegramNotifier(links repositories.TelegramLinkRepository, bot *tgbotapi.BotAPI) StaffNotifier {
+	return &telegramNotifier{links: links, bot: bot}
+}
+
+func (n *telegramNotifier) Notify(userID int64, text string) {
+	if n.bot == nil || userID <= 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), staffNotifyTimeout)
+		defer cancel()
+		if err := n.send(ctx, userID, text); err != nil {
+			log.Printf("Telegram notification to user %d: %v", userID, err)
+		}
+	}()
+}
+
+func (n *telegramNotifier) send(ctx context.Context, userID int64, text string) error {
+	link, err := n.links.FindByUser(ctx, userID)
+	if err != nil || link == nil {
+		return err
+	}
+
+	_, err = n.bot.Send(tgbotapi.NewMessage(link.ChatID, text))
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+		// The user blocked the bot or deleted the chat: stop writing to it
+		return n.links.DeleteByChat(ctx, link.ChatID)
+	}
+	return err
+}
+
+type emailNotifier struct {
+	users repositories.UserRepository
+	email EmailService
+}
+
+// NewEmailNotifier creates a StaffNotifier that emails the user; the first line of the text
+// becomes the subject.
+func NewEmailNotifier(users repositories.UserRepository, email EmailService) StaffNotifier {
+	return &emailNotifier{users: users, email: email}
+}
+
+func (n *emailNotifier) Notify(userID int64, text string) {
+	if userID <= 0 {
+		return
+	}
+	go func() {
+		user, err := n.users.GetByID(int(userID))
+		if err != nil {
+			log.Printf("Email notification to user %d: %v", userID, err)
+			return
+		}
+		if user.Email == "" {
+			return
+		}
+		subject, _, _ := strings.Cut(text, "\n")
+		if runes := []rune(subject); len(runes) > 100 {
+			subject = string(runes[:100]) + "…"
+		}
+		if err := n.email.SendNotification(user.Email, "TurCompany: "+subject, text); err != nil {
+			log.Printf("Email notification to user %d: %v", userID, err)
+		}
+	}()
+}
+
+// NotificationPublisher pushes a new in-app notification to the user's open sessions.
+type NotificationPublisher interface {
+	NotifyNotification(n *models.Notification)
+}
+
+type inAppNotifier struct {
+	repo      repositories.NotificationRepository
+	publisher NotificationPublisher
+	now       func() time.Time
+}
+
+// NewInAppNotifier creates a StaffNotifier that stores the notification in the CRM and pushes
+// it to the user over WebSocket.
+func NewInAppNotifier(repo repositories.NotificationRepository, publisher NotificationPublisher) StaffNotifier {
+	return &inAppNotifier{repo: repo, publisher: publisher, now: time.Now}
+}
+
+func (n *inAppNotifier) Notify(userID int64, text string) {
+	if userID <= 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), staffNotifyTimeout)
+		defer cancel()
+		notification := &models.Notification{UserID: userID, Text: text, CreatedAt: n.now()}
+		if err := n.repo.Create(ctx, notification); err != nil {
+			log.Printf("In-app notification to user %d: %v", userID, err)
+			return
+		}
+		if n.publisher != nil {
+			n.publisher.NotifyNotification(notification)
+		}
+	}()
+}
+
+type multiNotifier []StaffNotifier
+
+// NewMultiNotifier creates a StaffNotifier that delivers through every given notifier.
+func NewMultiNotifier(notifiers ...StaffNotifier) StaffNotifier {
+	m := multiNotifier{}
+	for _, n := range notifiers {
+		if n != nil {
+			m = append(m, n)
+		}
+	}
+	return m
+}
+
+func (m multiNotifier) Notify(userID int64, text string) {
+	for _, n := range m {
+		n.Notify(userID, text)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+)
+
+var (
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrEmptyChecklistItem    = errors.New("checklist item title is required")
+	ErrTaskCommentNotFound   = errors.New("comment not found")
+	ErrEmptyTaskComment      = errors.New("comment is empty")
+	ErrInvalidCommentParent  = errors.New("the parent comment belongs to another task")
+	ErrNotCommentAuthor      = errors.New("only the author can change the comment")
+)
+
+func (s *taskService) GetDetails(ctx context.Context, id int64) (*models.TaskDetails, error) {
+	task, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	checklist, err := s.checklist.ListByTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.comments.ListByTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := s.activity.ListByTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TaskDetails{
+		Task:      *task,
+		Checklist: checklist,
+		Comments:  commentThreads(comments),
+		Activity:  activity,
+	}, nil
+}
+
+// commentThreads nests replies under their parents; comments come oldest first.
+func commentThreads(comments []models.TaskComment) []models.TaskComment {
+	children := map[int64][]models.TaskComment{}
+	var roots []models.TaskComment
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var build func(list []models.TaskComment) []models.TaskComment
+	build = func(list []models.TaskComment) []models.TaskComment {
+		for i := range list {
+			if replies, ok := children[list[i].ID]; ok {
+				list[i].Replies = build(replies)
+			}
+		}
+		return list
+	}
+	if roots == nil {
+		return []models.TaskComment{}
+	}
+	return build(roots)
+}
+
+func (s *taskService) AddChecklistItem(ctx context.Context, taskID int64, title string) (*models.TaskChecklistItem, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrEmptyChecklistItem
+	}
+	if _, err := s.GetByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	item := &models.TaskChecklistItem{TaskID: taskID, Title: title, CreatedAt: time.Now()}
+	if err := s.checklist.Create(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *taskService) UpdateChecklistItem(ctx context.Context, taskID, itemID int64, update models.TaskChecklistUpdate) (*models.TaskChecklistItem, error) {
+	item, err := s.checklistItem(ctx, taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Title != nil {
+		item.Title = strings.TrimSpace(*update.Title)
+		if item.Title == "" {
+			return nil, ErrEmptyChecklistItem
+		}
+	}
+	if update.Done != nil && *update.Done != item.Done {
+		item.Done = *update.Done
+		item.DoneAt = nil
+		if item.Done {
+			now := time.Now()
+			item.DoneAt = &now
+		}
+	}
+	if err := s.checklist.Update(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *taskService) DeleteChecklistItem(ctx context.Context, taskID, itemID int64) error {
+	if _, err := s.checklistItem(ctx, taskID, itemID); err != nil {
+		return err
+	}
+	return s.checklist.Delete(ctx, itemID)
+}
+
+func (s *taskService) checklistItem(ctx context.Context, taskID, itemID int64) (*models.TaskChecklistItem, error) {
+	item, err := s.checklist.FindByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || item.TaskID != taskID {
+		return nil, ErrChecklistItemNotFound
+	}
+	return item, nil
+}
+
+// AddComment stores a comment by comment.AuthorID; a reply must be to a comment of the same task.
+func (s *taskService) AddComment(ctx context.Context, comment *models.TaskComment) error {
+	comment.Content = strings.TrimSpace(comment.Content)
+	if comment.Content == "" {
+		return ErrEmptyTaskComment
+	}
+	if _, err := s.GetByID(ctx, comment.TaskID); err != nil {
+		return err
+	}
+	if comment.ParentID != nil {
+		parent, err := s.comments.FindByID(ctx, *comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent == nil || parent.TaskID != comment.TaskID {
+			return ErrInvalidCommentParent
+		}
+	}
+	comment.CreatedAt = time.Now()
+	return s.comments.Create(ctx, comment)
+}
+
+func (s *taskService) UpdateComment(ctx context.Context, userID, taskID, commentID int64, content string) (*models.TaskComment, error) {
+	comment, err := s.ownComment(ctx, userID, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	comment.Content = strings.TrimSpace(content)
+	if comment.Content == "" {
+		return nil, ErrEmptyTaskComment
+	}
+	comment.UpdatedAt = time.Now()
+	if err := s.comments.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment removes the comment together with its replies.
+func (s *taskService) DeleteComment(ctx context.Context, userID, taskID, commentID int64) error {
+	if _, err := s.ownComment(ctx, userID, taskID, commentID); err != nil {
+		return err
+	}
+	return s.comments.Delete(ctx, commentID)
+}
+
+func (s *taskService) ownComment(ctx context.Context, userID, taskID, commentID int64) (*models.TaskComment, error) {
+	comment, err := s.comments.FindByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.TaskID != taskID {
+		return nil, ErrTaskCommentNotFound
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrNotCommentAuthor
+	}
+	return comment, nil
+}
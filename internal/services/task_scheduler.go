@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// taskSchedulerLease is the lease name; only its holder runs the scheduler passes.
+const taskSchedulerLease = "task_scheduler"
+
+const taskDueLayout = "02.01.2006 15:04"
+
+// TaskReminderPolicy configures reminders and escalation of task due dates.
+type TaskReminderPolicy struct {
+	RemindBefore  []time.Duration // reminders to the assignee before the due date, e.g. 24h and 1h
+	EscalateAfter time.Duration   // how long a task stays overdue before its assignee's manager is told
+	LeaseTTL      time.Duration   // how long an instance keeps the scheduler after its last pass
+}
+
+// TaskScheduler reminds assignees about upcoming due dates, marks tasks overdue and escalates
+// them to the assignee's manager. Several instances may run it: each pass is done by the
+// instance holding the lease, and every reminder is recorded before it is sent.
+type TaskScheduler struct {
+	tasks    repositories.TaskRepository
+	users    repositories.UserRepository
+	leases   repositories.LeaseRepository
+	notifier StaffNotifier
+	policy   TaskReminderPolicy
+	holder   string
+	now      func() time.Time
+}
+
+// NewTaskScheduler creates a TaskScheduler. now is the clock used for all due date checks;
+// nil means time.Now.
+func NewTaskScheduler(
+	tasks repositories.TaskRepository,
+	users repositories.UserRepository,
+	leases repositories.LeaseRepository,
+	notifier StaffNotifier,
+	policy TaskReminderPolicy,
+	now func() time.Time,
+) *TaskScheduler {
+	if now == nil {
+		now = time.Now
+	}
+	if policy.LeaseTTL <= 0 {
+		policy.LeaseTTL = 5 * time.Minute
+	}
+	// Stages go from the closest to the due date, each covering the time up to the next one
+	var stages []time.Duration
+	for _, d := range policy.RemindBefore {
+		if d > 0 {
+			stages = append(stages, d)
+		}
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+	policy.RemindBefore = stages
+
+	return &TaskScheduler{
+		tasks:    tasks,
+		users:    users,
+		leases:   leases,
+		notifier: notifier,
+		policy:   policy,
+		holder:   schedulerHolder(),
+		now:      now,
+	}
+}
+
+// Run runs a pass every interval until ctx is cancelled, then releases the lease.
+func (s *TaskScheduler) Run(ctx context.Context, interval time.Duration) {
+	runLeased(ctx, s.leases, taskSchedulerLease, s.holder, interval, s.Tick)
+}
+
+// Tick runs one pass if this instance holds (or takes) the lease.
+func (s *TaskScheduler) Tick(ctx context.Context) error {
+	now := s.now()
+	held, err := s.leases.Acquire(ctx, taskSchedulerLease, s.holder, now, s.policy.LeaseTTL)
+	if err != nil {
+		return fmt.Errorf("acquire lease: %w", err)
+	}
+	if !held {
+		return nil
+	}
+	return errors.Join(s.remind(ctx, now), s.markOverdue(ctx, now), s.escalate(ctx, now))
+}
+
+func (s *TaskScheduler) remind(ctx context.Context, now time.Time) error {
+	var from time.Duration
+	for _, before := range s.policy.RemindBefore {
+		tasks, err := s.tasks.ClaimReminders(ctx, before.String(), now, now.Add(from), now.Add(before))
+		if err != nil {
+			return fmt.Errorf("reminders %s: %w", before, err)
+		}
+		for _, task := range tasks {
+			s.notifier.Notify(task.AssigneeID, fmt.Sprintf("Reminder: task #%d \"%s\" is due in %s, at %s.",
+				task.ID, task.Title, formatDuration(task.DueDate.Sub(now)), task.DueDate.Local().Format(taskDueLayout)))
+		}
+		from = before
+	}
+	return nil
+}
+
+func (s *TaskScheduler) markOverdue(ctx context.Context, now time.Time) error {
+	tasks, err := s.tasks.MarkOverdue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("mark overdue: %w", err)
+	}
+	for _, task := range tasks {
+		s.notifier.Notify(task.AssigneeID, fmt.Sprintf("Task #%d \"%s\" is overdue: it was due at %s.",
+			task.ID, task.Title, task.DueDate.Local().Format(taskDueLayout)))
+	}
+	return nil
+}
+
+func (s *TaskScheduler) escalate(ctx context.Context, now time.Time) error {
+	tasks, err := s.tasks.ClaimEscalations(ctx, now, now.Add(-s.policy.EscalateAfter))
+	if err != nil {
+		return fmt.Errorf("escalate: %w", err)
+	}
+	for _, task := range tasks {
+		target, assignee := s.escalationTarget(task)
+		if target == 0 {
+			log.Printf("Task %d is overdue, but its assignee has no manager to escalate to", task.ID)
+			continue
+		}
+		s.notifier.Notify(target, fmt.Sprintf("Escalation: task #%d \"%s\" assigned to %s is overdue by %s (due %s).",
+			task.ID, task.Title, assignee, formatDuration(now.Sub(*task.DueDate)), task.DueDate.Local().Format(taskDueLayout)))
+	}
+	return nil
+}
+
+// escalationTarget returns the assignee's manager, or the task creator if the assignee has no
+// manager, together with the assignee's name for the message.
+func (s *TaskScheduler) escalationTarget(task models.Task) (int64, string) {
+	assignee := fmt.Sprintf("user #%d", task.AssigneeID)
+	user, err := s.users.GetByID(int(task.AssigneeID))
+	if err != nil {
+		log.Printf("Escalate task %d: assignee %d: %v", task.ID, task.AssigneeID, err)
+	}
+	if user != nil {
+		if user.Email != "" {
+			assignee = user.Email
+		}
+		if user.ManagerID != nil && int64(*user.ManagerID) != task.AssigneeID {
+			return int64(*user.ManagerID), assignee
+		}
+	}
+	if task.CreatorID != task.AssigneeID {
+		return task.CreatorID, assignee
+	}
+	return 0, assignee
+}
+
+// formatDuration rounds d to minutes and drops zero units: 24h, 1h30m, 45m.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	text := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// runLeased calls tick every interval until ctx is cancelled, then releases the lease name
+// held by holder. tick is expected to acquire the lease itself and skip the pass without it.
+func runLeased(ctx context.Context, leases repositories.LeaseRepository, name, holder string, interval time.Duration, tick func(context.Context) error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := tick(ctx); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := leases.Release(releaseCtx, name, holder); err != nil {
+				log.Printf("Release %s lease: %v", name, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedulerHolder identifies this instance in the leases.
+func schedulerHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), randomName()[:8])
+}
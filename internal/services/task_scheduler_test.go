@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// fakeTaskStore повторяет в памяти запросы планировщика к таблицам tasks и task_reminders.
+type fakeTaskStore struct {
+	repositories.TaskRepository
+	tasks     []*models.Task
+	reminders map[string]bool
+}
+
+func (f *fakeTaskStore) open(task *models.Task) bool {
+	return task.DueDate != nil && (task.Status == models.StatusNew || task.Status == models.StatusInProgress)
+}
+
+func (f *fakeTaskStore) ClaimReminders(ctx context.Context, stage string, now, from, until time.Time) ([]models.Task, error) {
+	var claimed []models.Task
+	for _, task := range f.tasks {
+		if !f.open(task) || !task.DueDate.After(from) || task.DueDate.After(until) {
+			continue
+		}
+		key := stage + "/" + task.DueDate.String() + "/" + task.Title
+		if f.reminders[key] {
+			continue
+		}
+		f.reminders[key] = true
+		claimed = append(claimed, *task)
+	}
+	return claimed, nil
+}
+
+func (f *fakeTaskStore) MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error) {
+	var marked []models.Task
+	for _, task := range f.tasks {
+		if f.open(task) && !task.DueDate.After(now) && task.OverdueAt == nil {
+			at := now
+			task.OverdueAt = &at
+			marked = append(marked, *task)
+		}
+	}
+	return marked, nil
+}
+
+func (f *fakeTaskStore) ClaimEscalations(ctx context.Context, now, dueBefore time.Time) ([]models.Task, error) {
+	var claimed []models.Task
+	for _, task := range f.tasks {
+		if f.open(task) && task.OverdueAt != nil && task.EscalatedAt == nil && !task.DueDate.After(dueBefore) {
+			at := now
+			task.EscalatedAt = &at
+			claimed = append(claimed, *task)
+		}
+	}
+	return claimed, nil
+}
+
+type fakeUserStore struct {
+	repositories.UserRepository
+	users map[int]*models.User
+}
+
+func (f *fakeUserStore) GetByID(id int) (*models.User, error) {
+	return f.users[id], nil
+}
+
+// fakeLeases выдаёт аренду так же, как scheduler_leases.
+type fakeLeases struct {
+	holder  string
+	expires time.Time
+}
+
+func (f *fakeLeases) Acquire(ctx context.Context, name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	if f.holder != "" && f.holder != holder && f.expires.After(now) {
+		return false, nil
+	}
+	f.holder, f.expires = holder, now.Add(ttl)
+	return true, nil
+}
+
+func (f *fakeLeases) Release(ctx context.Context, name, holder string) error {
+	if f.holder == holder {
+		f.holder = ""
+	}
+	return nil
+}
+
+type notice struct {
+	userID int64
+	text   string
+}
+
+type recordingNotifier struct {
+	sent []notice
+}
+
+func (n *recordingNotifier) Notify(userID int64, text string) {
+	n.sent = append(n.sent, notice{userID, text})
+}
+
+// take возвращает накопленные уведомления и очищает список.
+func (n *recordingNotifier) take() []notice {
+	sent := n.sent
+	n.sent = nil
+	return sent
+}
+
+type schedulerTestEnv struct {
+	tasks  *fakeTaskStore
+	users  *fakeUserStore
+	leases *fakeLeases
+	clock  time.Time
+}
+
+func newSchedulerTestEnv() *schedulerTestEnv {
+	return &schedulerTestEnv{
+		tasks:  &fakeTaskStore{reminders: map[string]bool{}},
+		users:  &fakeUserStore{users: map[int]*models.User{}},
+		leases: &fakeLeases{},
+		clock:  time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func (e *schedulerTestEnv) scheduler(policy TaskReminderPolicy) (*TaskScheduler, *recordingNotifier) {
+	notifier := &recordingNotifier{}
+	return NewTaskScheduler(e.tasks, e.users, e.leases, notifier, policy, func() time.Time { return e.clock }), notifier
+}
+
+func (e *schedulerTestEnv) addTask(title string, assigneeID, creatorID int64, dueIn time.Duration) {
+	due := e.clock.Add(dueIn)
+	e.tasks.tasks = append(e.tasks.tasks, &models.Task{
+		ID:         int64(len(e.tasks.tasks) + 1),
+		Title:      title,
+		AssigneeID: assigneeID,
+		CreatorID:  creatorID,
+		DueDate:    &due,
+		Status:     models.StatusNew,
+	})
+}
+
+func tick(t *testing.T, s *TaskScheduler) {
+	t.Helper()
+	if err := s.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+}
+
+func expectNotices(t *testing.T, got []notice, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d notifications %v, want %d", len(got), got, len(want))
+	}
+	for i, n := range got {
+		if !strings.Contains(n.text, want[i]) {
+			t.Fatalf("notification %d = %q, want it to contain %q", i, n.text, want[i])
+		}
+	}
+}
+
+func TestTaskSchedulerReminderWindows(t *testing.T) {
+	env := newSchedulerTestEnv()
+	s, notifier := env.scheduler(TaskReminderPolicy{RemindBefore: []time.Duration{24 * time.Hour, time.Hour}, EscalateAfter: 24 * time.Hour})
+	env.addTask("soon", 1, 1, 30*time.Minute)
+	env.addTask("today", 1, 1, 5*time.Hour)
+	env.addTask("later", 1, 1, 27*time.Hour)
+
+	tick(t, s)
+	expectNotices(t, notifier.take(), `"soon" is due in 30m`, `"today" is due in 5h`)
+
+	tick(t, s)
+	expectNotices(t, notifier.take())
+
+	// "today" входит в часовое окно, "later" — в суточное, срок "soon" прошёл.
+	env.clock = env.clock.Add(4*time.Hour + 30*time.Minute)
+	tick(t, s)
+	expectNotices(t, notifier.take(), `"today" is due in 30m`, `"later" is due in 22h30m`, `"soon" is overdue`)
+}
+
+func TestTaskSchedulerOverdueAndEscalation(t *testing.T) {
+	env := newSchedulerTestEnv()
+	manager := 9
+	env.users.users[5] = &models.User{ID: 5, Email: "agent@turcompany.kz", ManagerID: &manager}
+	s, notifier := env.scheduler(TaskReminderPolicy{EscalateAfter: 2 * time.Hour})
+	env.addTask("visa", 5, 1, -time.Minute)
+	env.addTask("hotel", 6, 1, time.Hour)
+
+	tick(t, s)
+	sent := notifier.take()
+	expectNotices(t, sent, `"visa" is overdue`)
+	if sent[0].userID != 5 {
+		t.Fatalf("overdue notice went to %d, want the assignee", sent[0].userID)
+	}
+
+	env.clock = env.clock.Add(2 * time.Hour)
+	tick(t, s)
+	sent = notifier.take()
+	expectNotices(t, sent, `"hotel" is overdue`, `Escalation: task #1 "visa" assigned to agent@turcompany.kz is overdue by 2h1m`)
+	if sent[1].userID != 9 {
+		t.Fatalf("escalation went to %d, want the manager", sent[1].userID)
+	}
+
+	// Без руководителя эскалация уходит постановщику задачи.
+	env.clock = env.clock.Add(2 * time.Hour)
+	tick(t, s)
+	sent = notifier.take()
+	expectNotices(t, sent, `Escalation: task #2 "hotel" assigned to user #6`)
+	if sent[0].userID != 1 {
+		t.Fatalf("escalation went to %d, want the creator", sent[0].userID)
+	}
+
+	tick(t, s)
+	expectNotices(t, notifier.take())
+}
+
+func TestTaskSchedulerLease(t *testing.T) {
+	env := newSchedulerTestEnv()
+	policy := TaskReminderPolicy{EscalateAfter: 24 * time.Hour, LeaseTTL: 5 * time.Minute}
+	first, firstNotifier := env.scheduler(policy)
+	second, secondNotifier := env.scheduler(policy)
+	env.addTask("visa", 5, 1, -time.Minute)
+
+	tick(t, first)
+	tick(t, second)
+	expectNotices(t, firstNotifier.take(), `"visa" is overdue`)
+	expectNotices(t, secondNotifier.take())
+
+	// Держатель пропал: аренда истекает, и работу подхватывает другой экземпляр.
+	env.addTask("hotel", 5, 1, 4*time.Minute)
+	env.clock = env.clock.Add(4 * time.Minute)
+	tick(t, second)
+	expectNotices(t, secondNotifier.take())
+
+	env.clock = env.clock.Add(time.Minute)
+	tick(t, second)
+	expectNotices(t, secondNotifier.take(), `"hotel" is overdue`)
+	tick(t, first)
+	expectNotices(t, firstNotifier.take())
+
+	// После Release аренду сразу получает другой экземпляр.
+	if err := env.leases.Release(context.Background(), taskSchedulerLease, second.holder); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	env.addTask("flight", 5, 1, 0)
+	tick(t, first)
+	expectNotices(t, firstNotifier.take(), `"flight" is overdue`)
+}
@@ -2,8 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"turcompany/internal/models"
+	"turcompany/internal/recurrence"
 	"turcompany/internal/repositories"
 )
 
@@ -12,20 +19,57 @@ type TaskService interface {
 	Create(ctx context.Context, task *models.Task) (*models.Task, error)
 	GetByID(ctx context.Context, id int64) (*models.Task, error)
 	GetAll(ctx context.Context, filter models.TaskFilter) ([]models.Task, error)
-	Update(ctx context.Context, id int64, updateData *models.Task) (*models.Task, error)
+	List(ctx context.Context, filter models.TaskFilter, page, size int) (*models.TaskPage, error)
+	// Update changes the task on behalf of actorID (zero if unknown) and records changes of
+	// the status, assignee and due date in the activity log.
+	Update(ctx context.Context, actorID, id int64, updateData *models.Task) (*models.Task, error)
 	Delete(ctx context.Context, id int64) error
+
+	// GetDetails returns the task with its checklist, comment threads and activity log.
+	GetDetails(ctx context.Context, id int64) (*models.TaskDetails, error)
+	AddChecklistItem(ctx context.Context, taskID int64, title string) (*models.TaskChecklistItem, error)
+	UpdateChecklistItem(ctx context.Context, taskID, itemID int64, update models.TaskChecklistUpdate) (*models.TaskChecklistItem, error)
+	DeleteChecklistItem(ctx context.Context, taskID, itemID int64) error
+	AddComment(ctx context.Context, comment *models.TaskComment) error
+	UpdateComment(ctx context.Context, userID, taskID, commentID int64, content string) (*models.TaskComment, error)
+	DeleteComment(ctx context.Context, userID, taskID, commentID int64) error
 }
 
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	ErrRecurrenceNoDue   = errors.New("a recurring task needs a due date")
+	ErrInvalidTaskFilter = errors.New("invalid task filter")
+)
+
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 200
+)
+
 type taskService struct {
-	repo repositories.TaskRepository
+	repo       repositories.TaskRepository
+	checklist  repositories.TaskChecklistRepository
+	comments   repositories.TaskCommentRepository
+	activity   repositories.TaskActivityRepository
+	activities ActivityRecorder
 }
 
 // NewTaskService creates a new instance of TaskService.
-func NewTaskService(repo repositories.TaskRepository) TaskService {
-	return &taskService{repo: repo}
+func NewTaskService(
+	repo repositories.TaskRepository,
+	checklist repositories.TaskChecklistRepository,
+	comments repositories.TaskCommentRepository,
+	activity repositories.TaskActivityRepository,
+	activities ActivityRecorder,
+) TaskService {
+	return &taskService{repo: repo, checklist: checklist, comments: comments, activity: activity, activities: activities}
 }
 
 func (s *taskService) Create(ctx context.Context, task *models.Task) (*models.Task, error) {
+	if err := prepareRecurrence(task); err != nil {
+		return nil, err
+	}
 	task.Status = models.StatusNew
 	task.CreatedAt = time.Now()
 	task.UpdatedAt = time.Now()
@@ -37,33 +81,227 @@ func (s *taskService) Create(ctx context.Context, task *models.Task) (*models.Ta
 }
 
 func (s *taskService) GetByID(ctx context.Context, id int64) (*models.Task, error) {
-	return s.repo.FindByID(ctx, id)
+	task, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
 }
 
 func (s *taskService) GetAll(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
 	return s.repo.FindAll(ctx, filter)
 }
 
-func (s *taskService) Update(ctx context.Context, id int64, updateData *models.Task) (*models.Task, error) {
-	existingTask, err := s.repo.FindByID(ctx, id)
+// List returns one page of the filtered tasks with the total number of matches.
+func (s *taskService) List(ctx context.Context, filter models.TaskFilter, page, size int) (*models.TaskPage, error) {
+	if err := validateTaskFilter(filter); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultTaskPageSize
+	}
+	if size > maxTaskPageSize {
+		size = maxTaskPageSize
+	}
+	filter.Limit = size
+	filter.Offset = (page - 1) * size
+
+	tasks, err := s.repo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TaskPage{Tasks: tasks, Total: total, Page: page, Size: size}, nil
+}
+
+func validateTaskFilter(filter models.TaskFilter) error {
+	if filter.EntityType != nil {
+		switch *filter.EntityType {
+		case models.EntityTypeLead, models.EntityTypeDeal, models.EntityTypeDocument:
+		default:
+			return fmt.Errorf("%w: entity_type must be lead, deal or document", ErrInvalidTaskFilter)
+		}
+	}
+	if filter.Status != nil {
+		switch *filter.Status {
+		case models.StatusNew, models.StatusInProgress, models.StatusDone, models.StatusCancelled:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidTaskFilter, *filter.Status)
+		}
+	}
+	if filter.DueAfter != nil && filter.DueBefore != nil && !filter.DueAfter.Before(*filter.DueBefore) {
+		return fmt.Errorf("%w: due_after must be before due_before", ErrInvalidTaskFilter)
+	}
+	if filter.Sort != "" && !slices.Contains(models.TaskSortFields, strings.TrimPrefix(filter.Sort, "-")) {
+		return fmt.Errorf("%w: sort must be one of %s, optionally prefixed with -",
+			ErrInvalidTaskFilter, strings.Join(models.TaskSortFields, ", "))
+	}
+	return nil
+}
+
+func (s *taskService) Update(ctx context.Context, actorID, id int64, updateData *models.Task) (*models.Task, error) {
+	existingTask, err := s.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	wasOpen := isOpenTask(existingTask.Status)
+	previous := *existingTask
+
 	// Update fields if they are provided in the request
 	existingTask.AssigneeID = updateData.AssigneeID
 	existingTask.Title = updateData.Title
 	existingTask.Description = updateData.Description
 	existingTask.DueDate = updateData.DueDate
 	existingTask.Status = updateData.Status
+	existingTask.Recurrence = updateData.Recurrence
 	existingTask.UpdatedAt = time.Now()
+	if err := prepareRecurrence(existingTask); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.Update(ctx, existingTask); err != nil {
+	if err := s.repo.Update(ctx, existingTask, taskChanges(&previous, existingTask, actorID)); err != nil {
 		return nil, err
 	}
+
+	// Closing an occurrence of a recurring task opens the next one
+	if wasOpen && !isOpenTask(existingTask.Status) && existingTask.Recurrence != "" {
+		if err := s.createNextOccurrence(ctx, existingTask); err != nil {
+			log.Printf("Next occurrence of task %d: %v", existingTask.ID, err)
+		}
+	}
+	if previous.Status != models.StatusDone && existingTask.Status == models.StatusDone {
+		s.recordCompleted(ctx, existingTask, actorID)
+	}
 	return existingTask, nil
 }
 
+// recordCompleted adds the completed task to the timeline of the lead, deal or document it is about.
+func (s *taskService) recordCompleted(ctx context.Context, task *models.Task, actorID int64) {
+	switch task.EntityType {
+	case models.EntityTypeLead, models.EntityTypeDeal, models.EntityTypeDocument:
+	default:
+		return
+	}
+	recordActivity(ctx, s.activities, &models.Activity{
+		Type:       models.ActivityTaskCompleted,
+		EntityType: task.EntityType,
+		EntityID:   task.EntityID,
+		ActorID:    actorID,
+		Summary:    fmt.Sprintf("Task #%d completed: %s", task.ID, task.Title),
+		Details:    map[string]string{"task_id": strconv.FormatInt(task.ID, 10)},
+	})
+}
+
+// taskChanges lists the changes of the tracked fields for the activity log.
+func taskChanges(before, after *models.Task, actorID int64) []models.TaskActivity {
+	var changes []models.TaskActivity
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, models.TaskActivity{
+				TaskID:    after.ID,
+				ActorID:   actorID,
+				Field:     field,
+				OldValue:  oldValue,
+				NewValue:  newValue,
+				CreatedAt: after.UpdatedAt,
+			})
+		}
+	}
+	add(models.TaskFieldStatus, string(before.Status), string(after.Status))
+	add(models.TaskFieldAssignee, strconv.FormatInt(before.AssigneeID, 10), strconv.FormatInt(after.AssigneeID, 10))
+	add(models.TaskFieldDueDate, formatDueDate(before.DueDate), formatDueDate(after.DueDate))
+	return changes
+}
+
+func formatDueDate(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// createNextOccurrence stores the next task of the series. Occurrences missed while the task
+// stayed open are skipped, so the next one is due after now. Nothing is created if the series
+// already has a later open task, e.g. when a closed occurrence is reopened and closed again.
+func (s *taskService) createNextOccurrence(ctx context.Context, task *models.Task) error {
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return err
+	}
+	seriesID := task.ID
+	if task.SeriesID != nil {
+		seriesID = *task.SeriesID
+	}
+	pending, err := s.repo.HasOpenOccurrence(ctx, seriesID, *task.DueDate)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return nil
+	}
+	after := time.Now()
+	if task.DueDate.After(after) {
+		after = *task.DueDate
+	}
+	due, ok := rule.Next(*task.SeriesStart, after)
+	if !ok {
+		return nil // the series is over
+	}
+
+	next := &models.Task{
+		CreatorID:   task.CreatorID,
+		AssigneeID:  task.AssigneeID,
+		EntityID:    task.EntityID,
+		EntityType:  task.EntityType,
+		Title:       task.Title,
+		Description: task.Description,
+		DueDate:     &due,
+		Status:      models.StatusNew,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Recurrence:  task.Recurrence,
+		SeriesID:    &seriesID,
+		SeriesStart: task.SeriesStart,
+	}
+	return s.repo.Store(ctx, next)
+}
+
+// prepareRecurrence validates the rule, stores it in canonical form and fixes the series
+// start at the first due date.
+func prepareRecurrence(task *models.Task) error {
+	if task.Recurrence == "" {
+		task.SeriesStart = nil
+		return nil
+	}
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if task.DueDate == nil || task.DueDate.IsZero() {
+		return ErrRecurrenceNoDue
+	}
+	task.Recurrence = rule.String()
+	if task.SeriesStart == nil {
+		start := *task.DueDate
+		task.SeriesStart = &start
+	}
+	return nil
+}
+
+func isOpenTask(status models.TaskStatus) bool {
+	return status == models.StatusNew || status == models.StatusInProgress
+}
+
 func (s *taskService) Delete(ctx context.Context, id int64) error {
 	return s.repo.Delete(ctx, id)
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// fakeTaskRepo хранит задачи в памяти для проверок TaskService.
+type fakeTaskRepo struct {
+	repositories.TaskRepository
+	tasks []*models.Task
+}
+
+func (f *fakeTaskRepo) Store(ctx context.Context, task *models.Task) error {
+	task.ID = int64(len(f.tasks) + 1)
+	copied := *task
+	f.tasks = append(f.tasks, &copied)
+	return nil
+}
+
+func (f *fakeTaskRepo) FindByID(ctx context.Context, id int64) (*models.Task, error) {
+	if id < 1 || int(id) > len(f.tasks) {
+		return nil, nil
+	}
+	copied := *f.tasks[id-1]
+	return &copied, nil
+}
+
+func (f *fakeTaskRepo) Update(ctx context.Context, task *models.Task, activity []models.TaskActivity) error {
+	copied := *task
+	f.tasks[task.ID-1] = &copied
+	return nil
+}
+
+func (f *fakeTaskRepo) HasOpenOccurrence(ctx context.Context, seriesID int64, dueAfter time.Time) (bool, error) {
+	for _, task := range f.tasks {
+		inSeries := task.ID == seriesID || task.SeriesID != nil && *task.SeriesID == seriesID
+		if inSeries && isOpenTask(task.Status) && task.DueDate.After(dueAfter) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestTaskReopenDoesNotDuplicateNextOccurrence(t *testing.T) {
+	repo := &fakeTaskRepo{}
+	s := NewTaskService(repo, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	due := time.Now().Add(time.Hour).Truncate(time.Second)
+	task, err := s.Create(ctx, &models.Task{Title: "report", DueDate: &due, Recurrence: "FREQ=WEEKLY"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	setStatus := func(status models.TaskStatus) {
+		t.Helper()
+		update := *task
+		update.Status = status
+		if _, err := s.Update(ctx, 1, task.ID, &update); err != nil {
+			t.Fatalf("Update to %s: %v", status, err)
+		}
+	}
+	setStatus(models.StatusDone)
+	if len(repo.tasks) != 2 {
+		t.Fatalf("got %d tasks after closing, want the next occurrence", len(repo.tasks))
+	}
+
+	// Повторное закрытие не создаёт ещё одно повторение, пока следующее открыто.
+	setStatus(models.StatusNew)
+	setStatus(models.StatusDone)
+	if len(repo.tasks) != 2 {
+		t.Fatalf("got %d tasks after reopening and closing, want 2", len(repo.tasks))
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+var (
+	ErrTaskTemplateNotFound   = errors.New("task template not found")
+	ErrInvalidTaskTemplate    = errors.New("a task template needs a name, a deal status and titled items")
+	ErrTemplateDealNotFound   = errors.New("deal not found")
+	ErrNoDepartureDate        = errors.New("the deal has no departure date")
+	ErrTemplateAlreadyApplied = errors.New("the template was already applied to the deal")
+)
+
+// TaskTemplateService manages task templates and creates their tasks for deals.
+type TaskTemplateService interface {
+	Create(ctx context.Context, t *models.TaskTemplate) error
+	Update(ctx context.Context, t *models.TaskTemplate) error
+	GetByID(ctx context.Context, id int64) (*models.TaskTemplate, error)
+	List(ctx context.Context, dealStatus string) ([]models.TaskTemplate, error)
+	Delete(ctx context.Context, id int64) error
+	// Apply creates the template's tasks for the deal, due relative to its departure date.
+	Apply(ctx context.Context, templateID, dealID int64) ([]*models.Task, error)
+	// OnDealStage applies the active templates of the deal's current status. A deal without
+	// a departure date gets them once the date is set.
+	OnDealStage(ctx context.Context, deal *models.Deals)
+}
+
+type taskTemplateService struct {
+	repo  repositories.TaskTemplateRepository
+	deals *repositories.DealRepository
+	leads *repositories.LeadRepository
+	now   func() time.Time
+}
+
+// NewTaskTemplateService creates a new instance of TaskTemplateService.
+func NewTaskTemplateService(
+	repo repositories.TaskTemplateRepository,
+	deals *repositories.DealRepository,
+	leads *repositories.LeadRepository,
+) TaskTemplateService {
+	return &taskTemplateService{repo: repo, deals: deals, leads: leads, now: time.Now}
+}
+
+func (s *taskTemplateService) Create(ctx context.Context, t *models.TaskTemplate) error {
+	if err := validateTaskTemplate(t); err != nil {
+		return err
+	}
+	t.CreatedAt = s.now()
+	return s.repo.Create(ctx, t)
+}
+
+func (s *taskTemplateService) Update(ctx context.Context, t *models.TaskTemplate) error {
+	existing, err := s.GetByID(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateTaskTemplate(t); err != nil {
+		return err
+	}
+	t.CreatedBy = existing.CreatedBy
+	t.CreatedAt = existing.CreatedAt
+	return s.repo.Update(ctx, t)
+}
+
+func validateTaskTemplate(t *models.TaskTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.DealStatus = strings.TrimSpace(t.DealStatus)
+	if t.Name == "" || t.DealStatus == "" || len(t.Items) == 0 {
+		return ErrInvalidTaskTemplate
+	}
+	for i := range t.Items {
+		t.Items[i].Title = strings.TrimSpace(t.Items[i].Title)
+		if t.Items[i].Title == "" {
+			return ErrInvalidTaskTemplate
+		}
+	}
+	return nil
+}
+
+func (s *taskTemplateService) GetByID(ctx context.Context, id int64) (*models.TaskTemplate, error) {
+	t, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTaskTemplateNotFound
+	}
+	return t, nil
+}
+
+func (s *taskTemplateService) List(ctx context.Context, dealStatus string) ([]models.TaskTemplate, error) {
+	return s.repo.List(ctx, dealStatus, false)
+}
+
+func (s *taskTemplateService) Delete(ctx context.Context, id int64) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *taskTemplateService) Apply(ctx context.Context, templateID, dealID int64) ([]*models.Task, error) {
+	t, err := s.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	deal, err := s.deals.GetByID(int(dealID))
+	if err != nil {
+		return nil, err
+	}
+	if deal == nil {
+		return nil, ErrTemplateDealNotFound
+	}
+	return s.apply(ctx, t, deal)
+}
+
+func (s *taskTemplateService) OnDealStage(ctx context.Context, deal *models.Deals) {
+	templates, err := s.repo.List(ctx, deal.Status, true)
+	if err != nil {
+		log.Printf("Task templates for deal %d: %v", deal.ID, err)
+		return
+	}
+	if len(templates) == 0 {
+		return
+	}
+	if deal.DepartureDate == nil {
+		log.Printf("Task templates for deal %d (%s) postponed until the departure date is set", deal.ID, deal.Status)
+		return
+	}
+	for i := range templates {
+		_, err := s.apply(ctx, &templates[i], deal)
+		if err != nil && !errors.Is(err, ErrTemplateAlreadyApplied) {
+			log.Printf("Apply task template %d to deal %d: %v", templates[i].ID, deal.ID, err)
+		}
+	}
+}
+
+// apply creates the tasks of t for the deal. Tasks without an assignee go to the lead owner,
+// who is also the creator if the template has none.
+func (s *taskTemplateService) apply(ctx context.Context, t *models.TaskTemplate, deal *models.Deals) ([]*models.Task, error) {
+	if deal.DepartureDate == nil {
+		return nil, ErrNoDepartureDate
+	}
+	var ownerID int64
+	lead, err := s.leads.GetByID(deal.LeadID)
+	if err != nil {
+		return nil, err
+	}
+	if lead != nil {
+		ownerID = int64(lead.OwnerID)
+	}
+	creatorID := t.CreatedBy
+	if creatorID == 0 {
+		creatorID = ownerID
+	}
+
+	now := s.now()
+	tasks := make([]*models.Task, 0, len(t.Items))
+	for _, item := range t.Items {
+		assigneeID := ownerID
+		if item.AssigneeID != nil {
+			assigneeID = *item.AssigneeID
+		}
+		due := deal.DepartureDate.AddDate(0, 0, item.OffsetDays)
+		tasks = append(tasks, &models.Task{
+			CreatorID:   creatorID,
+			AssigneeID:  assigneeID,
+			EntityID:    int64(deal.ID),
+			EntityType:  models.EntityTypeDeal,
+			Title:       item.Title,
+			Description: item.Description,
+			DueDate:     &due,
+			Status:      models.StatusNew,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	created, err := s.repo.CreateTasks(ctx, t.ID, int64(deal.ID), tasks)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrTemplateAlreadyApplied
+	}
+	return tasks, nil
+}
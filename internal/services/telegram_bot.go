@@ -1,31 +1,60 @@
-// internal/services/telegram_bot.go
-
 package services
 
 import (
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"context"
 	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// The service no longer holds a reference to the handlers
+// telegramAllowedUpdates are the update types the bot handles.
+var telegramAllowedUpdates = []string{"message", "callback_query"}
+
+// TelegramBotService connects to the Telegram Bot API.
 type TelegramBotService struct {
-	Bot *tgbotapi.BotAPI // <-- Make the Bot field public
+	Bot *tgbotapi.BotAPI
 }
 
-// The handlers are no longer needed here
-func NewTelegramBotService(token string) (*TelegramBotService, error) {
+// NewTelegramBotService authorizes the bot with the token.
+func NewTelegramBotService(token string, debug bool) (*TelegramBotService, error) {
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
-	bot.Debug = true // Optional: for debugging
+	bot.Debug = debug
 	return &TelegramBotService{Bot: bot}, nil
 }
 
-// This function now returns the channel of updates for main() to process.
-func (s *TelegramBotService) GetUpdatesChannel() tgbotapi.UpdatesChannel {
+// GetUpdatesChannel starts long polling. The channel is closed after ctx is cancelled.
+func (s *TelegramBotService) GetUpdatesChannel(ctx context.Context) tgbotapi.UpdatesChannel {
 	log.Printf("Authorized on account %s", s.Bot.Self.UserName)
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	return s.Bot.GetUpdatesChan(u)
+	u.AllowedUpdates = telegramAllowedUpdates
+	updates := s.Bot.GetUpdatesChan(u)
+
+	go func() {
+		<-ctx.Done()
+		s.Bot.StopReceivingUpdates()
+	}()
+	return updates
+}
+
+// SetWebhook makes Telegram POST updates to webhookURL with secretToken in the
+// X-Telegram-Bot-Api-Secret-Token header.
+func (s *TelegramBotService) SetWebhook(webhookURL, secretToken string, maxConnections int) error {
+	params := tgbotapi.Params{"url": webhookURL}
+	params.AddNonEmpty("secret_token", secretToken)
+	params.AddNonZero("max_connections", maxConnections)
+	if err := params.AddInterface("allowed_updates", telegramAllowedUpdates); err != nil {
+		return err
+	}
+	_, err := s.Bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+// DeleteWebhook switches the bot back to long polling; getUpdates fails while a webhook is set.
+func (s *TelegramBotService) DeleteWebhook() error {
+	_, err := s.Bot.Request(tgbotapi.DeleteWebhookConfig{})
+	return err
 }
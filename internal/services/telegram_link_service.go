@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+// TelegramLinkService defines the interface for binding Telegram chats to CRM users.
+type TelegramLinkService interface {
+	GenerateCode(ctx context.Context, userID int64) (*models.TelegramLinkCode, error)
+	Link(ctx context.Context, code string, chatID int64, username string) (*models.TelegramLink, error)
+	GetLink(ctx context.Context, userID int64) (*models.TelegramLink, error)
+	FindByChat(ctx context.Context, chatID int64) (*models.TelegramLink, error)
+	Unlink(ctx context.Context, userID int64) error
+	UnlinkChat(ctx context.Context, chatID int64) error
+}
+
+var (
+	ErrInvalidLinkCode   = errors.New("link code is invalid or expired")
+	ErrTelegramNotLinked = errors.New("telegram is not linked")
+)
+
+const (
+	linkCodeAlphabet      = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // without look-alike characters
+	linkCodeLength        = 8
+	defaultLinkCodeTTL    = 10 * time.Minute
+	linkCodeDeepLinkStart = "link_"
+)
+
+type telegramLinkService struct {
+	repo        repositories.TelegramLinkRepository
+	codeTTL     time.Duration
+	botUsername string
+	now         func() time.Time
+}
+
+// NewTelegramLinkService creates a new instance of TelegramLinkService. With botUsername set,
+// generated codes also come with a t.me deep link that links the chat in one tap.
+func NewTelegramLinkService(repo repositories.TelegramLinkRepository, codeTTL time.Duration, botUsername string) TelegramLinkService {
+	if codeTTL <= 0 {
+		codeTTL = defaultLinkCodeTTL
+	}
+	return &telegramLinkService{
+		repo:        repo,
+		codeTTL:     codeTTL,
+		botUsername: strings.TrimPrefix(botUsername, "@"),
+		now:         time.Now,
+	}
+}
+
+// GenerateCode issues a one-time code; the user's previous unused codes stop working.
+func (s *telegramLinkService) GenerateCode(ctx context.Context, userID int64) (*models.TelegramLinkCode, error) {
+	code := make([]byte, linkCodeLength)
+	random := make([]byte, linkCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	for i, b := range random {
+		code[i] = linkCodeAlphabet[int(b)%len(linkCodeAlphabet)]
+	}
+
+	result := &models.TelegramLinkCode{
+		Code:      string(code),
+		Command:   "/link " + string(code),
+		ExpiresAt: s.now().Add(s.codeTTL),
+	}
+	if s.botUsername != "" {
+		result.URL = "https://t.me/" + s.botUsername + "?start=" + linkCodeDeepLinkStart + result.Code
+	}
+
+	if err := s.repo.CreateCode(ctx, userID, hashLinkCode(result.Code), result.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Link binds the chat to the user who generated the code.
+func (s *telegramLinkService) Link(ctx context.Context, code string, chatID int64, username string) (*models.TelegramLink, error) {
+	code = strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(code, linkCodeDeepLinkStart)))
+	if len(code) != linkCodeLength {
+		return nil, ErrInvalidLinkCode
+	}
+	link, err := s.repo.LinkByCode(ctx, hashLinkCode(code), chatID, username, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrInvalidLinkCode
+	}
+	return link, nil
+}
+
+func (s *telegramLinkService) GetLink(ctx context.Context, userID int64) (*models.TelegramLink, error) {
+	link, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrTelegramNotLinked
+	}
+	return link, nil
+}
+
+// FindByChat returns the link of the chat; nil if the chat is not linked.
+func (s *telegramLinkService) FindByChat(ctx context.Context, chatID int64) (*models.TelegramLink, error) {
+	return s.repo.FindByChat(ctx, chatID)
+}
+
+func (s *telegramLinkService) Unlink(ctx context.Context, userID int64) error {
+	return s.repo.DeleteByUser(ctx, userID)
+}
+
+func (s *telegramLinkService) UnlinkChat(ctx context.Context, chatID int64) error {
+	return s.repo.DeleteByChat(ctx, chatID)
+}
+
+// IsLinkCodeStart reports whether a /start payload carries a link code from a deep link.
+func IsLinkCodeStart(payload string) bool {
+	return strings.HasPrefix(payload, linkCodeDeepLinkStart)
+}
+
+func hashLinkCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
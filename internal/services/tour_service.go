@@ -1,20 +1,63 @@
-// in internal/services/tour_service.go
 package services
 
-// TourService will handle the business logic for tours.
-// Later, you might add a database repository here.
+import (
+	"context"
+	"errors"
+	"time"
+	"turcompany/internal/models"
+	"turcompany/internal/repositories"
+)
+
+var (
+	ErrTourNotFound      = errors.New("tour not found")
+	ErrDepartureNotFound = errors.New("departure not found")
+)
+
+// TourService provides the tour catalogue shown to customers.
 type TourService struct {
-	// For example: tourRepo repositories.TourRepository
+	repo repositories.TourRepository
+	now  func() time.Time
+}
+
+// NewTourService creates a new TourService.
+func NewTourService(repo repositories.TourRepository) *TourService {
+	return &TourService{repo: repo, now: time.Now}
+}
+
+// ListTours returns the tours open for booking.
+func (s *TourService) ListTours(ctx context.Context) ([]models.Tour, error) {
+	return s.repo.FindActive(ctx)
+}
+
+// GetTour returns an active tour.
+func (s *TourService) GetTour(ctx context.Context, id int64) (*models.Tour, error) {
+	tour, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tour == nil || !tour.Active {
+		return nil, ErrTourNotFound
+	}
+	return tour, nil
 }
 
-// NewTourService is the constructor for our tour service.
-func NewTourService() *TourService {
-	return &TourService{}
+// ListDepartures returns the upcoming departures of the tour.
+func (s *TourService) ListDepartures(ctx context.Context, tourID int64) ([]models.TourDeparture, error) {
+	return s.repo.FindDepartures(ctx, tourID, s.now())
 }
 
-// GetAvailableTours is a placeholder method.
-// Later, this will fetch tours from your database.
-func (s *TourService) GetAvailableTours() []string {
-	// For now, we just return some sample data.
-	return []string{"1. Mountain Adventure", "2. City Exploration", "3. Beach Holiday"}
+// GetDeparture returns an upcoming departure together with its tour.
+func (s *TourService) GetDeparture(ctx context.Context, id int64) (*models.TourDeparture, *models.Tour, error) {
+	departure, err := s.repo.FindDeparture(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if departure == nil || !departure.DepartsAt.After(s.now()) {
+		return nil, nil, ErrDepartureNotFound
+	}
+	tour, err := s.GetTour(ctx, departure.TourID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return departure, tour, nil
 }
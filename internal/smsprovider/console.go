@@ -0,0 +1,65 @@
+package smsprovider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Console провайдер для разработки: вместо отправки печатает SMS
+// в stdout или дописывает в файл. Все сообщения считаются доставленными.
+type Console struct {
+	mu     sync.Mutex
+	out    io.Writer
+	path   string
+	nextID atomic.Int64
+}
+
+// NewConsole создаёт консольный провайдер. Если path пуст, сообщения
+// печатаются в stdout, иначе дописываются в файл path.
+func NewConsole(path string) *Console {
+	return &Console{out: os.Stdout, path: path}
+}
+
+func (c *Console) Name() string {
+	return "console"
+}
+
+func (c *Console) Send(ctx context.Context, msg Message) (*SendResult, error) {
+	id := fmt.Sprintf("console-%d-%d", time.Now().Unix(), c.nextID.Add(1))
+	line := fmt.Sprintf("%s [%s] -> %s: %s\n", time.Now().Format(time.RFC3339), id, msg.To, msg.Text)
+
+	if err := c.write(line); err != nil {
+		return nil, err
+	}
+	var cost float64
+	return &SendResult{Provider: c.Name(), MessageID: id, Status: StatusDelivered, Cost: &cost}, nil
+}
+
+func (c *Console) Status(ctx context.Context, messageID string) (*StatusResult, error) {
+	var cost float64
+	return &StatusResult{Status: StatusDelivered, Cost: &cost}, nil
+}
+
+func (c *Console) write(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" {
+		_, err := io.WriteString(c.out, line)
+		return err
+	}
+
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("console sms: %w", err)
+	}
+	defer f.Close()
+
+	_, err = io.WriteString(f, line)
+	return err
+}
@@ -0,0 +1,59 @@
+package smsprovider
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Failover отправляет через основного провайдера и, если тот недоступен
+// (ErrUnavailable), повторяет отправку через резервного. Ошибки API (например,
+// неверный номер) и таймауты возвращаются как есть: в первом случае резервный
+// провайдер откажет так же, во втором сообщение могло уже уйти.
+type Failover struct {
+	primary   Provider
+	secondary Provider
+}
+
+func NewFailover(primary, secondary Provider) *Failover {
+	return &Failover{primary: primary, secondary: secondary}
+}
+
+func (f *Failover) Name() string {
+	return f.primary.Name() + "+" + f.secondary.Name()
+}
+
+func (f *Failover) Send(ctx context.Context, msg Message) (*SendResult, error) {
+	result, err := f.primary.Send(ctx, msg)
+	if err == nil || !errors.Is(err, ErrUnavailable) {
+		return result, err
+	}
+
+	log.Printf("SMS через %s не отправлено (%v), пробуем %s", f.primary.Name(), err, f.secondary.Name())
+	return f.secondary.Send(ctx, msg)
+}
+
+// Status опрашивает провайдеров по очереди; ID сообщения известен только
+// тому провайдеру, который его отправил.
+func (f *Failover) Status(ctx context.Context, messageID string) (*StatusResult, error) {
+	status, err := f.primary.Status(ctx, messageID)
+	if err == nil {
+		return status, nil
+	}
+	return f.secondary.Status(ctx, messageID)
+}
+
+// Lookup возвращает провайдера по имени из SendResult.Provider.
+func (f *Failover) Lookup(name string) Provider {
+	for _, p := range []Provider{f.primary, f.secondary} {
+		if p.Name() == name {
+			return p
+		}
+		if nested, ok := p.(*Failover); ok {
+			if found := nested.Lookup(name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package smsprovider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"turcompany/internal/smsprovider/mobizontest"
+)
+
+func newTestFailover(server *mobizontest.Server, timeout time.Duration) (*Failover, *bytes.Buffer) {
+	var out bytes.Buffer
+	console := NewConsole("")
+	console.out = &out
+	return NewFailover(NewMobizon(server.URL, testAPIKey, "", timeout), console), &out
+}
+
+func TestFailoverOnServerError(t *testing.T) {
+	server := mobizontest.NewServer(testAPIKey)
+	defer server.Close()
+	server.FailWithHTTPStatus(http.StatusServiceUnavailable)
+
+	failover, out := newTestFailover(server, time.Second)
+	result, err := failover.Send(context.Background(), Message{To: "77010000001", Text: "x"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if result.Provider != "console" || out.Len() == 0 {
+		t.Fatalf("message was not sent through the fallback: %+v", result)
+	}
+}
+
+func TestFailoverKeepsPrimaryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*mobizontest.Server)
+		want  error
+	}{
+		{name: "validation", setup: func(s *mobizontest.Server) { s.FailWithCode(MobizonCodeValidationError) }},
+		{name: "timeout", setup: func(s *mobizontest.Server) { s.Delay(300 * time.Millisecond) }, want: ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mobizontest.NewServer(testAPIKey)
+			defer server.Close()
+			tt.setup(server)
+
+			failover, out := newTestFailover(server, 50*time.Millisecond)
+			_, err := failover.Send(context.Background(), Message{To: "77010000001", Text: "x"})
+			if err == nil {
+				t.Fatal("Send succeeded, want the primary error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if out.Len() != 0 {
+				t.Fatalf("fallback was used: %q", out.String())
+			}
+		})
+	}
+}
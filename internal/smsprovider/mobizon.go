@@ -0,0 +1,203 @@
+package smsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMobizonURL     = "https://api.mobizon.kz"
+	defaultMobizonTimeout = 10 * time.Second
+)
+
+// Mobizon коды ответа API, на которые стоит реагировать отдельно.
+const (
+	MobizonCodeOK              = 0
+	MobizonCodeValidationError = 1
+	MobizonCodeNotFound        = 2
+	MobizonCodeAccessDenied    = 8
+)
+
+// Mobizon провайдер SMS на базе API mobizon.kz.
+type Mobizon struct {
+	apiURL string
+	apiKey string
+	sender string
+	client *http.Client
+}
+
+// NewMobizon создаёт клиента Mobizon. Пустой apiURL означает боевой адрес,
+// sender — подпись отправителя (должна быть зарегистрирована в Mobizon).
+func NewMobizon(apiURL, apiKey, sender string, timeout time.Duration) *Mobizon {
+	if apiURL == "" {
+		apiURL = DefaultMobizonURL
+	}
+	if timeout <= 0 {
+		timeout = defaultMobizonTimeout
+	}
+	return &Mobizon{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		apiKey: apiKey,
+		sender: sender,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (m *Mobizon) Name() string {
+	return "mobizon"
+}
+
+func (m *Mobizon) Send(ctx context.Context, msg Message) (*SendResult, error) {
+	form := url.Values{
+		"recipient": {msg.To},
+		"text":      {msg.Text},
+	}
+	if m.sender != "" {
+		form.Set("from", m.sender)
+	}
+
+	var data struct {
+		CampaignID json.Number `json:"campaignId"`
+		MessageID  json.Number `json:"messageId"`
+		Cost       json.Number `json:"cost"`
+	}
+	if err := m.call(ctx, "message/sendsmsmessage", form, &data); err != nil {
+		return nil, err
+	}
+
+	return &SendResult{
+		Provider:  m.Name(),
+		MessageID: data.MessageID.String(),
+		Status:    StatusQueued,
+		Cost:      mobizonCost(data.Cost),
+	}, nil
+}
+
+func (m *Mobizon) Status(ctx context.Context, messageID string) (*StatusResult, error) {
+	form := url.Values{"ids[]": {messageID}}
+
+	var data []struct {
+		ID     json.Number `json:"id"`
+		Status string      `json:"status"`
+		Cost   json.Number `json:"cost"`
+	}
+	if err := m.call(ctx, "message/getsmsstatus", form, &data); err != nil {
+		return nil, err
+	}
+
+	for _, item := range data {
+		if item.ID.String() == messageID {
+			return &StatusResult{Status: mobizonStatus(item.Status), Cost: mobizonCost(item.Cost)}, nil
+		}
+	}
+	return nil, &APIError{Provider: m.Name(), Code: MobizonCodeNotFound, Message: "сообщение не найдено"}
+}
+
+// call выполняет метод API Mobizon и раскладывает поле data ответа в out.
+func (m *Mobizon) call(ctx context.Context, method string, form url.Values, out interface{}) error {
+	query := url.Values{
+		"output": {"json"},
+		"api":    {"v1"},
+		"apiKey": {m.apiKey},
+	}
+	endpoint := fmt.Sprintf("%s/service/%s?%s", m.apiURL, method, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("mobizon request: %v", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return fmt.Errorf("%w: mobizon %s: %v", ErrTimeout, method, withoutURL(err))
+		}
+		return fmt.Errorf("%w: mobizon %s: %v", ErrUnavailable, method, withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: mobizon: HTTP %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	// Запрос уже принят сервером, поэтому обрыв при чтении ответа считается таймаутом.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: mobizon: read response: %v", ErrTimeout, err)
+	}
+
+	var envelope struct {
+		Code    int             `json:"code"`
+		Data    json.RawMessage `json:"data"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("mobizon: parse response: %w", err)
+	}
+	if envelope.Code != MobizonCodeOK {
+		return &APIError{Provider: m.Name(), Code: envelope.Code, Message: envelope.Message}
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("mobizon: parse data: %w", err)
+		}
+	}
+	return nil
+}
+
+// mobizonCost разбирает стоимость из ответа; nil, если Mobizon её не вернул.
+func mobizonCost(cost json.Number) *float64 {
+	if cost == "" {
+		return nil
+	}
+	value, err := cost.Float64()
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// isTimeout сообщает, что запрос оборвался по таймауту клиента или отмене контекста.
+// withoutURL убирает адрес запроса из ошибки http.Client: в нём передаётся apiKey,
+// а текст ошибки попадает в журнал SMS и ответы API.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// mobizonStatus переводит статус Mobizon (SMPP-статусы) в DeliveryStatus.
+func mobizonStatus(status string) DeliveryStatus {
+	switch strings.ToUpper(status) {
+	case "NEW", "ENQUEUD":
+		return StatusQueued
+	case "ACCEPTD":
+		return StatusSent
+	case "DELIVRD", "PDLIVRD":
+		return StatusDelivered
+	case "UNDELIV", "REJECTD", "EXPIRED", "DELETED":
+		return StatusFailed
+	default:
+		return StatusUnknown
+	}
+}
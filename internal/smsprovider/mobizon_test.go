@@ -0,0 +1,156 @@
+package smsprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"turcompany/internal/smsprovider/mobizontest"
+)
+
+const testAPIKey = "test-key"
+
+func TestMobizonSend(t *testing.T) {
+	server := mobizontest.NewServer(testAPIKey)
+	defer server.Close()
+
+	m := NewMobizon(server.URL, testAPIKey, "TURCOMPANY", time.Second)
+	result, err := m.Send(context.Background(), Message{To: "77010000001", Text: "Код подтверждения: 123456"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if result.Provider != "mobizon" || result.MessageID != "1" || result.Status != StatusQueued {
+		t.Fatalf("Send result = %+v", result)
+	}
+
+	sent := server.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("server got %d messages, want 1", len(sent))
+	}
+	want := mobizontest.SentMessage{ID: "1", Recipient: "77010000001", Text: "Код подтверждения: 123456", From: "TURCOMPANY"}
+	if sent[0] != want {
+		t.Fatalf("server got %+v, want %+v", sent[0], want)
+	}
+}
+
+func TestMobizonErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiKey      string
+		setup       func(*mobizontest.Server)
+		code        int
+		unavailable bool
+	}{
+		{name: "wrong key", apiKey: "other-key", code: MobizonCodeAccessDenied},
+		{name: "validation", apiKey: testAPIKey, setup: func(s *mobizontest.Server) { s.FailWithCode(MobizonCodeValidationError) }, code: MobizonCodeValidationError},
+		{name: "server error", apiKey: testAPIKey, setup: func(s *mobizontest.Server) { s.FailWithHTTPStatus(http.StatusBadGateway) }, unavailable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mobizontest.NewServer(testAPIKey)
+			defer server.Close()
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+
+			_, err := NewMobizon(server.URL, tt.apiKey, "", time.Second).Send(context.Background(), Message{To: "77010000001", Text: "x"})
+			if got := errors.Is(err, ErrUnavailable); got != tt.unavailable {
+				t.Fatalf("errors.Is(%v, ErrUnavailable) = %v, want %v", err, got, tt.unavailable)
+			}
+			var apiErr *APIError
+			if tt.code != 0 && (!errors.As(err, &apiErr) || apiErr.Code != tt.code) {
+				t.Fatalf("err = %v, want APIError with code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestMobizonUnreachable(t *testing.T) {
+	server := mobizontest.NewServer(testAPIKey)
+	url := server.URL
+	server.Close()
+
+	_, err := NewMobizon(url, testAPIKey, "", time.Second).Send(context.Background(), Message{To: "77010000001", Text: "x"})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestMobizonTimeout(t *testing.T) {
+	server := mobizontest.NewServer(testAPIKey)
+	defer server.Close()
+	server.Delay(300 * time.Millisecond)
+
+	_, err := NewMobizon(server.URL, testAPIKey, "", 50*time.Millisecond).Send(context.Background(), Message{To: "77010000001", Text: "x"})
+	if !errors.Is(err, ErrTimeout) || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+}
+
+func TestMobizonStatus(t *testing.T) {
+	server := mobizontest.NewServer(testAPIKey)
+	defer server.Close()
+
+	m := NewMobizon(server.URL, testAPIKey, "", time.Second)
+	result, err := m.Send(context.Background(), Message{To: "77010000001", Text: "x"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	for _, step := range []struct {
+		mobizon string
+		want    DeliveryStatus
+	}{
+		{"NEW", StatusQueued},
+		{"ACCEPTD", StatusSent},
+		{"DELIVRD", StatusDelivered},
+		{"UNDELIV", StatusFailed},
+	} {
+		server.SetStatus(result.MessageID, step.mobizon)
+		status, err := m.Status(context.Background(), result.MessageID)
+		if err != nil || status.Status != step.want {
+			t.Fatalf("Status after %s = %+v, %v; want %q", step.mobizon, status, err, step.want)
+		}
+		if status.Cost != nil {
+			t.Fatalf("Status after %s: cost = %v before it was charged", step.mobizon, *status.Cost)
+		}
+	}
+
+	server.SetCost(result.MessageID, 12.5)
+	status, err := m.Status(context.Background(), result.MessageID)
+	if err != nil || status.Cost == nil || *status.Cost != 12.5 {
+		t.Fatalf("Status after charge = %+v, %v; want cost 12.5", status, err)
+	}
+
+	_, err = m.Status(context.Background(), "404")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != MobizonCodeNotFound {
+		t.Fatalf("Status of unknown message: err = %v, want APIError with code %d", err, MobizonCodeNotFound)
+	}
+}
+
+func TestMobizonErrorsHideAPIKey(t *testing.T) {
+	const secret = "secret-api-key-0001"
+	slow := mobizontest.NewServer(secret)
+	defer slow.Close()
+	slow.Delay(300 * time.Millisecond)
+
+	closed := mobizontest.NewServer(secret)
+	closedURL := closed.URL
+	closed.Close()
+
+	for name, url := range map[string]string{"timeout": slow.URL, "unreachable": closedURL} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMobizon(url, secret, "", 50*time.Millisecond).Send(context.Background(), Message{To: "77010000001", Text: "x"})
+			if err == nil {
+				t.Fatal("Send succeeded")
+			}
+			if strings.Contains(err.Error(), secret) {
+				t.Fatalf("error reveals the API key: %v", err)
+			}
+		})
+	}
+}
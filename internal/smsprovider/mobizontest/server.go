@@ -0,0 +1,178 @@
+// Package mobizontest поднимает фейковый API Mobizon на httptest.Server,
+// чтобы проверять отправку SMS без обращения к api.mobizon.kz.
+package mobizontest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SentMessage сообщение, принятое фейковым сервером.
+type SentMessage struct {
+	ID        string
+	Recipient string
+	Text      string
+	From      string
+}
+
+// Server фейковый Mobizon. URL сервера передаётся в smsprovider.NewMobizon.
+type Server struct {
+	*httptest.Server
+
+	APIKey string
+
+	mu        sync.Mutex
+	sent      []SentMessage
+	statuses  map[string]string
+	costs     map[string]float64
+	errorCode int
+	httpError int
+	delay     time.Duration
+	nextID    int
+}
+
+// NewServer запускает фейковый сервер, принимающий ключ apiKey.
+// Сервер нужно остановить вызовом Close.
+func NewServer(apiKey string) *Server {
+	s := &Server{APIKey: apiKey, statuses: map[string]string{}, costs: map[string]float64{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/service/message/sendsmsmessage", s.handleSend)
+	mux.HandleFunc("/service/message/getsmsstatus", s.handleStatus)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Sent возвращает копию принятых сообщений.
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.sent...)
+}
+
+// SetStatus задаёт статус Mobizon (например, "DELIVRD") для сообщения.
+func (s *Server) SetStatus(messageID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[messageID] = status
+}
+
+// SetCost задаёт стоимость, которую сервер вернёт в статусе сообщения.
+func (s *Server) SetCost(messageID string, cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.costs[messageID] = cost
+}
+
+// FailWithCode заставляет сервер отвечать кодом ошибки API (0 — без ошибок).
+func (s *Server) FailWithCode(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorCode = code
+}
+
+// FailWithHTTPStatus заставляет сервер отвечать HTTP-ошибкой (0 — без ошибок).
+func (s *Server) FailWithHTTPStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpError = status
+}
+
+// Delay заставляет сервер отвечать с задержкой d, чтобы проверить таймауты клиента.
+func (s *Server) Delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	if !s.check(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	msg := SentMessage{
+		ID:        strconv.Itoa(s.nextID),
+		Recipient: r.PostFormValue("recipient"),
+		Text:      r.PostFormValue("text"),
+		From:      r.PostFormValue("from"),
+	}
+	s.sent = append(s.sent, msg)
+	s.statuses[msg.ID] = "NEW"
+	s.mu.Unlock()
+
+	writeJSON(w, 0, map[string]string{"campaignId": msg.ID, "messageId": msg.ID}, "")
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.check(w, r) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, 1, nil, err.Error())
+		return
+	}
+
+	type item struct {
+		ID     string   `json:"id"`
+		Status string   `json:"status"`
+		Cost   *float64 `json:"cost,omitempty"`
+	}
+	items := []item{}
+
+	s.mu.Lock()
+	for _, id := range r.PostForm["ids[]"] {
+		if status, ok := s.statuses[id]; ok {
+			it := item{ID: id, Status: status}
+			if cost, ok := s.costs[id]; ok {
+				it.Cost = &cost
+			}
+			items = append(items, it)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, 0, items, "")
+}
+
+// check проверяет ключ API и отдаёт настроенные ошибки.
+func (s *Server) check(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	errorCode, httpError, delay := s.errorCode, s.httpError, s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	if httpError != 0 {
+		w.WriteHeader(httpError)
+		return false
+	}
+	if r.URL.Query().Get("apiKey") != s.APIKey {
+		writeJSON(w, 8, nil, "Неверный ключ API")
+		return false
+	}
+	if errorCode != 0 {
+		writeJSON(w, errorCode, nil, "Ошибка, заданная в тесте")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    code,
+		"data":    data,
+		"message": message,
+	})
+}
@@ -0,0 +1,72 @@
+// Package smsprovider содержит отправщиков SMS: Mobizon, консольный провайдер
+// для разработки и обёртку, переключающуюся на резервного провайдера.
+package smsprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DeliveryStatus статус доставки сообщения, приведённый к общему виду.
+type DeliveryStatus string
+
+const (
+	StatusQueued    DeliveryStatus = "queued"
+	StatusSent      DeliveryStatus = "sent"
+	StatusDelivered DeliveryStatus = "delivered"
+	StatusFailed    DeliveryStatus = "failed"
+	StatusUnknown   DeliveryStatus = "unknown"
+)
+
+var (
+	// ErrUnavailable возвращается, когда провайдер недоступен (нет соединения, 5xx):
+	// сообщение точно не принято, и его можно отправить через другого провайдера.
+	ErrUnavailable = errors.New("sms provider unavailable")
+	// ErrTimeout возвращается, когда провайдер не ответил вовремя. Сообщение могло
+	// уйти, поэтому повторная отправка через другого провайдера не делается.
+	ErrTimeout = errors.New("sms provider timeout")
+)
+
+// Message исходящее SMS.
+type Message struct {
+	To   string
+	Text string
+}
+
+// SendResult результат успешной отправки. Cost заполняется, только если
+// провайдер сообщает стоимость сообщения.
+type SendResult struct {
+	Provider  string
+	MessageID string
+	Status    DeliveryStatus
+	Cost      *float64
+}
+
+// StatusResult результат опроса статуса. Cost заполняется, если провайдер
+// сообщил стоимость сообщения (у Mobizon она известна после тарификации).
+type StatusResult struct {
+	Status DeliveryStatus
+	Cost   *float64
+}
+
+// Provider отправляет SMS и сообщает статус их доставки.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg Message) (*SendResult, error)
+	Status(ctx context.Context, messageID string) (*StatusResult, error)
+}
+
+// APIError ошибка, которую вернул API провайдера.
+type APIError struct {
+	Provider string
+	Code     int
+	Message  string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: код ошибки %d", e.Provider, e.Code)
+	}
+	return fmt.Sprintf("%s: код ошибки %d: %s", e.Provider, e.Code, e.Message)
+}
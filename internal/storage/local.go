@@ -0,0 +1,92 @@
+// Package storage хранит файлы приложения (документы, вложения чата) на локальном диске.
+// В БД сохраняется путь вида "document_storage/<относительный путь>", который не зависит
+// от того, где на сервере расположен каталог хранилища.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix префикс путей к файлам, хранимых в БД.
+const Prefix = "document_storage"
+
+// ErrTooLarge файл превышает допустимый размер.
+var ErrTooLarge = errors.New("file exceeds size limit")
+
+// Local файловое хранилище в каталоге basePath.
+type Local struct {
+	basePath string
+}
+
+func NewLocal(basePath string) *Local {
+	return &Local{basePath: basePath}
+}
+
+// Path возвращает путь для хранения в БД и путь на диске для относительного пути rel.
+func (l *Local) Path(rel string) (storedPath, absPath string) {
+	return filepath.Join(Prefix, rel), filepath.Join(l.basePath, rel)
+}
+
+// Resolve переводит путь из БД ("document_storage/...") в путь на диске.
+// Пути вне хранилища возвращаются без изменений.
+func (l *Local) Resolve(storedPath string) string {
+	rel, err := filepath.Rel(Prefix, storedPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return storedPath
+	}
+	return filepath.Join(l.basePath, rel)
+}
+
+// Save записывает содержимое r в файл rel и возвращает путь для БД и размер файла.
+// Если maxSize > 0 и файл больше, возвращается ErrTooLarge и ничего не сохраняется.
+// Файл сначала пишется во временный, поэтому частично записанных файлов не остаётся.
+func (l *Local) Save(rel string, r io.Reader, maxSize int64) (string, int64, error) {
+	storedPath, absPath := l.Path(rel)
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return "", 0, fmt.Errorf("создание директории: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(absPath), ".upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("создание временного файла: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	src := r
+	if maxSize > 0 {
+		src = io.LimitReader(r, maxSize+1)
+	}
+	size, err := io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("запись файла: %w", err)
+	}
+	if maxSize > 0 && size > maxSize {
+		return "", 0, ErrTooLarge
+	}
+
+	if err := os.Rename(tmp.Name(), absPath); err != nil {
+		return "", 0, fmt.Errorf("сохранение файла: %w", err)
+	}
+	return storedPath, size, nil
+}
+
+// Open открывает файл по пути из БД.
+func (l *Local) Open(storedPath string) (*os.File, error) {
+	return os.Open(l.Resolve(storedPath))
+}
+
+// Remove удаляет файл по пути из БД; отсутствие файла не считается ошибкой.
+func (l *Local) Remove(storedPath string) error {
+	if err := os.Remove(l.Resolve(storedPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}